@@ -8,13 +8,21 @@ import (
 	"plugin"
 	"sync"
 
-	"github.com/coiloffaraday/python_sast/parser"
+	"github.com/coiloffaraday/python_sast/project"
+	"github.com/coiloffaraday/python_sast/reporter"
 
 	"gopkg.in/yaml.v2"
 )
 
+// Context 是规则检查一个模块时可见的内容：模块本身，以及它所在的整个项目，
+// 规则可以通过 Project 查询其他模块中的定义
+type Context struct {
+	Module  *project.Module
+	Project *project.Graph
+}
+
 type Rule interface {
-	Check(*parser.Program) []ReportItem
+	Check(ctx *Context) []reporter.ReportItem
 }
 
 type RuleConfig struct {
@@ -24,18 +32,26 @@ type RuleConfig struct {
 }
 
 type Analyzer struct {
-	rules      []Rule
-	configFile string
+	rules    []Rule
+	reporter *reporter.Reporter
 }
 
-func NewAnalyzer(configFile string) *Analyzer {
+// NewAnalyzer 创建一个新的 Analyzer，发现的问题会添加到 reporter 中
+func NewAnalyzer(reporter *reporter.Reporter, rules ...Rule) *Analyzer {
 	return &Analyzer{
-		configFile: configFile,
+		rules:    rules,
+		reporter: reporter,
 	}
 }
 
-func (a *Analyzer) LoadRules() error {
-	ruleConfigs, err := a.loadRuleConfigs()
+// AddRule 添加一条规则
+func (a *Analyzer) AddRule(rule Rule) {
+	a.rules = append(a.rules, rule)
+}
+
+// LoadRules 从配置文件中加载规则插件
+func (a *Analyzer) LoadRules(configFile string) error {
+	ruleConfigs, err := a.loadRuleConfigs(configFile)
 	if err != nil {
 		return err
 	}
@@ -51,16 +67,18 @@ func (a *Analyzer) LoadRules() error {
 	return nil
 }
 
-func (a *Analyzer) Analyze(program *parser.Program) []ReportItem {
-	reportItems := make([]ReportItem, 0)
+// Analyze 对项目中的一个模块运行所有规则，返回发现的问题并将其添加到报告中
+func (a *Analyzer) Analyze(module *project.Module, graph *project.Graph) []reporter.ReportItem {
+	ctx := &Context{Module: module, Project: graph}
+	reportItems := make([]reporter.ReportItem, 0)
 	var wg sync.WaitGroup
-	reportItemChan := make(chan []ReportItem, len(a.rules))
+	reportItemChan := make(chan []reporter.ReportItem, len(a.rules))
 
 	for _, rule := range a.rules {
 		wg.Add(1)
 		go func(rule Rule) {
 			defer wg.Done()
-			reportItemChan <- rule.Check(program)
+			reportItemChan <- rule.Check(ctx)
 		}(rule)
 	}
 
@@ -71,11 +89,26 @@ func (a *Analyzer) Analyze(program *parser.Program) []ReportItem {
 		reportItems = append(reportItems, items...)
 	}
 
+	if a.reporter != nil {
+		for _, item := range reportItems {
+			a.reporter.AddReportItem(item)
+		}
+	}
+
+	return reportItems
+}
+
+// AnalyzeProject 按模块名顺序分析项目中的所有模块
+func (a *Analyzer) AnalyzeProject(graph *project.Graph) []reporter.ReportItem {
+	reportItems := make([]reporter.ReportItem, 0)
+	for _, module := range graph.Modules() {
+		reportItems = append(reportItems, a.Analyze(module, graph)...)
+	}
 	return reportItems
 }
 
-func (a *Analyzer) loadRuleConfigs() ([]RuleConfig, error) {
-	configBytes, err := ioutil.ReadFile(a.configFile)
+func (a *Analyzer) loadRuleConfigs(configFile string) ([]RuleConfig, error) {
+	configBytes, err := ioutil.ReadFile(configFile)
 	if err != nil {
 		return nil, err
 	}
@@ -1 +1,49 @@
 package analyzer_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/analyzer"
+	"github.com/coiloffaraday/python_sast/parser"
+	"github.com/coiloffaraday/python_sast/project"
+	"github.com/coiloffaraday/python_sast/reporter"
+)
+
+// callsIntoRule reports calls to functions defined in another module of
+// the project.
+type callsIntoRule struct{}
+
+func (callsIntoRule) Check(ctx *analyzer.Context) []reporter.ReportItem {
+	var items []reporter.ReportItem
+	for _, call := range parser.FindAll[*parser.CallExpression](ctx.Module.Program) {
+		m, sym := ctx.Project.Lookup(ctx.Module.Info.CallName(call))
+		if m == nil || sym == nil || m == ctx.Module {
+			continue
+		}
+		items = append(items, reporter.ReportItem{
+			RuleID:      "CALL",
+			Description: fmt.Sprintf("calls %s.%s", m.Name, sym.Name),
+			Location:    fmt.Sprintf("%s:%d", ctx.Module.Name, call.Pos().Line),
+		})
+	}
+	return items
+}
+
+func TestAnalyzeProject(t *testing.T) {
+	g, err := project.New(t.TempDir(), project.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.AddSource(g.Dir+"/views.py", "import helpers\nhelpers.run(1)\nprint(2)\n")
+	g.AddSource(g.Dir+"/helpers.py", "def run(x):\n    return x\nrun(0)\n")
+
+	a := analyzer.NewAnalyzer(reporter.NewReporter(), callsIntoRule{})
+	items := a.AnalyzeProject(g)
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %v", items)
+	}
+	if items[0].Description != "calls helpers.run" || items[0].Location != "views:2" {
+		t.Errorf("got %+v", items[0])
+	}
+}
@@ -0,0 +1,519 @@
+package cfg
+
+import (
+	"github.com/coiloffaraday/python_sast/parser"
+)
+
+// New builds the control-flow graph of a *FunctionDef, *AsyncFunctionDef or
+// *Program. It returns nil for other nodes.
+func New(node parser.Node) *CFG {
+	var body []parser.Statement
+	switch n := node.(type) {
+	case *parser.Program:
+		body = n.Statements
+	case *parser.FunctionDef:
+		body = n.Body.Statements
+	case *parser.AsyncFunctionDef:
+		body = n.Body.Statements
+	default:
+		return nil
+	}
+
+	g := &CFG{Node: node, blockOf: map[parser.Node]*Block{}}
+	b := &builder{cfg: g}
+	g.Entry = b.newBlock(EntryBlock)
+	g.Exit = b.newBlock(ExitBlock)
+	g.Raise = b.newBlock(RaiseBlock)
+
+	b.cur = g.Entry
+	b.stmts(body)
+	b.edge(b.cur, g.Exit, Normal)
+
+	return g
+}
+
+// Functions builds the graphs of a module's top-level code and of every
+// function in it, nested ones included, in source order.
+func Functions(program *parser.Program) []*CFG {
+	graphs := []*CFG{New(program)}
+	parser.Inspect(program, func(node parser.Node) bool {
+		switch node.(type) {
+		case *parser.FunctionDef, *parser.AsyncFunctionDef:
+			graphs = append(graphs, New(node))
+		}
+		return true
+	})
+	return graphs
+}
+
+// jump is a transfer of control that may have to pass through finally
+// blocks on its way.
+type jump int
+
+const (
+	jumpReturn jump = iota
+	jumpBreak
+	jumpContinue
+	jumpRaise
+)
+
+// frame is a statement that jumps out of its body must respect.
+type frame struct {
+	// A loop, where break and continue go.
+	loop        bool
+	brk, cont   *Block
+	handler     *Block // The first except test of a try body, where exceptions go
+	finally     *Block // A finally block or with exit, which every jump passes through
+	finallyExit []jump // The jumps that entered the finally block
+}
+
+type builder struct {
+	cfg    *CFG
+	cur    *Block // Nil when the current code is unreachable
+	frames []*frame
+}
+
+func (b *builder) newBlock(kind BlockKind) *Block {
+	block := &Block{Index: len(b.cfg.Blocks), Kind: kind}
+	b.cfg.Blocks = append(b.cfg.Blocks, block)
+	return block
+}
+
+func (b *builder) edge(from, to *Block, kind EdgeKind) {
+	if from == nil {
+		return
+	}
+	for _, e := range from.Succs {
+		if e.To == to && e.Kind == kind {
+			return
+		}
+	}
+	e := &Edge{Kind: kind, From: from, To: to}
+	from.Succs = append(from.Succs, e)
+	to.Preds = append(to.Preds, e)
+}
+
+// add appends a node to the current block. The first node of a block in a
+// try or with body gives it an edge to where its exceptions go.
+func (b *builder) add(node parser.Node) {
+	if b.cur == nil {
+		b.cur = b.newBlock(UnreachableBlock)
+	}
+	if len(b.cur.Nodes) == 0 && b.inTry() {
+		b.jump(b.cur, jumpRaise)
+	}
+	b.cur.Nodes = append(b.cur.Nodes, node)
+	b.cfg.blockOf[node] = b.cur
+}
+
+// start ends the current block with an edge into a new block of the given
+// kind, which becomes current.
+func (b *builder) start(kind BlockKind) *Block {
+	block := b.newBlock(kind)
+	b.edge(b.cur, block, Normal)
+	b.cur = block
+	return block
+}
+
+func (b *builder) inTry() bool {
+	for _, f := range b.frames {
+		if f.handler != nil || f.finally != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *builder) push(f *frame) *frame {
+	b.frames = append(b.frames, f)
+	return f
+}
+
+func (b *builder) pop() {
+	b.frames = b.frames[:len(b.frames)-1]
+}
+
+// jump adds the edge for a return, break, continue or raise from block,
+// going through the innermost finally block if there is one in the way.
+func (b *builder) jump(from *Block, j jump) {
+	if from == nil {
+		return
+	}
+	kind := Normal
+	if j == jumpRaise {
+		kind = Exception
+	}
+
+	for i := len(b.frames) - 1; i >= 0; i-- {
+		f := b.frames[i]
+		switch {
+		case f.finally != nil:
+			b.edge(from, f.finally, kind)
+			for _, seen := range f.finallyExit {
+				if seen == j {
+					return
+				}
+			}
+			f.finallyExit = append(f.finallyExit, j)
+			return
+		case f.handler != nil && j == jumpRaise:
+			b.edge(from, f.handler, Exception)
+			return
+		case f.loop && j == jumpBreak:
+			b.edge(from, f.brk, Normal)
+			return
+		case f.loop && j == jumpContinue:
+			b.edge(from, f.cont, Normal)
+			return
+		}
+	}
+
+	switch j {
+	case jumpReturn:
+		b.edge(from, b.cfg.Exit, Normal)
+	case jumpRaise:
+		b.edge(from, b.cfg.Raise, Exception)
+	}
+}
+
+// finish replays the jumps that entered a finally block or with exit from
+// its end, now that the frame is popped. If normal is set, control also
+// continues after the statement.
+func (b *builder) finish(f *frame, end *Block, normal bool) {
+	for _, j := range f.finallyExit {
+		b.jump(end, j)
+	}
+	if normal && end != nil {
+		b.cur = end
+		b.start(BodyBlock)
+	} else {
+		b.cur = nil
+	}
+}
+
+func (b *builder) stmts(stmts []parser.Statement) {
+	for _, stmt := range stmts {
+		b.stmt(stmt)
+	}
+}
+
+func (b *builder) block(block *parser.BlockStatement) {
+	if block != nil {
+		b.stmts(block.Statements)
+	}
+}
+
+func (b *builder) stmt(stmt parser.Statement) {
+	switch s := stmt.(type) {
+	case *parser.BlockStatement:
+		b.block(s)
+	case *parser.ReturnStatement:
+		b.add(s)
+		b.jump(b.cur, jumpReturn)
+		b.cur = nil
+	case *parser.RaiseStatement:
+		b.add(s)
+		b.jump(b.cur, jumpRaise)
+		b.cur = nil
+	case *parser.BreakStatement:
+		b.add(s)
+		b.jump(b.cur, jumpBreak)
+		b.cur = nil
+	case *parser.ContinueStatement:
+		b.add(s)
+		b.jump(b.cur, jumpContinue)
+		b.cur = nil
+	case *parser.AssertStatement:
+		b.add(s)
+		test := b.cur
+		b.jump(test, jumpRaise)
+		b.cur = b.newBlock(BodyBlock)
+		b.edge(test, b.cur, True)
+	case *parser.IfStatement:
+		b.ifStmt(s)
+	case *parser.WhileStatement:
+		b.whileStmt(s)
+	case *parser.ForStatement:
+		b.forStmt(s)
+	case *parser.TryStatement:
+		b.tryStmt(s)
+	case *parser.WithStatement:
+		b.withStmt(s)
+	case *parser.MatchStatement:
+		b.matchStmt(s)
+	case *parser.ErrorNode:
+		b.errorNode(s)
+	default:
+		b.add(stmt)
+	}
+}
+
+func (b *builder) ifStmt(s *parser.IfStatement) {
+	var ends []*Block
+
+	b.add(s.Condition)
+	test := b.cur
+	b.cur = b.newBlock(IfThenBlock)
+	b.edge(test, b.cur, True)
+	b.block(s.Consequence)
+	ends = append(ends, b.cur)
+
+	for _, elif := range s.ElifClauses {
+		b.cur = b.newBlock(IfElseBlock)
+		b.edge(test, b.cur, False)
+		b.add(elif.Condition)
+		test = b.cur
+		b.cur = b.newBlock(IfThenBlock)
+		b.edge(test, b.cur, True)
+		b.block(elif.Body)
+		ends = append(ends, b.cur)
+	}
+
+	if s.ElseClause != nil {
+		b.cur = b.newBlock(IfElseBlock)
+		b.edge(test, b.cur, False)
+		b.block(s.ElseClause.Body)
+		ends = append(ends, b.cur)
+	} else {
+		ends = append(ends, test)
+	}
+
+	b.join(ends, test, s.ElseClause == nil)
+}
+
+// join continues after a compound statement from the blocks that complete
+// it. If falseFrom is set, test's edge to the join is a False edge.
+func (b *builder) join(ends []*Block, test *Block, falseFrom bool) {
+	reachable := false
+	for _, end := range ends {
+		if end != nil {
+			reachable = true
+		}
+	}
+	if !reachable {
+		b.cur = nil
+		return
+	}
+
+	after := b.newBlock(BodyBlock)
+	for _, end := range ends {
+		kind := Normal
+		if falseFrom && end == test {
+			kind = False
+		}
+		b.edge(end, after, kind)
+	}
+	b.cur = after
+}
+
+// loop builds the body and else clause of a loop whose head has been
+// built. The head's True edge enters the body and its False edge the else
+// clause, unless the loop can only be left by break.
+func (b *builder) loop(head *Block, body, orelse *parser.BlockStatement, infinite bool) {
+	after := b.newBlock(BodyBlock)
+
+	b.cur = b.newBlock(LoopBodyBlock)
+	b.edge(head, b.cur, True)
+	b.push(&frame{loop: true, brk: after, cont: head})
+	b.block(body)
+	b.pop()
+	b.edge(b.cur, head, Normal)
+
+	if !infinite {
+		if orelse != nil {
+			b.cur = b.newBlock(LoopElseBlock)
+			b.edge(head, b.cur, False)
+			b.block(orelse)
+			b.edge(b.cur, after, Normal)
+		} else {
+			b.edge(head, after, False)
+		}
+	}
+
+	b.cur = after
+	if len(after.Preds) == 0 {
+		b.cur = nil
+	}
+}
+
+func (b *builder) whileStmt(s *parser.WhileStatement) {
+	head := b.start(LoopHeadBlock)
+	b.add(s.Condition)
+	b.loop(head, s.Body, s.ElseBody, isTrue(s.Condition))
+}
+
+// errorNode builds a statement that failed to parse. What its header would
+// have done is unknown, so the block recovered after it, if any, is built
+// as the body of a loop: it may run any number of times, and break and
+// continue in it stay within the statement.
+func (b *builder) errorNode(s *parser.ErrorNode) {
+	if s.Body == nil {
+		b.add(s)
+		return
+	}
+	head := b.start(LoopHeadBlock)
+	b.add(s)
+	b.loop(head, s.Body, nil, false)
+}
+
+// isTrue reports whether a loop condition is a constant that always holds,
+// as in "while True" or "while 1".
+func isTrue(expr parser.Expression) bool {
+	switch e := expr.(type) {
+	case *parser.BooleanLiteral:
+		return e.Value
+	case *parser.IntegerLiteral:
+		return e.Value != 0 || e.Big != nil
+	}
+	return false
+}
+
+func (b *builder) forStmt(s *parser.ForStatement) {
+	// The iterable is evaluated once; the head assigns the next item.
+	b.add(s.Iterable)
+	head := b.start(LoopHeadBlock)
+	b.add(s.Target)
+	b.loop(head, s.Body, s.ElseBody, false)
+}
+
+func (b *builder) tryStmt(s *parser.TryStatement) {
+	var fin *frame
+	if s.FinallyClause != nil {
+		fin = b.push(&frame{finally: b.newBlock(FinallyBlock)})
+	}
+	tests := make([]*Block, len(s.ExceptClauses))
+	for i := range tests {
+		tests[i] = b.newBlock(ExceptBlock)
+	}
+
+	if len(tests) > 0 {
+		b.push(&frame{handler: tests[0]})
+	}
+	b.start(TryBodyBlock)
+	b.block(s.TryBlock)
+	if len(tests) > 0 {
+		b.pop()
+	}
+
+	// The else clause runs when the body completes; its exceptions are not
+	// handled by the except clauses.
+	if s.ElseClause != nil {
+		b.start(TryElseBlock)
+		b.block(s.ElseClause.Body)
+	}
+	ends := []*Block{b.cur}
+
+	for i, except := range s.ExceptClauses {
+		b.cur = tests[i]
+		if except.ExceptionType != nil {
+			b.add(except.ExceptionType)
+		}
+		test := tests[i]
+		b.cur = b.newBlock(ExceptBodyBlock)
+		if except.ExceptionType == nil {
+			b.edge(test, b.cur, Normal)
+		} else {
+			b.edge(test, b.cur, True)
+			if i+1 < len(tests) {
+				b.edge(test, tests[i+1], False)
+			} else {
+				// No clause matched: the exception propagates.
+				b.jump(test, jumpRaise)
+			}
+		}
+		if except.Name != nil {
+			b.add(except.Name)
+		}
+		b.block(except.Body)
+		ends = append(ends, b.cur)
+	}
+
+	if fin == nil {
+		b.join(ends, nil, false)
+		return
+	}
+
+	b.pop()
+	normal := false
+	for _, end := range ends {
+		if end != nil {
+			b.edge(end, fin.finally, Normal)
+			normal = true
+		}
+	}
+	b.cur = fin.finally
+	b.block(s.FinallyClause.Body)
+	b.finish(fin, b.cur, normal)
+}
+
+// withStmt builds a with statement. The exit of its context managers works
+// like a finally block; since a context manager may suppress an exception
+// raised in the body, control can also continue after the statement from
+// there.
+func (b *builder) withStmt(s *parser.WithStatement) {
+	for _, item := range s.Items {
+		b.add(item)
+	}
+
+	exit := b.push(&frame{finally: b.newBlock(WithExitBlock)})
+	b.start(WithBodyBlock)
+	b.block(s.Body)
+	b.pop()
+
+	normal := b.cur != nil
+	b.edge(b.cur, exit.finally, Normal)
+	for _, j := range exit.finallyExit {
+		if j == jumpRaise {
+			normal = true
+		}
+	}
+	b.finish(exit, exit.finally, normal)
+}
+
+func (b *builder) matchStmt(s *parser.MatchStatement) {
+	b.add(s.Subject)
+
+	var ends []*Block
+	prev, kind := b.cur, Normal
+	for _, c := range s.Cases {
+		b.cur = b.newBlock(CaseBlock)
+		b.edge(prev, b.cur, kind)
+		b.add(c.Pattern)
+		if c.Guard != nil {
+			b.add(c.Guard)
+		}
+		test := b.cur
+
+		b.cur = b.newBlock(CaseBodyBlock)
+		b.edge(test, b.cur, True)
+		b.block(c.Body)
+		ends = append(ends, b.cur)
+
+		prev, kind = test, False
+		if c.Guard == nil && irrefutable(c.Pattern) {
+			prev = nil
+			break
+		}
+	}
+
+	ends = append(ends, prev)
+	b.join(ends, prev, true)
+}
+
+// irrefutable reports whether a case pattern matches any subject, so that
+// no case after it is tried.
+func irrefutable(pattern parser.Pattern) bool {
+	switch p := pattern.(type) {
+	case *parser.WildcardPattern, *parser.CapturePattern:
+		return true
+	case *parser.AsPattern:
+		return irrefutable(p.Pattern)
+	case *parser.OrPattern:
+		for _, alt := range p.Patterns {
+			if irrefutable(alt) {
+				return true
+			}
+		}
+	}
+	return false
+}
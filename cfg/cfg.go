@@ -0,0 +1,262 @@
+// Package cfg builds intra-procedural control-flow graphs of Python
+// functions, so that analyses can ask whether something happens on every
+// path to a statement rather than anywhere in the function.
+//
+// A graph is made of basic blocks holding simple statements and the
+// expressions that decide where control goes next: conditions, loop
+// targets, exception types, with items and case patterns. Compound
+// statements themselves never appear in a block; their parts do. A
+// statement that failed to parse heads a loop over the block recovered
+// after it, since what its header would have done is unknown.
+//
+// Exceptions are modelled inside try and with statements, where every
+// block that can raise has an Exception edge to the handlers, the finally
+// block or the with statement's exit. Outside of them only raise and
+// assert lead to the graph's Raise block. A finally block, and the exit of
+// a with statement, exists once: its end has an edge to every place that
+// the paths entering it continue to. Short-circuit operators and
+// conditional expressions are not split into blocks, and nested functions
+// and classes are single nodes with graphs of their own.
+package cfg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coiloffaraday/python_sast/parser"
+)
+
+// CFG is the control-flow graph of a function or of a module's top-level
+// code.
+type CFG struct {
+	Node   parser.Node // The *FunctionDef, *AsyncFunctionDef or *Program
+	Entry  *Block
+	Exit   *Block // Reached by returning, or by falling off the end
+	Raise  *Block // Reached by an exception leaving the function
+	Blocks []*Block
+
+	blockOf map[parser.Node]*Block
+	idom    []*Block // Immediate dominators by block index, computed on demand
+}
+
+type BlockKind int
+
+const (
+	EntryBlock       BlockKind = iota
+	ExitBlock                  // The normal exit
+	RaiseBlock                 // The exceptional exit
+	BodyBlock                  // Straight-line code, such as the code after a compound statement
+	UnreachableBlock           // Code after a return, raise, break or continue
+	IfThenBlock
+	IfElseBlock // An else body, or the condition of an elif
+	LoopHeadBlock
+	LoopBodyBlock
+	LoopElseBlock
+	TryBodyBlock
+	TryElseBlock
+	ExceptBlock     // The test of an except clause
+	ExceptBodyBlock // The body of an except clause
+	FinallyBlock
+	WithBodyBlock
+	WithExitBlock // Where the context managers exit
+	CaseBlock     // The pattern and guard of a case clause
+	CaseBodyBlock
+)
+
+var blockKindNames = [...]string{
+	EntryBlock:       "Entry",
+	ExitBlock:        "Exit",
+	RaiseBlock:       "Raise",
+	BodyBlock:        "Body",
+	UnreachableBlock: "Unreachable",
+	IfThenBlock:      "IfThen",
+	IfElseBlock:      "IfElse",
+	LoopHeadBlock:    "LoopHead",
+	LoopBodyBlock:    "LoopBody",
+	LoopElseBlock:    "LoopElse",
+	TryBodyBlock:     "TryBody",
+	TryElseBlock:     "TryElse",
+	ExceptBlock:      "Except",
+	ExceptBodyBlock:  "ExceptBody",
+	FinallyBlock:     "Finally",
+	WithBodyBlock:    "WithBody",
+	WithExitBlock:    "WithExit",
+	CaseBlock:        "Case",
+	CaseBodyBlock:    "CaseBody",
+}
+
+func (k BlockKind) String() string {
+	if int(k) < len(blockKindNames) {
+		return blockKindNames[k]
+	}
+	return fmt.Sprintf("BlockKind(%d)", int(k))
+}
+
+type EdgeKind int
+
+const (
+	Normal    EdgeKind = iota
+	True               // The condition held, the loop has another item or the pattern matched
+	False              // The condition failed, the loop is exhausted or the pattern did not match
+	Exception          // An exception was raised
+)
+
+func (k EdgeKind) String() string {
+	switch k {
+	case Normal:
+		return "Normal"
+	case True:
+		return "True"
+	case False:
+		return "False"
+	case Exception:
+		return "Exception"
+	}
+	return fmt.Sprintf("EdgeKind(%d)", int(k))
+}
+
+type Edge struct {
+	Kind EdgeKind
+	From *Block
+	To   *Block
+}
+
+// Block is a basic block: nodes executed in order, with control entering
+// at the first and leaving after the last.
+type Block struct {
+	Index int
+	Kind  BlockKind
+	Nodes []parser.Node // Simple statements and the expressions of compound ones
+	Succs []*Edge
+	Preds []*Edge
+}
+
+// BlockOf returns the block holding node, which must be one of the nodes
+// of a block, or nil.
+func (g *CFG) BlockOf(node parser.Node) *Block {
+	return g.blockOf[node]
+}
+
+// Reachable reports whether control can reach b from the entry.
+func (g *CFG) Reachable(b *Block) bool {
+	return g.dominators()[b.Index] != nil
+}
+
+// Dominates reports whether every path from the entry to b passes through
+// a. A block dominates itself, and an unreachable block is dominated by
+// every block.
+func (g *CFG) Dominates(a, b *Block) bool {
+	idom := g.dominators()
+	if idom[b.Index] == nil {
+		return true
+	}
+	for {
+		if b == a {
+			return true
+		}
+		if b == g.Entry {
+			return false
+		}
+		b = idom[b.Index]
+	}
+}
+
+// dominators computes the immediate dominator of each block with the
+// iterative algorithm of Cooper, Harvey and Kennedy. The entry is its own
+// immediate dominator; unreachable blocks have none.
+func (g *CFG) dominators() []*Block {
+	if g.idom != nil {
+		return g.idom
+	}
+
+	// Number the reachable blocks in reverse postorder.
+	var postorder []*Block
+	visited := make([]bool, len(g.Blocks))
+	var visit func(b *Block)
+	visit = func(b *Block) {
+		visited[b.Index] = true
+		for _, e := range b.Succs {
+			if !visited[e.To.Index] {
+				visit(e.To)
+			}
+		}
+		postorder = append(postorder, b)
+	}
+	visit(g.Entry)
+	order := make([]int, len(g.Blocks))
+	for i, b := range postorder {
+		order[b.Index] = i
+	}
+
+	idom := make([]*Block, len(g.Blocks))
+	idom[g.Entry.Index] = g.Entry
+	intersect := func(a, b *Block) *Block {
+		for a != b {
+			for order[a.Index] < order[b.Index] {
+				a = idom[a.Index]
+			}
+			for order[b.Index] < order[a.Index] {
+				b = idom[b.Index]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(postorder) - 1; i >= 0; i-- {
+			b := postorder[i]
+			if b == g.Entry {
+				continue
+			}
+			var dom *Block
+			for _, e := range b.Preds {
+				if idom[e.From.Index] == nil {
+					continue
+				}
+				if dom == nil {
+					dom = e.From
+				} else {
+					dom = intersect(dom, e.From)
+				}
+			}
+			if idom[b.Index] != dom {
+				idom[b.Index] = dom
+				changed = true
+			}
+		}
+	}
+
+	g.idom = idom
+	return idom
+}
+
+// String lists the blocks of the graph with their nodes and successors,
+// one block per line, for debugging and tests.
+func (g *CFG) String() string {
+	var sb strings.Builder
+	for _, b := range g.Blocks {
+		fmt.Fprintf(&sb, "b%d %s", b.Index, b.Kind)
+		if len(b.Nodes) > 0 {
+			nodes := make([]string, len(b.Nodes))
+			for i, node := range b.Nodes {
+				if e, ok := node.(*parser.ErrorNode); ok {
+					// Its body has blocks of its own.
+					node = &parser.ErrorNode{Token: e.Token, EndToken: e.EndToken}
+				}
+				nodes[i] = strings.ReplaceAll(strings.TrimSpace(parser.Unparse(node)), "\n", "; ")
+			}
+			fmt.Fprintf(&sb, " [%s]", strings.Join(nodes, "; "))
+		}
+		if len(b.Succs) > 0 {
+			sb.WriteString(" ->")
+			for _, e := range b.Succs {
+				fmt.Fprintf(&sb, " b%d", e.To.Index)
+				if e.Kind != Normal {
+					fmt.Fprintf(&sb, "(%s)", e.Kind)
+				}
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
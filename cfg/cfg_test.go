@@ -0,0 +1,232 @@
+package cfg_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/cfg"
+	"github.com/coiloffaraday/python_sast/lexer"
+	"github.com/coiloffaraday/python_sast/parser"
+)
+
+// build parses input, which must define a single function, and returns the
+// function's graph.
+func build(t *testing.T, input string) (*cfg.CFG, *parser.FunctionDef) {
+	t.Helper()
+	p := parser.New(lexer.NewLexer(input, "test.py"))
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatalf("%q: unexpected errors: %v", input, p.Diagnostics())
+	}
+	def, ok := parser.FindFirst[*parser.FunctionDef](program)
+	if !ok {
+		t.Fatalf("%q: no function", input)
+	}
+	return cfg.New(def), def
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"def f(x):\n    y = x\n    return y",
+			`
+b0 Entry [y = x; return y] -> b1
+b1 Exit
+b2 Raise
+`,
+		},
+		{
+			"def f(x):\n    if x:\n        a()\n    elif y:\n        b()\n    else:\n        return\n    c()",
+			`
+b0 Entry [x] -> b3(True) b4(False)
+b1 Exit
+b2 Raise
+b3 IfThen [a()] -> b7
+b4 IfElse [y] -> b5(True) b6(False)
+b5 IfThen [b()] -> b7
+b6 IfElse [return] -> b1
+b7 Body [c()] -> b1
+`,
+		},
+		{
+			"def f(xs):\n    for x in xs:\n        if x:\n            break\n        if not x:\n            continue\n        g(x)\n    else:\n        h()\n    return 1",
+			`
+b0 Entry [xs] -> b3
+b1 Exit
+b2 Raise
+b3 LoopHead [x] -> b5(True) b10(False)
+b4 Body [return 1] -> b1
+b5 LoopBody [x] -> b6(True) b7(False)
+b6 IfThen [break] -> b4
+b7 Body [not x] -> b8(True) b9(False)
+b8 IfThen [continue] -> b3
+b9 Body [g(x)] -> b3
+b10 LoopElse [h()] -> b4
+`,
+		},
+		{
+			"def f():\n    while True:\n        if g():\n            break\n    return 2\n",
+			`
+b0 Entry -> b3
+b1 Exit
+b2 Raise
+b3 LoopHead [True] -> b5(True)
+b4 Body [return 2] -> b1
+b5 LoopBody [g()] -> b6(True) b7(False)
+b6 IfThen [break] -> b4
+b7 Body -> b3
+`,
+		},
+		{
+			"def f():\n    try:\n        a()\n    except ValueError as e:\n        b(e)\n    except:\n        raise\n    else:\n        c()\n    d()",
+			`
+b0 Entry -> b5
+b1 Exit
+b2 Raise
+b3 Except [ValueError] -> b7(True) b4(False)
+b4 Except -> b8
+b5 TryBody [a()] -> b3(Exception) b6
+b6 TryElse [c()] -> b9
+b7 ExceptBody [e; b(e)] -> b9
+b8 ExceptBody [raise] -> b2(Exception)
+b9 Body [d()] -> b1
+`,
+		},
+		{
+			"def f():\n    for x in xs:\n        try:\n            return a(x)\n        finally:\n            if x:\n                continue\n    b()",
+			`
+b0 Entry [xs] -> b3
+b1 Exit
+b2 Raise
+b3 LoopHead [x] -> b5(True) b4(False)
+b4 Body [b()] -> b1
+b5 LoopBody -> b7
+b6 Finally [x] -> b8(True) b9(False)
+b7 TryBody [return a(x)] -> b6(Exception) b6
+b8 IfThen [continue] -> b3
+b9 Body -> b2(Exception) b1
+`,
+		},
+		{
+			"def f(p):\n    with open(p) as fh:\n        data = fh.read()\n    return data",
+			`
+b0 Entry [open(p) as fh] -> b4
+b1 Exit
+b2 Raise
+b3 WithExit -> b2(Exception) b5
+b4 WithBody [data = fh.read()] -> b3(Exception) b3
+b5 Body [return data] -> b1
+`,
+		},
+		{
+			"def f(v):\n    match v:\n        case 1 | 2:\n            a()\n        case [x] if x:\n            return x\n        case _:\n            b()\n    c()",
+			`
+b0 Entry [v] -> b3
+b1 Exit
+b2 Raise
+b3 Case [1 | 2] -> b4(True) b5(False)
+b4 CaseBody [a()] -> b9
+b5 Case [[x]; x] -> b6(True) b7(False)
+b6 CaseBody [return x] -> b1
+b7 Case [_] -> b8(True)
+b8 CaseBody [b()] -> b9
+b9 Body [c()] -> b1
+`,
+		},
+		{
+			"def f(x):\n    assert x\n    return x\n    dead()",
+			`
+b0 Entry [assert x] -> b2(Exception) b3(True)
+b1 Exit
+b2 Raise
+b3 Body [return x] -> b1
+b4 Unreachable [dead()] -> b1
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		g, _ := build(t, tt.input)
+		if got := g.String(); got != strings.TrimPrefix(tt.expected, "\n") {
+			t.Errorf("%q:\n got:\n%s\nwant:%s", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestDominates(t *testing.T) {
+	input := `def view(request):
+    q = request.args["q"]
+    if strict:
+        q = escape(q)
+    else:
+        log(q)
+    q2 = escape(q)
+    db.execute(q2)
+    return
+    unreachable()
+`
+	g, def := build(t, input)
+	stmts := def.Body.Statements
+	sanitizeIf := g.BlockOf(stmts[1].(*parser.IfStatement).Consequence.Statements[0])
+	sanitize := g.BlockOf(stmts[2])
+	sink := g.BlockOf(stmts[3])
+	dead := g.BlockOf(stmts[5])
+
+	if !g.Dominates(sanitize, sink) {
+		t.Errorf("the sanitizer before the sink should dominate it")
+	}
+	if g.Dominates(sanitizeIf, sink) {
+		t.Errorf("a sanitizer in one branch should not dominate the sink")
+	}
+	if !g.Dominates(g.Entry, sink) || !g.Dominates(sink, sink) {
+		t.Errorf("entry and self dominance")
+	}
+	if g.Reachable(dead) || !g.Reachable(sink) {
+		t.Errorf("reachability: dead %v, sink %v", g.Reachable(dead), g.Reachable(sink))
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	p := parser.New(lexer.NewLexer("x = 1\ndef f():\n    def g():\n        pass\nclass C:\n    async def m(self):\n        pass\n", "test.py"))
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatal(p.Diagnostics())
+	}
+
+	graphs := cfg.Functions(program)
+	if len(graphs) != 4 {
+		t.Fatalf("expected 4 graphs, got %d", len(graphs))
+	}
+	if graphs[0].Node != program {
+		t.Errorf("the first graph should be the module's")
+	}
+	if _, ok := graphs[3].Node.(*parser.AsyncFunctionDef); !ok {
+		t.Errorf("the last graph should be the async method's, got %T", graphs[3].Node)
+	}
+}
+
+func TestErrorNode(t *testing.T) {
+	// The body of a statement that failed to parse may run any number of
+	// times.
+	input := "def f(x):\n    y = x\n    while x y:\n        g(y)\n        break\n    return y\n"
+	p := parser.New(lexer.NewLexer(input, "test.py"))
+	program, _ := p.ParseProgram()
+	if len(p.Diagnostics()) != 1 {
+		t.Fatalf("expected one diagnostic, got %v", p.Diagnostics())
+	}
+	def, _ := parser.FindFirst[*parser.FunctionDef](program)
+	expected := `
+b0 Entry [y = x] -> b3
+b1 Exit
+b2 Raise
+b3 LoopHead [# syntax error: while] -> b5(True) b4(False)
+b4 Body [return y] -> b1
+b5 LoopBody [g(y); break] -> b4
+`
+	if got := "\n" + cfg.New(def).String(); got != expected {
+		t.Errorf("got:%s\nwant:%s", got, expected)
+	}
+}
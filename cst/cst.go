@@ -0,0 +1,193 @@
+// Package cst is a lossless view of a Python file: every token together with
+// the whitespace, comments and line continuations around it, so that the file
+// can be reproduced byte for byte. It sits beside the abstract syntax tree of
+// package parser, whose node positions index into the same source, so rules
+// can work on the AST and map their findings back to exact source text.
+package cst
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/coiloffaraday/python_sast/lexer"
+	"github.com/coiloffaraday/python_sast/parser"
+	sasttoken "github.com/coiloffaraday/python_sast/token"
+)
+
+type TriviaKind int
+
+const (
+	Whitespace   TriviaKind = iota // Spaces, tabs and form feeds
+	Comment                        // A '#' comment, including a shebang line
+	Newline                        // A line break that does not end a logical line
+	Continuation                   // The backslash of an explicit line joining
+	Skipped                        // Text outside any token, such as a byte order mark
+)
+
+// Trivia is a piece of source text between tokens.
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+}
+
+// Token is a token with its exact source text and the trivia around it.
+// Trailing trivia runs from the token to the end of its line and is only
+// set on the last token of a line; everything else between two tokens is
+// leading trivia of the second.
+type Token struct {
+	sasttoken.Token
+	Text     string // The source text, which differs from Literal for normalized identifiers
+	Leading  []Trivia
+	Trailing []Trivia
+}
+
+// File is the concrete syntax of a source file.
+type File struct {
+	Path     string
+	Source   string // The text of the file, transcoded to UTF-8
+	Encoding string // The declared encoding of the file; "" for UTF-8
+	Tokens   []Token
+}
+
+// New tokenizes source, the contents of the file at filePath.
+func New(source string, filePath string) *File {
+	l := lexer.NewLexer(source, filePath)
+	f := &File{Path: filePath, Source: l.Source(), Encoding: l.Encoding()}
+	src := f.Source
+
+	prevEnd := 0
+	for {
+		tok := l.NextToken()
+		start := tok.Offset
+		if start < prevEnd {
+			start = prevEnd
+		}
+		end := tok.EndOffset
+		if end < start {
+			end = start
+		}
+
+		gap := src[prevEnd:start]
+		if n := len(f.Tokens); n > 0 && f.Tokens[n-1].Type != sasttoken.NEWLINE {
+			// The rest of the previous token's line is its trailing trivia.
+			cut := strings.IndexAny(gap, "\r\n")
+			if cut < 0 && tok.Type == sasttoken.NEWLINE {
+				cut = len(gap)
+			}
+			if cut >= 0 {
+				f.Tokens[n-1].Trailing = splitTrivia(gap[:cut])
+				gap = gap[cut:]
+			}
+		}
+
+		f.Tokens = append(f.Tokens, Token{Token: tok, Text: src[start:end], Leading: splitTrivia(gap)})
+		prevEnd = end
+		if tok.Type == sasttoken.EOF {
+			break
+		}
+	}
+
+	if prevEnd < len(src) {
+		last := &f.Tokens[len(f.Tokens)-1]
+		last.Trailing = append(last.Trailing, splitTrivia(src[prevEnd:])...)
+	}
+
+	return f
+}
+
+// splitTrivia divides the text between two tokens into trivia.
+func splitTrivia(s string) []Trivia {
+	var trivia []Trivia
+	add := func(kind TriviaKind, n int) {
+		if last := len(trivia) - 1; last >= 0 && kind == Skipped && trivia[last].Kind == Skipped {
+			trivia[last].Text += s[:n]
+		} else {
+			trivia = append(trivia, Trivia{Kind: kind, Text: s[:n]})
+		}
+		s = s[n:]
+	}
+
+	for len(s) > 0 {
+		switch {
+		case s[0] == ' ' || s[0] == '\t' || s[0] == '\f':
+			n := len(s) - len(strings.TrimLeft(s, " \t\f"))
+			add(Whitespace, n)
+		case s[0] == '#':
+			n := strings.IndexAny(s, "\r\n")
+			if n < 0 {
+				n = len(s)
+			}
+			add(Comment, n)
+		case strings.HasPrefix(s, "\r\n"):
+			add(Newline, 2)
+		case s[0] == '\n' || s[0] == '\r':
+			add(Newline, 1)
+		case s[0] == '\\' && (len(s) == 1 || s[1] == '\n' || s[1] == '\r'):
+			add(Continuation, 1)
+		default:
+			_, n := utf8.DecodeRuneInString(s)
+			add(Skipped, n)
+		}
+	}
+
+	return trivia
+}
+
+// String reproduces the source text from the tokens and their trivia.
+func (f *File) String() string {
+	var out strings.Builder
+	for _, tok := range f.Tokens {
+		writeTrivia(&out, tok.Leading)
+		out.WriteString(tok.Text)
+		writeTrivia(&out, tok.Trailing)
+	}
+	return out.String()
+}
+
+func writeTrivia(out *strings.Builder, trivia []Trivia) {
+	for _, t := range trivia {
+		out.WriteString(t.Text)
+	}
+}
+
+// Bytes reproduces the file as it was read, in its declared encoding.
+func (f *File) Bytes() ([]byte, error) {
+	encoded, err := lexer.EncodeSource(f.String(), f.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(encoded), nil
+}
+
+// Text returns the exact source text of node.
+func (f *File) Text(node parser.Node) string {
+	return f.Source[node.Pos().Offset:node.End().Offset]
+}
+
+// NodeTokens returns the tokens making up node, with their trivia.
+func (f *File) NodeTokens(node parser.Node) []Token {
+	start, end := node.Pos().Offset, node.End().Offset
+	i := sort.Search(len(f.Tokens), func(i int) bool { return f.Tokens[i].Offset >= start })
+	j := sort.Search(len(f.Tokens), func(j int) bool { return f.Tokens[j].Offset >= end })
+	return f.Tokens[i:j]
+}
+
+// Lines returns the complete source lines on which node lies, without the
+// final line break, for showing it in context.
+func (f *File) Lines(node parser.Node) string {
+	start, end := node.Pos().Offset, node.End().Offset
+	start = strings.LastIndexAny(f.Source[:start], "\r\n") + 1
+	if i := strings.IndexAny(f.Source[end:], "\r\n"); i >= 0 {
+		end += i
+	} else {
+		end = len(f.Source)
+	}
+	return f.Source[start:end]
+}
+
+// Replace returns the source with the text of node replaced by text, leaving
+// every other byte untouched.
+func (f *File) Replace(node parser.Node, text string) string {
+	return f.Source[:node.Pos().Offset] + text + f.Source[node.End().Offset:]
+}
@@ -0,0 +1,106 @@
+package cst_test
+
+import (
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/cst"
+	"github.com/coiloffaraday/python_sast/lexer"
+	"github.com/coiloffaraday/python_sast/parser"
+)
+
+func TestRoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		"x = 1",
+		"x = 1\n",
+		"\ufeff#!/usr/bin/env python\n# -*- coding: utf-8 -*-\nimport os  # os\n",
+		"def f(a,  # first\n      b):\n\n    # body\n    return a + \\\n        b\n\n\n",
+		"if x:\r\n\tpass\r\nelse:\r\n\tpass  \r\n",
+		"s = '''multi\nline''' ; t = f\"{s!r:>{w}}\"\n",
+		"x = $ + 1\nclass C: pass   \n  # trailing comment",
+		"nam\u00e9 = \ufb01 = 1\n",
+	}
+
+	for _, input := range inputs {
+		f := cst.New(input, "test.py")
+		if got := f.String(); got != input {
+			t.Errorf("%q: reproduced as %q", input, got)
+		}
+	}
+}
+
+func TestRoundTripOtherEncoding(t *testing.T) {
+	input := "# -*- coding: latin-1 -*-\ns = '\xe9t\xe9'\n"
+	f := cst.New(input, "test.py")
+	if f.Encoding != "latin-1" {
+		t.Errorf("encoding: got %q", f.Encoding)
+	}
+	if got := f.String(); got != "# -*- coding: latin-1 -*-\ns = 'été'\n" {
+		t.Errorf("decoded text: got %q", got)
+	}
+	got, err := f.Bytes()
+	if err != nil || string(got) != input {
+		t.Errorf("bytes: got %q, %v", got, err)
+	}
+}
+
+func TestTrivia(t *testing.T) {
+	f := cst.New("x = 1  # one\n\n# two\ny = \\\n  2\n", "test.py")
+
+	var one, y, assign, two cst.Token
+	for _, tok := range f.Tokens {
+		switch tok.Text {
+		case "1":
+			one = tok
+		case "y":
+			y = tok
+		case "=":
+			assign = tok
+		case "2":
+			two = tok
+		}
+	}
+
+	if len(one.Trailing) != 2 || one.Trailing[1].Kind != cst.Comment || one.Trailing[1].Text != "# one" {
+		t.Errorf("trailing trivia of 1: got %+v", one.Trailing)
+	}
+	wantLeading := []cst.Trivia{{cst.Newline, "\n"}, {cst.Comment, "# two"}, {cst.Newline, "\n"}}
+	if len(y.Leading) != len(wantLeading) {
+		t.Fatalf("leading trivia of y: got %+v", y.Leading)
+	}
+	for i, want := range wantLeading {
+		if y.Leading[i] != want {
+			t.Errorf("leading trivia %d of y: got %+v, want %+v", i, y.Leading[i], want)
+		}
+	}
+	if n := len(assign.Trailing); n == 0 || assign.Trailing[n-1].Kind != cst.Continuation {
+		t.Errorf("trailing trivia of =: got %+v", assign.Trailing)
+	}
+	if len(two.Leading) != 2 || two.Leading[1] != (cst.Trivia{cst.Whitespace, "  "}) {
+		t.Errorf("leading trivia of 2: got %+v", two.Leading)
+	}
+}
+
+func TestNodeText(t *testing.T) {
+	source := "result = os.system(  # run it\n    cmd + arg)\nprint(result)\n"
+	f := cst.New(source, "test.py")
+	p := parser.New(lexer.NewLexer(source, "test.py"))
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call, _ := parser.FindFirst[*parser.CallExpression](program)
+	if got := f.Text(call); got != "os.system(  # run it\n    cmd + arg)" {
+		t.Errorf("text: got %q", got)
+	}
+	if got := f.Lines(call); got != "result = os.system(  # run it\n    cmd + arg)" {
+		t.Errorf("lines: got %q", got)
+	}
+	if got := len(f.NodeTokens(call)); got != 8 {
+		t.Errorf("expected 8 tokens, got %d", got)
+	}
+	if got := f.Replace(call.Arguments[0], "shlex.quote(cmd + arg)"); got != "result = os.system(  # run it\n    shlex.quote(cmd + arg))\nprint(result)\n" {
+		t.Errorf("replace: got %q", got)
+	}
+}
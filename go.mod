@@ -1,5 +1,8 @@
 module github.com/coiloffaraday/python_sast
-require gopkg.in/yaml.v2 v2.4.0
 
+require (
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v2 v2.4.0
+)
 
 go 1.20
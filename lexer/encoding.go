@@ -0,0 +1,107 @@
+package lexer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+const utf8BOM = "\xef\xbb\xbf"
+
+// codingPattern matches a PEP 263 encoding declaration such as
+// "# -*- coding: latin-1 -*-".
+var codingPattern = regexp.MustCompile(`^[ \t\f]*#.*?coding[:=][ \t]*([-\w.]+)`)
+
+// detectEncoding returns the encoding declared on the first or second line
+// of src, or "" if there is none. The second line only counts when the
+// first is blank or a comment.
+func detectEncoding(src string) string {
+	lines := strings.SplitN(src, "\n", 3)
+	for i, line := range lines {
+		if i == 2 {
+			break
+		}
+		if m := codingPattern.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+		trimmed := strings.TrimLeft(line, " \t\f\r")
+		if trimmed != "" && trimmed[0] != '#' {
+			break
+		}
+	}
+	return ""
+}
+
+// isUTF8 reports whether a declared encoding name denotes UTF-8.
+func isUTF8(name string) bool {
+	switch normalizeEncodingName(name) {
+	case "utf-8", "utf8", "utf-8-sig":
+		return true
+	}
+	return strings.HasPrefix(normalizeEncodingName(name), "utf-8-")
+}
+
+func normalizeEncodingName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+}
+
+// lookupEncoding maps a Python codec name to a decoder.
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	normalized := normalizeEncodingName(name)
+	switch normalized {
+	case "latin-1", "latin1", "iso-8859-1", "iso8859-1", "l1":
+		normalized = "iso-8859-1"
+	case "ascii", "us-ascii":
+		normalized = "us-ascii"
+	}
+	if enc, err := ianaindex.IANA.Encoding(normalized); err == nil && enc != nil {
+		return enc, nil
+	}
+	if enc, err := htmlindex.Get(normalized); err == nil {
+		return enc, nil
+	}
+	return nil, fmt.Errorf("unknown encoding: %s", name)
+}
+
+// decodeSource transcodes src to UTF-8 according to its encoding
+// declaration. A UTF-8 byte order mark is kept; the lexer skips it.
+func decodeSource(src string) (string, error) {
+	hasBOM := strings.HasPrefix(src, utf8BOM)
+	name := detectEncoding(strings.TrimPrefix(src, utf8BOM))
+	if name == "" || isUTF8(name) {
+		return src, nil
+	}
+	if hasBOM {
+		return src, fmt.Errorf("encoding problem: %s with BOM", name)
+	}
+	enc, err := lookupEncoding(name)
+	if err != nil {
+		return src, err
+	}
+	decoded, err := enc.NewDecoder().String(src)
+	if err != nil {
+		return src, fmt.Errorf("could not decode source as %s: %v", name, err)
+	}
+	return decoded, nil
+}
+
+// EncodeSource transcodes UTF-8 text back to the named encoding, as
+// returned by Lexer.Encoding. An empty name means UTF-8.
+func EncodeSource(text string, name string) (string, error) {
+	if name == "" || isUTF8(name) {
+		return text, nil
+	}
+	enc, err := lookupEncoding(name)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := enc.NewEncoder().String(text)
+	if err != nil {
+		return "", fmt.Errorf("could not encode source as %s: %v", name, err)
+	}
+	return encoded, nil
+}
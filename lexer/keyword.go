@@ -0,0 +1,158 @@
+package lexer
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	sasttoken "github.com/coiloffaraday/python_sast/token"
+)
+
+// softKeyword returns the keyword type of the soft keyword name, which has
+// just been read, or IDENT when it is used as an ordinary name. Like
+// CPython's parser, it decides from the rest of the logical line:
+//
+//	match <subject>:          the line ends with the ':'
+//	case <pattern>: <body>    a top-level ':' follows the pattern
+//	type <name>[...] = ...
+func (l *Lexer) softKeyword(name string) sasttoken.TokenType {
+	tokType, ok := sasttoken.SoftKeywords[name]
+	if !ok || l.Version == sasttoken.Python2 || !l.atStatementStart() {
+		return sasttoken.IDENT
+	}
+
+	switch tokType {
+	case sasttoken.MATCH, sasttoken.CASE:
+		first, colon, endsWithColon := l.scanLogicalLine()
+		if first == 0 || strings.ContainsRune("=.,;:)]}", first) || !colon {
+			return sasttoken.IDENT
+		}
+		if tokType == sasttoken.MATCH && !endsWithColon {
+			return sasttoken.IDENT
+		}
+		return tokType
+	case sasttoken.TYPE:
+		if l.isTypeAlias() {
+			return tokType
+		}
+	}
+	return sasttoken.IDENT
+}
+
+// atStatementStart reports whether the next token begins a statement. A
+// simple statement may follow the ':' of a compound statement on one line.
+func (l *Lexer) atStatementStart() bool {
+	switch l.lastType {
+	case "", sasttoken.NEWLINE, sasttoken.INDENT, sasttoken.DEDENT, sasttoken.SEMICOLON:
+		return true
+	case sasttoken.COLON:
+		return l.parenDepth == 0
+	}
+	return false
+}
+
+// scanLogicalLine looks ahead from the current character to the end of the
+// logical line without consuming anything. It returns the first significant
+// character, whether a ':' occurs outside brackets, and whether the line
+// ends with one.
+func (l *Lexer) scanLogicalLine() (first rune, colon bool, endsWithColon bool) {
+	input := l.input
+	depth := 0
+	var last rune
+	for i := l.position; i < len(input); {
+		r, width := utf8.DecodeRuneInString(input[i:])
+		switch {
+		case r == '\\' && i+1 < len(input) && (input[i+1] == '\n' || input[i+1] == '\r'):
+			i += 2
+			continue
+		case r == ' ' || r == '\t' || r == '\f':
+			i += width
+			continue
+		case r == '#':
+			for i < len(input) && input[i] != '\n' && input[i] != '\r' {
+				i++
+			}
+			continue
+		case r == '\n' || r == '\r':
+			if depth == 0 {
+				return first, colon, last == ':'
+			}
+			i += width
+			continue
+		}
+
+		if first == 0 {
+			first = r
+		}
+		last = r
+		switch r {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		case ':':
+			if depth == 0 && (i+1 >= len(input) || input[i+1] != '=') {
+				colon = true
+			}
+		case '\'', '"':
+			i = skipQuoted(input, i)
+			continue
+		}
+		i += width
+	}
+	return first, colon, last == ':'
+}
+
+// skipQuoted returns the offset just past the string literal whose opening
+// quote is at input[i]. An unterminated literal extends to the end of the
+// line, or of the input if it is triple-quoted.
+func skipQuoted(input string, i int) int {
+	delim := input[i : i+1]
+	if strings.HasPrefix(input[i:], strings.Repeat(delim, 3)) {
+		delim = strings.Repeat(delim, 3)
+	}
+	i += len(delim)
+	for i < len(input) {
+		switch {
+		case input[i] == '\\':
+			i += 2
+		case strings.HasPrefix(input[i:], delim):
+			return i + len(delim)
+		case len(delim) == 1 && (input[i] == '\n' || input[i] == '\r'):
+			return i
+		default:
+			i++
+		}
+	}
+	return len(input)
+}
+
+// isTypeAlias reports whether "type" just read starts a type alias, that is
+// whether it is followed by a name and then '=' or '['.
+func (l *Lexer) isTypeAlias() bool {
+	input := l.input
+	i := skipBlanks(input, l.position)
+	if r, _ := utf8.DecodeRuneInString(input[i:]); !isIdentifierStart(r) {
+		return false
+	}
+	for i < len(input) {
+		r, width := utf8.DecodeRuneInString(input[i:])
+		if !isIdentifierContinue(r) {
+			break
+		}
+		i += width
+	}
+	i = skipBlanks(input, i)
+	if i >= len(input) {
+		return false
+	}
+	return input[i] == '[' || (input[i] == '=' && (i+1 >= len(input) || input[i+1] != '='))
+}
+
+func skipBlanks(input string, i int) int {
+	for i < len(input) && (input[i] == ' ' || input[i] == '\t' || input[i] == '\f') {
+		i++
+	}
+	return i
+}
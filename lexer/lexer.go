@@ -1,99 +1,319 @@
 package lexer
 
 import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+
 	sasttoken "github.com/coiloffaraday/python_sast/token"
 )
 
+// tabSize is the column width a tab advances to, as in CPython's tokenizer.
+const tabSize = 8
+
 type Lexer struct {
 	input        string
+	encoding     string // the declared encoding input was decoded from; "" for UTF-8
+	decodeErr    error  // the error, if any, in decoding input
 	position     int
 	readPosition int
-	ch           byte
+	base         int  // offset of input within the file, for NewLexerAt
+	ch           rune // current character, decoded from UTF-8
+	column       int  // 1-based column of ch, counted in characters
 	Line         int
 	FilePath     string
+
+	// start is the position of the first character of a fragment created
+	// by NewLexerAt, and is unset for a whole file.
+	start sasttoken.Position
+
+	// Version selects the keywords to recognize. It may be set before the
+	// first call to NextToken.
+	Version sasttoken.Version
+
+	// indentStack holds the indentation columns of the enclosing blocks, with
+	// tabs expanded to tabSize. altIndentStack holds the same levels measured
+	// with tabs counted as one column; the two must agree on every comparison
+	// or the indentation depends on the tab width.
+	indentStack    []int
+	altIndentStack []int
+	pending        []sasttoken.Token
+	parenDepth     int
+	atLineStart    bool
+	lastType       sasttoken.TokenType
+	errors         []Error
+
+	// trivia holds the comments read since the last token was returned;
+	// they are attached to the next one. comments holds every comment.
+	trivia   []sasttoken.Comment
+	comments []sasttoken.Comment
 }
 
+// NewLexer creates a lexer for the source of a Python file. The source is
+// transcoded to UTF-8 according to its PEP 263 encoding declaration, and a
+// leading byte order mark and shebang line are skipped.
 func NewLexer(input string, filePath string) *Lexer {
-	l := &Lexer{input: input, Line: 1, FilePath: filePath}
+	decoded, err := decodeSource(input)
+	l := newFileLexer(decoded, filePath)
+	if err != nil {
+		l.decodeErr = err
+		l.addError(sasttoken.Position{Line: 1}, "%s", err)
+	} else if decoded != input {
+		l.encoding = detectEncoding(input)
+	}
+	return l
+}
+
+// newFileLexer creates a lexer for the UTF-8 text of a whole file.
+func newFileLexer(input string, filePath string) *Lexer {
+	l := &Lexer{
+		input:          input,
+		Line:           1,
+		FilePath:       filePath,
+		indentStack:    []int{0},
+		altIndentStack: []int{0},
+		atLineStart:    true,
+	}
+	if strings.HasPrefix(l.input, utf8BOM) {
+		l.readPosition = len(utf8BOM)
+	}
+
 	l.readChar()
+	if l.ch == '#' && l.peekChar() == '!' {
+		for l.ch != '\n' && l.ch != '\r' && l.ch != 0 {
+			l.readChar()
+		}
+	}
 	return l
 }
 
+// NewLexerAt creates a lexer for a fragment of a file, such as an f-string
+// replacement field, whose first character is at start. Token positions are
+// reported relative to the whole file.
+func NewLexerAt(input string, filePath string, start sasttoken.Position) *Lexer {
+	l := &Lexer{
+		input:          input,
+		Line:           start.Line,
+		column:         start.Column - 1,
+		base:           start.Offset,
+		start:          start,
+		FilePath:       filePath,
+		indentStack:    []int{0},
+		altIndentStack: []int{0},
+		atLineStart:    true,
+	}
+	l.readChar()
+	return l
+}
+
+// Rewind returns a new lexer that reads the same input from the start with
+// the same Version, so that a file can be tokenized again, for instance as
+// another language version. Errors in decoding the file are carried over.
+func (l *Lexer) Rewind() *Lexer {
+	var r *Lexer
+	if l.start.IsValid() {
+		r = NewLexerAt(l.input, l.FilePath, l.start)
+	} else {
+		r = newFileLexer(l.input, l.FilePath)
+		r.encoding, r.decodeErr = l.encoding, l.decodeErr
+		if r.decodeErr != nil {
+			r.addError(sasttoken.Position{Line: 1}, "%s", r.decodeErr)
+		}
+	}
+	r.Version = l.Version
+	return r
+}
+
+// Source returns the text being tokenized, transcoded to UTF-8. Token
+// offsets index into it.
+func (l *Lexer) Source() string {
+	return l.input
+}
+
+// Encoding returns the encoding the source was decoded from, or "" if it
+// is UTF-8.
+func (l *Lexer) Encoding() string {
+	return l.encoding
+}
+
+// Error is a lexical error, such as an unterminated string.
+type Error struct {
+	Line   int
+	Column int // 1-based; 0 for errors in decoding the file
+	Msg    string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Errors returns the lexical errors found so far, formatted with their line.
+func (l *Lexer) Errors() []string {
+	errs := make([]string, len(l.errors))
+	for i, err := range l.errors {
+		errs[i] = err.Error()
+	}
+	return errs
+}
+
+// SyntaxErrors returns the lexical errors found so far.
+func (l *Lexer) SyntaxErrors() []Error {
+	return l.errors
+}
+
+// Comments returns every comment read so far, in source order.
+func (l *Lexer) Comments() []sasttoken.Comment {
+	return l.comments
+}
+
+func (l *Lexer) addError(pos sasttoken.Position, format string, args ...interface{}) {
+	l.errors = append(l.errors, Error{Line: pos.Line, Column: pos.Column, Msg: fmt.Sprintf(format, args...)})
+}
+
 func (l *Lexer) readChar() {
+	if l.ch == 0 && l.position == len(l.input) && l.column > 0 {
+		return
+	}
+	if l.ch == '\n' || (l.ch == '\r' && l.peekChar() != '\n') {
+		l.Line++
+		l.column = 0
+	}
+	l.position = l.readPosition
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		r, width := utf8.DecodeRuneInString(l.input[l.readPosition:])
+		l.ch = r
+		l.readPosition += width
 	}
-	l.position = l.readPosition
-	l.readPosition++
-	if l.ch == '\n' {
-		l.Line++
+	l.column++
+}
+
+func (l *Lexer) peekChar() rune {
+	if l.readPosition >= len(l.input) {
+		return 0
 	}
+	r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return r
 }
 
+// NextToken returns the next token of the input. Besides the ordinary tokens
+// it produces NEWLINE at the end of every logical line and INDENT/DEDENT
+// whenever the indentation of a logical line changes.
 func (l *Lexer) NextToken() sasttoken.Token {
 	var tok sasttoken.Token
+	if len(l.pending) > 0 {
+		tok = l.pending[0]
+		l.pending = l.pending[1:]
+	} else {
+		tok = l.scanToken()
+	}
+	if len(l.trivia) > 0 {
+		tok.Comments = append(l.trivia, tok.Comments...)
+		l.trivia = nil
+	}
+	l.lastType = tok.Type
+	return tok
+}
+
+func (l *Lexer) scanToken() sasttoken.Token {
+	if l.atLineStart {
+		l.atLineStart = false
+		if l.readIndentation() {
+			return l.NextToken()
+		}
+	}
 
 	l.skipWhitespace()
+	if l.ch == 0 {
+		return l.readEOF()
+	}
+
+	start := l.pos()
+	tok := l.readToken()
+	setSpan(&tok, start, l.pos())
+	tok.FilePath = l.FilePath
+	return tok
+}
+
+// pos returns the position of the current character.
+func (l *Lexer) pos() sasttoken.Position {
+	return sasttoken.Position{Line: l.Line, Column: l.column, Offset: l.base + l.position}
+}
+
+func setSpan(tok *sasttoken.Token, start, end sasttoken.Position) {
+	tok.Line, tok.Column, tok.Offset = start.Line, start.Column, start.Offset
+	tok.EndLine, tok.EndColumn, tok.EndOffset = end.Line, end.Column, end.Offset
+}
+
+// readToken reads the token starting at the current character. The caller
+// fills in its position.
+func (l *Lexer) readToken() sasttoken.Token {
+	var tok sasttoken.Token
 
 	switch l.ch {
-	case '=':
-		tok = newToken(sasttoken.ASSIGN, l.ch, l.Line, l.FilePath)
-	case ';':
-		tok = newToken(sasttoken.SEMICOLON, l.ch, l.Line, l.FilePath)
+	case '\n', '\r':
+		tok = l.readNewline()
+		l.atLineStart = true
+		return tok
+	case '\\':
+		// skipWhitespace consumes every valid continuation, so this one is
+		// followed by something other than a line break.
+		l.addError(l.pos(), "unexpected character after line continuation character")
+		tok = newToken(sasttoken.ILLEGAL, l.ch)
 	case '(':
-		tok = newToken(sasttoken.LPAREN, l.ch, l.Line, l.FilePath)
+		l.parenDepth++
+		tok = newToken(sasttoken.LPAREN, l.ch)
 	case ')':
-		tok = newToken(sasttoken.RPAREN, l.ch, l.Line, l.FilePath)
-	case ',':
-		tok = newToken(sasttoken.COMMA, l.ch, l.Line, l.FilePath)
-	case '+':
-		tok = newToken(sasttoken.PLUS, l.ch, l.Line, l.FilePath)
-	case '-':
-		tok = newToken(sasttoken.MINUS, l.ch, l.Line, l.FilePath)
-	case '!':
-		tok = newToken(sasttoken.BANG, l.ch, l.Line, l.FilePath)
-	case '/':
-		tok = newToken(sasttoken.SLASH, l.ch, l.Line, l.FilePath)
-	case '*':
-		tok = newToken(sasttoken.ASTERISK, l.ch, l.Line, l.FilePath)
-	case '<':
-		tok = newToken(sasttoken.LT, l.ch, l.Line, l.FilePath)
-	case '>':
-		tok = newToken(sasttoken.GT, l.ch, l.Line, l.FilePath)
-	case ':':
-		tok = newToken(sasttoken.COLON, l.ch, l.Line, l.FilePath)
-	case '|':
-		tok = newToken(sasttoken.PIPE, l.ch, l.Line, l.FilePath)
-	case '&':
-		tok = newToken(sasttoken.AMPERSAND, l.ch, l.Line, l.FilePath)
-	case '%':
-		tok = newToken(sasttoken.MOD, l.ch, l.Line, l.FilePath)
-	case '^':
-		tok = newToken(sasttoken.CARET, l.ch, l.Line, l.FilePath)
-	case '~':
-		tok = newToken(sasttoken.TILDE, l.ch, l.Line, l.FilePath)
-	case 0:
-		tok.Literal = ""
-		tok.Type = sasttoken.EOF
-		tok.Line = l.Line
-		tok.FilePath = l.FilePath
+		l.closeParen()
+		tok = newToken(sasttoken.RPAREN, l.ch)
+	case '[':
+		l.parenDepth++
+		tok = newToken(sasttoken.LBRACKET, l.ch)
+	case ']':
+		l.closeParen()
+		tok = newToken(sasttoken.RBRACKET, l.ch)
+	case '{':
+		l.parenDepth++
+		tok = newToken(sasttoken.LBRACE, l.ch)
+	case '}':
+		l.closeParen()
+		tok = newToken(sasttoken.RBRACE, l.ch)
+	case '\'', '"':
+		return l.readString(l.position)
+	case '`':
+		if l.Version == sasttoken.Python2 {
+			tok = newToken(sasttoken.BACKTICK, l.ch)
+		} else {
+			l.addError(l.pos(), "invalid character '%c' (U+%04X)", l.ch, l.ch)
+			tok = newToken(sasttoken.ILLEGAL, l.ch)
+		}
 	default:
-		if isLetter(l.ch) {
+		if isIdentifierStart(l.ch) {
+			start := l.position
 			tok.Literal = l.readIdentifier()
-			tok.Type = sasttoken.LookupIdent(tok.Literal)
-			tok.Line = l.Line
-			tok.FilePath = l.FilePath
+			if isQuote(l.ch) && l.isStringPrefix(tok.Literal) {
+				return l.readString(start)
+			}
+			tok.Type = sasttoken.LookupIdentVersion(tok.Literal, l.Version)
+			if tok.Type == sasttoken.IDENT {
+				tok.Type = l.softKeyword(tok.Literal)
+			}
 			return tok
-		} else if isDigit(l.ch) {
-			tok.Type = sasttoken.INT
-			tok.Literal = l.readNumber()
-			tok.Line = l.Line
-			tok.FilePath = l.FilePath
+		} else if isDigit(l.ch) || (l.ch == '.' && isDigit(l.peekChar())) {
+			tok.Type, tok.Literal = l.readNumber()
 			return tok
+		} else if op, ok := l.readOperator(); ok {
+			return op
+		} else if l.ch == utf8.RuneError && l.readPosition-l.position == 1 {
+			l.addError(l.pos(), "invalid UTF-8 byte 0x%02x", l.input[l.position])
+			tok = sasttoken.Token{Type: sasttoken.ILLEGAL, Literal: l.input[l.position:l.readPosition]}
 		} else {
-			tok = newToken(sasttoken.ILLEGAL, l.ch, l.Line, l.FilePath)
+			l.addError(l.pos(), "invalid character '%c' (U+%04X)", l.ch, l.ch)
+			tok = newToken(sasttoken.ILLEGAL, l.ch)
 		}
 	}
 
@@ -101,36 +321,278 @@ func (l *Lexer) NextToken() sasttoken.Token {
 	return tok
 }
 
-func newToken(tokenType sasttoken.TokenType, ch byte, line int, filePath string) sasttoken.Token {
-	return sasttoken.Token{Type: tokenType, Literal: string(ch), Line: line, FilePath: filePath}
+func newToken(tokenType sasttoken.TokenType, ch rune) sasttoken.Token {
+	return sasttoken.Token{Type: tokenType, Literal: string(ch)}
 }
 
-func (l *Lexer) readIdentifier() string {
-	position := l.position
-	for isLetter(l.ch) {
+// readOperator reads the longest operator or delimiter starting at the
+// current position.
+func (l *Lexer) readOperator() (sasttoken.Token, bool) {
+	if l.Version == sasttoken.Python2 && strings.HasPrefix(l.input[l.position:], "<>") {
+		l.readChar()
 		l.readChar()
+		return sasttoken.Token{Type: sasttoken.NE, Literal: "<>"}, true
+	}
+	for n := sasttoken.MaxOperatorLen; n > 0; n-- {
+		if l.position+n > len(l.input) {
+			continue
+		}
+		literal := l.input[l.position : l.position+n]
+		if tokType, ok := sasttoken.Operators[literal]; ok {
+			tok := sasttoken.Token{Type: tokType, Literal: literal}
+			for i := 0; i < n; i++ {
+				l.readChar()
+			}
+			return tok, true
+		}
+	}
+	return sasttoken.Token{}, false
+}
+
+func (l *Lexer) closeParen() {
+	if l.parenDepth > 0 {
+		l.parenDepth--
 	}
-	return l.input[position:l.position]
 }
 
-func (l *Lexer) readNumber() string {
+// readIndentation measures the indentation of the line starting at the
+// current position and queues the INDENT or DEDENT tokens it implies. Blank
+// lines are consumed without affecting the indentation. It reports whether
+// any tokens were queued.
+func (l *Lexer) readIndentation() bool {
+	for {
+		start := l.pos()
+		col, altCol := 0, 0
+		for {
+			if l.ch == ' ' {
+				col++
+				altCol++
+			} else if l.ch == '\t' {
+				col = (col/tabSize + 1) * tabSize
+				altCol++
+			} else if l.ch == '\f' {
+				col, altCol = 0, 0
+			} else {
+				break
+			}
+			l.readChar()
+		}
+
+		if l.ch == '#' {
+			l.readComment()
+		}
+		if l.ch == '\n' || l.ch == '\r' {
+			l.readNewline()
+			continue
+		}
+		if l.ch == 0 {
+			return false
+		}
+
+		pos := l.pos()
+		top := len(l.indentStack) - 1
+		switch {
+		case col == l.indentStack[top]:
+			if altCol != l.altIndentStack[top] {
+				l.addError(pos, "inconsistent use of tabs and spaces in indentation")
+			}
+		case col > l.indentStack[top]:
+			if altCol <= l.altIndentStack[top] {
+				l.addError(pos, "inconsistent use of tabs and spaces in indentation")
+			}
+			l.indentStack = append(l.indentStack, col)
+			l.altIndentStack = append(l.altIndentStack, altCol)
+			indent := sasttoken.Token{Type: sasttoken.INDENT, Literal: l.input[start.Offset-l.base : l.position], FilePath: l.FilePath}
+			setSpan(&indent, start, l.pos())
+			l.pending = append(l.pending, indent)
+		default:
+			for len(l.indentStack) > 1 && col < l.indentStack[len(l.indentStack)-1] {
+				l.indentStack = l.indentStack[:len(l.indentStack)-1]
+				l.altIndentStack = l.altIndentStack[:len(l.altIndentStack)-1]
+				dedent := sasttoken.Token{Type: sasttoken.DEDENT, FilePath: l.FilePath}
+				setSpan(&dedent, l.pos(), l.pos())
+				l.pending = append(l.pending, dedent)
+			}
+			top = len(l.indentStack) - 1
+			if col != l.indentStack[top] {
+				l.addError(pos, "unindent does not match any outer indentation level")
+			} else if altCol != l.altIndentStack[top] {
+				l.addError(pos, "inconsistent use of tabs and spaces in indentation")
+			}
+		}
+		return len(l.pending) > 0
+	}
+}
+
+// readNewline consumes a "\n", "\r\n" or "\r" line break.
+func (l *Lexer) readNewline() sasttoken.Token {
+	tok := sasttoken.Token{Type: sasttoken.NEWLINE, FilePath: l.FilePath}
+	start, position := l.pos(), l.position
+	if l.ch == '\r' && l.peekChar() == '\n' {
+		l.readChar()
+	}
+	l.readChar()
+	tok.Literal = l.input[position:l.position]
+	setSpan(&tok, start, l.pos())
+	return tok
+}
+
+// readEOF terminates the last logical line and closes every open block
+// before returning EOF.
+func (l *Lexer) readEOF() sasttoken.Token {
+	eof := sasttoken.Token{Type: sasttoken.EOF, FilePath: l.FilePath}
+	setSpan(&eof, l.pos(), l.pos())
+	switch l.lastType {
+	case "", sasttoken.NEWLINE, sasttoken.INDENT, sasttoken.DEDENT, sasttoken.EOF:
+	default:
+		newline := eof
+		newline.Type = sasttoken.NEWLINE
+		l.pending = append(l.pending, newline)
+	}
+	for len(l.indentStack) > 1 {
+		l.indentStack = l.indentStack[:len(l.indentStack)-1]
+		l.altIndentStack = l.altIndentStack[:len(l.altIndentStack)-1]
+		dedent := eof
+		dedent.Type = sasttoken.DEDENT
+		l.pending = append(l.pending, dedent)
+	}
+	if len(l.pending) == 0 {
+		return eof
+	}
+	l.pending = append(l.pending, eof)
+	return l.NextToken()
+}
+
+// readIdentifier reads an identifier and returns it in NFKC normal form, as
+// PEP 3131 requires, so that differently spelled equivalent names compare
+// equal.
+func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isDigit(l.ch) {
+	ascii := true
+	for isIdentifierContinue(l.ch) {
+		if l.ch >= utf8.RuneSelf {
+			ascii = false
+		}
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	if ascii {
+		return l.input[position:l.position]
+	}
+	return norm.NFKC.String(l.input[position:l.position])
 }
 
+// skipWhitespace skips blanks within a line. Line breaks are skipped as well
+// inside brackets (implicit line joining) and after a backslash (explicit
+// line joining); elsewhere they end the logical line.
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\f':
+			l.readChar()
+		case (l.ch == '\n' || l.ch == '\r') && l.parenDepth > 0:
+			if l.startsStatement() {
+				// The brackets were never closed. Ending the logical line
+				// here confines the error to it, rather than to the rest
+				// of the file.
+				l.parenDepth = 0
+				return
+			}
+			l.readNewline()
+		case l.ch == '\\' && (l.peekChar() == '\n' || l.peekChar() == '\r'):
+			l.readChar()
+			l.readNewline()
+		case l.ch == '#':
+			l.readComment()
+		default:
+			return
+		}
+	}
+}
+
+// recoveryKeywords begin the statements that cannot continue an expression
+// left open on the line before.
+var recoveryKeywords = []string{
+	"def", "class", "if", "for", "while", "try", "with", "import", "from", "return",
+}
+
+// startsStatement reports whether the physical line after the line break at
+// the current character starts a statement: it is indented at or left of
+// the enclosing block and begins with a decorator or one of
+// recoveryKeywords.
+func (l *Lexer) startsStatement() bool {
+	i := l.position
+	if l.input[i] == '\r' && i+1 < len(l.input) && l.input[i+1] == '\n' {
+		i++
+	}
+	i++
+	col := 0
+	for ; i < len(l.input); i++ {
+		if l.input[i] == ' ' {
+			col++
+		} else if l.input[i] == '\t' {
+			col = (col/tabSize + 1) * tabSize
+		} else {
+			break
+		}
+	}
+	if col > l.indentStack[len(l.indentStack)-1] {
+		return false
+	}
+
+	rest := l.input[i:]
+	if strings.HasPrefix(rest, "@") {
+		return true
+	}
+	for _, keyword := range recoveryKeywords {
+		if !strings.HasPrefix(rest, keyword) {
+			continue
+		}
+		next, _ := utf8.DecodeRuneInString(rest[len(keyword):])
+		if !isIdentifierContinue(next) {
+			return true
+		}
+	}
+	return false
+}
+
+// readComment reads a comment up to, but not including, the line break.
+func (l *Lexer) readComment() {
+	position := l.position
+	comment := sasttoken.Comment{Line: l.Line}
+	for l.ch != '\n' && l.ch != '\r' && l.ch != 0 {
 		l.readChar()
 	}
+	comment.Text = l.input[position:l.position]
+	l.trivia = append(l.trivia, comment)
+	l.comments = append(l.comments, comment)
+}
+
+// isIdentifierStart reports whether ch may begin an identifier. Non-ASCII
+// characters follow PEP 3131: the ID_Start property, which Go exposes as the
+// letter categories plus Other_ID_Start.
+func isIdentifierStart(ch rune) bool {
+	if ch < utf8.RuneSelf {
+		return isLetter(ch)
+	}
+	return unicode.In(ch, unicode.Lu, unicode.Ll, unicode.Lt, unicode.Lm, unicode.Lo, unicode.Nl, unicode.Other_ID_Start) &&
+		!unicode.In(ch, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
+}
+
+// isIdentifierContinue reports whether ch may continue an identifier
+// (ID_Continue).
+func isIdentifierContinue(ch rune) bool {
+	if ch < utf8.RuneSelf {
+		return isLetter(ch) || isDigit(ch)
+	}
+	return isIdentifierStart(ch) ||
+		unicode.In(ch, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue) &&
+			!unicode.In(ch, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
 }
 
-func isLetter(ch byte) bool {
+func isLetter(ch rune) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
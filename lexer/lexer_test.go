@@ -1 +1,633 @@
 package lexer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/lexer"
+	sasttoken "github.com/coiloffaraday/python_sast/token"
+)
+
+func tokenTypes(t *testing.T, input string) []sasttoken.TokenType {
+	t.Helper()
+	l := lexer.NewLexer(input, "test.py")
+	var types []sasttoken.TokenType
+	for i := 0; i < 1000; i++ {
+		tok := l.NextToken()
+		types = append(types, tok.Type)
+		if tok.Type == sasttoken.EOF {
+			return types
+		}
+	}
+	t.Fatalf("lexer did not reach EOF for %q", input)
+	return nil
+}
+
+func assertTypes(t *testing.T, input string, expected ...sasttoken.TokenType) {
+	t.Helper()
+	got := tokenTypes(t, input)
+	if len(got) != len(expected) {
+		t.Fatalf("%q: expected %v, got %v", input, expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("%q: token %d: expected %v, got %v (all: %v)", input, i, expected[i], got[i], got)
+		}
+	}
+}
+
+func TestIndentation(t *testing.T) {
+	input := "def f(x):\n    if x:\n        return x\n\n    return 0\ny = 1\n"
+	assertTypes(t, input,
+		sasttoken.DEF, sasttoken.IDENT, sasttoken.LPAREN, sasttoken.IDENT, sasttoken.RPAREN, sasttoken.COLON, sasttoken.NEWLINE,
+		sasttoken.INDENT, sasttoken.IF, sasttoken.IDENT, sasttoken.COLON, sasttoken.NEWLINE,
+		sasttoken.INDENT, sasttoken.RETURN, sasttoken.IDENT, sasttoken.NEWLINE,
+		sasttoken.DEDENT, sasttoken.RETURN, sasttoken.INT, sasttoken.NEWLINE,
+		sasttoken.DEDENT, sasttoken.IDENT, sasttoken.ASSIGN, sasttoken.INT, sasttoken.NEWLINE,
+		sasttoken.EOF,
+	)
+}
+
+func TestDedentAtEOF(t *testing.T) {
+	assertTypes(t, "while x:\n  pass",
+		sasttoken.WHILE, sasttoken.IDENT, sasttoken.COLON, sasttoken.NEWLINE,
+		sasttoken.INDENT, sasttoken.PASS, sasttoken.NEWLINE,
+		sasttoken.DEDENT, sasttoken.EOF,
+	)
+}
+
+func TestImplicitLineJoining(t *testing.T) {
+	assertTypes(t, "f(a,\n      b,\n  [c,\n d])\n",
+		sasttoken.IDENT, sasttoken.LPAREN, sasttoken.IDENT, sasttoken.COMMA,
+		sasttoken.IDENT, sasttoken.COMMA,
+		sasttoken.LBRACKET, sasttoken.IDENT, sasttoken.COMMA,
+		sasttoken.IDENT, sasttoken.RBRACKET, sasttoken.RPAREN, sasttoken.NEWLINE,
+		sasttoken.EOF,
+	)
+}
+
+func TestUnclosedBracket(t *testing.T) {
+	// A line that starts a statement ends the brackets left open before it.
+	assertTypes(t, "x = f(a,\ndef g(): pass\n",
+		sasttoken.IDENT, sasttoken.ASSIGN, sasttoken.IDENT, sasttoken.LPAREN, sasttoken.IDENT, sasttoken.COMMA, sasttoken.NEWLINE,
+		sasttoken.DEF, sasttoken.IDENT, sasttoken.LPAREN, sasttoken.RPAREN, sasttoken.COLON, sasttoken.PASS, sasttoken.NEWLINE,
+		sasttoken.EOF,
+	)
+	// Indented further than the enclosing block, or not a keyword, it
+	// continues the expression.
+	assertTypes(t, "x = [a\n  for a in b\n  if a, classes\n]\n",
+		sasttoken.IDENT, sasttoken.ASSIGN, sasttoken.LBRACKET, sasttoken.IDENT,
+		sasttoken.FOR, sasttoken.IDENT, sasttoken.IN, sasttoken.IDENT,
+		sasttoken.IF, sasttoken.IDENT, sasttoken.COMMA, sasttoken.IDENT,
+		sasttoken.RBRACKET, sasttoken.NEWLINE,
+		sasttoken.EOF,
+	)
+	assertTypes(t, "if x:\n    f(a,\n    @d\n",
+		sasttoken.IF, sasttoken.IDENT, sasttoken.COLON, sasttoken.NEWLINE,
+		sasttoken.INDENT, sasttoken.IDENT, sasttoken.LPAREN, sasttoken.IDENT, sasttoken.COMMA, sasttoken.NEWLINE,
+		sasttoken.AT, sasttoken.IDENT, sasttoken.NEWLINE,
+		sasttoken.DEDENT, sasttoken.EOF,
+	)
+}
+
+func TestBackslashContinuation(t *testing.T) {
+	assertTypes(t, "x = 1 + \\\n        2\ny = 3\r\n",
+		sasttoken.IDENT, sasttoken.ASSIGN, sasttoken.INT, sasttoken.PLUS, sasttoken.INT, sasttoken.NEWLINE,
+		sasttoken.IDENT, sasttoken.ASSIGN, sasttoken.INT, sasttoken.NEWLINE,
+		sasttoken.EOF,
+	)
+}
+
+func TestBlankLinesDoNotChangeIndentation(t *testing.T) {
+	assertTypes(t, "if a:\n\n   \n\tb\n",
+		sasttoken.IF, sasttoken.IDENT, sasttoken.COLON, sasttoken.NEWLINE,
+		sasttoken.INDENT, sasttoken.IDENT, sasttoken.NEWLINE,
+		sasttoken.DEDENT, sasttoken.EOF,
+	)
+}
+
+func TestInconsistentTabsAndSpaces(t *testing.T) {
+	l := lexer.NewLexer("if a:\n\tb\n        c\n", "test.py")
+	for tok := l.NextToken(); tok.Type != sasttoken.EOF; tok = l.NextToken() {
+	}
+	errs := l.Errors()
+	if len(errs) != 1 || !strings.Contains(errs[0], "inconsistent use of tabs and spaces") || !strings.HasPrefix(errs[0], "line 3:") {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
+
+func TestUnindentMismatch(t *testing.T) {
+	l := lexer.NewLexer("if a:\n    b\n  c\n", "test.py")
+	for tok := l.NextToken(); tok.Type != sasttoken.EOF; tok = l.NextToken() {
+	}
+	errs := l.Errors()
+	if len(errs) != 1 || !strings.Contains(errs[0], "unindent does not match") {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input   string
+		literal string
+		value   string
+	}{
+		{`'abc'`, `'abc'`, "abc"},
+		{`"it's"`, `"it's"`, "it's"},
+		{`"a\tb\n"`, `"a\tb\n"`, "a\tb\n"},
+		{`'\x41\101\u00e9\U0001F600'`, `'\x41\101\u00e9\U0001F600'`, "AA\u00e9\U0001F600"},
+		{`r"C:\path\n"`, `r"C:\path\n"`, `C:\path\n`},
+		{`Rb'\d+'`, `Rb'\d+'`, `\d+`},
+		{`b'\xff'`, `b'\xff'`, "\xff"},
+		{`u"\q"`, `u"\q"`, `\q`},
+		{`f"id={uid}"`, `f"id={uid}"`, "id={uid}"},
+		{`"say \"hi\""`, `"say \"hi\""`, `say "hi"`},
+		{`r"\""`, `r"\""`, `\"`},
+		{"'''a\n'b'\n\"\"'''", "'''a\n'b'\n\"\"'''", "a\n'b'\n\"\""},
+		{"\"\"\"x\\\ny\"\"\"", "\"\"\"x\\\ny\"\"\"", "xy"},
+		{`''`, `''`, ""},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input, "test.py")
+		tok := l.NextToken()
+		if tok.Type != sasttoken.STRING {
+			t.Fatalf("%s: expected STRING, got %s (%v)", tt.input, tok.Type, l.Errors())
+		}
+		if tok.Literal != tt.literal {
+			t.Errorf("%s: expected literal %q, got %q", tt.input, tt.literal, tok.Literal)
+		}
+		if tok.Value != tt.value {
+			t.Errorf("%s: expected value %q, got %q", tt.input, tt.value, tok.Value)
+		}
+		if next := l.NextToken(); next.Type != sasttoken.NEWLINE {
+			t.Errorf("%s: expected NEWLINE after string, got %s %q", tt.input, next.Type, next.Literal)
+		}
+		if len(l.Errors()) != 0 {
+			t.Errorf("%s: unexpected errors %v", tt.input, l.Errors())
+		}
+	}
+}
+
+func TestStringPrefixIsNotIdentifier(t *testing.T) {
+	assertTypes(t, "x = rb'a' + f\"b\" + br\n",
+		sasttoken.IDENT, sasttoken.ASSIGN, sasttoken.STRING, sasttoken.PLUS, sasttoken.STRING, sasttoken.PLUS, sasttoken.IDENT, sasttoken.NEWLINE,
+		sasttoken.EOF,
+	)
+}
+
+func TestUnterminatedString(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"x = 1\ny = 'abc\nz = 2\n", "line 2: unterminated string literal"},
+		{"x = 1\n\ny = \"\"\"abc\n\nz = 2\n", "line 3: unterminated triple-quoted string literal"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input, "test.py")
+		for tok := l.NextToken(); tok.Type != sasttoken.EOF; tok = l.NextToken() {
+		}
+		errs := l.Errors()
+		if len(errs) != 1 || errs[0] != tt.err {
+			t.Errorf("%q: expected error %q, got %v", tt.input, tt.err, errs)
+		}
+	}
+}
+
+func TestFStringNestedQuotes(t *testing.T) {
+	tests := []string{
+		`f"{d["key"]}"`,
+		`f'{x:{width}} {y!r:>10}'`,
+		`f"{'}'}"`,
+		`f"{{literal}} {a}"`,
+	}
+
+	for _, input := range tests {
+		l := lexer.NewLexer(input, "test.py")
+		tok := l.NextToken()
+		if tok.Type != sasttoken.STRING || tok.Literal != input {
+			t.Errorf("%s: expected a single STRING token, got %s %q", input, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestNumericLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected sasttoken.TokenType
+	}{
+		{"0", sasttoken.INT},
+		{"123", sasttoken.INT},
+		{"1_000_000", sasttoken.INT},
+		{"00", sasttoken.INT},
+		{"0x1F", sasttoken.INT},
+		{"0X_ff", sasttoken.INT},
+		{"0o755", sasttoken.INT},
+		{"0O777", sasttoken.INT},
+		{"0b1010", sasttoken.INT},
+		{"123456789012345678901234567890", sasttoken.INT},
+		{"3.14", sasttoken.FLOAT},
+		{"10.", sasttoken.FLOAT},
+		{".5", sasttoken.FLOAT},
+		{"1e-3", sasttoken.FLOAT},
+		{"1E+10", sasttoken.FLOAT},
+		{"6.02e23", sasttoken.FLOAT},
+		{"1_0.0_1e1_0", sasttoken.FLOAT},
+		{"012.5", sasttoken.FLOAT},
+		{"2j", sasttoken.IMAGINARY},
+		{"1.5J", sasttoken.IMAGINARY},
+		{"1e3j", sasttoken.IMAGINARY},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input, "test.py")
+		tok := l.NextToken()
+		if tok.Type != tt.expected || tok.Literal != tt.input {
+			t.Errorf("%s: expected %s %q, got %s %q (%v)", tt.input, tt.expected, tt.input, tok.Type, tok.Literal, l.Errors())
+		}
+		if next := l.NextToken(); next.Type != sasttoken.NEWLINE {
+			t.Errorf("%s: expected NEWLINE after number, got %s %q", tt.input, next.Type, next.Literal)
+		}
+	}
+}
+
+func TestInvalidNumericLiterals(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"0777", "leading zeros in decimal integer literals"},
+		{"1__0", "invalid decimal literal"},
+		{"1_", "invalid decimal literal"},
+		{"0x", "invalid hexadecimal literal"},
+		{"0o8", "invalid octal literal"},
+		{"0b102", "invalid digit '2' in binary literal"},
+		{"1abc", "invalid decimal literal"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input, "test.py")
+		tok := l.NextToken()
+		if tok.Type != sasttoken.ILLEGAL || tok.Literal != tt.input {
+			t.Errorf("%s: expected ILLEGAL %q, got %s %q", tt.input, tt.input, tok.Type, tok.Literal)
+		}
+		if errs := l.Errors(); len(errs) != 1 || !strings.Contains(errs[0], tt.err) {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.err, errs)
+		}
+	}
+}
+
+func TestNumberFollowedByKeyword(t *testing.T) {
+	assertTypes(t, "x = 1if y else 2\n",
+		sasttoken.IDENT, sasttoken.ASSIGN, sasttoken.INT, sasttoken.IF, sasttoken.IDENT, sasttoken.ELSE, sasttoken.INT, sasttoken.NEWLINE,
+		sasttoken.EOF,
+	)
+}
+
+func TestOperators(t *testing.T) {
+	input := "a ** b // c @ d << e >> f <= g >= h == i != j -> k := l ... m.n "
+	input += "+= -= *= **= /= //= @= %= &= |= ^= <<= >>= = < > + - * / % & | ^ ~ , ; :"
+
+	expected := []sasttoken.TokenType{
+		sasttoken.IDENT, sasttoken.POWER, sasttoken.IDENT, sasttoken.FLOORDIV, sasttoken.IDENT, sasttoken.AT,
+		sasttoken.IDENT, sasttoken.LSHIFT, sasttoken.IDENT, sasttoken.RSHIFT, sasttoken.IDENT, sasttoken.LE,
+		sasttoken.IDENT, sasttoken.GE, sasttoken.IDENT, sasttoken.EQ, sasttoken.IDENT, sasttoken.NE,
+		sasttoken.IDENT, sasttoken.ARROW, sasttoken.IDENT, sasttoken.WALRUS, sasttoken.IDENT, sasttoken.ELLIPSIS,
+		sasttoken.IDENT, sasttoken.DOT, sasttoken.IDENT,
+		sasttoken.PLUS_ASSIGN, sasttoken.MINUS_ASSIGN, sasttoken.ASTERISK_ASSIGN, sasttoken.POWER_ASSIGN,
+		sasttoken.SLASH_ASSIGN, sasttoken.FLOORDIV_ASSIGN, sasttoken.AT_ASSIGN, sasttoken.MOD_ASSIGN,
+		sasttoken.AMPERSAND_ASSIGN, sasttoken.PIPE_ASSIGN, sasttoken.CARET_ASSIGN, sasttoken.LSHIFT_ASSIGN,
+		sasttoken.RSHIFT_ASSIGN, sasttoken.ASSIGN, sasttoken.LT, sasttoken.GT, sasttoken.PLUS, sasttoken.MINUS,
+		sasttoken.ASTERISK, sasttoken.SLASH, sasttoken.MOD, sasttoken.AMPERSAND, sasttoken.PIPE, sasttoken.CARET,
+		sasttoken.TILDE, sasttoken.COMMA, sasttoken.SEMICOLON, sasttoken.COLON,
+		sasttoken.NEWLINE, sasttoken.EOF,
+	}
+	assertTypes(t, input, expected...)
+}
+
+func TestDottedCall(t *testing.T) {
+	l := lexer.NewLexer("os.system(cmd)", "test.py")
+	var literals []string
+	for tok := l.NextToken(); tok.Type != sasttoken.NEWLINE; tok = l.NextToken() {
+		literals = append(literals, tok.Literal)
+	}
+	if got := strings.Join(literals, " "); got != "os . system ( cmd )" {
+		t.Fatalf("unexpected tokens %q", got)
+	}
+}
+
+func TestMaximalMunch(t *testing.T) {
+	assertTypes(t, "a**=b\n",
+		sasttoken.IDENT, sasttoken.POWER_ASSIGN, sasttoken.IDENT, sasttoken.NEWLINE, sasttoken.EOF)
+	assertTypes(t, "a....b\n",
+		sasttoken.IDENT, sasttoken.ELLIPSIS, sasttoken.DOT, sasttoken.IDENT, sasttoken.NEWLINE, sasttoken.EOF)
+	assertTypes(t, "x=-1.5\n",
+		sasttoken.IDENT, sasttoken.ASSIGN, sasttoken.MINUS, sasttoken.FLOAT, sasttoken.NEWLINE, sasttoken.EOF)
+}
+
+func TestComments(t *testing.T) {
+	input := "# header\nx = 1  # nosec\nif x:\n    # inside\n    y = (1,  # first\n         2)\n# trailing\n"
+	assertTypes(t, input,
+		sasttoken.IDENT, sasttoken.ASSIGN, sasttoken.INT, sasttoken.NEWLINE,
+		sasttoken.IF, sasttoken.IDENT, sasttoken.COLON, sasttoken.NEWLINE,
+		sasttoken.INDENT, sasttoken.IDENT, sasttoken.ASSIGN, sasttoken.LPAREN, sasttoken.INT, sasttoken.COMMA,
+		sasttoken.INT, sasttoken.RPAREN, sasttoken.NEWLINE,
+		sasttoken.DEDENT, sasttoken.EOF,
+	)
+
+	l := lexer.NewLexer(input, "test.py")
+	attached := map[string]sasttoken.TokenType{}
+	for tok := l.NextToken(); tok.Type != sasttoken.EOF; tok = l.NextToken() {
+		for _, c := range tok.Comments {
+			attached[c.Text] = tok.Type
+		}
+	}
+	expected := map[string]sasttoken.TokenType{
+		"# header":   sasttoken.IDENT,
+		"# nosec":    sasttoken.NEWLINE,
+		"# inside":   sasttoken.INDENT,
+		"# first":    sasttoken.INT,
+		"# trailing": sasttoken.DEDENT,
+	}
+	for text, tokType := range expected {
+		if attached[text] != tokType {
+			t.Errorf("comment %q: expected to be attached to %s, got %q", text, tokType, attached[text])
+		}
+	}
+	if n := len(l.Comments()); n != len(expected) {
+		t.Errorf("expected %d comments, got %d", len(expected), n)
+	}
+}
+
+func TestShebangAndBOM(t *testing.T) {
+	input := "\xef\xbb\xbf#!/usr/bin/env python3\nimport os\n"
+	l := lexer.NewLexer(input, "test.py")
+	tok := l.NextToken()
+	if tok.Type != sasttoken.IMPORT || tok.Line != 2 || len(tok.Comments) != 0 {
+		t.Fatalf("expected IMPORT on line 2 without comments, got %s on line %d %v", tok.Type, tok.Line, tok.Comments)
+	}
+}
+
+func TestEncodingDeclaration(t *testing.T) {
+	input := "#!/usr/bin/python\n# -*- coding: latin-1 -*-\nname = '\xe9t\xe9'\n"
+	l := lexer.NewLexer(input, "test.py")
+	var value string
+	for tok := l.NextToken(); tok.Type != sasttoken.EOF; tok = l.NextToken() {
+		if tok.Type == sasttoken.STRING {
+			value = tok.Value
+		}
+	}
+	if value != "été" {
+		t.Fatalf("expected latin-1 source to be transcoded, got %q", value)
+	}
+	if len(l.Errors()) != 0 {
+		t.Fatalf("unexpected errors: %v", l.Errors())
+	}
+}
+
+func TestUnknownEncoding(t *testing.T) {
+	l := lexer.NewLexer("# coding=klingon\nx = 1\n", "test.py")
+	if errs := l.Errors(); len(errs) != 1 || !strings.Contains(errs[0], "unknown encoding: klingon") {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	input := "用户名 = 获取(请求)\nπ = 3.14\nℌ = 1\n"
+	l := lexer.NewLexer(input, "test.py")
+	var idents []string
+	for tok := l.NextToken(); tok.Type != sasttoken.EOF; tok = l.NextToken() {
+		if tok.Type == sasttoken.ILLEGAL {
+			t.Fatalf("unexpected ILLEGAL token %q", tok.Literal)
+		}
+		if tok.Type == sasttoken.IDENT {
+			idents = append(idents, tok.Literal)
+		}
+	}
+	// U+210C is normalized to "H" under NFKC.
+	if got := strings.Join(idents, " "); got != "用户名 获取 请求 π H" {
+		t.Fatalf("unexpected identifiers %q", got)
+	}
+}
+
+func TestUnicodeStrings(t *testing.T) {
+	l := lexer.NewLexer("\"\"\"中文文档\"\"\"\nx = '你好' + y\n", "test.py")
+	tok := l.NextToken()
+	if tok.Type != sasttoken.STRING || tok.Value != "中文文档" {
+		t.Fatalf("expected docstring, got %s %q", tok.Type, tok.Value)
+	}
+	assertTypes(t, "x = '你好' + y\n",
+		sasttoken.IDENT, sasttoken.ASSIGN, sasttoken.STRING, sasttoken.PLUS, sasttoken.IDENT, sasttoken.NEWLINE, sasttoken.EOF)
+}
+
+func TestInvalidCharacter(t *testing.T) {
+	l := lexer.NewLexer("x = 1 € 2\n", "test.py")
+	for tok := l.NextToken(); tok.Type != sasttoken.EOF; tok = l.NextToken() {
+	}
+	if errs := l.Errors(); len(errs) != 1 || !strings.Contains(errs[0], "invalid character '€' (U+20AC)") {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
+
+func TestTokenColumnsAndOffsets(t *testing.T) {
+	input := "名前 = 'é'\n    \n"
+	l := lexer.NewLexer(input, "test.py")
+	expected := []struct {
+		tokType sasttoken.TokenType
+		column  int
+		offset  int
+	}{
+		{sasttoken.IDENT, 1, 0},
+		{sasttoken.ASSIGN, 4, 7},
+		{sasttoken.STRING, 6, 9},
+		{sasttoken.NEWLINE, 9, 13},
+		{sasttoken.EOF, 1, 19},
+	}
+	for _, e := range expected {
+		tok := l.NextToken()
+		if tok.Type != e.tokType || tok.Column != e.column || tok.Offset != e.offset {
+			t.Errorf("expected %s at column %d offset %d, got %s at column %d offset %d",
+				e.tokType, e.column, e.offset, tok.Type, tok.Column, tok.Offset)
+		}
+	}
+}
+
+func TestTokenSpans(t *testing.T) {
+	input := "x = '''a\nb''' + 名\n"
+	l := lexer.NewLexer(input, "test.py")
+	expected := []struct {
+		tokType    sasttoken.TokenType
+		start, end sasttoken.Position
+	}{
+		{sasttoken.IDENT, sasttoken.Position{Line: 1, Column: 1, Offset: 0}, sasttoken.Position{Line: 1, Column: 2, Offset: 1}},
+		{sasttoken.ASSIGN, sasttoken.Position{Line: 1, Column: 3, Offset: 2}, sasttoken.Position{Line: 1, Column: 4, Offset: 3}},
+		{sasttoken.STRING, sasttoken.Position{Line: 1, Column: 5, Offset: 4}, sasttoken.Position{Line: 2, Column: 5, Offset: 13}},
+		{sasttoken.PLUS, sasttoken.Position{Line: 2, Column: 6, Offset: 14}, sasttoken.Position{Line: 2, Column: 7, Offset: 15}},
+		{sasttoken.IDENT, sasttoken.Position{Line: 2, Column: 8, Offset: 16}, sasttoken.Position{Line: 2, Column: 9, Offset: 19}},
+		{sasttoken.NEWLINE, sasttoken.Position{Line: 2, Column: 9, Offset: 19}, sasttoken.Position{Line: 3, Column: 1, Offset: 20}},
+	}
+	for _, e := range expected {
+		tok := l.NextToken()
+		if tok.Type != e.tokType || tok.Pos() != e.start || tok.End() != e.end {
+			t.Errorf("expected %s %+v-%+v, got %s %+v-%+v", e.tokType, e.start, e.end, tok.Type, tok.Pos(), tok.End())
+		}
+	}
+}
+
+func TestNewLexerAt(t *testing.T) {
+	start := sasttoken.Position{Line: 3, Column: 10, Offset: 40}
+	l := lexer.NewLexerAt("a.b", "test.py", start)
+	tok := l.NextToken()
+	tok = l.NextToken()
+	tok = l.NextToken()
+	if tok.Literal != "b" || tok.Line != 3 || tok.Column != 12 || tok.Offset != 42 || tok.EndOffset != 43 {
+		t.Fatalf("unexpected position for %q: %+v-%+v", tok.Literal, tok.Pos(), tok.End())
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	assertTypes(t, "x = True if None else False\n",
+		sasttoken.IDENT, sasttoken.ASSIGN, sasttoken.TRUE, sasttoken.IF, sasttoken.NONE, sasttoken.ELSE, sasttoken.FALSE, sasttoken.NEWLINE,
+		sasttoken.EOF,
+	)
+	assertTypes(t, "async def f(): await g\n",
+		sasttoken.ASYNC, sasttoken.DEF, sasttoken.IDENT, sasttoken.LPAREN, sasttoken.RPAREN, sasttoken.COLON, sasttoken.AWAIT, sasttoken.IDENT, sasttoken.NEWLINE,
+		sasttoken.EOF,
+	)
+	// Neither lower-case constants nor Python 2 statements are reserved.
+	assertTypes(t, "true, fn, let, print, exec\n",
+		sasttoken.IDENT, sasttoken.COMMA, sasttoken.IDENT, sasttoken.COMMA, sasttoken.IDENT, sasttoken.COMMA,
+		sasttoken.IDENT, sasttoken.COMMA, sasttoken.IDENT, sasttoken.NEWLINE,
+		sasttoken.EOF,
+	)
+}
+
+func TestPython2Keywords(t *testing.T) {
+	l := lexer.NewLexer("print nonlocal\nexec await\n", "test.py")
+	l.Version = sasttoken.Python2
+	expected := []sasttoken.TokenType{
+		sasttoken.PRINT, sasttoken.IDENT, sasttoken.NEWLINE,
+		sasttoken.EXEC, sasttoken.IDENT, sasttoken.NEWLINE,
+		sasttoken.EOF,
+	}
+	for i, want := range expected {
+		if tok := l.NextToken(); tok.Type != want {
+			t.Fatalf("token %d: expected %v, got %v %q", i, want, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestPython2Tokens(t *testing.T) {
+	l := lexer.NewLexer("`x` <> ur'\\d' 0777 0o7 10L 0xffl 09\n", "test.py")
+	l.Version = sasttoken.Python2
+	expected := []struct {
+		typ     sasttoken.TokenType
+		literal string
+	}{
+		{sasttoken.BACKTICK, "`"}, {sasttoken.IDENT, "x"}, {sasttoken.BACKTICK, "`"},
+		{sasttoken.NE, "<>"}, {sasttoken.STRING, "ur'\\d'"},
+		{sasttoken.INT, "0777"}, {sasttoken.INT, "0o7"}, {sasttoken.INT, "10L"}, {sasttoken.INT, "0xffl"}, {sasttoken.ILLEGAL, "09"},
+		{sasttoken.NEWLINE, "\n"}, {sasttoken.EOF, ""},
+	}
+	for i, want := range expected {
+		if tok := l.NextToken(); tok.Type != want.typ || tok.Literal != want.literal {
+			t.Fatalf("token %d: expected %v %q, got %v %q", i, want.typ, want.literal, tok.Type, tok.Literal)
+		}
+	}
+	if errs := l.Errors(); len(errs) != 1 || !strings.Contains(errs[0], "invalid digit '9' in octal literal") {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	// None of these is Python 3.
+	assertTypes(t, "`x` <> ur'' 10L 1.5L\n",
+		sasttoken.ILLEGAL, sasttoken.IDENT, sasttoken.ILLEGAL, sasttoken.LT, sasttoken.GT, sasttoken.IDENT, sasttoken.STRING,
+		sasttoken.ILLEGAL, sasttoken.ILLEGAL, sasttoken.NEWLINE, sasttoken.EOF,
+	)
+}
+
+func TestPython2UnicodeEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`'\u00e9\x41'`, `\u00e9A`},
+		{`'\U0001F600'`, `\U0001F600`},
+		{`u'\u00e9\x41'`, "éA"},
+		{`b'\u00e9'`, `\u00e9`},
+		{`ur'\d'`, `\d`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input, "test.py")
+		l.Version = sasttoken.Python2
+		if tok := l.NextToken(); tok.Type != sasttoken.STRING || tok.Value != tt.expected {
+			t.Errorf("%s: expected the value %q, got %v %q", tt.input, tt.expected, tok.Type, tok.Value)
+		}
+	}
+
+	// Python 3 strings are Unicode.
+	if value, err := lexer.DecodeString(`\u00e9`, ""); err != nil || value != "é" {
+		t.Errorf("unexpected Python 3 value %q, %v", value, err)
+	}
+}
+
+func TestRewind(t *testing.T) {
+	l := lexer.NewLexer("# -*- coding: latin-1 -*-\nprint '\xe9'\n", "test.py")
+	for l.NextToken().Type != sasttoken.EOF {
+	}
+	l.Version = sasttoken.Python2
+	r := l.Rewind()
+	if r.Encoding() != "latin-1" || r.Version != sasttoken.Python2 {
+		t.Errorf("rewound lexer has encoding %q and version %v", r.Encoding(), r.Version)
+	}
+	if tok := r.NextToken(); tok.Type != sasttoken.PRINT || tok.Line != 2 {
+		t.Errorf("expected print on line 2, got %v %q on line %d", tok.Type, tok.Literal, tok.Line)
+	}
+	if tok := r.NextToken(); tok.Value != "é" {
+		t.Errorf("expected the decoded string, got %q", tok.Value)
+	}
+}
+
+func TestSoftKeywords(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected sasttoken.TokenType
+	}{
+		{"match x:\n", sasttoken.MATCH},
+		{"match (x, y):  # comment\n", sasttoken.MATCH},
+		{"match -x:\n", sasttoken.MATCH},
+		{"match = 1\n", sasttoken.IDENT},
+		{"match(x)\n", sasttoken.IDENT},
+		{"match.group(1)\n", sasttoken.IDENT},
+		{"match(x).y: int\n", sasttoken.IDENT},
+		{"match = {1: 2}\n", sasttoken.IDENT},
+		{"match = 'a:'\n", sasttoken.IDENT},
+		{"case [a, b]:\n", sasttoken.CASE},
+		{"case 1: return x\n", sasttoken.CASE},
+		{"case: int = 1\n", sasttoken.IDENT},
+		{"type Point = tuple[int, int]\n", sasttoken.TYPE},
+		{"type List[T] = list[T]\n", sasttoken.TYPE},
+		{"type(x)\n", sasttoken.IDENT},
+		{"type = 1\n", sasttoken.IDENT},
+		{"type x == y\n", sasttoken.IDENT},
+		{"_ = 1\n", sasttoken.IDENT},
+	}
+	for _, tt := range tests {
+		if got := tokenTypes(t, tt.input)[0]; got != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.input, tt.expected, got)
+		}
+	}
+
+	// Soft keywords are names outside statement-start context.
+	assertTypes(t, "x = match\nif x: type T = int\n",
+		sasttoken.IDENT, sasttoken.ASSIGN, sasttoken.IDENT, sasttoken.NEWLINE,
+		sasttoken.IF, sasttoken.IDENT, sasttoken.COLON, sasttoken.TYPE, sasttoken.IDENT, sasttoken.ASSIGN, sasttoken.IDENT, sasttoken.NEWLINE,
+		sasttoken.EOF,
+	)
+	assertTypes(t, "f(match, type)\n",
+		sasttoken.IDENT, sasttoken.LPAREN, sasttoken.IDENT, sasttoken.COMMA, sasttoken.IDENT, sasttoken.RPAREN, sasttoken.NEWLINE,
+		sasttoken.EOF,
+	)
+}
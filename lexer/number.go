@@ -0,0 +1,169 @@
+package lexer
+
+import (
+	"unicode/utf8"
+
+	sasttoken "github.com/coiloffaraday/python_sast/token"
+)
+
+// readNumber reads an integer, floating point or imaginary literal. The
+// literal keeps its source spelling, underscores and base prefix included.
+func (l *Lexer) readNumber() (sasttoken.TokenType, string) {
+	position := l.position
+	start := l.pos()
+	tokType := sasttoken.TokenType(sasttoken.INT)
+
+	if l.ch == '0' && isBasePrefix(l.peekChar()) {
+		l.readChar()
+		base := l.ch | 0x20
+		l.readChar()
+		if l.ch == '_' {
+			l.readChar()
+		}
+		if !l.readDigits(func(ch rune) bool { return isDigitInBase(ch, base) }) {
+			return l.invalidNumber(position, start, "invalid "+baseName(base)+" literal")
+		}
+		if isDigit(l.ch) {
+			return l.invalidNumber(position, start, "invalid digit '"+string(l.ch)+"' in "+baseName(base)+" literal")
+		}
+		return l.finishNumber(position, start, tokType)
+	}
+
+	if l.ch != '.' {
+		if !l.readDigits(isDigit) {
+			return l.invalidNumber(position, start, "invalid decimal literal")
+		}
+		digits := l.input[position:l.position]
+		if len(digits) > 1 && digits[0] == '0' && l.ch != '.' && l.ch != 'e' && l.ch != 'E' && l.ch != 'j' && l.ch != 'J' {
+			if l.Version == sasttoken.Python2 {
+				// Python 2 reads a leading zero as an octal prefix.
+				for i := 0; i < len(digits); i++ {
+					if !isDigitInBase(rune(digits[i]), 'o') {
+						return l.invalidNumber(position, start, "invalid digit '"+digits[i:i+1]+"' in octal literal")
+					}
+				}
+				return l.finishNumber(position, start, tokType)
+			}
+			for i := 0; i < len(digits); i++ {
+				if digits[i] != '0' && digits[i] != '_' {
+					return l.invalidNumber(position, start, "leading zeros in decimal integer literals are not permitted; use an 0o prefix for octal integers")
+				}
+			}
+		}
+	}
+
+	if l.ch == '.' {
+		tokType = sasttoken.FLOAT
+		l.readChar()
+		if isDigit(l.ch) && !l.readDigits(isDigit) {
+			return l.invalidNumber(position, start, "invalid decimal literal")
+		}
+	}
+
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if isDigit(next) || ((next == '+' || next == '-') && l.readPosition+1 < len(l.input) && isDigit(rune(l.input[l.readPosition+1]))) {
+			tokType = sasttoken.FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			if !l.readDigits(isDigit) {
+				return l.invalidNumber(position, start, "invalid decimal literal")
+			}
+		}
+	}
+
+	if l.ch == 'j' || l.ch == 'J' {
+		tokType = sasttoken.IMAGINARY
+		l.readChar()
+	}
+
+	return l.finishNumber(position, start, tokType)
+}
+
+// readDigits reads digits separated by single underscores. It reports
+// whether at least one digit was read and no underscore was misplaced.
+func (l *Lexer) readDigits(isValid func(rune) bool) bool {
+	if !isValid(l.ch) {
+		return false
+	}
+	for {
+		for isValid(l.ch) {
+			l.readChar()
+		}
+		if l.ch != '_' {
+			return true
+		}
+		l.readChar()
+		if !isValid(l.ch) {
+			return false
+		}
+	}
+}
+
+// finishNumber rejects a number that runs straight into an identifier, as
+// in "1abc" or "0x1g". Like CPython it still accepts a keyword right after
+// the number, as in "1if x else 2". In Python 2 an integer may end in the
+// long suffix "L" or "l", which is kept in the literal.
+func (l *Lexer) finishNumber(position int, start sasttoken.Position, tokType sasttoken.TokenType) (sasttoken.TokenType, string) {
+	if l.Version == sasttoken.Python2 && tokType == sasttoken.INT && (l.ch == 'L' || l.ch == 'l') {
+		l.readChar()
+	}
+	if isDigit(l.ch) {
+		return l.invalidNumber(position, start, "invalid decimal literal")
+	}
+	if isIdentifierStart(l.ch) {
+		end := l.position
+		for end < len(l.input) {
+			r, width := utf8.DecodeRuneInString(l.input[end:])
+			if !isIdentifierContinue(r) {
+				break
+			}
+			end += width
+		}
+		if sasttoken.LookupIdentVersion(l.input[l.position:end], l.Version) == sasttoken.IDENT {
+			return l.invalidNumber(position, start, "invalid decimal literal")
+		}
+	}
+	return tokType, l.input[position:l.position]
+}
+
+// invalidNumber consumes the rest of a malformed number and reports it.
+func (l *Lexer) invalidNumber(position int, start sasttoken.Position, msg string) (sasttoken.TokenType, string) {
+	for isIdentifierContinue(l.ch) || l.ch == '.' {
+		l.readChar()
+	}
+	l.addError(start, "%s", msg)
+	return sasttoken.ILLEGAL, l.input[position:l.position]
+}
+
+func isBasePrefix(ch rune) bool {
+	switch ch {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+	return false
+}
+
+func isDigitInBase(ch rune, base rune) bool {
+	switch base {
+	case 'b':
+		return ch == '0' || ch == '1'
+	case 'o':
+		return '0' <= ch && ch <= '7'
+	default:
+		return isDigit(ch) || 'a' <= ch|0x20 && ch|0x20 <= 'f'
+	}
+}
+
+func baseName(base rune) string {
+	switch base {
+	case 'b':
+		return "binary"
+	case 'o':
+		return "octal"
+	default:
+		return "hexadecimal"
+	}
+}
@@ -0,0 +1,311 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	sasttoken "github.com/coiloffaraday/python_sast/token"
+)
+
+// stringPrefixes lists the valid string prefixes, lower-cased.
+var stringPrefixes = map[string]bool{
+	"":   true,
+	"r":  true,
+	"u":  true,
+	"b":  true,
+	"f":  true,
+	"br": true,
+	"rb": true,
+	"fr": true,
+	"rf": true,
+}
+
+func isQuote(ch rune) bool {
+	return ch == '\'' || ch == '"'
+}
+
+// python2StringPrefixes lists the valid string prefixes of Python 2, which
+// has "ur" but no f-strings.
+var python2StringPrefixes = map[string]bool{
+	"":   true,
+	"r":  true,
+	"u":  true,
+	"b":  true,
+	"br": true,
+	"ur": true,
+}
+
+// isStringPrefix reports whether s followed by a quote starts a string.
+func (l *Lexer) isStringPrefix(s string) bool {
+	if l.Version == sasttoken.Python2 {
+		return python2StringPrefixes[strings.ToLower(s)]
+	}
+	return stringPrefixes[strings.ToLower(s)]
+}
+
+// readString reads a string literal whose prefix starts at start and whose
+// opening quote is the current character.
+func (l *Lexer) readString(start int) sasttoken.Token {
+	// The literal starts at its prefix, which is ASCII: one column a byte.
+	pos := l.pos()
+	pos.Column -= l.position - start
+	prefix := strings.ToLower(l.input[start:l.position])
+	quote := l.ch
+	triple := l.hasTripleQuote(quote)
+	quoteLen := 1
+	if triple {
+		quoteLen = 3
+	}
+	for i := 0; i < quoteLen; i++ {
+		l.readChar()
+	}
+	bodyStart := l.position
+
+	// fields tracks the replacement fields of an f-string that are open at
+	// the current position, so that quotes nested in them do not end it.
+	isFString := strings.Contains(prefix, "f")
+	var fields []fstringField
+
+	for {
+		inExpression := len(fields) > 0 && !fields[len(fields)-1].inSpec
+		switch {
+		case l.ch == 0 || (!triple && (l.ch == '\n' || l.ch == '\r')):
+			if triple {
+				l.addError(pos, "unterminated triple-quoted string literal")
+			} else {
+				l.addError(pos, "unterminated string literal")
+			}
+			return sasttoken.Token{Type: sasttoken.ILLEGAL, Literal: l.input[start:l.position], Line: pos.Line, FilePath: l.FilePath}
+		case inExpression:
+			fields = l.readFStringExpression(fields)
+		case l.ch == '\\':
+			// Even in raw strings a backslash keeps the following character,
+			// quote or line break, from ending the literal.
+			l.readChar()
+			if l.ch == '\r' && l.peekChar() == '\n' {
+				l.readChar()
+			}
+			if l.ch != 0 {
+				l.readChar()
+			}
+		case isFString && l.ch == '{':
+			if len(fields) == 0 && l.peekChar() == '{' {
+				l.readChar()
+			} else {
+				fields = append(fields, fstringField{})
+			}
+			l.readChar()
+		case isFString && l.ch == '}':
+			if len(fields) > 0 {
+				fields = fields[:len(fields)-1]
+			} else if l.peekChar() == '}' {
+				l.readChar()
+			}
+			l.readChar()
+		case l.ch == quote && (!triple || l.hasTripleQuote(quote)):
+			body := l.input[bodyStart:l.position]
+			for i := 0; i < quoteLen; i++ {
+				l.readChar()
+			}
+			tok := sasttoken.Token{Type: sasttoken.STRING, Literal: l.input[start:l.position], Line: pos.Line, FilePath: l.FilePath}
+			value, err := DecodeStringVersion(body, prefix, l.Version)
+			if err != nil {
+				l.addError(pos, "%s", err)
+			}
+			tok.Value = value
+			return tok
+		default:
+			l.readChar()
+		}
+	}
+}
+
+// fstringField is a replacement field of an f-string being read.
+type fstringField struct {
+	depth  int  // bracket nesting within the expression
+	inSpec bool // past the ':' that starts the format spec
+}
+
+// readFStringExpression consumes one character, or a whole nested string,
+// of the expression part of the innermost open replacement field.
+func (l *Lexer) readFStringExpression(fields []fstringField) []fstringField {
+	f := &fields[len(fields)-1]
+	switch l.ch {
+	case '(', '[', '{':
+		f.depth++
+	case ')', ']':
+		if f.depth > 0 {
+			f.depth--
+		}
+	case '}':
+		if f.depth == 0 {
+			fields = fields[:len(fields)-1]
+		} else {
+			f.depth--
+		}
+	case ':':
+		if f.depth == 0 {
+			f.inSpec = true
+		}
+	case '\'', '"':
+		l.skipNestedString()
+		return fields
+	}
+	l.readChar()
+	return fields
+}
+
+// skipNestedString consumes a string literal inside an f-string expression.
+// Since Python 3.12 it may use the same quotes as the enclosing f-string.
+func (l *Lexer) skipNestedString() {
+	quote := l.ch
+	triple := l.hasTripleQuote(quote)
+	if triple {
+		l.readChar()
+		l.readChar()
+	}
+	l.readChar()
+	for l.ch != 0 {
+		switch {
+		case !triple && (l.ch == '\n' || l.ch == '\r'):
+			return
+		case l.ch == '\\':
+			l.readChar()
+		case l.ch == quote && (!triple || l.hasTripleQuote(quote)):
+			if triple {
+				l.readChar()
+				l.readChar()
+			}
+			l.readChar()
+			return
+		}
+		l.readChar()
+	}
+}
+
+// hasTripleQuote reports whether the current character, a quote, is
+// followed by two more of the same.
+func (l *Lexer) hasTripleQuote(quote rune) bool {
+	q := byte(quote)
+	return l.readPosition+1 < len(l.input) && l.input[l.readPosition] == q && l.input[l.readPosition+1] == q
+}
+
+// DecodeString returns the value of a string literal body, the text between
+// the quotes, given its lower-cased prefix. Raw strings are returned as is;
+// otherwise escape sequences are decoded. Bytes literals decode to one byte
+// per element, so their value need not be valid UTF-8. Named escapes
+// (\N{...}) are kept verbatim since no character name table is available.
+func DecodeString(body string, prefix string) (string, error) {
+	return DecodeStringVersion(body, prefix, sasttoken.Python3)
+}
+
+// DecodeStringVersion is DecodeString for the given Python version. In
+// Python 2 a literal without the "u" prefix is a byte string, in which \u
+// and \U are not escapes.
+func DecodeStringVersion(body string, prefix string, v sasttoken.Version) (string, error) {
+	isBytes := strings.Contains(prefix, "b")
+	// noUnicodeEscapes is set for the literals where \u and \U stay as is.
+	noUnicodeEscapes := isBytes || (v == sasttoken.Python2 && !strings.Contains(prefix, "u"))
+	if isBytes {
+		for i := 0; i < len(body); i++ {
+			if body[i] >= utf8.RuneSelf {
+				return body, fmt.Errorf("bytes can only contain ASCII literal characters")
+			}
+		}
+	}
+	if strings.Contains(prefix, "r") || !strings.Contains(body, "\\") {
+		return body, nil
+	}
+
+	var out strings.Builder
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c != '\\' || i+1 >= len(body) {
+			out.WriteByte(c)
+			continue
+		}
+		i++
+		c = body[i]
+		switch c {
+		case '\n':
+		case '\r':
+			if i+1 < len(body) && body[i+1] == '\n' {
+				i++
+			}
+		case '\\', '\'', '"':
+			out.WriteByte(c)
+		case 'a':
+			out.WriteByte('\a')
+		case 'b':
+			out.WriteByte('\b')
+		case 'f':
+			out.WriteByte('\f')
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 't':
+			out.WriteByte('\t')
+		case 'v':
+			out.WriteByte('\v')
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			j := i
+			for j < len(body) && j < i+3 && '0' <= body[j] && body[j] <= '7' {
+				j++
+			}
+			n, _ := strconv.ParseUint(body[i:j], 8, 32)
+			writeCode(&out, rune(n), isBytes)
+			i = j - 1
+		case 'x', 'u', 'U':
+			width := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+			if c != 'x' && noUnicodeEscapes {
+				out.WriteByte('\\')
+				out.WriteByte(c)
+				continue
+			}
+			if i+width >= len(body) || !isHex(body[i+1:i+1+width]) {
+				fail(fmt.Errorf("truncated \\%cXX escape", c))
+				out.WriteByte('\\')
+				out.WriteByte(c)
+				continue
+			}
+			n, _ := strconv.ParseUint(body[i+1:i+1+width], 16, 32)
+			if n > utf8.MaxRune {
+				fail(fmt.Errorf("illegal Unicode character in \\U escape"))
+			}
+			writeCode(&out, rune(n), isBytes)
+			i += width
+		default:
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		}
+	}
+	return out.String(), firstErr
+}
+
+func writeCode(out *strings.Builder, code rune, isBytes bool) {
+	if isBytes {
+		out.WriteByte(byte(code))
+	} else {
+		out.WriteRune(code)
+	}
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
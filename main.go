@@ -3,14 +3,13 @@ package main
 import (
 	"flag"
 	"fmt"
-	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/coiloffaraday/python_sast/analyzer"
-	"github.com/coiloffaraday/python_sast/lexer"
-	"github.com/coiloffaraday/python_sast/parser"
+	"github.com/coiloffaraday/python_sast/project"
 	"github.com/coiloffaraday/python_sast/reporter"
+	"github.com/coiloffaraday/python_sast/rules"
+	semrules "github.com/coiloffaraday/python_sast/rules/sem"
 )
 
 func main() {
@@ -20,6 +19,8 @@ func main() {
 	dirFlagLong := flag.String("dir", "", "Directory path to analyze")
 	fileFlag := flag.String("f", "", "File path to analyze")
 	fileFlagLong := flag.String("file", "", "File path to analyze")
+	pathFlag := flag.String("p", "", "Import roots of the project, separated by the OS path list separator")
+	pathFlagLong := flag.String("pythonpath", "", "Import roots of the project, separated by the OS path list separator")
 
 	flag.Parse()
 
@@ -38,29 +39,39 @@ func main() {
 		file = *fileFlagLong
 	}
 
+	pythonPath := *pathFlag
+	if *pathFlagLong != "" {
+		pythonPath = *pathFlagLong
+	}
+
 	if dir == "" && file == "" {
 		fmt.Println("Error: You must provide either a directory or a file to analyze.")
 		displayHelp()
 		return
 	}
 
+	var config project.Config
+	if pythonPath != "" {
+		config.SearchPaths = filepath.SplitList(pythonPath)
+	}
+
 	rep := reporter.NewReporter()
+	a := analyzer.NewAnalyzer(rep,
+		rules.NewRuleSSRF(rep),
+		rules.NewRuleIO(rep),
+		rules.NewRuleCSRF(rep),
+		rules.NewRuleFileInclude(rep),
+		rules.NewRuleSensitiveInfo(rep),
+		rules.NewRuleSQLInjection(rep),
+		rules.NewRuleXSS(rep),
+		semrules.NewRuleSQLInjection(),
+		semrules.NewRuleXSSSemantic(),
+	)
 
 	if dir != "" {
-		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				fmt.Printf("Error while accessing path %q: %v\n", path, err)
-				return err
-			}
-
-			if !info.IsDir() && strings.HasSuffix(strings.ToLower(info.Name()), ".py") {
-				analyzeFile(path, rep)
-			}
-
-			return nil
-		})
+		analyzeProject(dir, config, a)
 	} else if file != "" {
-		analyzeFile(file, rep)
+		analyzeFile(file, config, a)
 	}
 
 	rep.PrintReport()
@@ -71,26 +82,54 @@ func displayHelp() {
 	fmt.Println("  -h, --help       Display help message")
 	fmt.Println("  -d, --dir DIR    Analyze all Python files in the specified directory")
 	fmt.Println("  -f, --file FILE  Analyze the specified Python file")
+	fmt.Println("  -p, --pythonpath PATHS")
+	fmt.Println("                   Import roots relative to DIR, like sys.path entries;")
+	fmt.Println("                   defaults to DIR and DIR/src")
+}
+
+// analyzeProject parses every Python file under dir into one module graph,
+// so that rules can follow names across modules, and then analyzes each
+// module.
+func analyzeProject(dir string, config project.Config, a *analyzer.Analyzer) {
+	graph, err := project.Load(dir, config)
+	if graph == nil {
+		fmt.Printf("Error while loading %q: %v\n", dir, err)
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error while reading files: %v\n", err)
+	}
+
+	for _, module := range graph.Modules() {
+		fmt.Printf("Analyzing file: %s\n", module.Path)
+		printDiagnostics(module)
+	}
+	a.AnalyzeProject(graph)
 }
 
-func analyzeFile(file string, rep *reporter.Reporter) {
+func analyzeFile(file string, config project.Config, a *analyzer.Analyzer) {
 	fmt.Printf("Analyzing file: %s\n", file)
 
-	content, err := os.ReadFile(file)
+	graph, err := project.New(filepath.Dir(file), config)
 	if err != nil {
 		fmt.Printf("Error while reading file %q: %v\n", file, err)
 		return
 	}
-
-	l := lexer.NewLexer(string(content))
-	p := parser.New(l)
-	program, err := p.ParseProgram()
-
+	module, err := graph.AddFile(file)
 	if err != nil {
-		fmt.Printf("Error while parsing file %q: %v\n", file, err)
+		fmt.Printf("Error while reading file %q: %v\n", file, err)
 		return
 	}
 
-	analyzer := analyzer.NewAnalyzer(rep)
-	analyzer.Analyze(program)
+	printDiagnostics(module)
+	a.Analyze(module, graph)
+}
+
+// printDiagnostics reports a module's syntax errors. Statements that failed
+// to parse stay in the program as error nodes, and the rest of the file,
+// including the blocks nested under them, is still analyzed.
+func printDiagnostics(module *project.Module) {
+	for _, d := range module.Diagnostics {
+		fmt.Printf("Syntax error: %v\n", d)
+	}
 }
@@ -1,6 +1,9 @@
 package parser
 
 import (
+	"fmt"
+	"math/big"
+	"strconv"
 	"strings"
 
 	sasttoken "github.com/coiloffaraday/python_sast/token"
@@ -9,6 +12,9 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() sasttoken.Position // Position of the node's first character
+	End() sasttoken.Position // Position just after the node's last character
+	Children() []Node        // The node's direct children; see children.go
 }
 
 type Statement interface {
@@ -26,9 +32,11 @@ type Identifier struct {
 	Value string
 }
 
-func (i *Identifier) expressionNode()      {}
-func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
-func (i *Identifier) String() string       { return i.Value }
+func (i *Identifier) expressionNode()         {}
+func (i *Identifier) TokenLiteral() string    { return i.Token.Literal }
+func (i *Identifier) Pos() sasttoken.Position { return i.Token.Pos() }
+func (i *Identifier) End() sasttoken.Position { return i.Token.End() }
+func (i *Identifier) String() string          { return i.Value }
 
 type Program struct {
 	Statements []Statement
@@ -41,47 +49,49 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
-func (p *Program) String() string {
-	var out strings.Builder
-	for _, s := range p.Statements {
-		out.WriteString(s.String())
+func (p *Program) Pos() sasttoken.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
 	}
-	return out.String()
+	return sasttoken.Position{}
 }
 
-type FunctionLiteral struct {
-	Token      sasttoken.Token // The token.FUNCTION token
-	Parameters []*Identifier
-	Body       *BlockStatement
+func (p *Program) End() sasttoken.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return sasttoken.Position{}
 }
 
-func (fl *FunctionLiteral) expressionNode()      {}
-func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
-func (fl *FunctionLiteral) String() string {
-	var out strings.Builder
+// Docstring returns the module's docstring, or "" if it has none.
+func (p *Program) Docstring() string { return docstring(p.Statements) }
 
-	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+func (p *Program) String() string {
+	var out strings.Builder
+	for _, s := range p.Statements {
+		out.WriteString(s.String())
 	}
-
-	out.WriteString(fl.TokenLiteral())
-	out.WriteString(" ")
-	out.WriteString(strings.Join(params, ", "))
-	out.WriteString(" ")
-	out.WriteString(fl.Body.String())
-
 	return out.String()
 }
 
+// CallExpression is a call. Positional arguments, including "*args", are
+// in Arguments and keyword arguments, including "**kwargs", in Keywords.
+// It also stands for the Python 2 constructs that became calls in Python
+// 3: a print or exec statement, whose Token is the keyword and Rparen the
+// statement's last token, and a backtick repr, whose Token and Rparen are
+// the backticks.
 type CallExpression struct {
 	Token     sasttoken.Token // The '(' token
-	Function  Expression      // Identifier or FunctionLiteral
+	Function  Expression
 	Arguments []Expression
+	Keywords  []*KeywordArgument
+	Rparen    sasttoken.Token // The ')' token
 }
 
-func (ce *CallExpression) expressionNode()      {}
-func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) expressionNode()         {}
+func (ce *CallExpression) TokenLiteral() string    { return ce.Token.Literal }
+func (ce *CallExpression) Pos() sasttoken.Position { return ce.Function.Pos() }
+func (ce *CallExpression) End() sasttoken.Position { return ce.Rparen.End() }
 func (ce *CallExpression) String() string {
 	var out strings.Builder
 
@@ -89,6 +99,9 @@ func (ce *CallExpression) String() string {
 	for _, a := range ce.Arguments {
 		args = append(args, a.String())
 	}
+	for _, k := range ce.Keywords {
+		args = append(args, k.String())
+	}
 
 	out.WriteString(ce.Function.String())
 	out.WriteString("(")
@@ -98,38 +111,73 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// KeywordArgument is a "name=value" argument of a call, or "**value" when
+// Name is nil.
+type KeywordArgument struct {
+	Token sasttoken.Token // The name, or the '**' token
+	Name  *Identifier
+	Value Expression
+}
+
+func (ka *KeywordArgument) expressionNode()         {}
+func (ka *KeywordArgument) TokenLiteral() string    { return ka.Token.Literal }
+func (ka *KeywordArgument) Pos() sasttoken.Position { return ka.Token.Pos() }
+func (ka *KeywordArgument) End() sasttoken.Position { return ka.Value.End() }
+func (ka *KeywordArgument) String() string {
+	if ka.Name == nil {
+		return "**" + ka.Value.String()
+	}
+	return ka.Name.String() + "=" + ka.Value.String()
+}
+
+// StarredExpression is "*value", as in a call's "*args".
+type StarredExpression struct {
+	Token sasttoken.Token // The '*' token
+	Value Expression
+}
+
+func (se *StarredExpression) expressionNode()         {}
+func (se *StarredExpression) TokenLiteral() string    { return se.Token.Literal }
+func (se *StarredExpression) Pos() sasttoken.Position { return se.Token.Pos() }
+func (se *StarredExpression) End() sasttoken.Position { return se.Value.End() }
+func (se *StarredExpression) String() string          { return "*" + se.Value.String() }
+
+// IfExpression is a conditional expression, "Consequence if Condition else
+// Alternative".
 type IfExpression struct {
 	Token       sasttoken.Token // The token.IF token
+	Consequence Expression
 	Condition   Expression
-	Consequence *BlockStatement
-	Alternative *BlockStatement
+	Alternative Expression
 }
 
-func (ie *IfExpression) expressionNode()      {}
-func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) expressionNode()         {}
+func (ie *IfExpression) TokenLiteral() string    { return ie.Token.Literal }
+func (ie *IfExpression) Pos() sasttoken.Position { return ie.Consequence.Pos() }
+func (ie *IfExpression) End() sasttoken.Position { return ie.Alternative.End() }
 func (ie *IfExpression) String() string {
-	var out strings.Builder
-
-	out.WriteString("if ")
-	out.WriteString(ie.Condition.String())
-	out.WriteString(" ")
-	out.WriteString(ie.Consequence.String())
-
-	if ie.Alternative != nil {
-		out.WriteString(" else ")
-		out.WriteString(ie.Alternative.String())
-	}
-
-	return out.String()
+	return "(" + ie.Consequence.String() + " if " + ie.Condition.String() + " else " + ie.Alternative.String() + ")"
 }
 
 type BlockStatement struct {
-	Token      sasttoken.Token // The '{' token
+	Token      sasttoken.Token // The INDENT token, or the first token of a one-line block
 	Statements []Statement
 }
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() sasttoken.Position {
+	if len(bs.Statements) > 0 {
+		return bs.Statements[0].Pos()
+	}
+	return bs.Token.Pos()
+}
+func (bs *BlockStatement) End() sasttoken.Position {
+	if len(bs.Statements) > 0 {
+		return bs.Statements[len(bs.Statements)-1].End()
+	}
+	return bs.Token.End()
+}
 func (bs *BlockStatement) String() string {
 	var out strings.Builder
 
@@ -143,10 +191,13 @@ func (bs *BlockStatement) String() string {
 type ListLiteral struct {
 	Token    sasttoken.Token // The '[' token
 	Elements []Expression
+	Rbracket sasttoken.Token // The ']' token
 }
 
-func (ll *ListLiteral) expressionNode()      {}
-func (ll *ListLiteral) TokenLiteral() string { return ll.Token.Literal }
+func (ll *ListLiteral) expressionNode()         {}
+func (ll *ListLiteral) TokenLiteral() string    { return ll.Token.Literal }
+func (ll *ListLiteral) Pos() sasttoken.Position { return ll.Token.Pos() }
+func (ll *ListLiteral) End() sasttoken.Position { return ll.Rbracket.End() }
 func (ll *ListLiteral) String() string {
 	var out strings.Builder
 
@@ -164,7 +215,8 @@ func (ll *ListLiteral) String() string {
 
 type ForStatement struct {
 	Token    sasttoken.Token // The token.FOR token
-	Iterator *Identifier
+	Async    sasttoken.Token // The token.ASYNC token of "async for"; unset otherwise
+	Target   Expression
 	Iterable Expression
 	Body     *BlockStatement
 	ElseBody *BlockStatement // Optional else block
@@ -172,18 +224,33 @@ type ForStatement struct {
 
 func (fs *ForStatement) statementNode()       {}
 func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() sasttoken.Position {
+	if fs.Async.Type != "" {
+		return fs.Async.Pos()
+	}
+	return fs.Token.Pos()
+}
+func (fs *ForStatement) End() sasttoken.Position {
+	if fs.ElseBody != nil {
+		return fs.ElseBody.End()
+	}
+	return fs.Body.End()
+}
 func (fs *ForStatement) String() string {
 	var out strings.Builder
 
+	if fs.Async.Type != "" {
+		out.WriteString("async ")
+	}
 	out.WriteString("for ")
-	out.WriteString(fs.Iterator.String())
+	out.WriteString(fs.Target.String())
 	out.WriteString(" in ")
 	out.WriteString(fs.Iterable.String())
 	out.WriteString(": ")
 	out.WriteString(fs.Body.String())
 
 	if fs.ElseBody != nil {
-		out.WriteString(" else ")
+		out.WriteString(" else: ")
 		out.WriteString(fs.ElseBody.String())
 	}
 
@@ -197,8 +264,15 @@ type WhileStatement struct {
 	ElseBody  *BlockStatement // Optional else block
 }
 
-func (ws *WhileStatement) statementNode()       {}
-func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) statementNode()          {}
+func (ws *WhileStatement) TokenLiteral() string    { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() sasttoken.Position { return ws.Token.Pos() }
+func (ws *WhileStatement) End() sasttoken.Position {
+	if ws.ElseBody != nil {
+		return ws.ElseBody.End()
+	}
+	return ws.Body.End()
+}
 func (ws *WhileStatement) String() string {
 	var out strings.Builder
 
@@ -208,218 +282,1766 @@ func (ws *WhileStatement) String() string {
 	out.WriteString(ws.Body.String())
 
 	if ws.ElseBody != nil {
-		out.WriteString(" else ")
+		out.WriteString(" else: ")
 		out.WriteString(ws.ElseBody.String())
 	}
 
 	return out.String()
 }
 
-// ... existing code ...
-
-type TupleLiteral struct {
-	Token    sasttoken.Token // The '(' token
-	Elements []Expression
+type FunctionDef struct {
+	Token      sasttoken.Token // The token.DEF token
+	Decorators []*Decorator
+	Name       *Identifier
+	TypeParams []*TypeParam
+	Parameters []*Parameter
+	Returns    Expression // The return annotation; optional
+	Body       *BlockStatement
 }
 
-func (tl *TupleLiteral) expressionNode()      {}
-func (tl *TupleLiteral) TokenLiteral() string { return tl.Token.Literal }
-func (tl *TupleLiteral) String() string {
+func (fd *FunctionDef) statementNode()       {}
+func (fd *FunctionDef) TokenLiteral() string { return fd.Token.Literal }
+func (fd *FunctionDef) Pos() sasttoken.Position {
+	if len(fd.Decorators) > 0 {
+		return fd.Decorators[0].Pos()
+	}
+	return fd.Token.Pos()
+}
+func (fd *FunctionDef) End() sasttoken.Position { return fd.Body.End() }
+func (fd *FunctionDef) String() string {
 	var out strings.Builder
 
-	elements := []string{}
-	for _, el := range tl.Elements {
-		elements = append(elements, el.String())
+	for _, d := range fd.Decorators {
+		out.WriteString(d.String())
+		out.WriteString(" ")
 	}
-
+	out.WriteString("def ")
+	out.WriteString(fd.Name.String())
+	out.WriteString(joinTypeParams(fd.TypeParams))
 	out.WriteString("(")
-	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString(joinParameters(fd.Parameters))
 	out.WriteString(")")
+	if fd.Returns != nil {
+		out.WriteString(" -> ")
+		out.WriteString(fd.Returns.String())
+	}
+	out.WriteString(": ")
+	out.WriteString(fd.Body.String())
 
 	return out.String()
 }
 
-type DictLiteral struct {
-	Token sasttoken.Token // The '{' token
-	Pairs map[Expression]Expression
+// Docstring returns the function's docstring, or "" if it has none.
+func (fd *FunctionDef) Docstring() string { return docstring(fd.Body.Statements) }
+
+type ReturnStatement struct {
+	Token       sasttoken.Token // The token.RETURN token
+	ReturnValue Expression      // Optional
 }
 
-func (dl *DictLiteral) expressionNode()      {}
-func (dl *DictLiteral) TokenLiteral() string { return dl.Token.Literal }
-func (dl *DictLiteral) String() string {
+func (rs *ReturnStatement) statementNode()          {}
+func (rs *ReturnStatement) TokenLiteral() string    { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() sasttoken.Position { return rs.Token.Pos() }
+func (rs *ReturnStatement) End() sasttoken.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+	return rs.Token.End()
+}
+func (rs *ReturnStatement) String() string {
+	if rs.ReturnValue != nil {
+		return "return " + rs.ReturnValue.String()
+	}
+	return "return"
+}
+
+// AssignmentStatement is "a = b = value"; Targets holds a and b.
+type AssignmentStatement struct {
+	Token   sasttoken.Token // The first '=' token
+	Targets []Expression
+	Value   Expression
+}
+
+func (as *AssignmentStatement) statementNode()          {}
+func (as *AssignmentStatement) TokenLiteral() string    { return as.Token.Literal }
+func (as *AssignmentStatement) Pos() sasttoken.Position { return as.Targets[0].Pos() }
+func (as *AssignmentStatement) End() sasttoken.Position { return as.Value.End() }
+func (as *AssignmentStatement) String() string {
 	var out strings.Builder
 
-	pairs := []string{}
-	for key, value := range dl.Pairs {
-		pairs = append(pairs, key.String()+": "+value.String())
+	for _, target := range as.Targets {
+		out.WriteString(target.String())
+		out.WriteString(" = ")
 	}
-
-	out.WriteString("{")
-	out.WriteString(strings.Join(pairs, ", "))
-	out.WriteString("}")
+	out.WriteString(as.Value.String())
 
 	return out.String()
 }
 
-type ImportStatement struct {
-	Token  sasttoken.Token // The token.IMPORT token
-	Module *Identifier
-	Alias  *Identifier // Optional alias
+type ExpressionStatement struct {
+	Token      sasttoken.Token // The first token of the expression
+	Expression Expression
 }
 
-func (is *ImportStatement) statementNode()       {}
-func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
-func (is *ImportStatement) String() string {
+func (es *ExpressionStatement) statementNode()          {}
+func (es *ExpressionStatement) TokenLiteral() string    { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() sasttoken.Position { return es.Expression.Pos() }
+func (es *ExpressionStatement) End() sasttoken.Position { return es.Expression.End() }
+func (es *ExpressionStatement) String() string          { return es.Expression.String() }
+
+type IfStatement struct {
+	Token       sasttoken.Token // The token.IF token
+	Condition   Expression
+	Consequence *BlockStatement
+	ElifClauses []*ElifStatement
+	ElseClause  *ElseStatement // Optional
+}
+
+func (is *IfStatement) statementNode()          {}
+func (is *IfStatement) TokenLiteral() string    { return is.Token.Literal }
+func (is *IfStatement) Pos() sasttoken.Position { return is.Token.Pos() }
+func (is *IfStatement) End() sasttoken.Position {
+	if is.ElseClause != nil {
+		return is.ElseClause.End()
+	}
+	if n := len(is.ElifClauses); n > 0 {
+		return is.ElifClauses[n-1].End()
+	}
+	return is.Consequence.End()
+}
+func (is *IfStatement) String() string {
 	var out strings.Builder
 
-	out.WriteString("import ")
-	out.WriteString(is.Module.String())
+	out.WriteString("if ")
+	out.WriteString(is.Condition.String())
+	out.WriteString(": ")
+	out.WriteString(is.Consequence.String())
 
-	if is.Alias != nil {
-		out.WriteString(" as ")
-		out.WriteString(is.Alias.String())
+	for _, elif := range is.ElifClauses {
+		out.WriteString(" ")
+		out.WriteString(elif.String())
+	}
+
+	if is.ElseClause != nil {
+		out.WriteString(" ")
+		out.WriteString(is.ElseClause.String())
 	}
 
 	return out.String()
 }
 
-type FromImportStatement struct {
-	Token      sasttoken.Token // The token.FROM token
-	Module     *Identifier
-	Level      int // Relative import level, 0 for absolute imports
-	ImportList []*ImportSpec
+type ElifStatement struct {
+	Token     sasttoken.Token // The token.ELIF token
+	Condition Expression
+	Body      *BlockStatement
 }
 
-type ImportSpec struct {
-	Name  *Identifier
-	Alias *Identifier // Optional alias
+func (es *ElifStatement) statementNode()          {}
+func (es *ElifStatement) TokenLiteral() string    { return es.Token.Literal }
+func (es *ElifStatement) Pos() sasttoken.Position { return es.Token.Pos() }
+func (es *ElifStatement) End() sasttoken.Position { return es.Body.End() }
+func (es *ElifStatement) String() string {
+	return "elif " + es.Condition.String() + ": " + es.Body.String()
 }
 
-func (fis *FromImportStatement) statementNode()       {}
-func (fis *FromImportStatement) TokenLiteral() string { return fis.Token.Literal }
-func (fis *FromImportStatement) String() string {
+// ElseStatement is the else clause of an if or try statement.
+type ElseStatement struct {
+	Token sasttoken.Token // The token.ELSE token
+	Body  *BlockStatement
+}
+
+func (es *ElseStatement) statementNode()          {}
+func (es *ElseStatement) TokenLiteral() string    { return es.Token.Literal }
+func (es *ElseStatement) Pos() sasttoken.Position { return es.Token.Pos() }
+func (es *ElseStatement) End() sasttoken.Position { return es.Body.End() }
+func (es *ElseStatement) String() string          { return "else: " + es.Body.String() }
+
+type TryStatement struct {
+	Token         sasttoken.Token // The token.TRY token
+	TryBlock      *BlockStatement
+	ExceptClauses []*ExceptStatement
+	ElseClause    *ElseStatement    // Optional
+	FinallyClause *FinallyStatement // Optional
+}
+
+func (ts *TryStatement) statementNode()          {}
+func (ts *TryStatement) TokenLiteral() string    { return ts.Token.Literal }
+func (ts *TryStatement) Pos() sasttoken.Position { return ts.Token.Pos() }
+func (ts *TryStatement) End() sasttoken.Position {
+	switch {
+	case ts.FinallyClause != nil:
+		return ts.FinallyClause.End()
+	case ts.ElseClause != nil:
+		return ts.ElseClause.End()
+	case len(ts.ExceptClauses) > 0:
+		return ts.ExceptClauses[len(ts.ExceptClauses)-1].End()
+	}
+	return ts.TryBlock.End()
+}
+func (ts *TryStatement) String() string {
 	var out strings.Builder
 
-	out.WriteString("from ")
-	if fis.Level > 0 {
-		out.WriteString(strings.Repeat(".", fis.Level))
+	out.WriteString("try: ")
+	out.WriteString(ts.TryBlock.String())
+
+	for _, except := range ts.ExceptClauses {
+		out.WriteString(" ")
+		out.WriteString(except.String())
 	}
-	out.WriteString(fis.Module.String())
-	out.WriteString(" import ")
 
-	importList := []string{}
-	for _, spec := range fis.ImportList {
-		if spec.Alias != nil {
-			importList = append(importList, spec.Name.String()+" as "+spec.Alias.String())
-		} else {
-			importList = append(importList, spec.Name.String())
-		}
+	if ts.ElseClause != nil {
+		out.WriteString(" ")
+		out.WriteString(ts.ElseClause.String())
 	}
 
-	out.WriteString(strings.Join(importList, ", "))
+	if ts.FinallyClause != nil {
+		out.WriteString(" ")
+		out.WriteString(ts.FinallyClause.String())
+	}
 
 	return out.String()
 }
 
-// ... existing code ...
-
-type SetLiteral struct {
-	Token    sasttoken.Token // The '{' token
-	Elements []Expression
+type ExceptStatement struct {
+	Token         sasttoken.Token // The token.EXCEPT token
+	Star          bool            // An "except*" clause of an exception group
+	ExceptionType Expression      // Optional
+	Name          *Identifier     // Optional, the name after "as"
+	Body          *BlockStatement
 }
 
-func (sl *SetLiteral) expressionNode()      {}
-func (sl *SetLiteral) TokenLiteral() string { return sl.Token.Literal }
-func (sl *SetLiteral) String() string {
+func (es *ExceptStatement) statementNode()          {}
+func (es *ExceptStatement) TokenLiteral() string    { return es.Token.Literal }
+func (es *ExceptStatement) Pos() sasttoken.Position { return es.Token.Pos() }
+func (es *ExceptStatement) End() sasttoken.Position { return es.Body.End() }
+func (es *ExceptStatement) String() string {
 	var out strings.Builder
 
-	elements := []string{}
-	for _, el := range sl.Elements {
-		elements = append(elements, el.String())
+	out.WriteString("except")
+	if es.Star {
+		out.WriteString("*")
+	}
+	if es.ExceptionType != nil {
+		out.WriteString(" ")
+		out.WriteString(es.ExceptionType.String())
 	}
+	if es.Name != nil {
+		out.WriteString(" as ")
+		out.WriteString(es.Name.String())
+	}
+	out.WriteString(": ")
+	out.WriteString(es.Body.String())
 
-	out.WriteString("{")
-	out.WriteString(strings.Join(elements, ", "))
-	out.WriteString("}")
+	return out.String()
+}
+
+type FinallyStatement struct {
+	Token sasttoken.Token // The token.FINALLY token
+	Body  *BlockStatement
+}
+
+func (fs *FinallyStatement) statementNode()          {}
+func (fs *FinallyStatement) TokenLiteral() string    { return fs.Token.Literal }
+func (fs *FinallyStatement) Pos() sasttoken.Position { return fs.Token.Pos() }
+func (fs *FinallyStatement) End() sasttoken.Position { return fs.Body.End() }
+func (fs *FinallyStatement) String() string          { return "finally: " + fs.Body.String() }
+
+type PassStatement struct {
+	Token sasttoken.Token // The token.PASS token
+}
+
+func (ps *PassStatement) statementNode()          {}
+func (ps *PassStatement) TokenLiteral() string    { return ps.Token.Literal }
+func (ps *PassStatement) Pos() sasttoken.Position { return ps.Token.Pos() }
+func (ps *PassStatement) End() sasttoken.Position { return ps.Token.End() }
+func (ps *PassStatement) String() string          { return "pass" }
+
+type BreakStatement struct {
+	Token sasttoken.Token // The token.BREAK token
+}
+
+func (bs *BreakStatement) statementNode()          {}
+func (bs *BreakStatement) TokenLiteral() string    { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() sasttoken.Position { return bs.Token.Pos() }
+func (bs *BreakStatement) End() sasttoken.Position { return bs.Token.End() }
+func (bs *BreakStatement) String() string          { return "break" }
+
+type ContinueStatement struct {
+	Token sasttoken.Token // The token.CONTINUE token
+}
+
+func (cs *ContinueStatement) statementNode()          {}
+func (cs *ContinueStatement) TokenLiteral() string    { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() sasttoken.Position { return cs.Token.Pos() }
+func (cs *ContinueStatement) End() sasttoken.Position { return cs.Token.End() }
+func (cs *ContinueStatement) String() string          { return "continue" }
+
+// AsyncFunctionDef is "async def". Its fields are those of FunctionDef.
+type AsyncFunctionDef struct {
+	FunctionDef
+	Async sasttoken.Token // The token.ASYNC token
+}
+
+func (af *AsyncFunctionDef) Pos() sasttoken.Position {
+	if len(af.Decorators) > 0 {
+		return af.Decorators[0].Pos()
+	}
+	return af.Async.Pos()
+}
+func (af *AsyncFunctionDef) String() string {
+	var out strings.Builder
+
+	for _, d := range af.Decorators {
+		out.WriteString(d.String())
+		out.WriteString(" ")
+	}
+	undecorated := af.FunctionDef
+	undecorated.Decorators = nil
+	out.WriteString("async ")
+	out.WriteString(undecorated.String())
 
 	return out.String()
 }
 
-type ListComprehension struct {
-	Token      sasttoken.Token // The '[' token
+// Decorator is "@expression" on the line before a def or class.
+type Decorator struct {
+	Token      sasttoken.Token // The '@' token
 	Expression Expression
-	ForClauses []*ForClause
-	IfClauses  []*IfClause
 }
 
-type ForClause struct {
-	Target Expression
-	Iter   Expression
+func (d *Decorator) TokenLiteral() string    { return d.Token.Literal }
+func (d *Decorator) Pos() sasttoken.Position { return d.Token.Pos() }
+func (d *Decorator) End() sasttoken.Position { return d.Expression.End() }
+func (d *Decorator) String() string          { return "@" + d.Expression.String() }
+
+// ClassDef is a class definition. Keyword arguments in the base list, such
+// as "metaclass=Meta", are in Keywords.
+type ClassDef struct {
+	Token      sasttoken.Token // The token.CLASS token
+	Decorators []*Decorator
+	Name       *Identifier
+	TypeParams []*TypeParam
+	Bases      []Expression
+	Keywords   []*KeywordArgument
+	Body       *BlockStatement
 }
 
-type IfClause struct {
-	Condition Expression
+func (cd *ClassDef) statementNode()       {}
+func (cd *ClassDef) TokenLiteral() string { return cd.Token.Literal }
+func (cd *ClassDef) Pos() sasttoken.Position {
+	if len(cd.Decorators) > 0 {
+		return cd.Decorators[0].Pos()
+	}
+	return cd.Token.Pos()
 }
+func (cd *ClassDef) End() sasttoken.Position { return cd.Body.End() }
+func (cd *ClassDef) String() string {
+	var out strings.Builder
 
-func (lc *ListComprehension) expressionNode()      {}
-func (lc *ListComprehension) TokenLiteral() string { return lc.Token.Literal }
-func (lc *ListComprehension) String() string {
+	for _, d := range cd.Decorators {
+		out.WriteString(d.String())
+		out.WriteString(" ")
+	}
+	out.WriteString("class ")
+	out.WriteString(cd.Name.String())
+	out.WriteString(joinTypeParams(cd.TypeParams))
+	if len(cd.Bases) > 0 || len(cd.Keywords) > 0 {
+		bases := []string{}
+		for _, b := range cd.Bases {
+			bases = append(bases, b.String())
+		}
+		for _, k := range cd.Keywords {
+			bases = append(bases, k.String())
+		}
+		out.WriteString("(")
+		out.WriteString(strings.Join(bases, ", "))
+		out.WriteString(")")
+	}
+	out.WriteString(": ")
+	out.WriteString(cd.Body.String())
+
+	return out.String()
+}
+
+// Docstring returns the class's docstring, or "" if it has none.
+func (cd *ClassDef) Docstring() string { return docstring(cd.Body.Statements) }
+
+// TypeAliasStatement is a type alias, "type Name[T] = value".
+type TypeAliasStatement struct {
+	Token      sasttoken.Token // The token.TYPE token
+	Name       *Identifier
+	TypeParams []*TypeParam
+	Value      Expression
+}
+
+func (ta *TypeAliasStatement) statementNode()          {}
+func (ta *TypeAliasStatement) TokenLiteral() string    { return ta.Token.Literal }
+func (ta *TypeAliasStatement) Pos() sasttoken.Position { return ta.Token.Pos() }
+func (ta *TypeAliasStatement) End() sasttoken.Position { return ta.Value.End() }
+func (ta *TypeAliasStatement) String() string {
+	return "type " + ta.Name.String() + joinTypeParams(ta.TypeParams) + " = " + ta.Value.String()
+}
+
+type TypeParamKind int
+
+const (
+	TypeVar      TypeParamKind = iota // T, T: bound
+	TypeVarTuple                      // *Ts
+	ParamSpec                         // **P
+)
+
+func (k TypeParamKind) String() string {
+	switch k {
+	case TypeVar:
+		return "TypeVar"
+	case TypeVarTuple:
+		return "TypeVarTuple"
+	case ParamSpec:
+		return "ParamSpec"
+	}
+	return fmt.Sprintf("TypeParamKind(%d)", int(k))
+}
+
+// TypeParam is one parameter of a generic class, function or type alias.
+type TypeParam struct {
+	Token   sasttoken.Token // The name, or the '*' or '**' token
+	Kind    TypeParamKind
+	Name    *Identifier
+	Bound   Expression // Optional, the bound or constraints of a TypeVar
+	Default Expression // Optional
+}
+
+func (tp *TypeParam) TokenLiteral() string    { return tp.Token.Literal }
+func (tp *TypeParam) Pos() sasttoken.Position { return tp.Token.Pos() }
+func (tp *TypeParam) End() sasttoken.Position {
+	switch {
+	case tp.Default != nil:
+		return tp.Default.End()
+	case tp.Bound != nil:
+		return tp.Bound.End()
+	}
+	return tp.Name.End()
+}
+func (tp *TypeParam) String() string {
 	var out strings.Builder
 
-	out.WriteString("[")
-	out.WriteString(lc.Expression.String())
+	switch tp.Kind {
+	case TypeVarTuple:
+		out.WriteString("*")
+	case ParamSpec:
+		out.WriteString("**")
+	}
+	out.WriteString(tp.Name.String())
+	if tp.Bound != nil {
+		out.WriteString(": ")
+		out.WriteString(tp.Bound.String())
+	}
+	if tp.Default != nil {
+		out.WriteString(" = ")
+		out.WriteString(tp.Default.String())
+	}
+
+	return out.String()
+}
 
-	for _, forClause := range lc.ForClauses {
-		out.WriteString(" for ")
-		out.WriteString(forClause.Target.String())
-		out.WriteString(" in ")
-		out.WriteString(forClause.Iter.String())
+// joinTypeParams formats a type parameter list with its brackets, or
+// returns "" for an empty one.
+func joinTypeParams(params []*TypeParam) string {
+	if len(params) == 0 {
+		return ""
+	}
+	parts := []string{}
+	for _, param := range params {
+		parts = append(parts, param.String())
 	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
 
-	for _, ifClause := range lc.IfClauses {
-		out.WriteString(" if ")
-		out.WriteString(ifClause.Condition.String())
+// docstring returns the value of the string literal that begins a body,
+// as Python's ast.get_docstring does without cleaning the indentation.
+func docstring(body []Statement) string {
+	if len(body) == 0 {
+		return ""
+	}
+	stmt, ok := body[0].(*ExpressionStatement)
+	if !ok {
+		return ""
+	}
+	str, ok := stmt.Expression.(*StringLiteral)
+	if !ok {
+		return ""
+	}
+	if prefix, _ := splitStringLiteral(str.Token.Literal); strings.Contains(prefix, "b") {
+		// Bytes are not docstrings.
+		return ""
+	}
+	return str.Value
+}
+
+// AugAssignStatement is an augmented assignment such as "x += 1".
+type AugAssignStatement struct {
+	Token    sasttoken.Token // The operator token, such as '+='
+	Target   Expression
+	Operator string // The operator without its '=', such as "+"
+	Value    Expression
+}
+
+func (as *AugAssignStatement) statementNode()          {}
+func (as *AugAssignStatement) TokenLiteral() string    { return as.Token.Literal }
+func (as *AugAssignStatement) Pos() sasttoken.Position { return as.Target.Pos() }
+func (as *AugAssignStatement) End() sasttoken.Position { return as.Value.End() }
+func (as *AugAssignStatement) String() string {
+	return as.Target.String() + " " + as.Operator + "= " + as.Value.String()
+}
+
+// AnnAssignStatement is an annotated assignment, "target: annotation" with
+// an optional "= value".
+type AnnAssignStatement struct {
+	Token      sasttoken.Token // The ':' token
+	Target     Expression
+	Annotation Expression
+	Value      Expression // Optional
+}
+
+func (as *AnnAssignStatement) statementNode()          {}
+func (as *AnnAssignStatement) TokenLiteral() string    { return as.Token.Literal }
+func (as *AnnAssignStatement) Pos() sasttoken.Position { return as.Target.Pos() }
+func (as *AnnAssignStatement) End() sasttoken.Position {
+	if as.Value != nil {
+		return as.Value.End()
 	}
+	return as.Annotation.End()
+}
+func (as *AnnAssignStatement) String() string {
+	if as.Value == nil {
+		return as.Target.String() + ": " + as.Annotation.String()
+	}
+	return as.Target.String() + ": " + as.Annotation.String() + " = " + as.Value.String()
+}
 
-	out.WriteString("]")
+// RaiseStatement is "raise [Exception [from Cause]]".
+type RaiseStatement struct {
+	Token     sasttoken.Token // The token.RAISE token
+	Exception Expression      // Optional
+	Cause     Expression      // Optional
+}
+
+func (rs *RaiseStatement) statementNode()          {}
+func (rs *RaiseStatement) TokenLiteral() string    { return rs.Token.Literal }
+func (rs *RaiseStatement) Pos() sasttoken.Position { return rs.Token.Pos() }
+func (rs *RaiseStatement) End() sasttoken.Position {
+	switch {
+	case rs.Cause != nil:
+		return rs.Cause.End()
+	case rs.Exception != nil:
+		return rs.Exception.End()
+	}
+	return rs.Token.End()
+}
+func (rs *RaiseStatement) String() string {
+	var out strings.Builder
+
+	out.WriteString("raise")
+	if rs.Exception != nil {
+		out.WriteString(" ")
+		out.WriteString(rs.Exception.String())
+	}
+	if rs.Cause != nil {
+		out.WriteString(" from ")
+		out.WriteString(rs.Cause.String())
+	}
 
 	return out.String()
 }
 
-// ... Add additional AST nodes here as needed.
-type IntegerLiteral struct {
-	Token sasttoken.Token
-	Value int64
+type AssertStatement struct {
+	Token   sasttoken.Token // The token.ASSERT token
+	Test    Expression
+	Message Expression // Optional
 }
 
-type FloatLiteral struct {
-	Token sasttoken.Token
-	Value float64
+func (as *AssertStatement) statementNode()          {}
+func (as *AssertStatement) TokenLiteral() string    { return as.Token.Literal }
+func (as *AssertStatement) Pos() sasttoken.Position { return as.Token.Pos() }
+func (as *AssertStatement) End() sasttoken.Position {
+	if as.Message != nil {
+		return as.Message.End()
+	}
+	return as.Test.End()
+}
+func (as *AssertStatement) String() string {
+	if as.Message != nil {
+		return "assert " + as.Test.String() + ", " + as.Message.String()
+	}
+	return "assert " + as.Test.String()
 }
 
-type StringLiteral struct {
-	Token sasttoken.Token
-	Value string
+type DelStatement struct {
+	Token   sasttoken.Token // The token.DEL token
+	Targets []Expression
 }
 
-type BooleanLiteral struct {
-	Token sasttoken.Token
-	Value bool
+func (ds *DelStatement) statementNode()          {}
+func (ds *DelStatement) TokenLiteral() string    { return ds.Token.Literal }
+func (ds *DelStatement) Pos() sasttoken.Position { return ds.Token.Pos() }
+func (ds *DelStatement) End() sasttoken.Position { return ds.Targets[len(ds.Targets)-1].End() }
+func (ds *DelStatement) String() string {
+	targets := []string{}
+	for _, t := range ds.Targets {
+		targets = append(targets, t.String())
+	}
+	return "del " + strings.Join(targets, ", ")
 }
 
-type NoneLiteral struct {
-	Token sasttoken.Token
+type GlobalStatement struct {
+	Token sasttoken.Token // The token.GLOBAL token
+	Names []*Identifier
 }
 
-type PrefixExpression struct {
-	Token    sasttoken.Token
-	Operator string
-	Right    Expression
+func (gs *GlobalStatement) statementNode()          {}
+func (gs *GlobalStatement) TokenLiteral() string    { return gs.Token.Literal }
+func (gs *GlobalStatement) Pos() sasttoken.Position { return gs.Token.Pos() }
+func (gs *GlobalStatement) End() sasttoken.Position { return gs.Names[len(gs.Names)-1].End() }
+func (gs *GlobalStatement) String() string          { return "global " + joinIdentifiers(gs.Names) }
+
+type NonlocalStatement struct {
+	Token sasttoken.Token // The token.NONLOCAL token
+	Names []*Identifier
 }
 
-type InfixExpression struct {
-	Token    sasttoken.Token
-	Left     Expression
-	Operator string
+func (ns *NonlocalStatement) statementNode()          {}
+func (ns *NonlocalStatement) TokenLiteral() string    { return ns.Token.Literal }
+func (ns *NonlocalStatement) Pos() sasttoken.Position { return ns.Token.Pos() }
+func (ns *NonlocalStatement) End() sasttoken.Position { return ns.Names[len(ns.Names)-1].End() }
+func (ns *NonlocalStatement) String() string          { return "nonlocal " + joinIdentifiers(ns.Names) }
+
+func joinIdentifiers(idents []*Identifier) string {
+	names := []string{}
+	for _, ident := range idents {
+		names = append(names, ident.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// YieldExpression is "yield [Value]" or "yield from Value".
+type YieldExpression struct {
+	Token sasttoken.Token // The token.YIELD token
+	From  bool
+	Value Expression // Optional unless From is set
+}
+
+func (ye *YieldExpression) expressionNode()         {}
+func (ye *YieldExpression) TokenLiteral() string    { return ye.Token.Literal }
+func (ye *YieldExpression) Pos() sasttoken.Position { return ye.Token.Pos() }
+func (ye *YieldExpression) End() sasttoken.Position {
+	if ye.Value != nil {
+		return ye.Value.End()
+	}
+	return ye.Token.End()
+}
+func (ye *YieldExpression) String() string {
+	switch {
+	case ye.From:
+		return "(yield from " + ye.Value.String() + ")"
+	case ye.Value != nil:
+		return "(yield " + ye.Value.String() + ")"
+	}
+	return "(yield)"
+}
+
+type WithStatement struct {
+	Token sasttoken.Token // The token.WITH token
+	Async sasttoken.Token // The token.ASYNC token of "async with"; unset otherwise
+	Items []*WithItem
+	Body  *BlockStatement
+}
+
+// WithItem is one "Context [as Target]" of a with statement.
+type WithItem struct {
+	Context Expression
+	Target  Expression // Optional
+}
+
+func (wi *WithItem) TokenLiteral() string    { return wi.Context.TokenLiteral() }
+func (wi *WithItem) Pos() sasttoken.Position { return wi.Context.Pos() }
+func (wi *WithItem) End() sasttoken.Position {
+	if wi.Target != nil {
+		return wi.Target.End()
+	}
+	return wi.Context.End()
+}
+func (wi *WithItem) String() string {
+	if wi.Target != nil {
+		return wi.Context.String() + " as " + wi.Target.String()
+	}
+	return wi.Context.String()
+}
+
+func (ws *WithStatement) statementNode()       {}
+func (ws *WithStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WithStatement) Pos() sasttoken.Position {
+	if ws.Async.Type != "" {
+		return ws.Async.Pos()
+	}
+	return ws.Token.Pos()
+}
+func (ws *WithStatement) End() sasttoken.Position { return ws.Body.End() }
+func (ws *WithStatement) String() string {
+	var out strings.Builder
+
+	items := []string{}
+	for _, item := range ws.Items {
+		items = append(items, item.String())
+	}
+
+	if ws.Async.Type != "" {
+		out.WriteString("async ")
+	}
+	out.WriteString("with ")
+	out.WriteString(strings.Join(items, ", "))
+	out.WriteString(": ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+
+type MatchStatement struct {
+	Token   sasttoken.Token // The token.MATCH token
+	Subject Expression
+	Cases   []*MatchCase
+}
+
+func (ms *MatchStatement) statementNode()          {}
+func (ms *MatchStatement) TokenLiteral() string    { return ms.Token.Literal }
+func (ms *MatchStatement) Pos() sasttoken.Position { return ms.Token.Pos() }
+func (ms *MatchStatement) End() sasttoken.Position { return ms.Cases[len(ms.Cases)-1].End() }
+func (ms *MatchStatement) String() string {
+	var out strings.Builder
+
+	out.WriteString("match ")
+	out.WriteString(ms.Subject.String())
+	out.WriteString(":")
+	for _, c := range ms.Cases {
+		out.WriteString(" ")
+		out.WriteString(c.String())
+	}
+
+	return out.String()
+}
+
+type MatchCase struct {
+	Token   sasttoken.Token // The token.CASE token
+	Pattern Pattern
+	Guard   Expression // Optional, the condition after "if"
+	Body    *BlockStatement
+}
+
+func (mc *MatchCase) TokenLiteral() string    { return mc.Token.Literal }
+func (mc *MatchCase) Pos() sasttoken.Position { return mc.Token.Pos() }
+func (mc *MatchCase) End() sasttoken.Position { return mc.Body.End() }
+func (mc *MatchCase) String() string {
+	var out strings.Builder
+
+	out.WriteString("case ")
+	out.WriteString(mc.Pattern.String())
+	if mc.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(mc.Guard.String())
+	}
+	out.WriteString(": ")
+	out.WriteString(mc.Body.String())
+
+	return out.String()
+}
+
+// Pattern is the pattern of a case clause.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// LiteralPattern matches a number, string, None, True or False. Value is a
+// literal, a negated number or a complex number such as -1+2j.
+type LiteralPattern struct {
+	Token sasttoken.Token // The first token of the literal
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode()            {}
+func (lp *LiteralPattern) TokenLiteral() string    { return lp.Token.Literal }
+func (lp *LiteralPattern) Pos() sasttoken.Position { return lp.Value.Pos() }
+func (lp *LiteralPattern) End() sasttoken.Position { return lp.Value.End() }
+func (lp *LiteralPattern) String() string          { return lp.Value.String() }
+
+// ValuePattern matches the value of a dotted name such as Color.RED.
+type ValuePattern struct {
+	Token sasttoken.Token // The first token of the name
+	Value Expression      // An AttributeExpression
+}
+
+func (vp *ValuePattern) patternNode()            {}
+func (vp *ValuePattern) TokenLiteral() string    { return vp.Token.Literal }
+func (vp *ValuePattern) Pos() sasttoken.Position { return vp.Value.Pos() }
+func (vp *ValuePattern) End() sasttoken.Position { return vp.Value.End() }
+func (vp *ValuePattern) String() string          { return vp.Value.String() }
+
+// CapturePattern matches anything and binds it to Name.
+type CapturePattern struct {
+	Token sasttoken.Token // The token.IDENT token
+	Name  *Identifier
+}
+
+func (cp *CapturePattern) patternNode()            {}
+func (cp *CapturePattern) TokenLiteral() string    { return cp.Token.Literal }
+func (cp *CapturePattern) Pos() sasttoken.Position { return cp.Name.Pos() }
+func (cp *CapturePattern) End() sasttoken.Position { return cp.Name.End() }
+func (cp *CapturePattern) String() string          { return cp.Name.String() }
+
+// WildcardPattern is "_", which matches anything without binding it.
+type WildcardPattern struct {
+	Token sasttoken.Token // The "_" token
+}
+
+func (wp *WildcardPattern) patternNode()            {}
+func (wp *WildcardPattern) TokenLiteral() string    { return wp.Token.Literal }
+func (wp *WildcardPattern) Pos() sasttoken.Position { return wp.Token.Pos() }
+func (wp *WildcardPattern) End() sasttoken.Position { return wp.Token.End() }
+func (wp *WildcardPattern) String() string          { return "_" }
+
+// StarPattern matches the rest of a sequence, as in "[first, *rest]".
+type StarPattern struct {
+	Token   sasttoken.Token // The '*' token
+	Pattern Pattern         // A CapturePattern or WildcardPattern
+}
+
+func (sp *StarPattern) patternNode()            {}
+func (sp *StarPattern) TokenLiteral() string    { return sp.Token.Literal }
+func (sp *StarPattern) Pos() sasttoken.Position { return sp.Token.Pos() }
+func (sp *StarPattern) End() sasttoken.Position { return sp.Pattern.End() }
+func (sp *StarPattern) String() string          { return "*" + sp.Pattern.String() }
+
+// SequencePattern matches a sequence element by element. It is written in
+// brackets, in parentheses, or bare at the top of a case clause.
+type SequencePattern struct {
+	Token    sasttoken.Token // The '[' or '(' token, or the first token of a bare sequence
+	Patterns []Pattern
+	Close    sasttoken.Token // The ']' or ')' token; unset for a bare sequence
+}
+
+func (sp *SequencePattern) patternNode()            {}
+func (sp *SequencePattern) TokenLiteral() string    { return sp.Token.Literal }
+func (sp *SequencePattern) Pos() sasttoken.Position { return sp.Token.Pos() }
+func (sp *SequencePattern) End() sasttoken.Position {
+	if sp.Close.Type != "" || len(sp.Patterns) == 0 {
+		return sp.Close.End()
+	}
+	return sp.Patterns[len(sp.Patterns)-1].End()
+}
+func (sp *SequencePattern) String() string {
+	patterns := []string{}
+	for _, pattern := range sp.Patterns {
+		patterns = append(patterns, pattern.String())
+	}
+	elements := strings.Join(patterns, ", ")
+
+	switch sp.Close.Type {
+	case sasttoken.RBRACKET:
+		return "[" + elements + "]"
+	case sasttoken.RPAREN:
+		if len(patterns) == 1 {
+			elements += ","
+		}
+		return "(" + elements + ")"
+	}
+	return elements
+}
+
+// MappingPattern matches a mapping by its keys, as in
+// {"id": id, **rest}.
+type MappingPattern struct {
+	Token    sasttoken.Token // The '{' token
+	Keys     []Expression    // Literals or dotted names
+	Patterns []Pattern       // The pattern for each key
+	Rest     *Identifier     // Optional, the name after "**"
+	Rbrace   sasttoken.Token // The '}' token
+}
+
+func (mp *MappingPattern) patternNode()            {}
+func (mp *MappingPattern) TokenLiteral() string    { return mp.Token.Literal }
+func (mp *MappingPattern) Pos() sasttoken.Position { return mp.Token.Pos() }
+func (mp *MappingPattern) End() sasttoken.Position { return mp.Rbrace.End() }
+
+// Children lists keys and patterns alternately, in source order.
+func (mp *MappingPattern) Children() []Node {
+	var nodes []Node
+	for i, key := range mp.Keys {
+		nodes = append(nodes, key, mp.Patterns[i])
+	}
+	if mp.Rest != nil {
+		nodes = append(nodes, mp.Rest)
+	}
+	return nodes
+}
+func (mp *MappingPattern) String() string {
+	items := []string{}
+	for i, key := range mp.Keys {
+		items = append(items, key.String()+": "+mp.Patterns[i].String())
+	}
+	if mp.Rest != nil {
+		items = append(items, "**"+mp.Rest.String())
+	}
+	return "{" + strings.Join(items, ", ") + "}"
+}
+
+// ClassPattern matches an instance of Class whose attributes match the
+// given patterns, as in Point(0, y=y).
+type ClassPattern struct {
+	Token    sasttoken.Token // The '(' token
+	Class    Expression      // A name or dotted name
+	Patterns []Pattern
+	Keywords []*KeywordPattern
+	Rparen   sasttoken.Token // The ')' token
+}
+
+func (cp *ClassPattern) patternNode()            {}
+func (cp *ClassPattern) TokenLiteral() string    { return cp.Token.Literal }
+func (cp *ClassPattern) Pos() sasttoken.Position { return cp.Class.Pos() }
+func (cp *ClassPattern) End() sasttoken.Position { return cp.Rparen.End() }
+func (cp *ClassPattern) String() string {
+	args := []string{}
+	for _, pattern := range cp.Patterns {
+		args = append(args, pattern.String())
+	}
+	for _, keyword := range cp.Keywords {
+		args = append(args, keyword.String())
+	}
+	return cp.Class.String() + "(" + strings.Join(args, ", ") + ")"
+}
+
+// KeywordPattern is a "name=pattern" argument of a class pattern.
+type KeywordPattern struct {
+	Token   sasttoken.Token // The name
+	Name    *Identifier
+	Pattern Pattern
+}
+
+func (kp *KeywordPattern) TokenLiteral() string    { return kp.Token.Literal }
+func (kp *KeywordPattern) Pos() sasttoken.Position { return kp.Token.Pos() }
+func (kp *KeywordPattern) End() sasttoken.Position { return kp.Pattern.End() }
+func (kp *KeywordPattern) String() string          { return kp.Name.String() + "=" + kp.Pattern.String() }
+
+// OrPattern matches if any of its alternatives does.
+type OrPattern struct {
+	Token    sasttoken.Token // The first '|' token
+	Patterns []Pattern
+}
+
+func (op *OrPattern) patternNode()            {}
+func (op *OrPattern) TokenLiteral() string    { return op.Token.Literal }
+func (op *OrPattern) Pos() sasttoken.Position { return op.Patterns[0].Pos() }
+func (op *OrPattern) End() sasttoken.Position { return op.Patterns[len(op.Patterns)-1].End() }
+func (op *OrPattern) String() string {
+	alternatives := []string{}
+	for _, pattern := range op.Patterns {
+		if _, ok := pattern.(*AsPattern); ok {
+			alternatives = append(alternatives, "("+pattern.String()+")")
+		} else {
+			alternatives = append(alternatives, pattern.String())
+		}
+	}
+	return strings.Join(alternatives, " | ")
+}
+
+// AsPattern matches Pattern and binds the subject to Name.
+type AsPattern struct {
+	Token   sasttoken.Token // The token.AS token
+	Pattern Pattern
+	Name    *Identifier
+}
+
+func (ap *AsPattern) patternNode()            {}
+func (ap *AsPattern) TokenLiteral() string    { return ap.Token.Literal }
+func (ap *AsPattern) Pos() sasttoken.Position { return ap.Pattern.Pos() }
+func (ap *AsPattern) End() sasttoken.Position { return ap.Name.End() }
+func (ap *AsPattern) String() string {
+	if _, ok := ap.Pattern.(*AsPattern); ok {
+		return "(" + ap.Pattern.String() + ") as " + ap.Name.String()
+	}
+	return ap.Pattern.String() + " as " + ap.Name.String()
+}
+
+// ErrorNode stands in for a statement that could not be parsed. It spans
+// the tokens skipped while recovering; Body holds the indented block that
+// followed them, if any, parsed as usual.
+type ErrorNode struct {
+	Token    sasttoken.Token // The first token of the statement
+	EndToken sasttoken.Token // The last token skipped
+	Body     *BlockStatement // Optional
+}
+
+func (en *ErrorNode) statementNode()          {}
+func (en *ErrorNode) TokenLiteral() string    { return en.Token.Literal }
+func (en *ErrorNode) Pos() sasttoken.Position { return en.Token.Pos() }
+func (en *ErrorNode) End() sasttoken.Position {
+	if en.Body != nil {
+		return en.Body.End()
+	}
+	return en.EndToken.End()
+}
+func (en *ErrorNode) String() string {
+	if en.Body != nil {
+		return "<error>: " + en.Body.String()
+	}
+	return "<error>"
+}
+
+// ... existing code ...
+
+type TupleLiteral struct {
+	Token    sasttoken.Token // The '(' token, or the first token of a bare tuple
+	Elements []Expression
+	Rparen   sasttoken.Token // The ')' token; unset for a bare tuple
+}
+
+func (tl *TupleLiteral) expressionNode()         {}
+func (tl *TupleLiteral) TokenLiteral() string    { return tl.Token.Literal }
+func (tl *TupleLiteral) Pos() sasttoken.Position { return tl.Token.Pos() }
+func (tl *TupleLiteral) End() sasttoken.Position {
+	if tl.Rparen.Type != "" || len(tl.Elements) == 0 {
+		return tl.Rparen.End()
+	}
+	return tl.Elements[len(tl.Elements)-1].End()
+}
+func (tl *TupleLiteral) String() string {
+	var out strings.Builder
+
+	elements := []string{}
+	for _, el := range tl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(elements, ", "))
+	if len(elements) == 1 {
+		out.WriteString(",")
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// DictLiteral is a dict display. A nil key marks "**value" unpacking.
+type DictLiteral struct {
+	Token  sasttoken.Token // The '{' token
+	Keys   []Expression
+	Values []Expression
+	Rbrace sasttoken.Token // The '}' token
+}
+
+func (dl *DictLiteral) expressionNode()         {}
+func (dl *DictLiteral) TokenLiteral() string    { return dl.Token.Literal }
+func (dl *DictLiteral) Pos() sasttoken.Position { return dl.Token.Pos() }
+func (dl *DictLiteral) End() sasttoken.Position { return dl.Rbrace.End() }
+
+// Children lists keys and values alternately, in source order.
+func (dl *DictLiteral) Children() []Node {
+	var nodes []Node
+	for i, key := range dl.Keys {
+		if key != nil {
+			nodes = append(nodes, key)
+		}
+		nodes = append(nodes, dl.Values[i])
+	}
+	return nodes
+}
+func (dl *DictLiteral) String() string {
+	var out strings.Builder
+
+	pairs := []string{}
+	for i, key := range dl.Keys {
+		if key == nil {
+			pairs = append(pairs, "**"+dl.Values[i].String())
+			continue
+		}
+		pairs = append(pairs, key.String()+": "+dl.Values[i].String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// ImportStatement is "import a.b as c, d".
+type ImportStatement struct {
+	Token sasttoken.Token // The token.IMPORT token
+	Names []*ImportSpec
+}
+
+func (is *ImportStatement) statementNode()          {}
+func (is *ImportStatement) TokenLiteral() string    { return is.Token.Literal }
+func (is *ImportStatement) Pos() sasttoken.Position { return is.Token.Pos() }
+func (is *ImportStatement) End() sasttoken.Position { return is.Names[len(is.Names)-1].End() }
+func (is *ImportStatement) String() string {
+	names := []string{}
+	for _, spec := range is.Names {
+		names = append(names, spec.String())
+	}
+	return "import " + strings.Join(names, ", ")
+}
+
+type FromImportStatement struct {
+	Token      sasttoken.Token // The token.FROM token
+	Module     *Identifier     // Nil in "from . import x"
+	Level      int             // Relative import level, 0 for absolute imports
+	ImportList []*ImportSpec   // A single "*" for a wildcard import
+	Rparen     sasttoken.Token // The ')' closing a parenthesized list; unset otherwise
+}
+
+// ImportSpec is one imported name. Name holds a dotted module path as a
+// single Identifier, such as "os.path".
+type ImportSpec struct {
+	Name  *Identifier
+	Alias *Identifier // Optional alias
+}
+
+func (is *ImportSpec) TokenLiteral() string    { return is.Name.TokenLiteral() }
+func (is *ImportSpec) Pos() sasttoken.Position { return is.Name.Pos() }
+func (is *ImportSpec) End() sasttoken.Position {
+	if is.Alias != nil {
+		return is.Alias.End()
+	}
+	return is.Name.End()
+}
+
+func (is *ImportSpec) String() string {
+	if is.Alias != nil {
+		return is.Name.String() + " as " + is.Alias.String()
+	}
+	return is.Name.String()
+}
+
+func (fis *FromImportStatement) statementNode()          {}
+func (fis *FromImportStatement) TokenLiteral() string    { return fis.Token.Literal }
+func (fis *FromImportStatement) Pos() sasttoken.Position { return fis.Token.Pos() }
+func (fis *FromImportStatement) End() sasttoken.Position {
+	if fis.Rparen.Type != "" {
+		return fis.Rparen.End()
+	}
+	return fis.ImportList[len(fis.ImportList)-1].End()
+}
+func (fis *FromImportStatement) String() string {
+	var out strings.Builder
+
+	out.WriteString("from ")
+	if fis.Level > 0 {
+		out.WriteString(strings.Repeat(".", fis.Level))
+	}
+	if fis.Module != nil {
+		out.WriteString(fis.Module.String())
+	}
+	out.WriteString(" import ")
+
+	importList := []string{}
+	for _, spec := range fis.ImportList {
+		importList = append(importList, spec.String())
+	}
+
+	out.WriteString(strings.Join(importList, ", "))
+
+	return out.String()
+}
+
+// ... existing code ...
+
+type SetLiteral struct {
+	Token    sasttoken.Token // The '{' token
+	Elements []Expression
+	Rbrace   sasttoken.Token // The '}' token
+}
+
+func (sl *SetLiteral) expressionNode()         {}
+func (sl *SetLiteral) TokenLiteral() string    { return sl.Token.Literal }
+func (sl *SetLiteral) Pos() sasttoken.Position { return sl.Token.Pos() }
+func (sl *SetLiteral) End() sasttoken.Position { return sl.Rbrace.End() }
+func (sl *SetLiteral) String() string {
+	var out strings.Builder
+
+	elements := []string{}
+	for _, el := range sl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+type ListComprehension struct {
+	Token      sasttoken.Token // The '[' token
+	Element    Expression
+	Generators []*ForClause
+	Rbracket   sasttoken.Token // The ']' token
+}
+
+func (lc *ListComprehension) expressionNode()         {}
+func (lc *ListComprehension) TokenLiteral() string    { return lc.Token.Literal }
+func (lc *ListComprehension) Pos() sasttoken.Position { return lc.Token.Pos() }
+func (lc *ListComprehension) End() sasttoken.Position { return lc.Rbracket.End() }
+func (lc *ListComprehension) String() string {
+	return "[" + lc.Element.String() + joinForClauses(lc.Generators) + "]"
+}
+
+type SetComprehension struct {
+	Token      sasttoken.Token // The '{' token
+	Element    Expression
+	Generators []*ForClause
+	Rbrace     sasttoken.Token // The '}' token
+}
+
+func (sc *SetComprehension) expressionNode()         {}
+func (sc *SetComprehension) TokenLiteral() string    { return sc.Token.Literal }
+func (sc *SetComprehension) Pos() sasttoken.Position { return sc.Token.Pos() }
+func (sc *SetComprehension) End() sasttoken.Position { return sc.Rbrace.End() }
+func (sc *SetComprehension) String() string {
+	return "{" + sc.Element.String() + joinForClauses(sc.Generators) + "}"
+}
+
+type DictComprehension struct {
+	Token      sasttoken.Token // The '{' token
+	Key        Expression
+	Value      Expression
+	Generators []*ForClause
+	Rbrace     sasttoken.Token // The '}' token
+}
+
+func (dc *DictComprehension) expressionNode()         {}
+func (dc *DictComprehension) TokenLiteral() string    { return dc.Token.Literal }
+func (dc *DictComprehension) Pos() sasttoken.Position { return dc.Token.Pos() }
+func (dc *DictComprehension) End() sasttoken.Position { return dc.Rbrace.End() }
+func (dc *DictComprehension) String() string {
+	return "{" + dc.Key.String() + ": " + dc.Value.String() + joinForClauses(dc.Generators) + "}"
+}
+
+// GeneratorExpression is a generator expression. As the sole argument of a
+// call its parentheses are the call's.
+type GeneratorExpression struct {
+	Token      sasttoken.Token // The '(' token
+	Element    Expression
+	Generators []*ForClause
+	Rparen     sasttoken.Token // The ')' token
+}
+
+func (ge *GeneratorExpression) expressionNode()         {}
+func (ge *GeneratorExpression) TokenLiteral() string    { return ge.Token.Literal }
+func (ge *GeneratorExpression) Pos() sasttoken.Position { return ge.Token.Pos() }
+func (ge *GeneratorExpression) End() sasttoken.Position { return ge.Rparen.End() }
+func (ge *GeneratorExpression) String() string {
+	return "(" + ge.Element.String() + joinForClauses(ge.Generators) + ")"
+}
+
+// ForClause is one "for Target in Iter" clause of a comprehension, with the
+// "if" conditions that follow it.
+type ForClause struct {
+	Token  sasttoken.Token // The token.FOR token
+	Async  sasttoken.Token // The token.ASYNC token of "async for"; unset otherwise
+	Target Expression
+	Iter   Expression
+	Ifs    []Expression
+}
+
+func (fc *ForClause) TokenLiteral() string { return fc.Token.Literal }
+func (fc *ForClause) Pos() sasttoken.Position {
+	if fc.Async.Type != "" {
+		return fc.Async.Pos()
+	}
+	return fc.Token.Pos()
+}
+
+func (fc *ForClause) End() sasttoken.Position {
+	if len(fc.Ifs) > 0 {
+		return fc.Ifs[len(fc.Ifs)-1].End()
+	}
+	return fc.Iter.End()
+}
+
+func (fc *ForClause) String() string {
+	var out strings.Builder
+
+	if fc.Async.Type != "" {
+		out.WriteString("async ")
+	}
+	out.WriteString("for ")
+	out.WriteString(fc.Target.String())
+	out.WriteString(" in ")
+	out.WriteString(fc.Iter.String())
+	for _, cond := range fc.Ifs {
+		out.WriteString(" if ")
+		out.WriteString(cond.String())
+	}
+
+	return out.String()
+}
+
+func joinForClauses(clauses []*ForClause) string {
+	var out strings.Builder
+	for _, clause := range clauses {
+		out.WriteString(" ")
+		out.WriteString(clause.String())
+	}
+	return out.String()
+}
+
+type LambdaExpression struct {
+	Token      sasttoken.Token // The token.LAMBDA token
+	Parameters []*Parameter
+	Body       Expression
+}
+
+func (le *LambdaExpression) expressionNode()         {}
+func (le *LambdaExpression) TokenLiteral() string    { return le.Token.Literal }
+func (le *LambdaExpression) Pos() sasttoken.Position { return le.Token.Pos() }
+func (le *LambdaExpression) End() sasttoken.Position { return le.Body.End() }
+func (le *LambdaExpression) String() string {
+	if len(le.Parameters) == 0 {
+		return "(lambda: " + le.Body.String() + ")"
+	}
+	return "(lambda " + joinParameters(le.Parameters) + ": " + le.Body.String() + ")"
+}
+
+// ParameterKind says how an argument is bound to a parameter.
+type ParameterKind int
+
+const (
+	PositionalOrKeyword ParameterKind = iota
+	PositionalOnly                    // Before "/"
+	VarPositional                     // *args
+	KeywordOnly                       // After "*" or "*args"
+	VarKeyword                        // **kwargs
+)
+
+func (k ParameterKind) String() string {
+	switch k {
+	case PositionalOrKeyword:
+		return "PositionalOrKeyword"
+	case PositionalOnly:
+		return "PositionalOnly"
+	case VarPositional:
+		return "VarPositional"
+	case KeywordOnly:
+		return "KeywordOnly"
+	case VarKeyword:
+		return "VarKeyword"
+	}
+	return fmt.Sprintf("ParameterKind(%d)", int(k))
+}
+
+// Parameter is one parameter of a function or lambda. The "/" and bare "*"
+// separators are not nodes; they are implied by the parameters' kinds.
+type Parameter struct {
+	Token      sasttoken.Token // The name, or the '*' or '**' token
+	Kind       ParameterKind
+	Name       *Identifier
+	Annotation Expression // Optional
+	Default    Expression // Optional
+}
+
+func (pa *Parameter) TokenLiteral() string    { return pa.Token.Literal }
+func (pa *Parameter) Pos() sasttoken.Position { return pa.Token.Pos() }
+func (pa *Parameter) End() sasttoken.Position {
+	switch {
+	case pa.Default != nil:
+		return pa.Default.End()
+	case pa.Annotation != nil:
+		return pa.Annotation.End()
+	}
+	return pa.Name.End()
+}
+func (pa *Parameter) String() string {
+	var out strings.Builder
+
+	switch pa.Kind {
+	case VarPositional:
+		out.WriteString("*")
+	case VarKeyword:
+		out.WriteString("**")
+	}
+	out.WriteString(pa.Name.String())
+	if pa.Annotation != nil {
+		out.WriteString(": ")
+		out.WriteString(pa.Annotation.String())
+	}
+	if pa.Default != nil {
+		if pa.Annotation != nil {
+			out.WriteString(" = ")
+		} else {
+			out.WriteString("=")
+		}
+		out.WriteString(pa.Default.String())
+	}
+
+	return out.String()
+}
+
+// joinParameters formats a parameter list, restoring the "/" and "*"
+// separators.
+func joinParameters(params []*Parameter) string {
+	var parts []string
+	for i, param := range params {
+		if i > 0 && params[i-1].Kind == PositionalOnly && param.Kind != PositionalOnly {
+			parts = append(parts, "/")
+		}
+		if param.Kind == KeywordOnly && (i == 0 || params[i-1].Kind != KeywordOnly && params[i-1].Kind != VarPositional) {
+			parts = append(parts, "*")
+		}
+		parts = append(parts, param.String())
+	}
+	if n := len(params); n > 0 && params[n-1].Kind == PositionalOnly {
+		parts = append(parts, "/")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ... Add additional AST nodes here as needed.
+type IntegerLiteral struct {
+	Token sasttoken.Token
+	Value int64
+	Big   *big.Int // Set instead of Value when the literal does not fit in an int64
+}
+
+func (il *IntegerLiteral) expressionNode()         {}
+func (il *IntegerLiteral) TokenLiteral() string    { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() sasttoken.Position { return il.Token.Pos() }
+func (il *IntegerLiteral) End() sasttoken.Position { return il.Token.End() }
+func (il *IntegerLiteral) String() string {
+	if il.Big != nil {
+		return il.Big.String()
+	}
+	return strconv.FormatInt(il.Value, 10)
+}
+
+type FloatLiteral struct {
+	Token sasttoken.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()         {}
+func (fl *FloatLiteral) TokenLiteral() string    { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() sasttoken.Position { return fl.Token.Pos() }
+func (fl *FloatLiteral) End() sasttoken.Position { return fl.Token.End() }
+func (fl *FloatLiteral) String() string          { return fl.Token.Literal }
+
+// ImaginaryLiteral is a literal such as 2j; Value is its imaginary part.
+type ImaginaryLiteral struct {
+	Token sasttoken.Token
+	Value float64
+}
+
+func (il *ImaginaryLiteral) expressionNode()         {}
+func (il *ImaginaryLiteral) TokenLiteral() string    { return il.Token.Literal }
+func (il *ImaginaryLiteral) Pos() sasttoken.Position { return il.Token.Pos() }
+func (il *ImaginaryLiteral) End() sasttoken.Position { return il.Token.End() }
+func (il *ImaginaryLiteral) String() string          { return il.Token.Literal }
+
+// StringLiteral is a string or bytes literal. Adjacent literals such as
+// "a" "b" are one StringLiteral holding their concatenation.
+type StringLiteral struct {
+	Token sasttoken.Token
+	Rest  []sasttoken.Token // The tokens of the literals after the first, if adjacent ones were concatenated
+	Value string
+}
+
+func (sl *StringLiteral) expressionNode()         {}
+func (sl *StringLiteral) TokenLiteral() string    { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() sasttoken.Position { return sl.Token.Pos() }
+func (sl *StringLiteral) End() sasttoken.Position { return lastToken(sl.Token, sl.Rest).End() }
+func (sl *StringLiteral) String() string          { return strconv.Quote(sl.Value) }
+
+type BooleanLiteral struct {
+	Token sasttoken.Token
+	Value bool
+}
+
+func (bl *BooleanLiteral) expressionNode()         {}
+func (bl *BooleanLiteral) TokenLiteral() string    { return bl.Token.Literal }
+func (bl *BooleanLiteral) Pos() sasttoken.Position { return bl.Token.Pos() }
+func (bl *BooleanLiteral) End() sasttoken.Position { return bl.Token.End() }
+func (bl *BooleanLiteral) String() string {
+	if bl.Value {
+		return "True"
+	}
+	return "False"
+}
+
+type NoneLiteral struct {
+	Token sasttoken.Token
+}
+
+func (nl *NoneLiteral) expressionNode()         {}
+func (nl *NoneLiteral) TokenLiteral() string    { return nl.Token.Literal }
+func (nl *NoneLiteral) Pos() sasttoken.Position { return nl.Token.Pos() }
+func (nl *NoneLiteral) End() sasttoken.Position { return nl.Token.End() }
+func (nl *NoneLiteral) String() string          { return "None" }
+
+// Ellipsis is the "..." literal, as in "def f(): ..." or "x[..., 0]".
+type Ellipsis struct {
+	Token sasttoken.Token
+}
+
+func (el *Ellipsis) expressionNode()         {}
+func (el *Ellipsis) TokenLiteral() string    { return el.Token.Literal }
+func (el *Ellipsis) Pos() sasttoken.Position { return el.Token.Pos() }
+func (el *Ellipsis) End() sasttoken.Position { return el.Token.End() }
+func (el *Ellipsis) String() string          { return "..." }
+
+type PrefixExpression struct {
+	Token    sasttoken.Token
+	Operator string
 	Right    Expression
 }
+
+func (pe *PrefixExpression) expressionNode()         {}
+func (pe *PrefixExpression) TokenLiteral() string    { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() sasttoken.Position { return pe.Token.Pos() }
+func (pe *PrefixExpression) End() sasttoken.Position { return pe.Right.End() }
+func (pe *PrefixExpression) String() string {
+	if pe.Operator == "not" {
+		return "(not " + pe.Right.String() + ")"
+	}
+	return "(" + pe.Operator + pe.Right.String() + ")"
+}
+
+type InfixExpression struct {
+	Token    sasttoken.Token
+	Left     Expression
+	Operator string
+	Right    Expression
+}
+
+func (ie *InfixExpression) expressionNode()         {}
+func (ie *InfixExpression) TokenLiteral() string    { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() sasttoken.Position { return ie.Left.Pos() }
+func (ie *InfixExpression) End() sasttoken.Position { return ie.Right.End() }
+func (ie *InfixExpression) String() string {
+	return "(" + ie.Left.String() + " " + ie.Operator + " " + ie.Right.String() + ")"
+}
+
+// ComparisonExpression is a possibly chained comparison such as
+// "a < b <= c", which compares each operand with the next.
+type ComparisonExpression struct {
+	Token       sasttoken.Token // The first operator token
+	Left        Expression
+	Operators   []string // Such as "<", "in", "not in" or "is not"
+	Comparators []Expression
+}
+
+func (ce *ComparisonExpression) expressionNode()         {}
+func (ce *ComparisonExpression) TokenLiteral() string    { return ce.Token.Literal }
+func (ce *ComparisonExpression) Pos() sasttoken.Position { return ce.Left.Pos() }
+func (ce *ComparisonExpression) End() sasttoken.Position {
+	return ce.Comparators[len(ce.Comparators)-1].End()
+}
+func (ce *ComparisonExpression) String() string {
+	var out strings.Builder
+
+	out.WriteString("(")
+	out.WriteString(ce.Left.String())
+	for i, op := range ce.Operators {
+		out.WriteString(" " + op + " ")
+		out.WriteString(ce.Comparators[i].String())
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// AttributeExpression is an attribute reference, "Object.Attribute".
+type AttributeExpression struct {
+	Token     sasttoken.Token // The '.' token
+	Object    Expression
+	Attribute *Identifier
+}
+
+func (ae *AttributeExpression) expressionNode()         {}
+func (ae *AttributeExpression) TokenLiteral() string    { return ae.Token.Literal }
+func (ae *AttributeExpression) Pos() sasttoken.Position { return ae.Object.Pos() }
+func (ae *AttributeExpression) End() sasttoken.Position { return ae.Attribute.End() }
+func (ae *AttributeExpression) String() string {
+	return ae.Object.String() + "." + ae.Attribute.String()
+}
+
+// SubscriptExpression is a subscription or slicing, "Object[Index]". A
+// slice index is a *SliceExpression, and several indices form a
+// *TupleLiteral.
+type SubscriptExpression struct {
+	Token    sasttoken.Token // The '[' token
+	Object   Expression
+	Index    Expression
+	Rbracket sasttoken.Token // The ']' token
+}
+
+func (se *SubscriptExpression) expressionNode()         {}
+func (se *SubscriptExpression) TokenLiteral() string    { return se.Token.Literal }
+func (se *SubscriptExpression) Pos() sasttoken.Position { return se.Object.Pos() }
+func (se *SubscriptExpression) End() sasttoken.Position { return se.Rbracket.End() }
+func (se *SubscriptExpression) String() string {
+	index := se.Index.String()
+	if tuple, ok := se.Index.(*TupleLiteral); ok && tuple.Rparen.Type == "" {
+		index = strings.TrimSuffix(strings.TrimPrefix(index, "("), ")")
+	}
+	return se.Object.String() + "[" + index + "]"
+}
+
+// SliceExpression is a slice inside a subscript, "Lower:Upper:Step", where
+// every part is optional.
+type SliceExpression struct {
+	Token     sasttoken.Token // The first ':' token
+	Lower     Expression
+	Upper     Expression
+	StepColon sasttoken.Token // The second ':' token; unset if absent
+	Step      Expression
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) Pos() sasttoken.Position {
+	if se.Lower != nil {
+		return se.Lower.Pos()
+	}
+	return se.Token.Pos()
+}
+func (se *SliceExpression) End() sasttoken.Position {
+	switch {
+	case se.Step != nil:
+		return se.Step.End()
+	case se.StepColon.Type != "":
+		return se.StepColon.End()
+	case se.Upper != nil:
+		return se.Upper.End()
+	}
+	return se.Token.End()
+}
+func (se *SliceExpression) String() string {
+	var out strings.Builder
+
+	if se.Lower != nil {
+		out.WriteString(se.Lower.String())
+	}
+	out.WriteString(":")
+	if se.Upper != nil {
+		out.WriteString(se.Upper.String())
+	}
+	if se.StepColon.Type != "" {
+		out.WriteString(":")
+	}
+	if se.Step != nil {
+		out.WriteString(se.Step.String())
+	}
+
+	return out.String()
+}
+
+// NamedExpression is an assignment expression, "Target := Value".
+type NamedExpression struct {
+	Token  sasttoken.Token // The ':=' token
+	Target *Identifier
+	Value  Expression
+}
+
+func (ne *NamedExpression) expressionNode()         {}
+func (ne *NamedExpression) TokenLiteral() string    { return ne.Token.Literal }
+func (ne *NamedExpression) Pos() sasttoken.Position { return ne.Target.Pos() }
+func (ne *NamedExpression) End() sasttoken.Position { return ne.Value.End() }
+func (ne *NamedExpression) String() string {
+	return "(" + ne.Target.String() + " := " + ne.Value.String() + ")"
+}
+
+type AwaitExpression struct {
+	Token sasttoken.Token // The token.AWAIT token
+	Value Expression
+}
+
+func (ae *AwaitExpression) expressionNode()         {}
+func (ae *AwaitExpression) TokenLiteral() string    { return ae.Token.Literal }
+func (ae *AwaitExpression) Pos() sasttoken.Position { return ae.Token.Pos() }
+func (ae *AwaitExpression) End() sasttoken.Position { return ae.Value.End() }
+func (ae *AwaitExpression) String() string          { return "(await " + ae.Value.String() + ")" }
+
+// JoinedStr is an f-string. Values holds its literal text as *StringLiteral
+// and its replacement fields as *FormattedValue, in source order. An
+// f-string concatenated with adjacent literals, such as "a" f"{b}", is one
+// JoinedStr.
+type JoinedStr struct {
+	Token  sasttoken.Token   // The token.STRING token; for a format spec, narrowed to the spec
+	Rest   []sasttoken.Token // The tokens of the literals after the first, if adjacent ones were concatenated
+	Values []Expression
+}
+
+func (js *JoinedStr) expressionNode()         {}
+func (js *JoinedStr) TokenLiteral() string    { return js.Token.Literal }
+func (js *JoinedStr) Pos() sasttoken.Position { return js.Token.Pos() }
+func (js *JoinedStr) End() sasttoken.Position { return lastToken(js.Token, js.Rest).End() }
+
+// lastToken returns the last of first and rest.
+func lastToken(first sasttoken.Token, rest []sasttoken.Token) sasttoken.Token {
+	if len(rest) > 0 {
+		return rest[len(rest)-1]
+	}
+	return first
+}
+func (js *JoinedStr) String() string {
+	var out strings.Builder
+
+	out.WriteString("f\"")
+	for _, v := range js.Values {
+		switch v := v.(type) {
+		case *StringLiteral:
+			text := strings.ReplaceAll(v.Value, "{", "{{")
+			out.WriteString(strings.ReplaceAll(text, "}", "}}"))
+		default:
+			out.WriteString(v.String())
+		}
+	}
+	out.WriteString("\"")
+
+	return out.String()
+}
+
+// FormattedValue is a replacement field of an f-string.
+type FormattedValue struct {
+	Token      sasttoken.Token // The f-string's token, narrowed to the field's "{...}"
+	Value      Expression
+	Conversion byte       // 's', 'r', 'a', or 0 for none
+	FormatSpec *JoinedStr // Optional format spec
+}
+
+func (fv *FormattedValue) expressionNode()         {}
+func (fv *FormattedValue) TokenLiteral() string    { return fv.Token.Literal }
+func (fv *FormattedValue) Pos() sasttoken.Position { return fv.Token.Pos() }
+func (fv *FormattedValue) End() sasttoken.Position { return fv.Token.End() }
+func (fv *FormattedValue) String() string {
+	var out strings.Builder
+
+	out.WriteString("{")
+	out.WriteString(fv.Value.String())
+	if fv.Conversion != 0 {
+		out.WriteString("!")
+		out.WriteByte(fv.Conversion)
+	}
+	if fv.FormatSpec != nil {
+		out.WriteString(":")
+		for _, v := range fv.FormatSpec.Values {
+			if lit, ok := v.(*StringLiteral); ok {
+				out.WriteString(lit.Value)
+			} else {
+				out.WriteString(v.String())
+			}
+		}
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
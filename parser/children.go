@@ -0,0 +1,865 @@
+// Code generated by gen_children.go; DO NOT EDIT.
+
+package parser
+
+func (n *Identifier) Children() []Node {
+	return nil
+}
+
+func (n *Program) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Statements {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *CallExpression) Children() []Node {
+	var nodes []Node
+	if n.Function != nil {
+		nodes = append(nodes, n.Function)
+	}
+	for _, c := range n.Arguments {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	for _, c := range n.Keywords {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *KeywordArgument) Children() []Node {
+	var nodes []Node
+	if n.Name != nil {
+		nodes = append(nodes, n.Name)
+	}
+	if n.Value != nil {
+		nodes = append(nodes, n.Value)
+	}
+	return nodes
+}
+
+func (n *StarredExpression) Children() []Node {
+	var nodes []Node
+	if n.Value != nil {
+		nodes = append(nodes, n.Value)
+	}
+	return nodes
+}
+
+func (n *IfExpression) Children() []Node {
+	var nodes []Node
+	if n.Consequence != nil {
+		nodes = append(nodes, n.Consequence)
+	}
+	if n.Condition != nil {
+		nodes = append(nodes, n.Condition)
+	}
+	if n.Alternative != nil {
+		nodes = append(nodes, n.Alternative)
+	}
+	return nodes
+}
+
+func (n *BlockStatement) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Statements {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *ListLiteral) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Elements {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *ForStatement) Children() []Node {
+	var nodes []Node
+	if n.Target != nil {
+		nodes = append(nodes, n.Target)
+	}
+	if n.Iterable != nil {
+		nodes = append(nodes, n.Iterable)
+	}
+	if n.Body != nil {
+		nodes = append(nodes, n.Body)
+	}
+	if n.ElseBody != nil {
+		nodes = append(nodes, n.ElseBody)
+	}
+	return nodes
+}
+
+func (n *WhileStatement) Children() []Node {
+	var nodes []Node
+	if n.Condition != nil {
+		nodes = append(nodes, n.Condition)
+	}
+	if n.Body != nil {
+		nodes = append(nodes, n.Body)
+	}
+	if n.ElseBody != nil {
+		nodes = append(nodes, n.ElseBody)
+	}
+	return nodes
+}
+
+func (n *FunctionDef) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Decorators {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	if n.Name != nil {
+		nodes = append(nodes, n.Name)
+	}
+	for _, c := range n.TypeParams {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	for _, c := range n.Parameters {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	if n.Returns != nil {
+		nodes = append(nodes, n.Returns)
+	}
+	if n.Body != nil {
+		nodes = append(nodes, n.Body)
+	}
+	return nodes
+}
+
+func (n *ReturnStatement) Children() []Node {
+	var nodes []Node
+	if n.ReturnValue != nil {
+		nodes = append(nodes, n.ReturnValue)
+	}
+	return nodes
+}
+
+func (n *AssignmentStatement) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Targets {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	if n.Value != nil {
+		nodes = append(nodes, n.Value)
+	}
+	return nodes
+}
+
+func (n *ExpressionStatement) Children() []Node {
+	var nodes []Node
+	if n.Expression != nil {
+		nodes = append(nodes, n.Expression)
+	}
+	return nodes
+}
+
+func (n *IfStatement) Children() []Node {
+	var nodes []Node
+	if n.Condition != nil {
+		nodes = append(nodes, n.Condition)
+	}
+	if n.Consequence != nil {
+		nodes = append(nodes, n.Consequence)
+	}
+	for _, c := range n.ElifClauses {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	if n.ElseClause != nil {
+		nodes = append(nodes, n.ElseClause)
+	}
+	return nodes
+}
+
+func (n *ElifStatement) Children() []Node {
+	var nodes []Node
+	if n.Condition != nil {
+		nodes = append(nodes, n.Condition)
+	}
+	if n.Body != nil {
+		nodes = append(nodes, n.Body)
+	}
+	return nodes
+}
+
+func (n *ElseStatement) Children() []Node {
+	var nodes []Node
+	if n.Body != nil {
+		nodes = append(nodes, n.Body)
+	}
+	return nodes
+}
+
+func (n *TryStatement) Children() []Node {
+	var nodes []Node
+	if n.TryBlock != nil {
+		nodes = append(nodes, n.TryBlock)
+	}
+	for _, c := range n.ExceptClauses {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	if n.ElseClause != nil {
+		nodes = append(nodes, n.ElseClause)
+	}
+	if n.FinallyClause != nil {
+		nodes = append(nodes, n.FinallyClause)
+	}
+	return nodes
+}
+
+func (n *ExceptStatement) Children() []Node {
+	var nodes []Node
+	if n.ExceptionType != nil {
+		nodes = append(nodes, n.ExceptionType)
+	}
+	if n.Name != nil {
+		nodes = append(nodes, n.Name)
+	}
+	if n.Body != nil {
+		nodes = append(nodes, n.Body)
+	}
+	return nodes
+}
+
+func (n *FinallyStatement) Children() []Node {
+	var nodes []Node
+	if n.Body != nil {
+		nodes = append(nodes, n.Body)
+	}
+	return nodes
+}
+
+func (n *PassStatement) Children() []Node {
+	return nil
+}
+
+func (n *BreakStatement) Children() []Node {
+	return nil
+}
+
+func (n *ContinueStatement) Children() []Node {
+	return nil
+}
+
+func (n *Decorator) Children() []Node {
+	var nodes []Node
+	if n.Expression != nil {
+		nodes = append(nodes, n.Expression)
+	}
+	return nodes
+}
+
+func (n *ClassDef) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Decorators {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	if n.Name != nil {
+		nodes = append(nodes, n.Name)
+	}
+	for _, c := range n.TypeParams {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	for _, c := range n.Bases {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	for _, c := range n.Keywords {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	if n.Body != nil {
+		nodes = append(nodes, n.Body)
+	}
+	return nodes
+}
+
+func (n *TypeAliasStatement) Children() []Node {
+	var nodes []Node
+	if n.Name != nil {
+		nodes = append(nodes, n.Name)
+	}
+	for _, c := range n.TypeParams {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	if n.Value != nil {
+		nodes = append(nodes, n.Value)
+	}
+	return nodes
+}
+
+func (n *TypeParam) Children() []Node {
+	var nodes []Node
+	if n.Name != nil {
+		nodes = append(nodes, n.Name)
+	}
+	if n.Bound != nil {
+		nodes = append(nodes, n.Bound)
+	}
+	if n.Default != nil {
+		nodes = append(nodes, n.Default)
+	}
+	return nodes
+}
+
+func (n *AugAssignStatement) Children() []Node {
+	var nodes []Node
+	if n.Target != nil {
+		nodes = append(nodes, n.Target)
+	}
+	if n.Value != nil {
+		nodes = append(nodes, n.Value)
+	}
+	return nodes
+}
+
+func (n *AnnAssignStatement) Children() []Node {
+	var nodes []Node
+	if n.Target != nil {
+		nodes = append(nodes, n.Target)
+	}
+	if n.Annotation != nil {
+		nodes = append(nodes, n.Annotation)
+	}
+	if n.Value != nil {
+		nodes = append(nodes, n.Value)
+	}
+	return nodes
+}
+
+func (n *RaiseStatement) Children() []Node {
+	var nodes []Node
+	if n.Exception != nil {
+		nodes = append(nodes, n.Exception)
+	}
+	if n.Cause != nil {
+		nodes = append(nodes, n.Cause)
+	}
+	return nodes
+}
+
+func (n *AssertStatement) Children() []Node {
+	var nodes []Node
+	if n.Test != nil {
+		nodes = append(nodes, n.Test)
+	}
+	if n.Message != nil {
+		nodes = append(nodes, n.Message)
+	}
+	return nodes
+}
+
+func (n *DelStatement) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Targets {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *GlobalStatement) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Names {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *NonlocalStatement) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Names {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *YieldExpression) Children() []Node {
+	var nodes []Node
+	if n.Value != nil {
+		nodes = append(nodes, n.Value)
+	}
+	return nodes
+}
+
+func (n *WithStatement) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Items {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	if n.Body != nil {
+		nodes = append(nodes, n.Body)
+	}
+	return nodes
+}
+
+func (n *WithItem) Children() []Node {
+	var nodes []Node
+	if n.Context != nil {
+		nodes = append(nodes, n.Context)
+	}
+	if n.Target != nil {
+		nodes = append(nodes, n.Target)
+	}
+	return nodes
+}
+
+func (n *MatchStatement) Children() []Node {
+	var nodes []Node
+	if n.Subject != nil {
+		nodes = append(nodes, n.Subject)
+	}
+	for _, c := range n.Cases {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *MatchCase) Children() []Node {
+	var nodes []Node
+	if n.Pattern != nil {
+		nodes = append(nodes, n.Pattern)
+	}
+	if n.Guard != nil {
+		nodes = append(nodes, n.Guard)
+	}
+	if n.Body != nil {
+		nodes = append(nodes, n.Body)
+	}
+	return nodes
+}
+
+func (n *LiteralPattern) Children() []Node {
+	var nodes []Node
+	if n.Value != nil {
+		nodes = append(nodes, n.Value)
+	}
+	return nodes
+}
+
+func (n *ValuePattern) Children() []Node {
+	var nodes []Node
+	if n.Value != nil {
+		nodes = append(nodes, n.Value)
+	}
+	return nodes
+}
+
+func (n *CapturePattern) Children() []Node {
+	var nodes []Node
+	if n.Name != nil {
+		nodes = append(nodes, n.Name)
+	}
+	return nodes
+}
+
+func (n *WildcardPattern) Children() []Node {
+	return nil
+}
+
+func (n *StarPattern) Children() []Node {
+	var nodes []Node
+	if n.Pattern != nil {
+		nodes = append(nodes, n.Pattern)
+	}
+	return nodes
+}
+
+func (n *SequencePattern) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Patterns {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *ClassPattern) Children() []Node {
+	var nodes []Node
+	if n.Class != nil {
+		nodes = append(nodes, n.Class)
+	}
+	for _, c := range n.Patterns {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	for _, c := range n.Keywords {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *KeywordPattern) Children() []Node {
+	var nodes []Node
+	if n.Name != nil {
+		nodes = append(nodes, n.Name)
+	}
+	if n.Pattern != nil {
+		nodes = append(nodes, n.Pattern)
+	}
+	return nodes
+}
+
+func (n *OrPattern) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Patterns {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *AsPattern) Children() []Node {
+	var nodes []Node
+	if n.Pattern != nil {
+		nodes = append(nodes, n.Pattern)
+	}
+	if n.Name != nil {
+		nodes = append(nodes, n.Name)
+	}
+	return nodes
+}
+
+func (n *ErrorNode) Children() []Node {
+	var nodes []Node
+	if n.Body != nil {
+		nodes = append(nodes, n.Body)
+	}
+	return nodes
+}
+
+func (n *TupleLiteral) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Elements {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *ImportStatement) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Names {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *FromImportStatement) Children() []Node {
+	var nodes []Node
+	if n.Module != nil {
+		nodes = append(nodes, n.Module)
+	}
+	for _, c := range n.ImportList {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *ImportSpec) Children() []Node {
+	var nodes []Node
+	if n.Name != nil {
+		nodes = append(nodes, n.Name)
+	}
+	if n.Alias != nil {
+		nodes = append(nodes, n.Alias)
+	}
+	return nodes
+}
+
+func (n *SetLiteral) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Elements {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *ListComprehension) Children() []Node {
+	var nodes []Node
+	if n.Element != nil {
+		nodes = append(nodes, n.Element)
+	}
+	for _, c := range n.Generators {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *SetComprehension) Children() []Node {
+	var nodes []Node
+	if n.Element != nil {
+		nodes = append(nodes, n.Element)
+	}
+	for _, c := range n.Generators {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *DictComprehension) Children() []Node {
+	var nodes []Node
+	if n.Key != nil {
+		nodes = append(nodes, n.Key)
+	}
+	if n.Value != nil {
+		nodes = append(nodes, n.Value)
+	}
+	for _, c := range n.Generators {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *GeneratorExpression) Children() []Node {
+	var nodes []Node
+	if n.Element != nil {
+		nodes = append(nodes, n.Element)
+	}
+	for _, c := range n.Generators {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *ForClause) Children() []Node {
+	var nodes []Node
+	if n.Target != nil {
+		nodes = append(nodes, n.Target)
+	}
+	if n.Iter != nil {
+		nodes = append(nodes, n.Iter)
+	}
+	for _, c := range n.Ifs {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *LambdaExpression) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Parameters {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	if n.Body != nil {
+		nodes = append(nodes, n.Body)
+	}
+	return nodes
+}
+
+func (n *Parameter) Children() []Node {
+	var nodes []Node
+	if n.Name != nil {
+		nodes = append(nodes, n.Name)
+	}
+	if n.Annotation != nil {
+		nodes = append(nodes, n.Annotation)
+	}
+	if n.Default != nil {
+		nodes = append(nodes, n.Default)
+	}
+	return nodes
+}
+
+func (n *IntegerLiteral) Children() []Node {
+	return nil
+}
+
+func (n *FloatLiteral) Children() []Node {
+	return nil
+}
+
+func (n *ImaginaryLiteral) Children() []Node {
+	return nil
+}
+
+func (n *StringLiteral) Children() []Node {
+	return nil
+}
+
+func (n *BooleanLiteral) Children() []Node {
+	return nil
+}
+
+func (n *NoneLiteral) Children() []Node {
+	return nil
+}
+
+func (n *Ellipsis) Children() []Node {
+	return nil
+}
+
+func (n *PrefixExpression) Children() []Node {
+	var nodes []Node
+	if n.Right != nil {
+		nodes = append(nodes, n.Right)
+	}
+	return nodes
+}
+
+func (n *InfixExpression) Children() []Node {
+	var nodes []Node
+	if n.Left != nil {
+		nodes = append(nodes, n.Left)
+	}
+	if n.Right != nil {
+		nodes = append(nodes, n.Right)
+	}
+	return nodes
+}
+
+func (n *ComparisonExpression) Children() []Node {
+	var nodes []Node
+	if n.Left != nil {
+		nodes = append(nodes, n.Left)
+	}
+	for _, c := range n.Comparators {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *AttributeExpression) Children() []Node {
+	var nodes []Node
+	if n.Object != nil {
+		nodes = append(nodes, n.Object)
+	}
+	if n.Attribute != nil {
+		nodes = append(nodes, n.Attribute)
+	}
+	return nodes
+}
+
+func (n *SubscriptExpression) Children() []Node {
+	var nodes []Node
+	if n.Object != nil {
+		nodes = append(nodes, n.Object)
+	}
+	if n.Index != nil {
+		nodes = append(nodes, n.Index)
+	}
+	return nodes
+}
+
+func (n *SliceExpression) Children() []Node {
+	var nodes []Node
+	if n.Lower != nil {
+		nodes = append(nodes, n.Lower)
+	}
+	if n.Upper != nil {
+		nodes = append(nodes, n.Upper)
+	}
+	if n.Step != nil {
+		nodes = append(nodes, n.Step)
+	}
+	return nodes
+}
+
+func (n *NamedExpression) Children() []Node {
+	var nodes []Node
+	if n.Target != nil {
+		nodes = append(nodes, n.Target)
+	}
+	if n.Value != nil {
+		nodes = append(nodes, n.Value)
+	}
+	return nodes
+}
+
+func (n *AwaitExpression) Children() []Node {
+	var nodes []Node
+	if n.Value != nil {
+		nodes = append(nodes, n.Value)
+	}
+	return nodes
+}
+
+func (n *JoinedStr) Children() []Node {
+	var nodes []Node
+	for _, c := range n.Values {
+		if c != nil {
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+func (n *FormattedValue) Children() []Node {
+	var nodes []Node
+	if n.Value != nil {
+		nodes = append(nodes, n.Value)
+	}
+	if n.FormatSpec != nil {
+		nodes = append(nodes, n.FormatSpec)
+	}
+	return nodes
+}
@@ -0,0 +1,230 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	sasttoken "github.com/coiloffaraday/python_sast/token"
+)
+
+// Diagnostic describes a lexical or syntax error in a file.
+type Diagnostic struct {
+	Pos      sasttoken.Position
+	FilePath string
+	Message  string
+	Expected string          // What the parser expected, such as "':'"; empty if not specific
+	Found    sasttoken.Token // The offending token; unset for lexical errors and errors about a whole expression
+}
+
+func (d *Diagnostic) Error() string {
+	var out strings.Builder
+	if d.FilePath != "" {
+		out.WriteString(d.FilePath)
+		out.WriteString(":")
+	}
+	fmt.Fprintf(&out, "%d:", d.Pos.Line)
+	if d.Pos.Column > 0 {
+		fmt.Fprintf(&out, "%d:", d.Pos.Column)
+	}
+	out.WriteString(" ")
+	out.WriteString(d.Message)
+	return out.String()
+}
+
+// DiagnosticList is a list of diagnostics that is itself an error.
+type DiagnosticList []*Diagnostic
+
+func (l DiagnosticList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0].Error(), len(l)-1)
+}
+
+// Sort orders the list by position. Errors without a column, such as those
+// in decoding a file, come first on their line.
+func (l DiagnosticList) Sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+		a, b := l[i].Pos, l[j].Pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// errorf returns a diagnostic at the current token.
+func (p *Parser) errorf(format string, args ...interface{}) *Diagnostic {
+	return &Diagnostic{
+		Pos:      p.curToken.Pos(),
+		FilePath: p.lexer.FilePath,
+		Message:  fmt.Sprintf(format, args...),
+		Found:    p.curToken,
+	}
+}
+
+// errorAt returns a diagnostic about an expression as a whole, placed at
+// its start.
+func (p *Parser) errorAt(expr Expression, format string, args ...interface{}) *Diagnostic {
+	return &Diagnostic{
+		Pos:      expr.Pos(),
+		FilePath: p.lexer.FilePath,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// expectedError returns a diagnostic saying that expected was wanted
+// instead of the current token.
+func (p *Parser) expectedError(expected string) *Diagnostic {
+	d := p.errorf("expected %s, got %s", expected, describeToken(p.curToken))
+	d.Expected = expected
+	return d
+}
+
+// addDiagnostic records err. Errors that are not diagnostics are placed
+// at the current token.
+func (p *Parser) addDiagnostic(err error) {
+	var d *Diagnostic
+	if !errors.As(err, &d) {
+		d = p.errorf("%s", err)
+	}
+	if d.Found.Type == sasttoken.ILLEGAL {
+		// The lexer has already reported the invalid token.
+		return
+	}
+	p.diagnostics = append(p.diagnostics, d)
+}
+
+// parseStatementOrRecover parses a statement. If that fails, the error is
+// recorded and an ErrorNode takes the statement's place.
+func (p *Parser) parseStatementOrRecover() Statement {
+	start := p.curToken
+	stmt, err := p.parseStatement()
+	if err == nil {
+		return stmt
+	}
+	p.addDiagnostic(err)
+	return p.recoverStatement(start)
+}
+
+// recoverStatement skips the rest of the logical line on which a statement
+// starting at start failed. When the line introduces an indented block, the
+// block is parsed into the ErrorNode's Body, so the statements of a function
+// whose header is unsupported are still analyzed: control-flow graphs, and
+// the taint analysis on them, run the Body as a loop's.
+func (p *Parser) recoverStatement(start sasttoken.Token) Statement {
+	node := &ErrorNode{Token: start}
+
+	// A statement that fails on the first token of the next line has
+	// already consumed its own line.
+	progressed := p.curToken.Offset != start.Offset || p.curToken.Type != start.Type
+	atLineStart := p.prevToken.Type == sasttoken.NEWLINE
+	if !p.curTokenIs(sasttoken.INDENT) && (!progressed || !atLineStart) {
+		for !p.curTokenIs(sasttoken.NEWLINE) && !p.curTokenIs(sasttoken.EOF) {
+			p.nextToken()
+		}
+		if p.curTokenIs(sasttoken.NEWLINE) {
+			p.nextToken()
+		}
+	}
+	node.EndToken = p.prevToken
+
+	if p.curTokenIs(sasttoken.INDENT) {
+		node.Body = &BlockStatement{Token: p.curToken}
+		p.parseIndentedBlock(node.Body)
+	}
+
+	return node
+}
+
+// describeExpression names the kind of an expression for use in a
+// diagnostic, as CPython does.
+func describeExpression(expr Expression) string {
+	switch e := expr.(type) {
+	case *IntegerLiteral, *FloatLiteral, *ImaginaryLiteral, *StringLiteral:
+		return "literal"
+	case *BooleanLiteral, *NoneLiteral:
+		return e.String()
+	case *Ellipsis:
+		return "ellipsis"
+	case *JoinedStr:
+		return "f-string expression"
+	case *CallExpression:
+		return "function call"
+	case *NamedExpression:
+		return "named expression"
+	case *LambdaExpression:
+		return "lambda"
+	case *ComparisonExpression:
+		return "comparison"
+	case *IfExpression:
+		return "conditional expression"
+	case *YieldExpression:
+		return "yield expression"
+	case *AwaitExpression:
+		return "await expression"
+	case *DictLiteral:
+		return "dict literal"
+	case *SetLiteral:
+		return "set display"
+	case *ListComprehension:
+		return "list comprehension"
+	case *SetComprehension:
+		return "set comprehension"
+	case *DictComprehension:
+		return "dict comprehension"
+	case *GeneratorExpression:
+		return "generator expression"
+	}
+	return "expression"
+}
+
+// describeToken names a token for use in a diagnostic.
+func describeToken(tok sasttoken.Token) string {
+	switch tok.Type {
+	case sasttoken.IDENT:
+		return fmt.Sprintf("name '%s'", tok.Literal)
+	case sasttoken.INT, sasttoken.FLOAT, sasttoken.IMAGINARY:
+		return fmt.Sprintf("number %s", tok.Literal)
+	case sasttoken.STRING:
+		return "string"
+	case sasttoken.ILLEGAL:
+		return fmt.Sprintf("invalid token %q", tok.Literal)
+	case sasttoken.NEWLINE, sasttoken.INDENT, sasttoken.DEDENT, sasttoken.EOF:
+		return describeType(tok.Type)
+	}
+	return fmt.Sprintf("'%s'", tok.Literal)
+}
+
+// describeType names a token type for use in a diagnostic.
+func describeType(t sasttoken.TokenType) string {
+	switch t {
+	case sasttoken.IDENT:
+		return "name"
+	case sasttoken.INT, sasttoken.FLOAT, sasttoken.IMAGINARY:
+		return "number"
+	case sasttoken.STRING:
+		return "string"
+	case sasttoken.NEWLINE:
+		return "newline"
+	case sasttoken.INDENT:
+		return "indent"
+	case sasttoken.DEDENT:
+		return "dedent"
+	case sasttoken.EOF:
+		return "end of file"
+	}
+	for _, table := range []map[string]sasttoken.TokenType{sasttoken.Keywords, sasttoken.Python2Keywords, sasttoken.SoftKeywords} {
+		for word, kw := range table {
+			if kw == t {
+				return fmt.Sprintf("'%s'", word)
+			}
+		}
+	}
+	return fmt.Sprintf("'%s'", t)
+}
@@ -2,46 +2,133 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
 
 	sasttoken "github.com/coiloffaraday/python_sast/token"
 )
 
-LOWEST := 0
-PREFIX := 1
+// Precedences, from the loosest binding to the tightest.
+const (
+	_ int = iota
+	LOWEST
+	NAMED      // x := y
+	TERNARY    // a if b else c
+	OR         // or
+	AND        // and
+	NOT        // not x
+	COMPARISON // in, not in, is, is not, <, <=, >, >=, !=, ==
+	BITOR      // |
+	BITXOR     // ^
+	BITAND     // &
+	SHIFT      // << >>
+	SUM        // + -
+	PRODUCT    // * @ / // %
+	PREFIX     // +x -x ~x
+	POWER      // **
+	AWAIT      // await x
+	CALL       // x.attr x[index] x(arguments)
+)
 
-func (p *Parser) parseExpression() (Expression, error) {
-	return p.parsePrefix()
+var precedenceTable = map[sasttoken.TokenType]int{
+	sasttoken.WALRUS:    NAMED,
+	sasttoken.IF:        TERNARY,
+	sasttoken.OR:        OR,
+	sasttoken.AND:       AND,
+	sasttoken.IN:        COMPARISON,
+	sasttoken.IS:        COMPARISON,
+	sasttoken.LT:        COMPARISON,
+	sasttoken.LE:        COMPARISON,
+	sasttoken.GT:        COMPARISON,
+	sasttoken.GE:        COMPARISON,
+	sasttoken.NE:        COMPARISON,
+	sasttoken.EQ:        COMPARISON,
+	sasttoken.PIPE:      BITOR,
+	sasttoken.CARET:     BITXOR,
+	sasttoken.AMPERSAND: BITAND,
+	sasttoken.LSHIFT:    SHIFT,
+	sasttoken.RSHIFT:    SHIFT,
+	sasttoken.PLUS:      SUM,
+	sasttoken.MINUS:     SUM,
+	sasttoken.ASTERISK:  PRODUCT,
+	sasttoken.AT:        PRODUCT,
+	sasttoken.SLASH:     PRODUCT,
+	sasttoken.FLOORDIV:  PRODUCT,
+	sasttoken.MOD:       PRODUCT,
+	sasttoken.POWER:     POWER,
+	sasttoken.DOT:       CALL,
+	sasttoken.LBRACKET:  CALL,
+	sasttoken.LPAREN:    CALL,
 }
 
-func (p *Parser) parsePrefix() (Expression, error) {
-	switch p.curToken.Type {
-	case sasttoken.INT:
-		return p.parseIntegerLiteral()
-	case sasttoken.FLOAT:
-		return p.parseFloatLiteral()
-	case sasttoken.IDENT:
-		return p.parseIdentifier()
-	case sasttoken.STRING:
-		return p.parseStringLiteral()
-	case sasttoken.TRUE, sasttoken.FALSE:
-		return p.parseBooleanLiteral()
-	case sasttoken.NONE:
-		return p.parseNoneLiteral()
-	case sasttoken.LAMBDA:
-		return p.parseLambdaExpression()
-	case sasttoken.LPAREN:
-		return p.parseGroupedExpression()
-	case sasttoken.LBRACKET:
-		return p.parseListLiteral()
-	case sasttoken.PLUS, sasttoken.MINUS, sasttoken.BANG:
-		return p.parsePrefixExpression()
-	default:
-		return nil, fmt.Errorf("unexpected token: %s", p.curToken)
-	}
-}
-
-// ... other functions such as parseIntegerLiteral, parseFloatLiteral, etc.
+// parseExpression parses an expression whose operators bind tighter than
+// precedence. It starts at the expression's first token and leaves the
+// parser on the token following it.
+func (p *Parser) parseExpression(precedence int) (Expression, error) {
+	prefix := p.prefixParseFns[p.curToken.Type]
+	if prefix == nil {
+		return nil, p.expectedError("expression")
+	}
+	left, err := prefix()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parseInfixExpressions(left, precedence)
+}
+
+// parseInfixExpressions continues an expression whose leftmost operand has
+// already been parsed, applying the operators that bind tighter than
+// precedence.
+func (p *Parser) parseInfixExpressions(left Expression, precedence int) (Expression, error) {
+	for precedence < p.curPrecedence() {
+		infix := p.infixParseFns[p.curToken.Type]
+		if infix == nil {
+			return left, nil
+		}
+		var err error
+		left, err = infix(left)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return left, nil
+}
+
+// parseExpressionList parses an expression, or several separated by commas
+// that form a tuple without parentheses, as on both sides of "a, b = b, a".
+// A trailing comma is allowed.
+func (p *Parser) parseExpressionList(precedence int) (Expression, error) {
+	start := p.curToken
+	first, err := p.parseExpression(precedence)
+	if err != nil || !p.curTokenIs(sasttoken.COMMA) {
+		return first, err
+	}
+
+	tuple := &TupleLiteral{Token: start, Elements: []Expression{first}}
+	for p.curTokenIs(sasttoken.COMMA) {
+		p.nextToken()
+		if !p.canStartExpression() {
+			break
+		}
+		element, err := p.parseExpression(precedence)
+		if err != nil {
+			return nil, err
+		}
+		tuple.Elements = append(tuple.Elements, element)
+	}
+
+	return tuple, nil
+}
+
+// canStartExpression reports whether the current token can begin an
+// expression.
+func (p *Parser) canStartExpression() bool {
+	_, ok := p.prefixParseFns[p.curToken.Type]
+	return ok
+}
 
 func (p *Parser) parseInfixExpression(left Expression) (Expression, error) {
 	infixExp := &InfixExpression{
@@ -51,6 +138,10 @@ func (p *Parser) parseInfixExpression(left Expression) (Expression, error) {
 	}
 
 	precedence := p.curPrecedence()
+	if infixExp.Token.Type == sasttoken.POWER {
+		// ** is right-associative.
+		precedence--
+	}
 	p.nextToken()
 
 	right, err := p.parseExpression(precedence)
@@ -63,136 +154,894 @@ func (p *Parser) parseInfixExpression(left Expression) (Expression, error) {
 	return infixExp, nil
 }
 
+// parseGroupedExpression parses a parenthesized expression, tuple or
+// generator expression.
 func (p *Parser) parseGroupedExpression() (Expression, error) {
+	lparen := p.curToken
 	p.nextToken()
-	exp, err := p.parseExpression(LOWEST)
+
+	if p.curTokenIs(sasttoken.RPAREN) {
+		tuple := &TupleLiteral{Token: lparen, Rparen: p.curToken}
+		p.nextToken()
+		return tuple, nil
+	}
+
+	first, err := p.parseExpression(LOWEST)
 	if err != nil {
 		return nil, err
 	}
 
-	if !p.expectPeek(sasttoken.RPAREN) {
-		return nil, fmt.Errorf("expected right parenthesis, got %s", p.peekToken.Type)
+	if p.atComprehensionFor() {
+		gen := &GeneratorExpression{Token: lparen, Element: first}
+		if gen.Generators, err = p.parseForClauses(); err != nil {
+			return nil, err
+		}
+		if gen.Rparen, err = p.expect(sasttoken.RPAREN); err != nil {
+			return nil, err
+		}
+		return gen, nil
+	}
+
+	if !p.curTokenIs(sasttoken.COMMA) {
+		if _, err := p.expect(sasttoken.RPAREN); err != nil {
+			return nil, err
+		}
+		return first, nil
+	}
+
+	tuple := &TupleLiteral{Token: lparen}
+	if tuple.Elements, err = p.parseElements(first, sasttoken.RPAREN); err != nil {
+		return nil, err
+	}
+	if tuple.Rparen, err = p.expect(sasttoken.RPAREN); err != nil {
+		return nil, err
 	}
 
-	return exp, nil
+	return tuple, nil
 }
 
+// parseListLiteral parses a list display or list comprehension.
 func (p *Parser) parseListLiteral() (Expression, error) {
-	// Consume the opening bracket.
+	lbracket := p.curToken
 	p.nextToken()
 
-	// Parse the list elements.
-	elements, err := p.parseListElements()
+	if p.curTokenIs(sasttoken.RBRACKET) {
+		list := &ListLiteral{Token: lbracket, Elements: []Expression{}, Rbracket: p.curToken}
+		p.nextToken()
+		return list, nil
+	}
+
+	first, err := p.parseExpression(LOWEST)
 	if err != nil {
 		return nil, err
 	}
 
-	// Consume the closing bracket.
-	if !p.expectPeek(sasttoken.RBRACKET) {
-		return nil, fmt.Errorf("missing closing bracket")
+	if p.atComprehensionFor() {
+		comp := &ListComprehension{Token: lbracket, Element: first}
+		if comp.Generators, err = p.parseForClauses(); err != nil {
+			return nil, err
+		}
+		if comp.Rbracket, err = p.expect(sasttoken.RBRACKET); err != nil {
+			return nil, err
+		}
+		return comp, nil
+	}
+
+	list := &ListLiteral{Token: lbracket}
+	if list.Elements, err = p.parseElements(first, sasttoken.RBRACKET); err != nil {
+		return nil, err
+	}
+	if list.Rbracket, err = p.expect(sasttoken.RBRACKET); err != nil {
+		return nil, err
 	}
 
-	return &ListLiteral{Elements: elements}, nil
+	return list, nil
 }
 
-func (p *Parser) parseIntegerLiteral() (Expression, error) {
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+// parseBraceLiteral parses a dict or set display or comprehension.
+func (p *Parser) parseBraceLiteral() (Expression, error) {
+	lbrace := p.curToken
+	p.nextToken()
+
+	if p.curTokenIs(sasttoken.RBRACE) {
+		dict := &DictLiteral{Token: lbrace, Rbrace: p.curToken}
+		p.nextToken()
+		return dict, nil
+	}
+
+	if p.curTokenIs(sasttoken.POWER) {
+		return p.parseDictLiteral(lbrace, nil)
+	}
+
+	first, err := p.parseExpression(LOWEST)
 	if err != nil {
 		return nil, err
 	}
 
+	if p.curTokenIs(sasttoken.COLON) {
+		return p.parseDictLiteral(lbrace, first)
+	}
+
+	if p.atComprehensionFor() {
+		comp := &SetComprehension{Token: lbrace, Element: first}
+		if comp.Generators, err = p.parseForClauses(); err != nil {
+			return nil, err
+		}
+		if comp.Rbrace, err = p.expect(sasttoken.RBRACE); err != nil {
+			return nil, err
+		}
+		return comp, nil
+	}
+
+	set := &SetLiteral{Token: lbrace}
+	if set.Elements, err = p.parseElements(first, sasttoken.RBRACE); err != nil {
+		return nil, err
+	}
+	if set.Rbrace, err = p.expect(sasttoken.RBRACE); err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// parseDictLiteral parses a dict display or comprehension. It starts at the
+// ':' after the first key, or at the '**' of a first entry that unpacks a
+// mapping, in which case key is nil.
+func (p *Parser) parseDictLiteral(lbrace sasttoken.Token, key Expression) (Expression, error) {
+	dict := &DictLiteral{Token: lbrace}
+
+	for {
+		p.nextToken() // Consume the ':' or '**'.
+		value, err := p.parseExpression(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(dict.Keys) == 0 && key != nil && p.atComprehensionFor() {
+			comp := &DictComprehension{Token: lbrace, Key: key, Value: value}
+			if comp.Generators, err = p.parseForClauses(); err != nil {
+				return nil, err
+			}
+			if comp.Rbrace, err = p.expect(sasttoken.RBRACE); err != nil {
+				return nil, err
+			}
+			return comp, nil
+		}
+
+		dict.Keys = append(dict.Keys, key)
+		dict.Values = append(dict.Values, value)
+
+		if !p.curTokenIs(sasttoken.COMMA) {
+			break
+		}
+		p.nextToken()
+		if p.curTokenIs(sasttoken.RBRACE) {
+			break
+		}
+
+		key = nil
+		if !p.curTokenIs(sasttoken.POWER) {
+			if key, err = p.parseExpression(LOWEST); err != nil {
+				return nil, err
+			}
+			if !p.curTokenIs(sasttoken.COLON) {
+				return nil, p.expectedError("':'")
+			}
+		}
+	}
+
+	rbrace, err := p.expect(sasttoken.RBRACE)
+	if err != nil {
+		return nil, err
+	}
+	dict.Rbrace = rbrace
+
+	return dict, nil
+}
+
+// parseElements parses the comma-separated elements of a display after the
+// first, up to the closing token, which it leaves alone. A trailing comma
+// is allowed.
+func (p *Parser) parseElements(first Expression, end sasttoken.TokenType) ([]Expression, error) {
+	elements := []Expression{first}
+
+	for p.curTokenIs(sasttoken.COMMA) {
+		p.nextToken() // Consume the comma.
+		if p.curTokenIs(end) {
+			break // A trailing comma.
+		}
+
+		element, err := p.parseExpression(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+
+	return elements, nil
+}
+
+// atComprehensionFor reports whether the current token starts the "for" or
+// "async for" clause of a comprehension.
+func (p *Parser) atComprehensionFor() bool {
+	return p.curTokenIs(sasttoken.FOR) || p.curTokenIs(sasttoken.ASYNC) && p.peekTokenIs(sasttoken.FOR)
+}
+
+// parseForClauses parses the "for" clauses of a comprehension and the "if"
+// conditions following each.
+func (p *Parser) parseForClauses() ([]*ForClause, error) {
+	var clauses []*ForClause
+
+	for p.atComprehensionFor() {
+		clause := &ForClause{}
+		if p.curTokenIs(sasttoken.ASYNC) {
+			clause.Async = p.curToken
+			p.nextToken()
+		}
+		clause.Token = p.curToken
+		p.nextToken()
+
+		target, err := p.parseExpressionList(COMPARISON)
+		if err != nil {
+			return nil, err
+		}
+		clause.Target = target
+
+		if _, err := p.expect(sasttoken.IN); err != nil {
+			return nil, err
+		}
+		// The iterable and conditions stop short of conditional
+		// expressions, whose "if" would be ambiguous.
+		iter, err := p.parseExpression(TERNARY)
+		if err != nil {
+			return nil, err
+		}
+		clause.Iter = iter
+
+		for p.curTokenIs(sasttoken.IF) {
+			p.nextToken()
+			cond, err := p.parseExpression(TERNARY)
+			if err != nil {
+				return nil, err
+			}
+			clause.Ifs = append(clause.Ifs, cond)
+		}
+
+		clauses = append(clauses, clause)
+	}
+
+	return clauses, nil
+}
+
+func (p *Parser) parseIntegerLiteral() (Expression, error) {
+	lit := &IntegerLiteral{Token: p.curToken}
+	// A Python 2 long literal ends in "L" or "l".
+	digits := strings.TrimRight(strings.ReplaceAll(p.curToken.Literal, "_", ""), "Ll")
+
+	value, err := strconv.ParseInt(digits, 0, 64)
+	if err != nil {
+		// Python integers are unbounded.
+		n, ok := new(big.Int).SetString(digits, 0)
+		if !ok {
+			return nil, fmt.Errorf("could not parse %q as integer", p.curToken.Literal)
+		}
+		lit.Big = n
+	}
+	lit.Value = value
+
 	p.nextToken()
 
-	return &IntegerLiteral{Value: value}, nil
+	return lit, nil
 }
 
 func (p *Parser) parseFloatLiteral() (Expression, error) {
-	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	value, err := parseFloat(p.curToken.Literal)
+	if err != nil {
+		return nil, err
+	}
+	lit := &FloatLiteral{Token: p.curToken, Value: value}
+
+	p.nextToken()
+
+	return lit, nil
+}
+
+func (p *Parser) parseImaginaryLiteral() (Expression, error) {
+	literal := p.curToken.Literal
+	value, err := parseFloat(literal[:len(literal)-1])
 	if err != nil {
 		return nil, err
 	}
+	lit := &ImaginaryLiteral{Token: p.curToken, Value: value}
 
 	p.nextToken()
 
-	return &FloatLiteral{Value: value}, nil
+	return lit, nil
+}
+
+// parseFloat parses a Python float literal. Values out of range become
+// infinite, as in Python, instead of failing.
+func parseFloat(literal string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(literal, "_", ""), 64)
+	if err != nil {
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+			return value, nil
+		}
+		return 0, fmt.Errorf("could not parse %q as float", literal)
+	}
+	return value, nil
 }
 
 func (p *Parser) parseIdentifier() (Expression, error) {
-	ident := &Identifier{Value: p.curToken.Literal}
+	ident := &Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	p.nextToken()
 	return ident, nil
 }
 
+// parseStringLiteral parses a string, or adjacent strings such as
+// "a" "b", which are concatenated into one StringLiteral. When any of them
+// is an f-string the result is a single JoinedStr.
 func (p *Parser) parseStringLiteral() (Expression, error) {
-	str := &StringLiteral{Value: p.curToken.Literal}
-	p.nextToken()
-	return str, nil
+	first := p.curToken
+	firstPrefix, _ := splitStringLiteral(first.Literal)
+	var parts []Expression
+	var rest []sasttoken.Token
+	fstring := false
+	for p.curTokenIs(sasttoken.STRING) {
+		tok := p.curToken
+		prefix, _ := splitStringLiteral(tok.Literal)
+		// Python 2 strings are bytes whatever their prefix.
+		if p.version != sasttoken.Python2 && strings.Contains(prefix, "b") != strings.Contains(firstPrefix, "b") {
+			return nil, p.errorf("cannot mix bytes and nonbytes literals")
+		}
+		if len(parts) > 0 {
+			rest = append(rest, tok)
+		}
+		if strings.Contains(prefix, "f") {
+			str, err := p.parseFString()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, str)
+			fstring = true
+			continue
+		}
+		parts = append(parts, &StringLiteral{Token: tok, Value: tok.Value})
+		p.nextToken()
+	}
+
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	if !fstring {
+		var value strings.Builder
+		for _, part := range parts {
+			value.WriteString(part.(*StringLiteral).Value)
+		}
+		return &StringLiteral{Token: first, Value: value.String(), Rest: rest}, nil
+	}
+
+	// Adjacent literal text is merged, as in the JoinedStr of CPython.
+	joined := &JoinedStr{Token: first, Rest: rest}
+	appendText := func(text *StringLiteral) {
+		if text.Value == "" {
+			return
+		}
+		if n := len(joined.Values); n > 0 {
+			if prev, ok := joined.Values[n-1].(*StringLiteral); ok {
+				// The merged text spans both, and has no spelling of its own.
+				tok := prev.Token
+				tok.Literal = ""
+				end := text.End()
+				tok.EndLine, tok.EndColumn, tok.EndOffset = end.Line, end.Column, end.Offset
+				joined.Values[n-1] = &StringLiteral{Token: tok, Value: prev.Value + text.Value}
+				return
+			}
+		}
+		joined.Values = append(joined.Values, text)
+	}
+	for _, part := range parts {
+		switch part := part.(type) {
+		case *StringLiteral:
+			appendText(part)
+		case *JoinedStr:
+			for _, value := range part.Values {
+				if text, ok := value.(*StringLiteral); ok {
+					appendText(text)
+				} else {
+					joined.Values = append(joined.Values, value)
+				}
+			}
+		}
+	}
+	return joined, nil
 }
 
 func (p *Parser) parseBooleanLiteral() (Expression, error) {
-	value := p.curToken.Type == sasttoken.TRUE
+	lit := &BooleanLiteral{Token: p.curToken, Value: p.curToken.Type == sasttoken.TRUE}
 	p.nextToken()
-	return &BooleanLiteral{Value: value}, nil
+	return lit, nil
 }
 
 func (p *Parser) parseNoneLiteral() (Expression, error) {
+	lit := &NoneLiteral{Token: p.curToken}
+	p.nextToken()
+	return lit, nil
+}
+
+func (p *Parser) parseEllipsis() (Expression, error) {
+	lit := &Ellipsis{Token: p.curToken}
 	p.nextToken()
-	return &NoneLiteral{}, nil
+	return lit, nil
+}
+
+// parseYieldExpression parses "yield", "yield value" or "yield from value".
+func (p *Parser) parseYieldExpression() (Expression, error) {
+	expr := &YieldExpression{Token: p.curToken}
+	p.nextToken()
+
+	if p.curTokenIs(sasttoken.FROM) {
+		expr.From = true
+		p.nextToken()
+		value, err := p.parseExpression(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		expr.Value = value
+		return expr, nil
+	}
+
+	if !p.canStartExpression() {
+		return expr, nil
+	}
+	value, err := p.parseExpressionList(LOWEST)
+	if err != nil {
+		return nil, err
+	}
+	expr.Value = value
+
+	return expr, nil
 }
 
 func (p *Parser) parseLambdaExpression() (Expression, error) {
-	// TODO: Implement lambda expression parsing
-	return nil, fmt.Errorf("lambda expression parsing not implemented")
+	lambda := &LambdaExpression{Token: p.curToken}
+	p.nextToken()
+
+	params, err := p.parseParameters(sasttoken.COLON, false)
+	if err != nil {
+		return nil, err
+	}
+	lambda.Parameters = params
+
+	if _, err := p.expect(sasttoken.COLON); err != nil {
+		return nil, err
+	}
+	body, err := p.parseExpression(NAMED)
+	if err != nil {
+		return nil, err
+	}
+	lambda.Body = body
+
+	return lambda, nil
+}
+
+// parseParameters parses a parameter list up to the end token, which it
+// leaves alone. Annotations are only allowed in function definitions.
+func (p *Parser) parseParameters(end sasttoken.TokenType, annotations bool) ([]*Parameter, error) {
+	var params []*Parameter
+	kind := PositionalOrKeyword
+	seenSlash := false
+
+	for !p.curTokenIs(end) {
+		param := &Parameter{Token: p.curToken, Kind: kind}
+
+		switch p.curToken.Type {
+		case sasttoken.SLASH:
+			if seenSlash || kind != PositionalOrKeyword || len(params) == 0 {
+				return nil, p.errorf("'/' must follow positional parameters")
+			}
+			seenSlash = true
+			for _, prev := range params {
+				prev.Kind = PositionalOnly
+			}
+			p.nextToken()
+			param = nil
+		case sasttoken.ASTERISK:
+			if kind != PositionalOrKeyword {
+				return nil, p.errorf("'*' may appear only once")
+			}
+			kind = KeywordOnly
+			p.nextToken()
+			if p.curTokenIs(sasttoken.COMMA) {
+				// A bare "*" only separates keyword-only parameters.
+				param = nil
+				break
+			}
+			param.Kind = VarPositional
+		case sasttoken.POWER:
+			param.Kind = VarKeyword
+			p.nextToken()
+		}
+
+		if param != nil {
+			if err := p.parseParameter(param, annotations); err != nil {
+				return nil, err
+			}
+			params = append(params, param)
+		}
+
+		if !p.curTokenIs(sasttoken.COMMA) {
+			break
+		}
+		p.nextToken()
+		if param != nil && param.Kind == VarKeyword && !p.curTokenIs(end) {
+			return nil, p.errorf("parameters cannot follow '**%s'", param.Name)
+		}
+	}
+
+	return params, nil
 }
 
-func (p *Parser) parseListElements() ([]Expression, error) {
-	elements := []Expression{}
+// parseParameter parses the name, annotation and default of param.
+func (p *Parser) parseParameter(param *Parameter, annotations bool) error {
+	if !p.curTokenIs(sasttoken.IDENT) {
+		return p.expectedError("parameter name")
+	}
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return err
+	}
+	param.Name = name.(*Identifier)
 
-	// If the next token is a closing bracket, the list is empty.
-	if p.peekToken.Type == sasttoken.RBRACKET {
-		return elements, nil
+	if annotations && p.curTokenIs(sasttoken.COLON) {
+		p.nextToken()
+		if param.Annotation, err = p.parseExpression(LOWEST); err != nil {
+			return err
+		}
+	}
+
+	if p.curTokenIs(sasttoken.ASSIGN) {
+		if param.Kind == VarPositional || param.Kind == VarKeyword {
+			return p.errorf("'%s%s' cannot have a default value", param.Token.Literal, param.Name)
+		}
+		p.nextToken()
+		if param.Default, err = p.parseExpression(LOWEST); err != nil {
+			return err
+		}
 	}
 
-	// Parse the first element.
-	element, err := p.parseExpression()
+	return nil
+}
+
+// parseStarredExpression parses "*value" in a display or assignment
+// target.
+func (p *Parser) parseStarredExpression() (Expression, error) {
+	starred := &StarredExpression{Token: p.curToken}
+	p.nextToken()
+
+	value, err := p.parseExpression(COMPARISON)
 	if err != nil {
 		return nil, err
 	}
-	elements = append(elements, element)
+	starred.Value = value
 
-	// Continue parsing the rest of the elements.
-	for p.peekToken.Type == sasttoken.COMMA {
-		p.nextToken() // Consume the comma.
-		p.nextToken() // Move to the next element.
+	return starred, nil
+}
 
-		element, err := p.parseExpression()
+func (p *Parser) parsePrefixExpression() (Expression, error) {
+	prefix := &PrefixExpression{Token: p.curToken, Operator: p.curToken.Literal}
+	precedence := PREFIX
+	if prefix.Token.Type == sasttoken.NOT {
+		precedence = NOT
+	}
+	p.nextToken()
+	right, err := p.parseExpression(precedence)
+	if err != nil {
+		return nil, err
+	}
+	prefix.Right = right
+	return prefix, nil
+}
+
+// parseComparisonExpression parses a comparison and any comparisons
+// chained to it.
+func (p *Parser) parseComparisonExpression(left Expression) (Expression, error) {
+	comparison := &ComparisonExpression{Token: p.curToken, Left: left}
+
+	for p.curPrecedence() == COMPARISON {
+		operator := p.curToken.Literal
+		switch {
+		case p.curTokenIs(sasttoken.NOT):
+			p.nextToken()
+			operator = "not in"
+		case p.curTokenIs(sasttoken.IS) && p.peekTokenIs(sasttoken.NOT):
+			p.nextToken()
+			operator = "is not"
+		case p.curTokenIs(sasttoken.NE):
+			// Python 2 also spells it "<>".
+			operator = sasttoken.NE
+		}
+		p.nextToken()
+
+		right, err := p.parseExpression(COMPARISON)
 		if err != nil {
 			return nil, err
 		}
-		elements = append(elements, element)
+		comparison.Operators = append(comparison.Operators, operator)
+		comparison.Comparators = append(comparison.Comparators, right)
 	}
 
-	return elements, nil
+	return comparison, nil
 }
 
-func (p *Parser) expectPeek(t sasttoken.TokenType) bool {
-	if p.peekToken.Type == t {
+// parseIfExpression parses "if condition else alternative" after the
+// consequence of a conditional expression.
+func (p *Parser) parseIfExpression(consequence Expression) (Expression, error) {
+	expr := &IfExpression{Token: p.curToken, Consequence: consequence}
+	p.nextToken()
+
+	condition, err := p.parseExpression(TERNARY)
+	if err != nil {
+		return nil, err
+	}
+	expr.Condition = condition
+
+	if _, err := p.expect(sasttoken.ELSE); err != nil {
+		return nil, err
+	}
+	alternative, err := p.parseExpression(NAMED)
+	if err != nil {
+		return nil, err
+	}
+	expr.Alternative = alternative
+
+	return expr, nil
+}
+
+func (p *Parser) parseNamedExpression(target Expression) (Expression, error) {
+	ident, ok := target.(*Identifier)
+	if !ok {
+		return nil, p.errorf("cannot use assignment expression with %s", target)
+	}
+	expr := &NamedExpression{Token: p.curToken, Target: ident}
+	p.nextToken()
+
+	value, err := p.parseExpression(NAMED)
+	if err != nil {
+		return nil, err
+	}
+	expr.Value = value
+
+	return expr, nil
+}
+
+func (p *Parser) parseAwaitExpression() (Expression, error) {
+	expr := &AwaitExpression{Token: p.curToken}
+	p.nextToken()
+
+	value, err := p.parseExpression(AWAIT)
+	if err != nil {
+		return nil, err
+	}
+	expr.Value = value
+
+	return expr, nil
+}
+
+// parseReprExpression parses a Python 2 backtick expression, `x`, into the
+// equivalent call repr(x).
+func (p *Parser) parseReprExpression() (Expression, error) {
+	call := &CallExpression{Token: p.curToken, Function: &Identifier{Token: p.curToken, Value: "repr"}}
+	p.nextToken()
+
+	value, err := p.parseExpressionList(NAMED)
+	if err != nil {
+		return nil, err
+	}
+	call.Arguments = []Expression{value}
+
+	if call.Rparen, err = p.expect(sasttoken.BACKTICK); err != nil {
+		return nil, err
+	}
+
+	return call, nil
+}
+
+func (p *Parser) parseAttributeExpression(object Expression) (Expression, error) {
+	expr := &AttributeExpression{Token: p.curToken, Object: object}
+	p.nextToken()
+
+	if !p.curTokenIs(sasttoken.IDENT) {
+		return nil, p.expectedError("attribute name")
+	}
+	attribute, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	expr.Attribute = attribute.(*Identifier)
+
+	return expr, nil
+}
+
+func (p *Parser) parseCallExpression(function Expression) (Expression, error) {
+	call := &CallExpression{Token: p.curToken, Function: function}
+
+	args, keywords, rparen, err := p.parseArguments()
+	if err != nil {
+		return nil, err
+	}
+	call.Arguments = args
+	call.Keywords = keywords
+	call.Rparen = rparen
+
+	return call, nil
+}
+
+// parseArguments parses the parenthesized argument list of a call or class
+// definition, from the '(' through the ')'.
+func (p *Parser) parseArguments() ([]Expression, []*KeywordArgument, sasttoken.Token, error) {
+	var args []Expression
+	var keywords []*KeywordArgument
+	lparen := p.curToken
+	p.nextToken()
+
+	for !p.curTokenIs(sasttoken.RPAREN) {
+		switch {
+		case p.curTokenIs(sasttoken.ASTERISK):
+			if hasUnpacking(keywords) {
+				return nil, nil, lparen, p.errorf("iterable argument unpacking follows keyword argument unpacking")
+			}
+			starred := &StarredExpression{Token: p.curToken}
+			p.nextToken()
+			value, err := p.parseExpression(LOWEST)
+			if err != nil {
+				return nil, nil, lparen, err
+			}
+			starred.Value = value
+			args = append(args, starred)
+		case p.curTokenIs(sasttoken.POWER):
+			keyword := &KeywordArgument{Token: p.curToken}
+			p.nextToken()
+			value, err := p.parseExpression(LOWEST)
+			if err != nil {
+				return nil, nil, lparen, err
+			}
+			keyword.Value = value
+			keywords = append(keywords, keyword)
+		case p.curTokenIs(sasttoken.IDENT) && p.peekTokenIs(sasttoken.ASSIGN):
+			keyword := &KeywordArgument{Token: p.curToken}
+			name, err := p.parseIdentifier()
+			if err != nil {
+				return nil, nil, lparen, err
+			}
+			keyword.Name = name.(*Identifier)
+			p.nextToken()
+			value, err := p.parseExpression(LOWEST)
+			if err != nil {
+				return nil, nil, lparen, err
+			}
+			keyword.Value = value
+			keywords = append(keywords, keyword)
+		default:
+			// Positional arguments come before keyword arguments, as they
+			// do in CPython.
+			if hasUnpacking(keywords) {
+				return nil, nil, lparen, p.errorf("positional argument follows keyword argument unpacking")
+			} else if len(keywords) > 0 {
+				return nil, nil, lparen, p.errorf("positional argument follows keyword argument")
+			}
+			arg, err := p.parseExpression(LOWEST)
+			if err != nil {
+				return nil, nil, lparen, err
+			}
+			if p.atComprehensionFor() && len(args) == 0 && len(keywords) == 0 {
+				// A generator expression as the sole argument needs no
+				// parentheses of its own.
+				gen := &GeneratorExpression{Token: lparen, Element: arg}
+				if gen.Generators, err = p.parseForClauses(); err != nil {
+					return nil, nil, lparen, err
+				}
+				if gen.Rparen, err = p.expect(sasttoken.RPAREN); err != nil {
+					return nil, nil, lparen, err
+				}
+				return []Expression{gen}, nil, gen.Rparen, nil
+			}
+			args = append(args, arg)
+		}
+
+		if !p.curTokenIs(sasttoken.COMMA) {
+			break
+		}
 		p.nextToken()
-		return true
-	} else {
-		return false
 	}
+
+	rparen, err := p.expect(sasttoken.RPAREN)
+	if err != nil {
+		return nil, nil, rparen, err
+	}
+
+	return args, keywords, rparen, nil
+}
+
+// hasUnpacking reports whether keywords include a "**" argument.
+func hasUnpacking(keywords []*KeywordArgument) bool {
+	for _, keyword := range keywords {
+		if keyword.Name == nil {
+			return true
+		}
+	}
+	return false
 }
 
-func (p *Parser) parsePrefixExpression() (*Expression, error) {
-	operator := p.curToken.Literal
+func (p *Parser) parseSubscriptExpression(object Expression) (Expression, error) {
+	expr := &SubscriptExpression{Token: p.curToken, Object: object}
 	p.nextToken()
-	right, err := p.parseExpression(PREFIX)
+
+	start := p.curToken
+	index, err := p.parseSliceItem()
+	if err != nil {
+		return nil, err
+	}
+	if p.curTokenIs(sasttoken.COMMA) {
+		tuple := &TupleLiteral{Token: start, Elements: []Expression{index}}
+		for p.curTokenIs(sasttoken.COMMA) {
+			p.nextToken()
+			if p.curTokenIs(sasttoken.RBRACKET) {
+				break
+			}
+			item, err := p.parseSliceItem()
+			if err != nil {
+				return nil, err
+			}
+			tuple.Elements = append(tuple.Elements, item)
+		}
+		index = tuple
+	}
+	expr.Index = index
+
+	rbracket, err := p.expect(sasttoken.RBRACKET)
 	if err != nil {
 		return nil, err
 	}
+	expr.Rbracket = rbracket
 
-	prefix := &PrefixExpression{Operator: operator, Right: right}
-	node := Expression(prefix)
-	return &node, nil
+	return expr, nil
+}
+
+// parseSliceItem parses one index of a subscript, which may be a slice.
+func (p *Parser) parseSliceItem() (Expression, error) {
+	var lower Expression
+	if !p.curTokenIs(sasttoken.COLON) {
+		index, err := p.parseExpression(LOWEST)
+		if err != nil || !p.curTokenIs(sasttoken.COLON) {
+			return index, err
+		}
+		lower = index
+	}
+
+	slice := &SliceExpression{Token: p.curToken, Lower: lower}
+	p.nextToken()
+
+	var err error
+	if slice.Upper, err = p.parseSliceBound(); err != nil {
+		return nil, err
+	}
+	if p.curTokenIs(sasttoken.COLON) {
+		slice.StepColon = p.curToken
+		p.nextToken()
+		if slice.Step, err = p.parseSliceBound(); err != nil {
+			return nil, err
+		}
+	}
+
+	return slice, nil
+}
+
+// parseSliceBound parses the optional upper bound or step of a slice.
+func (p *Parser) parseSliceBound() (Expression, error) {
+	switch p.curToken.Type {
+	case sasttoken.COLON, sasttoken.COMMA, sasttoken.RBRACKET:
+		return nil, nil
+	}
+	return p.parseExpression(LOWEST)
 }
@@ -0,0 +1,356 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/coiloffaraday/python_sast/lexer"
+	sasttoken "github.com/coiloffaraday/python_sast/token"
+)
+
+// splitStringLiteral splits the source spelling of a string token into its
+// lower-cased prefix and the body between the quotes.
+func splitStringLiteral(literal string) (prefix string, body string) {
+	i := strings.IndexAny(literal, `'"`)
+	if i < 0 {
+		return "", literal
+	}
+	prefix = strings.ToLower(literal[:i])
+	quoted := literal[i:]
+	quoteLen := 1
+	if len(quoted) >= 6 && (strings.HasPrefix(quoted, `'''`) || strings.HasPrefix(quoted, `"""`)) {
+		quoteLen = 3
+	}
+	return prefix, quoted[quoteLen : len(quoted)-quoteLen]
+}
+
+// parseFString turns the current f-string token into a JoinedStr whose
+// replacement fields are parsed as ordinary expressions.
+func (p *Parser) parseFString() (Expression, error) {
+	tok := p.curToken
+	prefix, body := splitStringLiteral(tok.Literal)
+	quoteLen := (len(tok.Literal) - len(body) - len(prefix)) / 2
+	opening := len(prefix) + quoteLen
+	fp := &fstringParser{
+		parser: p,
+		token:  tok,
+		src:    body,
+		prefix: strings.Replace(prefix, "f", "", 1),
+		base:   tok.Offset + opening,
+		line:   tok.Line,
+		column: tok.Column + opening,
+	}
+
+	str, err := fp.parseParts(false)
+	if err != nil {
+		return nil, err
+	}
+	if fp.pos < len(fp.src) {
+		return nil, fp.errorf("single '}' is not allowed")
+	}
+
+	p.nextToken()
+
+	return str, nil
+}
+
+type fstringParser struct {
+	parser *Parser
+	token  sasttoken.Token
+	src    string
+	pos    int
+	prefix string // string prefix without the 'f', used to decode literal text
+	base   int    // file offset of src
+	line   int    // line of the current position
+	column int    // column of the current position
+}
+
+// parseParts parses literal text and replacement fields up to the end of the
+// input or, inside a format spec, up to the closing '}' of the field. A
+// format spec's JoinedStr spans the spec's source; the whole f-string's
+// is given its token by the caller.
+func (fp *fstringParser) parseParts(inSpec bool) (*JoinedStr, error) {
+	str := &JoinedStr{Token: fp.token}
+	start := fp.position()
+	var text strings.Builder
+	var textStart sasttoken.Position
+
+	flush := func() error {
+		if text.Len() == 0 {
+			return nil
+		}
+		value, err := lexer.DecodeString(text.String(), fp.prefix)
+		if err != nil {
+			return fp.errorf("%v", err)
+		}
+		str.Values = append(str.Values, &StringLiteral{Token: fp.span(textStart, ""), Value: value})
+		text.Reset()
+		return nil
+	}
+	done := func() (*JoinedStr, error) {
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		if inSpec {
+			str.Token = fp.span(start, fp.src[start.Offset-fp.base:fp.pos])
+		}
+		return str, nil
+	}
+
+	for fp.pos < len(fp.src) {
+		if text.Len() == 0 {
+			textStart = fp.position()
+		}
+		ch := fp.src[fp.pos]
+		switch {
+		case ch == '{' && !inSpec && fp.peek(1) == '{':
+			text.WriteByte('{')
+			fp.advance(2)
+		case ch == '}' && !inSpec && fp.peek(1) == '}':
+			text.WriteByte('}')
+			fp.advance(2)
+		case ch == '}':
+			return done()
+		case ch == '{':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			value, err := fp.parseReplacementField()
+			if err != nil {
+				return nil, err
+			}
+			str.Values = append(str.Values, value...)
+		case ch == '\\' && !strings.Contains(fp.prefix, "r") && fp.peek(1) == 'N' && fp.peek(2) == '{':
+			// The braces of a named escape do not start a field.
+			end := strings.IndexByte(fp.src[fp.pos:], '}')
+			if end < 0 {
+				return nil, fp.errorf("malformed \\N character escape")
+			}
+			text.WriteString(fp.src[fp.pos : fp.pos+end+1])
+			fp.advance(end + 1)
+		case ch == '\\' && !strings.Contains(fp.prefix, "r") && fp.peek(1) == '\\':
+			text.WriteString(`\\`)
+			fp.advance(2)
+		default:
+			text.WriteByte(ch)
+			fp.advance(1)
+		}
+	}
+
+	return done()
+}
+
+// parseReplacementField parses "{expr[=][!conv][:spec]}" starting at the
+// opening brace. A self-documenting field ("{x=}") yields the literal text
+// "x=" in front of the formatted value, as CPython does.
+func (fp *fstringParser) parseReplacementField() ([]Expression, error) {
+	fieldStart := fp.position()
+	fp.advance(1)
+	start := fp.pos
+	startPos := fp.position()
+	end, err := fp.scanExpression()
+	if err != nil {
+		return nil, err
+	}
+	exprSrc := fp.src[start:end]
+	if strings.TrimSpace(exprSrc) == "" {
+		return nil, fp.errorf("valid expression required before '%c'", fp.peek(0))
+	}
+
+	value, err := fp.parseExpression(exprSrc, startPos)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []Expression
+	field := &FormattedValue{Value: value}
+
+	if fp.peek(0) == '=' {
+		fp.advance(1)
+		// Whitespace after '=' is part of the debug text.
+		for fp.peek(0) == ' ' {
+			fp.advance(1)
+		}
+		values = append(values, &StringLiteral{Token: fp.span(startPos, ""), Value: fp.src[start:fp.pos]})
+		if fp.peek(0) != '!' && fp.peek(0) != ':' {
+			field.Conversion = 'r'
+		}
+	}
+
+	if fp.peek(0) == '!' {
+		conv := fp.peek(1)
+		if conv != 's' && conv != 'r' && conv != 'a' {
+			return nil, fp.errorf("invalid conversion character %q: expected 's', 'r', or 'a'", string(conv))
+		}
+		field.Conversion = conv
+		fp.advance(2)
+	}
+
+	if fp.peek(0) == ':' {
+		fp.advance(1)
+		spec, err := fp.parseParts(true)
+		if err != nil {
+			return nil, err
+		}
+		field.FormatSpec = spec
+	}
+
+	if fp.peek(0) != '}' {
+		return nil, fp.errorf("expecting '}'")
+	}
+	fp.advance(1)
+	field.Token = fp.span(fieldStart, fp.src[fieldStart.Offset-fp.base:fp.pos])
+
+	return append(values, field), nil
+}
+
+// scanExpression advances over the expression of a replacement field,
+// skipping nested brackets and string literals, and returns its end offset.
+// It stops before a top-level '}', '!', ':' or '=' that ends the expression.
+func (fp *fstringParser) scanExpression() (int, error) {
+	depth := 0
+	for fp.pos < len(fp.src) {
+		ch := fp.src[fp.pos]
+		switch ch {
+		case '(', '[', '{':
+			depth++
+		case ')', ']':
+			depth--
+		case '}':
+			if depth == 0 {
+				return fp.pos, nil
+			}
+			depth--
+		case '\'', '"':
+			if err := fp.skipString(); err != nil {
+				return 0, err
+			}
+			continue
+		case '#':
+			return 0, fp.errorf("expression part cannot include '#'")
+		case '!':
+			if depth == 0 && fp.peek(1) != '=' {
+				return fp.pos, nil
+			}
+			if fp.peek(1) == '=' {
+				fp.advance(1)
+			}
+		case ':':
+			if depth == 0 {
+				return fp.pos, nil
+			}
+		case '=', '<', '>':
+			// Comparison operators are part of the expression; a lone '=' at
+			// the top level starts a self-documenting field.
+			if fp.peek(1) == '=' {
+				fp.advance(1)
+			} else if ch == '=' && depth == 0 {
+				return fp.pos, nil
+			}
+		}
+		fp.advance(1)
+	}
+	return 0, fp.errorf("expecting '}'")
+}
+
+// skipString advances over a string literal nested in an expression.
+func (fp *fstringParser) skipString() error {
+	quote := fp.src[fp.pos]
+	delim := string(quote)
+	if fp.peek(1) == quote && fp.peek(2) == quote {
+		delim = strings.Repeat(delim, 3)
+	}
+	fp.advance(len(delim))
+	for fp.pos < len(fp.src) {
+		if fp.src[fp.pos] == '\\' {
+			fp.advance(2)
+			continue
+		}
+		if strings.HasPrefix(fp.src[fp.pos:], delim) {
+			fp.advance(len(delim))
+			return nil
+		}
+		fp.advance(1)
+	}
+	return fp.errorf("unterminated string")
+}
+
+// parseExpression parses the source of a replacement field, which starts at
+// start, with a parser of its own. The source is parenthesized so that it may
+// span lines, as it can in a triple-quoted f-string, and so that "{a, b}" is
+// read as a tuple; the parenthesis is placed just before start.
+func (fp *fstringParser) parseExpression(src string, start sasttoken.Position) (Expression, error) {
+	start.Column--
+	start.Offset--
+	l := lexer.NewLexerAt("("+src+")", fp.token.FilePath, start)
+	sub := New(l)
+	expr, err := sub.parseExpression(LOWEST)
+	if err == nil && !sub.curTokenIs(sasttoken.NEWLINE) {
+		err = sub.expectedError("'}'")
+	}
+	if err == nil {
+		err = sub.Err()
+	}
+	if err != nil {
+		var d *Diagnostic
+		if errors.As(err, &d) {
+			f := *d
+			f.Message = "f-string: " + f.Message
+			return nil, &f
+		}
+		return nil, fp.errorf("%v", err)
+	}
+	return expr, nil
+}
+
+// errorf returns a diagnostic at the current position in the f-string.
+func (fp *fstringParser) errorf(format string, args ...interface{}) *Diagnostic {
+	return &Diagnostic{
+		Pos:      fp.position(),
+		FilePath: fp.token.FilePath,
+		Message:  "f-string: " + fmt.Sprintf(format, args...),
+		Found:    fp.token,
+	}
+}
+
+func (fp *fstringParser) peek(n int) byte {
+	if fp.pos+n < len(fp.src) {
+		return fp.src[fp.pos+n]
+	}
+	return 0
+}
+
+func (fp *fstringParser) advance(n int) {
+	for i := 0; i < n && fp.pos < len(fp.src); i++ {
+		c := fp.src[fp.pos]
+		if c == '\n' {
+			fp.line++
+			fp.column = 0
+		}
+		// Count characters, not UTF-8 continuation bytes.
+		if c&0xC0 != 0x80 {
+			fp.column++
+		}
+		fp.pos++
+	}
+}
+
+// span returns the f-string's token narrowed to the source from start to
+// the current position, with the given literal. The text parts of an
+// f-string have an empty literal, like other strings without a spelling of
+// their own.
+func (fp *fstringParser) span(start sasttoken.Position, literal string) sasttoken.Token {
+	tok := fp.token
+	tok.Literal = literal
+	tok.Comments = nil
+	end := fp.position()
+	tok.Line, tok.Column, tok.Offset = start.Line, start.Column, start.Offset
+	tok.EndLine, tok.EndColumn, tok.EndOffset = end.Line, end.Column, end.Offset
+	return tok
+}
+
+// position returns the file position of the current character.
+func (fp *fstringParser) position() sasttoken.Position {
+	return sasttoken.Position{Line: fp.line, Column: fp.column, Offset: fp.base + fp.pos}
+}
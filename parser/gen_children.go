@@ -0,0 +1,135 @@
+//go:build ignore
+
+// gen_children writes children.go, which gives every node type in package
+// parser a Children method listing its child nodes in field order. Node
+// types that define Children themselves are left alone.
+//
+// Run it with "go generate" after adding or changing a node type.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const output = "children.go"
+
+func main() {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		log.Fatal(err)
+	}
+	sort.Strings(files)
+
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	for _, name := range files {
+		if name == output || name == "gen_children.go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+		parsed = append(parsed, f)
+	}
+
+	// A node type is a struct type with its own TokenLiteral method.
+	methods := map[string]map[string]bool{}
+	for _, f := range parsed {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil {
+				continue
+			}
+			star, ok := fn.Recv.List[0].Type.(*ast.StarExpr)
+			if !ok {
+				continue
+			}
+			recv := star.X.(*ast.Ident).Name
+			if methods[recv] == nil {
+				methods[recv] = map[string]bool{}
+			}
+			methods[recv][fn.Name.Name] = true
+		}
+	}
+	isNode := func(name string) bool { return methods[name]["TokenLiteral"] }
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by gen_children.go; DO NOT EDIT.\n\npackage parser\n")
+
+	for _, f := range parsed {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				name := ts.Name.Name
+				if !ok || !isNode(name) || methods[name]["Children"] {
+					continue
+				}
+				writeChildren(&out, name, st, isNode)
+			}
+		}
+	}
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(output, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func writeChildren(out *bytes.Buffer, name string, st *ast.StructType, isNode func(string) bool) {
+	var body bytes.Buffer
+	for _, field := range st.Fields.List {
+		typ := field.Type
+		slice := false
+		if arr, ok := typ.(*ast.ArrayType); ok {
+			typ, slice = arr.Elt, true
+		}
+		if !isChildType(typ, isNode) {
+			continue
+		}
+		for _, ident := range field.Names {
+			if slice {
+				fmt.Fprintf(&body, "for _, c := range n.%s {\nif c != nil {\nnodes = append(nodes, c)\n}\n}\n", ident.Name)
+			} else {
+				fmt.Fprintf(&body, "if n.%s != nil {\nnodes = append(nodes, n.%[1]s)\n}\n", ident.Name)
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "\nfunc (n *%s) Children() []Node {\n", name)
+	if body.Len() == 0 {
+		fmt.Fprintf(out, "return nil\n}\n")
+		return
+	}
+	fmt.Fprintf(out, "var nodes []Node\n%sreturn nodes\n}\n", body.String())
+}
+
+// isChildType reports whether a field of type typ holds a child node.
+func isChildType(typ ast.Expr, isNode func(string) bool) bool {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		return t.Name == "Node" || t.Name == "Expression" || t.Name == "Statement" || t.Name == "Pattern"
+	case *ast.StarExpr:
+		ident, ok := t.X.(*ast.Ident)
+		return ok && isNode(ident.Name)
+	}
+	return false
+}
@@ -1,50 +1,107 @@
 package parser
 
 import (
-	"fmt"
-
 	"github.com/coiloffaraday/python_sast/lexer"
 	sasttoken "github.com/coiloffaraday/python_sast/token"
 )
 
 type Parser struct {
 	lexer          *lexer.Lexer
+	prevToken      sasttoken.Token // The last token consumed
 	curToken       sasttoken.Token
 	peekToken      sasttoken.Token
-	errors         []string
+	diagnostics    DiagnosticList
 	prefixParseFns map[sasttoken.TokenType]prefixParseFn
 	infixParseFns  map[sasttoken.TokenType]infixParseFn
+
+	version       sasttoken.Version
+	detectVersion bool // Parse again as Python 2 if the file fails as Python 3
+	printFunction bool // "from __future__ import print_function" was seen
 }
 
 type (
-	prefixParseFn func() (Node, error)
-	infixParseFn  func(Node) (Node, error)
+	prefixParseFn func() (Expression, error)
+	infixParseFn  func(Expression) (Expression, error)
 )
 
-func New(lexer *lexer.Lexer) *Parser {
+// An Option configures a Parser.
+type Option func(*Parser)
+
+// WithVersion selects the language version to parse. Python 2 code is
+// parsed into the nodes of the equivalent Python 3 code where there is one:
+// a print or exec statement becomes a call of the print or exec function
+// and a backtick expression a call of repr.
+func WithVersion(v sasttoken.Version) Option {
+	return func(p *Parser) {
+		p.version = v
+	}
+}
+
+// DetectVersion makes ParseProgram parse a file again as Python 2 when it
+// has errors as Python 3, and keep that result if it has none.
+func DetectVersion() Option {
+	return func(p *Parser) {
+		p.detectVersion = true
+	}
+}
+
+// New creates a parser reading tokens from lexer. The language version
+// defaults to that of the lexer.
+func New(lexer *lexer.Lexer, options ...Option) *Parser {
 	p := &Parser{
-		lexer:  lexer,
-		errors: []string{},
+		lexer:   lexer,
+		version: lexer.Version,
+	}
+	for _, option := range options {
+		option(p)
 	}
+	lexer.Version = p.version
 
 	p.prefixParseFns = make(map[sasttoken.TokenType]prefixParseFn)
 	p.registerPrefix(sasttoken.IDENT, p.parseIdentifier)
 	p.registerPrefix(sasttoken.INT, p.parseIntegerLiteral)
 	p.registerPrefix(sasttoken.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(sasttoken.IMAGINARY, p.parseImaginaryLiteral)
 	p.registerPrefix(sasttoken.STRING, p.parseStringLiteral)
 	p.registerPrefix(sasttoken.LBRACKET, p.parseListLiteral)
+	p.registerPrefix(sasttoken.LBRACE, p.parseBraceLiteral)
+	p.registerPrefix(sasttoken.ASTERISK, p.parseStarredExpression)
 	p.registerPrefix(sasttoken.TRUE, p.parseBooleanLiteral)
 	p.registerPrefix(sasttoken.FALSE, p.parseBooleanLiteral)
 	p.registerPrefix(sasttoken.NONE, p.parseNoneLiteral)
+	p.registerPrefix(sasttoken.ELLIPSIS, p.parseEllipsis)
 	p.registerPrefix(sasttoken.LAMBDA, p.parseLambdaExpression)
 	p.registerPrefix(sasttoken.LPAREN, p.parseGroupedExpression)
+	p.registerPrefix(sasttoken.PLUS, p.parsePrefixExpression)
+	p.registerPrefix(sasttoken.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(sasttoken.TILDE, p.parsePrefixExpression)
+	p.registerPrefix(sasttoken.NOT, p.parsePrefixExpression)
+	p.registerPrefix(sasttoken.AWAIT, p.parseAwaitExpression)
+	p.registerPrefix(sasttoken.YIELD, p.parseYieldExpression)
+	p.registerPrefix(sasttoken.BACKTICK, p.parseReprExpression)
 
 	p.infixParseFns = make(map[sasttoken.TokenType]infixParseFn)
-	p.registerInfix(sasttoken.PLUS, p.parseInfixExpression)
-	p.registerInfix(sasttoken.MINUS, p.parseInfixExpression)
-	p.registerInfix(sasttoken.ASTERISK, p.parseInfixExpression)
-	p.registerInfix(sasttoken.SLASH, p.parseInfixExpression)
-	p.registerInfix(sasttoken.EQ, p.parseInfixExpression)
+	for _, op := range []sasttoken.TokenType{
+		sasttoken.OR, sasttoken.AND,
+		sasttoken.PIPE, sasttoken.CARET, sasttoken.AMPERSAND,
+		sasttoken.LSHIFT, sasttoken.RSHIFT,
+		sasttoken.PLUS, sasttoken.MINUS,
+		sasttoken.ASTERISK, sasttoken.AT, sasttoken.SLASH, sasttoken.FLOORDIV, sasttoken.MOD,
+		sasttoken.POWER,
+	} {
+		p.registerInfix(op, p.parseInfixExpression)
+	}
+	for _, op := range []sasttoken.TokenType{
+		sasttoken.IN, sasttoken.NOT, sasttoken.IS,
+		sasttoken.LT, sasttoken.LE, sasttoken.GT, sasttoken.GE, sasttoken.NE, sasttoken.EQ,
+	} {
+		p.registerInfix(op, p.parseComparisonExpression)
+	}
+	p.registerInfix(sasttoken.IF, p.parseIfExpression)
+	p.registerInfix(sasttoken.WALRUS, p.parseNamedExpression)
+	p.registerInfix(sasttoken.DOT, p.parseAttributeExpression)
+	p.registerInfix(sasttoken.LPAREN, p.parseCallExpression)
+	p.registerInfix(sasttoken.LBRACKET, p.parseSubscriptExpression)
 
 	// Read two tokens so that both curToken and peekToken are set
 	p.nextToken()
@@ -54,22 +111,81 @@ func New(lexer *lexer.Lexer) *Parser {
 }
 
 func (p *Parser) nextToken() {
+	p.prevToken = p.curToken
 	p.curToken = p.peekToken
 	p.peekToken = p.lexer.NextToken()
+	if p.printFunction && p.peekToken.Type == sasttoken.PRINT {
+		p.peekToken.Type = sasttoken.IDENT
+	}
 }
 
-func (p *Parser) Parse() (*Node, error) {
-	return p.parseStatement()
+// ParseProgram parses a whole file. Statements that cannot be parsed are
+// replaced by ErrorNodes and parsing resumes at the next statement, so the
+// returned Program is never nil. The error, if any, is a DiagnosticList
+// holding every lexical and syntax error of the file.
+func (p *Parser) ParseProgram() (*Program, error) {
+	program := p.parseProgram()
+
+	if p.detectVersion && p.version != sasttoken.Python2 && len(p.Diagnostics()) > 0 {
+		legacy := New(p.lexer.Rewind(), WithVersion(sasttoken.Python2))
+		if legacyProgram := legacy.parseProgram(); len(legacy.Diagnostics()) == 0 {
+			p.lexer, p.diagnostics, p.version = legacy.lexer, nil, legacy.version
+			p.prevToken, p.curToken, p.peekToken = legacy.prevToken, legacy.curToken, legacy.peekToken
+			p.printFunction = legacy.printFunction
+			return legacyProgram, nil
+		}
+	}
+
+	return program, p.Err()
 }
 
-func (p *Parser) Errors() []string {
-	return p.errors
+func (p *Parser) parseProgram() *Program {
+	program := &Program{}
+
+	for !p.curTokenIs(sasttoken.EOF) {
+		program.Statements = append(program.Statements, p.parseStatementOrRecover())
+	}
+
+	return program
+}
+
+// Version returns the language version being parsed. With DetectVersion it
+// is Python 2 once ParseProgram has fallen back to it.
+func (p *Parser) Version() sasttoken.Version {
+	return p.version
+}
+
+// Diagnostics returns the lexical and syntax errors found so far, in source
+// order.
+func (p *Parser) Diagnostics() DiagnosticList {
+	var list DiagnosticList
+	for _, err := range p.lexer.SyntaxErrors() {
+		list = append(list, &Diagnostic{
+			Pos:      sasttoken.Position{Line: err.Line, Column: err.Column},
+			FilePath: p.lexer.FilePath,
+			Message:  err.Msg,
+		})
+	}
+	list = append(list, p.diagnostics...)
+	list.Sort()
+	return list
+}
+
+// Err returns the diagnostics as an error, or nil if there are none.
+func (p *Parser) Err() error {
+	if list := p.Diagnostics(); len(list) > 0 {
+		return list
+	}
+	return nil
 }
 
-func (p *Parser) peekError(t sasttoken.TokenType) error {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
-	return fmt.Errorf(msg)
+// Errors returns the diagnostics formatted as strings.
+func (p *Parser) Errors() []string {
+	var errs []string
+	for _, d := range p.Diagnostics() {
+		errs = append(errs, d.Error())
+	}
+	return errs
 }
 
 func (p *Parser) peekTokenIs(t sasttoken.TokenType) bool {
@@ -80,6 +196,17 @@ func (p *Parser) curTokenIs(t sasttoken.TokenType) bool {
 	return p.curToken.Type == t
 }
 
+// expect consumes the current token if it has type t, and otherwise
+// returns a diagnostic naming what was expected instead.
+func (p *Parser) expect(t sasttoken.TokenType) (sasttoken.Token, error) {
+	tok := p.curToken
+	if tok.Type != t {
+		return tok, p.expectedError(describeType(t))
+	}
+	p.nextToken()
+	return tok, nil
+}
+
 func (p *Parser) registerPrefix(tokenType sasttoken.TokenType, fn prefixParseFn) {
 	p.prefixParseFns[tokenType] = fn
 }
@@ -88,18 +215,16 @@ func (p *Parser) registerInfix(tokenType sasttoken.TokenType, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
-func (p *Parser) peekPrecedence() int {
-	if precedence, ok := precedenceTable[p.peekToken.Type]; ok {
-		return precedence
-	}
-	return LOWEST
-}
-
 func (p *Parser) curPrecedence() int {
+	if p.curTokenIs(sasttoken.NOT) {
+		// "not" is only an infix operator as part of "not in".
+		if p.peekTokenIs(sasttoken.IN) {
+			return COMPARISON
+		}
+		return LOWEST
+	}
 	if precedence, ok := precedenceTable[p.curToken.Type]; ok {
 		return precedence
 	}
 	return LOWEST
 }
-
-// All the statement and expression parsing functions should be included here as they are already defined in expression.go and statement.go files.
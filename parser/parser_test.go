@@ -1 +1,1262 @@
 package parser_test
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/lexer"
+	"github.com/coiloffaraday/python_sast/parser"
+	sasttoken "github.com/coiloffaraday/python_sast/token"
+)
+
+func parse(t *testing.T, input string) (*parser.Program, parser.DiagnosticList) {
+	t.Helper()
+	p := parser.New(lexer.NewLexer(input, "test.py"))
+	program, err := p.ParseProgram()
+	if program == nil {
+		t.Fatalf("%q: ParseProgram returned no program (%v)", input, err)
+	}
+	if err != nil && len(p.Diagnostics()) == 0 {
+		t.Fatalf("%q: error without diagnostics: %v", input, err)
+	}
+	return program, p.Diagnostics()
+}
+
+func parseValid(t *testing.T, input string) *parser.Program {
+	t.Helper()
+	program, diags := parse(t, input)
+	if len(diags) > 0 {
+		t.Fatalf("%q: unexpected errors: %v", input, diags)
+	}
+	return program
+}
+
+// parseExpr parses input as a single expression statement.
+func parseExpr(t *testing.T, input string) parser.Expression {
+	t.Helper()
+	program := parseValid(t, input)
+	if len(program.Statements) != 1 {
+		t.Fatalf("%q: expected 1 statement, got %d", input, len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*parser.ExpressionStatement)
+	if !ok {
+		t.Fatalf("%q: expected *parser.ExpressionStatement, got %T", input, program.Statements[0])
+	}
+	return stmt.Expression
+}
+
+func TestParseProgram(t *testing.T) {
+	input := "x = y = 1\nif x:\n    z = x + 2 * 3\nelse:\n    pass\nreturn\n"
+	program := parseValid(t, input)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("expected 3 statements, got %d: %s", len(program.Statements), program)
+	}
+	assign, ok := program.Statements[0].(*parser.AssignmentStatement)
+	if !ok || len(assign.Targets) != 2 {
+		t.Fatalf("expected a chained assignment, got %#v", program.Statements[0])
+	}
+	ifStmt, ok := program.Statements[1].(*parser.IfStatement)
+	if !ok {
+		t.Fatalf("expected *parser.IfStatement, got %T", program.Statements[1])
+	}
+	if got := ifStmt.Consequence.String(); got != "z = (x + (2 * 3))" {
+		t.Errorf("consequence: got %q", got)
+	}
+	if ifStmt.ElseClause == nil {
+		t.Errorf("missing else clause")
+	}
+}
+
+func TestRecoveryAtStatementBoundaries(t *testing.T) {
+	input := "x = = 1\ny = 2\ndef f(1):\n    z = 3\nw = 4\n"
+	program, diags := parse(t, input)
+
+	if len(diags) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %v", diags)
+	}
+	if d := diags[0]; d.Pos.Line != 1 || d.Pos.Column != 5 || d.Expected != "expression" || d.Found.Literal != "=" {
+		t.Errorf("first diagnostic: got %+v", d)
+	}
+	if d := diags[1]; d.Pos.Line != 3 || d.Pos.Column != 7 || d.Expected != "parameter name" {
+		t.Errorf("second diagnostic: got %+v", d)
+	}
+	if got := diags[0].Error(); got != "test.py:1:5: expected expression, got '='" {
+		t.Errorf("message: got %q", got)
+	}
+
+	if len(program.Statements) != 4 {
+		t.Fatalf("expected 4 statements, got %d: %s", len(program.Statements), program)
+	}
+	if _, ok := program.Statements[0].(*parser.ErrorNode); !ok {
+		t.Errorf("statement 0: expected *parser.ErrorNode, got %T", program.Statements[0])
+	}
+	if _, ok := program.Statements[1].(*parser.AssignmentStatement); !ok {
+		t.Errorf("statement 1: expected *parser.AssignmentStatement, got %T", program.Statements[1])
+	}
+	errNode, ok := program.Statements[2].(*parser.ErrorNode)
+	if !ok {
+		t.Fatalf("statement 2: expected *parser.ErrorNode, got %T", program.Statements[2])
+	}
+	// The body of the broken function is still parsed.
+	if errNode.Body == nil || len(errNode.Body.Statements) != 1 || errNode.Body.String() != "z = 3" {
+		t.Errorf("error node body: got %v", errNode.Body)
+	}
+	if got := program.Statements[3].String(); got != "w = 4" {
+		t.Errorf("statement 3: got %q", got)
+	}
+}
+
+func TestRecoveryInsideBlock(t *testing.T) {
+	input := "def f():\n    x = )\n    y = 2\nz = 3\n"
+	program, diags := parse(t, input)
+
+	if len(diags) != 1 || diags[0].Pos.Line != 2 {
+		t.Fatalf("expected 1 diagnostic on line 2, got %v", diags)
+	}
+	if len(program.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d: %s", len(program.Statements), program)
+	}
+	fn, ok := program.Statements[0].(*parser.FunctionDef)
+	if !ok {
+		t.Fatalf("expected *parser.FunctionDef, got %T", program.Statements[0])
+	}
+	if len(fn.Body.Statements) != 2 {
+		t.Fatalf("expected 2 statements in body, got %s", fn.Body)
+	}
+	if _, ok := fn.Body.Statements[0].(*parser.ErrorNode); !ok {
+		t.Errorf("expected *parser.ErrorNode, got %T", fn.Body.Statements[0])
+	}
+}
+
+func TestRecoveryFromUnclosedBracket(t *testing.T) {
+	input := "x = foo(a,\ndef view():\n    os.system(request.args['c'])\nclass C:\n    pass\n"
+	program, diags := parse(t, input)
+
+	if len(diags) != 1 || diags[0].Pos.Line != 1 {
+		t.Fatalf("expected 1 diagnostic on line 1, got %v", diags)
+	}
+	if len(program.Statements) != 3 {
+		t.Fatalf("expected 3 statements, got %d: %s", len(program.Statements), program)
+	}
+	if _, ok := program.Statements[0].(*parser.ErrorNode); !ok {
+		t.Errorf("statement 0: expected *parser.ErrorNode, got %T", program.Statements[0])
+	}
+	fn, ok := program.Statements[1].(*parser.FunctionDef)
+	if !ok || fn.Body.String() != "os.system(request.args[\"c\"])" {
+		t.Errorf("statement 1: got %s", program.Statements[1])
+	}
+	if _, ok := program.Statements[2].(*parser.ClassDef); !ok {
+		t.Errorf("statement 2: expected *parser.ClassDef, got %T", program.Statements[2])
+	}
+}
+
+func TestUnexpectedIndent(t *testing.T) {
+	program, diags := parse(t, "x = 1\n    y = 2\nz = 3\n")
+
+	if len(diags) != 1 || !strings.Contains(diags[0].Message, "unexpected indent") {
+		t.Fatalf("expected an unexpected indent diagnostic, got %v", diags)
+	}
+	if len(program.Statements) != 3 {
+		t.Fatalf("expected 3 statements, got %d: %s", len(program.Statements), program)
+	}
+	errNode, ok := program.Statements[1].(*parser.ErrorNode)
+	if !ok || errNode.Body == nil || errNode.Body.String() != "y = 2" {
+		t.Errorf("expected the indented line inside an error node, got %s", program.Statements[1])
+	}
+}
+
+func TestLexicalErrorsAreDiagnostics(t *testing.T) {
+	program, diags := parse(t, "s = 'abc\nt = 1\n")
+
+	if len(diags) != 1 || diags[0].Message != "unterminated string literal" || diags[0].Pos.Line != 1 {
+		t.Fatalf("expected one lexical diagnostic, got %v", diags)
+	}
+	if len(program.Statements) != 2 || program.Statements[1].String() != "t = 1" {
+		t.Errorf("expected parsing to continue, got %s", program)
+	}
+
+	// Lexical diagnostics point at the offending literal or character.
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"s = rb'abc\n", "test.py:1:5: unterminated string literal"},
+		{"x = 1\ny = 'é' + $\n", "test.py:2:11: invalid character '$' (U+0024)"},
+		{"if x:\n    y = 0x\n", "test.py:2:9: invalid hexadecimal literal"},
+		{"if x:\n    y = 1\n  z = 2\n", "test.py:3:3: unindent does not match any outer indentation level"},
+	}
+	for _, tt := range tests {
+		_, diags := parse(t, tt.input)
+		if len(diags) == 0 || diags[0].Error() != tt.expected {
+			t.Errorf("%q: expected %q, got %v", tt.input, tt.expected, diags)
+		}
+	}
+}
+
+func TestFString(t *testing.T) {
+	expr := parseExpr(t, `f"id={uid!r:>{width}} {{x}}"`)
+	str, ok := expr.(*parser.JoinedStr)
+	if !ok {
+		t.Fatalf("expected *parser.JoinedStr, got %T", expr)
+	}
+	if len(str.Values) != 3 {
+		t.Fatalf("expected 3 values, got %d: %s", len(str.Values), str)
+	}
+	if lit, ok := str.Values[0].(*parser.StringLiteral); !ok || lit.Value != "id=" {
+		t.Errorf("value 0: got %s", str.Values[0])
+	}
+	field, ok := str.Values[1].(*parser.FormattedValue)
+	if !ok {
+		t.Fatalf("value 1: expected *parser.FormattedValue, got %T", str.Values[1])
+	}
+	if field.Conversion != 'r' || field.Value.String() != "uid" {
+		t.Errorf("field: got %s", field)
+	}
+	if pos := field.Value.Pos(); pos.Line != 1 || pos.Column != 7 || pos.Offset != 6 {
+		t.Errorf("field position: got %+v", pos)
+	}
+	if field.FormatSpec == nil || len(field.FormatSpec.Values) != 2 {
+		t.Errorf("format spec: got %v", field.FormatSpec)
+	}
+	if lit, ok := str.Values[2].(*parser.StringLiteral); !ok || lit.Value != " {x}" {
+		t.Errorf("value 2: got %s", str.Values[2])
+	}
+}
+
+func TestFStringPositions(t *testing.T) {
+	// Every part spans its own source: the text, the braces of a field and
+	// its format spec.
+	expr := parseExpr(t, "f\"id={uid!r:>{width}} {{x}}\"\n")
+	want := `JoinedStr 1:1-1:29 {
+  Values: [
+    StringLiteral 1:3-1:6 {
+      Value: "id="
+    }
+    FormattedValue 1:6-1:22 {
+      Value: Identifier 1:7-1:10 {
+        Value: "uid"
+      }
+      Conversion: 'r'
+      FormatSpec: JoinedStr 1:13-1:21 {
+        Values: [
+          StringLiteral 1:13-1:14 {
+            Value: ">"
+          }
+          FormattedValue 1:14-1:21 {
+            Value: Identifier 1:15-1:20 {
+              Value: "width"
+            }
+          }
+        ]
+      }
+    }
+    StringLiteral 1:22-1:28 {
+      Value: " {x}"
+    }
+  ]
+}
+`
+	if got := parser.Dump(expr); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Text merged across adjacent literals spans all of it.
+	str := parseExpr(t, "f'{a}b' 'c'\n").(*parser.JoinedStr)
+	if text := str.Values[1]; text.Pos().Column != 6 || text.End().Column != 12 {
+		t.Errorf("merged text: got %+v-%+v", text.Pos(), text.End())
+	}
+}
+
+func TestFStringErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`f"{}"`, "f-string: valid expression required before '}'"},
+		{`f"{x!z}"`, "f-string: invalid conversion character"},
+		{`f"a}"`, "f-string: single '}' is not allowed"},
+		{`f"{x #}"`, "f-string: expression part cannot include '#'"},
+	}
+	for _, tt := range tests {
+		_, diags := parse(t, tt.input+"\n")
+		if len(diags) != 1 || !strings.Contains(diags[0].Message, tt.err) {
+			t.Errorf("%s: expected %q, got %v", tt.input, tt.err, diags)
+		}
+	}
+}
+
+func TestStringConcatenation(t *testing.T) {
+	str, ok := parseExpr(t, "'a' \"b\" r'\\c'\n").(*parser.StringLiteral)
+	if !ok || str.Value != `ab\c` || len(str.Rest) != 2 {
+		t.Errorf("plain strings: got %#v", str)
+	}
+
+	// Strings spanning lines inside parentheses end at the last one.
+	call := parseExpr(t, "execute(\"SELECT * \"\n        \"WHERE id=%s\" % uid)\n").(*parser.CallExpression)
+	infix, ok := call.Arguments[0].(*parser.InfixExpression)
+	if !ok {
+		t.Fatalf("expected the concatenation to be the left operand of %%, got %s", call.Arguments[0])
+	}
+	if lit, ok := infix.Left.(*parser.StringLiteral); !ok || lit.Value != "SELECT * WHERE id=%s" {
+		t.Errorf("multi-line strings: got %s", infix.Left)
+	} else if end := lit.End(); end.Line != 2 || end.Column != 22 {
+		t.Errorf("multi-line strings end: got %+v", end)
+	}
+
+	joined, ok := parseExpr(t, "'a{' f'{x}b' 'c' f'{y!r}'\n").(*parser.JoinedStr)
+	if !ok {
+		t.Fatalf("expected *parser.JoinedStr")
+	}
+	var values []string
+	for _, v := range joined.Values {
+		if lit, ok := v.(*parser.StringLiteral); ok {
+			values = append(values, fmt.Sprintf("%q", lit.Value))
+		} else {
+			values = append(values, v.String())
+		}
+	}
+	if got := strings.Join(values, " "); got != `"a{" {x} "bc" {y!r}` {
+		t.Errorf("f-string parts: got %s", got)
+	}
+
+	if _, diags := parse(t, "x = b'a' 'b'\n"); len(diags) != 1 || diags[0].Message != "cannot mix bytes and nonbytes literals" {
+		t.Errorf("bytes and str: got %v", diags)
+	}
+	p := parser.New(lexer.NewLexer("x = b'a' u'b' 'c'\n", "test.py"), parser.WithVersion(sasttoken.Python2))
+	if _, err := p.ParseProgram(); err != nil {
+		t.Errorf("Python 2 strings: unexpected errors: %v", err)
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	big := parseExpr(t, "1_180_591_620_717_411_303_424").(*parser.IntegerLiteral)
+	if big.Big == nil || big.String() != "1180591620717411303424" {
+		t.Errorf("big integer: got %s", big)
+	}
+	hex := parseExpr(t, "0x_ff").(*parser.IntegerLiteral)
+	if hex.Value != 255 || hex.Big != nil {
+		t.Errorf("hex integer: got %d", hex.Value)
+	}
+	inf := parseExpr(t, "1e400").(*parser.FloatLiteral)
+	if !math.IsInf(inf.Value, 1) {
+		t.Errorf("overflowing float: got %v", inf.Value)
+	}
+	imag := parseExpr(t, "2.5j").(*parser.ImaginaryLiteral)
+	if imag.Value != 2.5 {
+		t.Errorf("imaginary: got %v", imag.Value)
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	program := parseValid(t, "if x:\n    total = price * 2\n")
+	stmt := program.Statements[0].(*parser.IfStatement)
+	assign := stmt.Consequence.Statements[0].(*parser.AssignmentStatement)
+
+	if pos := assign.Pos(); pos.Line != 2 || pos.Column != 5 {
+		t.Errorf("assignment start: got %+v", pos)
+	}
+	if end := assign.End(); end.Line != 2 || end.Column != 22 {
+		t.Errorf("assignment end: got %+v", end)
+	}
+	if end := stmt.End(); end != assign.End() {
+		t.Errorf("if statement end: got %+v, want %+v", end, assign.End())
+	}
+}
+
+func TestStatements(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"import os.path as p, sys\n", "import os.path as p, sys"},
+		{"from .. import a\n", "from .. import a"},
+		{"from ...pkg.mod import (a as b, c,)\n", "from ...pkg.mod import a as b, c"},
+		{"from m import *\n", "from m import *"},
+		{"@dec\n@other\nclass C(Base, Mixin):\n    pass\n", "@dec @other class C(Base, Mixin): pass"},
+		{"@dec\nasync def f(a, b):\n    return a, b\n", "@dec async def f(a, b): return (a, b)"},
+		{"async for x, y in items:\n    pass\nelse:\n    pass\n", "async for (x, y) in items: pass else: pass"},
+		{"async with a as b, c:\n    pass\n", "async with a as b, c: pass"},
+		{"with (a as b, c as d,):\n    pass\n", "with a as b, c as d: pass"},
+		{"with (a) + 1 as b:\n    pass\n", "with (a + 1) as b: pass"},
+		{"try:\n    pass\nexcept* E as e:\n    raise X from e\nelse:\n    pass\nfinally:\n    pass\n",
+			"try: pass except* E as e: raise X from e else: pass finally: pass"},
+		{"while x:\n    break\nelse:\n    continue\n", "while x: break else: continue"},
+		{"if a: pass\nelif b: pass\nelse: pass\n", "if a: pass elif b: pass else: pass"},
+		{"x //= 2\n", "x //= 2"},
+		{"assert x, 'msg'\n", `assert x, "msg"`},
+		{"del a, b\n", "del a, b"},
+		{"global g, h\n", "global g, h"},
+		{"def f():\n    nonlocal n\n", "def f(): nonlocal n"},
+		{"x = yield\n", "x = (yield)"},
+		{"yield from g\n", "(yield from g)"},
+		{"yield 1, 2\n", "(yield (1, 2))"},
+		{"a, b = b, a\n", "(a, b) = (b, a)"},
+		{"match x:\n    case 1 if y:\n        pass\n    case _:\n        pass\n", "match x: case 1 if y: pass case _: pass"},
+	}
+
+	for _, tt := range tests {
+		program := parseValid(t, tt.input)
+		if len(program.Statements) != 1 {
+			t.Errorf("%q: expected 1 statement, got %d", tt.input, len(program.Statements))
+			continue
+		}
+		if got := program.String(); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestStatementNodes(t *testing.T) {
+	program := parseValid(t, "from . import x\nasync def f():\n    y -= 1\ntry:\n    pass\nexcept E:\n    pass\n")
+
+	from, ok := program.Statements[0].(*parser.FromImportStatement)
+	if !ok || from.Level != 1 || from.Module != nil {
+		t.Errorf("expected a relative import without module, got %#v", program.Statements[0])
+	}
+	fn, ok := program.Statements[1].(*parser.AsyncFunctionDef)
+	if !ok {
+		t.Fatalf("expected *parser.AsyncFunctionDef, got %T", program.Statements[1])
+	}
+	aug, ok := fn.Body.Statements[0].(*parser.AugAssignStatement)
+	if !ok || aug.Operator != "-" {
+		t.Errorf("expected an augmented subtraction, got %#v", fn.Body.Statements[0])
+	}
+	try, ok := program.Statements[2].(*parser.TryStatement)
+	if !ok || len(try.ExceptClauses) != 1 || try.ExceptClauses[0].Star || try.ExceptClauses[0].Name != nil {
+		t.Errorf("expected a try statement with one plain except clause, got %#v", program.Statements[2])
+	}
+}
+
+func TestStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try:\n    pass\nx = 1\n", "'except' or 'finally' block"},
+		{"@dec\nx = 1\n", "function or class definition"},
+		{"from . import\n", "name"},
+		{"import\n", "module name"},
+	}
+
+	for _, tt := range tests {
+		_, diags := parse(t, tt.input)
+		if len(diags) == 0 || diags[0].Expected != tt.expected {
+			t.Errorf("%q: expected an error wanting %s, got %v", tt.input, tt.expected, diags)
+		}
+	}
+}
+
+func TestOperatorPrecedence(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"a or b and not c", "(a or (b and (not c)))"},
+		{"not a == b", "(not (a == b))"},
+		{"a < b <= c", "(a < b <= c)"},
+		{"a not in b is not c", "(a not in b is not c)"},
+		{"a | b ^ c & d << e + f * g", "(a | (b ^ (c & (d << (e + (f * g))))))"},
+		{"a // b % c @ d", "(((a // b) % c) @ d)"},
+		{"-a ** -b", "(-(a ** (-b)))"},
+		{"a ** b ** c", "(a ** (b ** c))"},
+		{"~a + b", "((~a) + b)"},
+		{"a if b else c if d else e", "(a if b else (c if d else e))"},
+		{"a or b if c or d else e", "((a or b) if (c or d) else e)"},
+		{"(x := a + 1)", "(x := (a + 1))"},
+		{"await a.b ** 2", "((await a.b) ** 2)"},
+		{"a.b.c(d)[e]", "a.b.c(d)[e]"},
+		{"f(a, *b, c=1, **d)", "f(a, *b, c=1, **d)"},
+		{"f(a,)", "f(a)"},
+		{"a[1:2]", "a[1:2]"},
+		{"a[::2]", "a[::2]"},
+		{"a[:, i:]", "a[:, i:]"},
+		{"a[1, 2]", "a[1, 2]"},
+		{"-a[0].b", "(-a[0].b)"},
+	}
+
+	for _, tt := range tests {
+		if got := parseExpr(t, tt.input+"\n").String(); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestExpressionNodes(t *testing.T) {
+	call, ok := parseExpr(t, "os.system(cmd, shell=True)\n").(*parser.CallExpression)
+	if !ok {
+		t.Fatalf("expected *parser.CallExpression")
+	}
+	if attr, ok := call.Function.(*parser.AttributeExpression); !ok || attr.Attribute.Value != "system" {
+		t.Errorf("function: got %#v", call.Function)
+	}
+	if len(call.Arguments) != 1 || len(call.Keywords) != 1 || call.Keywords[0].Name.Value != "shell" {
+		t.Errorf("arguments: got %v and %v", call.Arguments, call.Keywords)
+	}
+	if end := call.End(); end.Line != 1 || end.Column != 27 {
+		t.Errorf("end: got %+v", end)
+	}
+
+	subscript, ok := parseExpr(t, "a[1:]\n").(*parser.SubscriptExpression)
+	if !ok {
+		t.Fatalf("expected *parser.SubscriptExpression")
+	}
+	if slice, ok := subscript.Index.(*parser.SliceExpression); !ok || slice.Lower == nil || slice.Upper != nil || slice.Step != nil {
+		t.Errorf("index: got %#v", subscript.Index)
+	}
+
+	comparison, ok := parseExpr(t, "a is not b\n").(*parser.ComparisonExpression)
+	if !ok || len(comparison.Operators) != 1 || comparison.Operators[0] != "is not" {
+		t.Errorf("expected an 'is not' comparison")
+	}
+}
+
+func TestEllipsis(t *testing.T) {
+	program := parseValid(t, "def m(self): ...\nx = ...\na[..., 0]\nf: Callable[..., T]\nfrom ...a import b\n")
+	ellipses := parser.FindAll[*parser.Ellipsis](program)
+	if len(ellipses) != 4 {
+		t.Fatalf("expected 4 ellipses, got %d", len(ellipses))
+	}
+	if pos, end := ellipses[2].Pos(), ellipses[2].End(); pos.Line != 3 || pos.Column != 3 || end.Column != 6 {
+		t.Errorf("position: got %+v-%+v", pos, end)
+	}
+	if got := parser.Dump(ellipses[1]); got != "Ellipsis 2:5-2:8 {}\n" {
+		t.Errorf("dump: got %q", got)
+	}
+	if imp, ok := program.Statements[4].(*parser.FromImportStatement); !ok || imp.Level != 3 {
+		t.Errorf("relative import: got %s", program.Statements[4])
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"a.b := 1\n", "test.py:1:5: cannot use assignment expression with a.b"},
+		{"a if b\n", "test.py:1:7: expected 'else', got newline"},
+		{"a.(b)\n", "test.py:1:3: expected attribute name, got '('"},
+		{"f(a b)\n", "test.py:1:5: expected ')', got name 'b'"},
+		{"f(a=1, b := 2)\n", "test.py:1:8: positional argument follows keyword argument"},
+		{"f(**k, x)\n", "test.py:1:8: positional argument follows keyword argument unpacking"},
+		{"f(**k, *a)\n", "test.py:1:8: iterable argument unpacking follows keyword argument unpacking"},
+	}
+
+	for _, tt := range tests {
+		_, diags := parse(t, tt.input)
+		if len(diags) != 1 || diags[0].Error() != tt.want {
+			t.Errorf("%q: got %v, want %q", tt.input, diags, tt.want)
+		}
+	}
+}
+
+func TestForTargetStopsAtIn(t *testing.T) {
+	program := parseValid(t, "for k, v in d.items() if x else y:\n    pass\n")
+	stmt := program.Statements[0].(*parser.ForStatement)
+	if got := stmt.Target.String(); got != "(k, v)" {
+		t.Errorf("target: got %q", got)
+	}
+	if got := stmt.Iterable.String(); got != "(d.items() if x else y)" {
+		t.Errorf("iterable: got %q", got)
+	}
+}
+
+func TestDisplaysAndComprehensions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"()", "()"},
+		{"(a)", "a"},
+		{"(a,)", "(a,)"},
+		{"(a, *b)", "(a, *b)"},
+		{"[a, *b, c,]", "[a, *b, c]"},
+		{"{}", "{}"},
+		{"{a: 1, **b, 'c': d}", `{a: 1, **b, "c": d}`},
+		{"{**a}", "{**a}"},
+		{"{a, b}", "{a, b}"},
+		{"[x * 2 for x in xs if x if not y]", "[(x * 2) for x in xs if x if (not y)]"},
+		{"[y for x in xs for y in x]", "[y for x in xs for y in x]"},
+		{"{k: v for k, v in d.items()}", "{k: v for (k, v) in d.items()}"},
+		{"{x async for x in aiter()}", "{x async for x in aiter()}"},
+		{"(x for x in xs)", "(x for x in xs)"},
+		{"sum(x for x in xs if x)", "sum((x for x in xs if x))"},
+		{"[a if b else c for a in d]", "[(a if b else c) for a in d]"},
+		{"lambda: 0", "(lambda: 0)"},
+		{"lambda x, y=1: x + y", "(lambda x, y=1: (x + y))"},
+		{"lambda a, /, b, *, c, **kw: a", "(lambda a, /, b, *, c, **kw: a)"},
+		{"lambda *args, key=None: key", "(lambda *args, key=None: key)"},
+		{"lambda x: lambda y: x if y else 0", "(lambda x: (lambda y: (x if y else 0)))"},
+	}
+
+	for _, tt := range tests {
+		if got := parseExpr(t, tt.input+"\n").String(); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestStarredAssignment(t *testing.T) {
+	program := parseValid(t, "first, *rest = items\n")
+	assign := program.Statements[0].(*parser.AssignmentStatement)
+	target, ok := assign.Targets[0].(*parser.TupleLiteral)
+	if !ok || len(target.Elements) != 2 {
+		t.Fatalf("expected a two-element target, got %#v", assign.Targets[0])
+	}
+	if _, ok := target.Elements[1].(*parser.StarredExpression); !ok {
+		t.Errorf("expected a starred target, got %T", target.Elements[1])
+	}
+}
+
+func TestTargetErrors(t *testing.T) {
+	parseValid(t, "a, *b.c, [d[0], (e)] = f = g\ndel a, (b.c, [d[0]])\n")
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1 = x\n", "test.py:1:1: cannot assign to literal"},
+		{"f() = 3\n", "test.py:1:1: cannot assign to function call"},
+		{"a = b + c = d\n", "test.py:1:5: cannot assign to expression"},
+		{"a, (b, None) = c\n", "test.py:1:8: cannot assign to None"},
+		{"*a = b\n", "test.py:1:1: starred assignment target must be in a list or tuple"},
+		{"*a, *b = c\n", "test.py:1:5: multiple starred expressions in assignment"},
+		{"del 1\n", "test.py:1:5: cannot delete literal"},
+		{"del a, f()\n", "test.py:1:8: cannot delete function call"},
+		{"del [*a]\n", "test.py:1:6: cannot delete starred"},
+		{"x = y := 10\n", "test.py:1:7: assignment expression must be parenthesized here"},
+		{"w := 5\n", "test.py:1:3: assignment expression must be parenthesized here"},
+		{"x = 1, y := 2\n", "test.py:1:10: assignment expression must be parenthesized here"},
+		{"return y := 1\n", "test.py:1:10: assignment expression must be parenthesized here"},
+	}
+
+	for _, tt := range tests {
+		program, diags := parse(t, tt.input)
+		if len(diags) != 1 || diags[0].Error() != tt.want {
+			t.Errorf("%q: got %v, want %q", tt.input, diags, tt.want)
+		}
+		if _, ok := program.Statements[0].(*parser.ErrorNode); !ok {
+			t.Errorf("%q: expected *parser.ErrorNode, got %T", tt.input, program.Statements[0])
+		}
+	}
+}
+
+func TestComprehensionNodes(t *testing.T) {
+	comp, ok := parseExpr(t, "[row for row in rows if row.ok for col in row if col]\n").(*parser.ListComprehension)
+	if !ok {
+		t.Fatalf("expected *parser.ListComprehension")
+	}
+	if len(comp.Generators) != 2 || len(comp.Generators[0].Ifs) != 1 || len(comp.Generators[1].Ifs) != 1 {
+		t.Errorf("generators: got %v", comp.Generators)
+	}
+
+	call := parseExpr(t, "f(x for x in y)\n").(*parser.CallExpression)
+	gen, ok := call.Arguments[0].(*parser.GeneratorExpression)
+	if !ok {
+		t.Fatalf("expected *parser.GeneratorExpression, got %T", call.Arguments[0])
+	}
+	if pos, end := gen.Pos(), gen.End(); pos.Column != 2 || end.Column != 16 {
+		t.Errorf("generator spans %+v to %+v", pos, end)
+	}
+
+	lambda := parseExpr(t, "lambda a, /, b, *c, d, **e: 0\n").(*parser.LambdaExpression)
+	kinds := []parser.ParameterKind{parser.PositionalOnly, parser.PositionalOrKeyword, parser.VarPositional, parser.KeywordOnly, parser.VarKeyword}
+	if len(lambda.Parameters) != len(kinds) {
+		t.Fatalf("expected %d parameters, got %d", len(kinds), len(lambda.Parameters))
+	}
+	for i, kind := range kinds {
+		if lambda.Parameters[i].Kind != kind {
+			t.Errorf("parameter %d: got kind %d, want %d", i, lambda.Parameters[i].Kind, kind)
+		}
+	}
+}
+
+func TestDefinitions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"def f(a, /, b: int = 1, *args: str, c, d=2, **kw) -> bool:\n    pass\n",
+			"def f(a, /, b: int = 1, *args: str, c, d=2, **kw) -> bool: pass"},
+		{"def f(*, key): pass\n", "def f(*, key): pass"},
+		{"def f(a, b, /): pass\n", "def f(a, b, /): pass"},
+		{"async def f() -> None: pass\n", "async def f() -> None: pass"},
+		{"class C(A, metaclass=Meta, **kw): pass\n", "class C(A, metaclass=Meta, **kw): pass"},
+		{"class C(): pass\n", "class C: pass"},
+		{"@app.route('/', methods=['POST'])\n@csrf_exempt\ndef view(request): pass\n",
+			`@app.route("/", methods=["POST"]) @csrf_exempt def view(request): pass`},
+	}
+
+	for _, tt := range tests {
+		program := parseValid(t, tt.input)
+		if got := program.String(); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSignatureErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"def f(/, a): pass\n", "test.py:1:7: '/' must follow positional parameters"},
+		{"def f(*a, *b): pass\n", "test.py:1:11: '*' may appear only once"},
+		{"def f(**kw, a): pass\n", "test.py:1:13: parameters cannot follow '**kw'"},
+		{"def f(*a=1): pass\n", "test.py:1:9: '*a' cannot have a default value"},
+	}
+
+	for _, tt := range tests {
+		_, diags := parse(t, tt.input)
+		if len(diags) != 1 || diags[0].Error() != tt.want {
+			t.Errorf("%q: got %v, want %q", tt.input, diags, tt.want)
+		}
+	}
+}
+
+func TestDocstrings(t *testing.T) {
+	input := `"""Module."""
+class C:
+    'Class.'
+    def f(self):
+        b"not a docstring"
+    def g(self):
+        x = 1
+`
+	program := parseValid(t, input)
+	if got := program.Docstring(); got != "Module." {
+		t.Errorf("module docstring: got %q", got)
+	}
+	class := program.Statements[1].(*parser.ClassDef)
+	if got := class.Docstring(); got != "Class." {
+		t.Errorf("class docstring: got %q", got)
+	}
+	for _, stmt := range class.Body.Statements[1:] {
+		if got := stmt.(*parser.FunctionDef).Docstring(); got != "" {
+			t.Errorf("%s: got docstring %q", stmt, got)
+		}
+	}
+}
+
+const walkInput = `import os
+@route("/run", methods=["POST"])
+async def run(request, *args, timeout: int = 5, **kw) -> str:
+    """Run a command."""
+    cmd = request.args.get("cmd", "")
+    with open(path) as f, lock:
+        data = {k: v for k, v in f if k}
+    for part in cmd.split():
+        if part in {"rm", *blocked}:
+            raise ValueError(f"bad {part!r}")
+    try:
+        result = await os.system(cmd[1:] + " " + lambda x: x)
+    except* OSError as e:
+        result = [x for x in (e,) if x is not None]
+    match result:
+        case 0 if timeout:
+            pass
+    return {"out": result, **kw}
+`
+
+func TestChildrenWithinParent(t *testing.T) {
+	program := parseValid(t, walkInput)
+	before := func(a, b sasttoken.Position) bool { return a.Offset < b.Offset }
+
+	parser.InspectWithStack(program, func(n parser.Node, stack []parser.Node) bool {
+		if len(stack) == 0 {
+			return true
+		}
+		parent := stack[len(stack)-1]
+		if before(n.Pos(), parent.Pos()) || before(parent.End(), n.End()) {
+			t.Errorf("%T %q at %+v-%+v lies outside its parent %T at %+v-%+v",
+				n, n, n.Pos(), n.End(), parent, parent.Pos(), parent.End())
+		}
+		return true
+	})
+}
+
+type countingVisitor struct {
+	visits, leaves *int
+}
+
+func (v countingVisitor) Visit(node parser.Node) parser.Visitor {
+	if node == nil {
+		*v.leaves++
+		return nil
+	}
+	*v.visits++
+	return v
+}
+
+func TestWalk(t *testing.T) {
+	program := parseValid(t, walkInput)
+
+	var visits, leaves int
+	parser.Walk(countingVisitor{&visits, &leaves}, program)
+	if visits == 0 || visits != leaves {
+		t.Errorf("got %d visits and %d calls with nil", visits, leaves)
+	}
+
+	idents := 0
+	parser.Inspect(program, func(n parser.Node) bool {
+		if _, ok := n.(*parser.Identifier); ok {
+			idents++
+		}
+		return true
+	})
+	if got := len(parser.FindAll[*parser.Identifier](program)); got != idents {
+		t.Errorf("FindAll found %d identifiers, Inspect %d", got, idents)
+	}
+
+	// Returning false prunes the subtree.
+	statements := 0
+	parser.Inspect(program, func(n parser.Node) bool {
+		if _, ok := n.(parser.Statement); ok {
+			statements++
+			return false
+		}
+		return true
+	})
+	if statements != len(program.Statements) {
+		t.Errorf("pruned walk saw %d statements, want %d", statements, len(program.Statements))
+	}
+}
+
+func TestFinders(t *testing.T) {
+	program := parseValid(t, walkInput)
+
+	calls := parser.FindAll[*parser.CallExpression](program)
+	var names []string
+	for _, call := range calls {
+		names = append(names, call.Function.String())
+	}
+	want := "route request.args.get open cmd.split ValueError os.system"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("calls: got %q, want %q", got, want)
+	}
+
+	fn, ok := parser.FindFirst[*parser.AsyncFunctionDef](program)
+	if !ok || fn.Name.Value != "run" || fn.Docstring() != "Run a command." {
+		t.Errorf("FindFirst: got %v", fn)
+	}
+	if _, ok := parser.FindFirst[*parser.ClassDef](program); ok {
+		t.Errorf("FindFirst found a class in a program without one")
+	}
+
+	parents := parser.Parents(program)
+	system := calls[len(calls)-1]
+	if _, ok := parents[system].(*parser.AwaitExpression); !ok {
+		t.Errorf("parent of %s: got %T", system, parents[system])
+	}
+	if _, ok := parents[program]; ok {
+		t.Errorf("the root has a parent")
+	}
+}
+
+func TestDictChildrenInSourceOrder(t *testing.T) {
+	dict := parseExpr(t, "{a: b, **c, d: e}\n")
+	var got []string
+	for _, child := range dict.Children() {
+		got = append(got, child.String())
+	}
+	if strings.Join(got, " ") != "a b c d e" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestFprint(t *testing.T) {
+	program := parseValid(t, "x = f(a, k=-1)\n")
+	want := `Program 1:1-1:15 {
+  Statements: [
+    AssignmentStatement 1:1-1:15 {
+      Targets: [
+        Identifier 1:1-1:2 {
+          Value: "x"
+        }
+      ]
+      Value: CallExpression 1:5-1:15 {
+        Function: Identifier 1:5-1:6 {
+          Value: "f"
+        }
+        Arguments: [
+          Identifier 1:7-1:8 {
+            Value: "a"
+          }
+        ]
+        Keywords: [
+          KeywordArgument 1:10-1:14 {
+            Name: Identifier 1:10-1:11 {
+              Value: "k"
+            }
+            Value: PrefixExpression 1:12-1:14 {
+              Operator: "-"
+              Right: IntegerLiteral 1:13-1:14 {
+                Value: 1
+              }
+            }
+          }
+        ]
+      }
+    }
+  ]
+}
+`
+	var out strings.Builder
+	if err := parser.Fprint(&out, program); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestUnparse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"(a + b) * c", "(a + b) * c"},
+		{"a - (b - c)", "a - (b - c)"},
+		{"(a - b) - c", "a - b - c"},
+		{"(a ** b) ** c", "(a ** b) ** c"},
+		{"(-2) ** -x", "(-2) ** (-x)"},
+		{"not (a and b)", "not (a and b)"},
+		{"(a < b) < c", "(a < b) < c"},
+		{"(a if b else c) if d else e", "(a if b else c) if d else e"},
+		{"f((yield), (x := 1), *a, k=(y := 2))", "f((yield), x := 1, *a, k=(y := 2))"},
+		{"f(x for x in y)", "f(x for x in y)"},
+		{"(1).real", "(1).real"},
+		{"a[1:2, ::3]", "a[1:2, ::3]"},
+		{"{**a, 'b': (lambda: 0)}", "{**a, 'b': lambda: 0}"},
+		{"((a, b), (c,))", "((a, b), (c,))"},
+		{"('a'\n 'b')", "'a' 'b'"},
+		{"b'a' rb'b'", "b'a' rb'b'"},
+		{"'a' f'{b}' \"c\"", "'a' f'{b}' \"c\""},
+	}
+
+	for _, tt := range tests {
+		if got := parser.Unparse(parseExpr(t, tt.input+"\n")); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	// Assignment expressions are never bare at statement level, and yield
+	// only as a statement or the value of an assignment.
+	statements := []string{
+		"x = (y := 10)\n",
+		"(w := 5)\n",
+		"a[(i := 0)] = b, (c := 1)\n",
+		"def f():\n    return (yield x)\n",
+		"def f():\n    x = yield y\n    x += yield\n    yield x\n",
+	}
+	for _, input := range statements {
+		if got := parser.Unparse(parseValid(t, input)); got != input {
+			t.Errorf("%q: got %q", input, got)
+		}
+	}
+}
+
+func TestUnparseRoundTrip(t *testing.T) {
+	inputs := []string{
+		walkInput,
+		"if a: pass\nelif b: x = 1; y = 2\nelse: pass\n",
+		"while (n := next(it)) is not None:\n    break\nelse:\n    pass\n",
+		"x = yield a, b\ndel a[0], b.c\nassert x, 'msg'\nglobal g\n",
+		"from ..a import (b as c, d)\nimport e.f as g, h\nfrom . import *\n",
+		"class C(B, metaclass=M):\n    def f(self, /, a: int = 1, *, b, **kw) -> None:\n        return a, *b\n",
+		"for i, (j, k) in enumerate(x): y += i,\n",
+		"try:\n    pass\nfinally:\n    raise\n",
+		"async def f():\n    async with a as (b, c):\n        async for x in y: await x\n",
+		"cur.execute('SELECT * '\n            'WHERE id=%s' % request.args['id'])\n",
+		"x = ('a' f'{b!r:>{w}}'\n     '}}{{' f'c')\n",
+		"class P(Protocol):\n    def m(self, x: Callable[..., T] = ...) -> T: ...\ny = a[..., 0]\n",
+		"x = (y := 10)\n",
+		"def f():\n    return (yield x)\n",
+		"(w := 5)\n",
+	}
+
+	for _, input := range inputs {
+		program := parseValid(t, input)
+		source := parser.Unparse(program)
+		reparsed, diags := parse(t, source)
+		if len(diags) > 0 {
+			t.Errorf("%q: unparsed source %q does not parse: %v", input, source, diags)
+			continue
+		}
+		if got, want := reparsed.String(), program.String(); got != want {
+			t.Errorf("%q: round trip through %q changed the tree:\ngot  %s\nwant %s", input, source, got, want)
+		}
+	}
+}
+
+func TestPython2(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"print\n", "print()\n"},
+		{"print x, y\n", "print(x, y)\n"},
+		{"print x,\n", "print(x, end=' ')\n"},
+		{"print >>sys.stderr, 'a' % b\n", "print('a' % b, file=sys.stderr)\n"},
+		{"print >>f\n", "print(file=f)\n"},
+		{"print(x)\n", "print(x)\n"},
+		{"if x: print x; exec s\n", "if x:\n    print(x)\n    exec(s)\n"},
+		{"exec code in g, l\n", "exec(code, g, l)\n"},
+		{"exec(code, g)\n", "exec(code, g)\n"},
+		{"y = `x, 1` + `z`\n", "y = repr((x, 1)) + repr(z)\n"},
+		{"a <> b\n", "a != b\n"},
+		{"x = ur'\\d' + u'\\d'\n", "x = r'\\d' + u'\\d'\n"},
+		{"os.chmod(p, 0755)\n", "os.chmod(p, 0o755)\n"},
+		{"try:\n    pass\nexcept (A, B), e:\n    pass\n", "try:\n    pass\nexcept (A, B) as e:\n    pass\n"},
+		{"nonlocal = async = 1\n", "nonlocal = async = 1\n"},
+		{"from __future__ import print_function\nprint('a', file=f)\n", "from __future__ import print_function\nprint('a', file=f)\n"},
+		{"raise E, 'msg'\n", "raise E('msg')\n"},
+		{"raise E, msg, tb\n", "raise E(msg).with_traceback(tb)\n"},
+		{"raise E, (a, b)\n", "raise E(a, b)\n"},
+		{"raise E, None, sys.exc_info()[2]\n", "raise E().with_traceback(sys.exc_info()[2])\n"},
+		{"x = 0xA2805140L + 10l + 0777L\n", "x = 0xA2805140 + 10 + 0o777\n"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.NewLexer(tt.input, "test.py"), parser.WithVersion(sasttoken.Python2))
+		program, err := p.ParseProgram()
+		if err != nil {
+			t.Errorf("%q: unexpected errors: %v", tt.input, err)
+			continue
+		}
+		if got := parser.Unparse(program); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	// A print statement is a call spanning the whole statement.
+	p := parser.New(lexer.NewLexer("print >>f, x,\n", "test.py"), parser.WithVersion(sasttoken.Python2))
+	program, _ := p.ParseProgram()
+	call, ok := parser.FindFirst[*parser.CallExpression](program)
+	if !ok || call.Pos().Column != 1 || call.End().Column != 14 {
+		t.Errorf("unexpected print call %v", call)
+	}
+
+	// Long literals keep their value, and \u is an escape in Unicode
+	// literals only.
+	p = parser.New(lexer.NewLexer("n = 0xA2805140L\ns = '\\u00e9'\nu = u'\\u00e9'\n", "test.py"), parser.WithVersion(sasttoken.Python2))
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := parser.FindFirst[*parser.IntegerLiteral](program); n == nil || n.Value != 0xA2805140 {
+		t.Errorf("unexpected long literal %v", n)
+	}
+	var values []string
+	parser.Inspect(program, func(node parser.Node) bool {
+		if s, ok := node.(*parser.StringLiteral); ok {
+			values = append(values, s.Value)
+		}
+		return true
+	})
+	if len(values) != 2 || values[0] != "\\u00e9" || values[1] != "é" {
+		t.Errorf("unexpected string values %q", values)
+	}
+
+	// The raise statement of Python 2 is not Python 3.
+	if _, err := parser.New(lexer.NewLexer("raise E, 'msg'\n", "test.py")).ParseProgram(); err == nil {
+		t.Error("expected an error for raise E, 'msg' in Python 3")
+	}
+}
+
+func TestDetectVersion(t *testing.T) {
+	tests := []struct {
+		input   string
+		version sasttoken.Version
+		errors  int
+	}{
+		{"print('a')\n", sasttoken.Python3, 0},
+		{"print 'a'\n", sasttoken.Python2, 0},
+		{"x = 0777\n", sasttoken.Python2, 0},
+		{"x = 10L\n", sasttoken.Python2, 0},
+		{"raise ValueError, 'bad'\n", sasttoken.Python2, 0},
+		{"print 'a'\nasync def f(): pass\n", sasttoken.Python3, 1},
+		{"x = (\n", sasttoken.Python3, 1},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.NewLexer(tt.input, "test.py"), parser.DetectVersion())
+		program, _ := p.ParseProgram()
+		if p.Version() != tt.version {
+			t.Errorf("%q: detected version %v, want %v", tt.input, p.Version(), tt.version)
+		}
+		if got := len(p.Diagnostics()); got != tt.errors {
+			t.Errorf("%q: got %d errors, want %d: %v", tt.input, got, tt.errors, p.Diagnostics())
+		}
+		if program == nil || len(program.Statements) == 0 {
+			t.Errorf("%q: no statements", tt.input)
+		}
+	}
+}
+
+func TestMatchPatterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string // The pattern's type, then its unparsed form
+	}{
+		{"1", "*parser.LiteralPattern 1"},
+		{"-1.5", "*parser.LiteralPattern -1.5"},
+		{"-1 + 2j", "*parser.LiteralPattern -1 + 2j"},
+		{"'a'", "*parser.LiteralPattern 'a'"},
+		{"None", "*parser.LiteralPattern None"},
+		{"x", "*parser.CapturePattern x"},
+		{"_", "*parser.WildcardPattern _"},
+		{"Color.RED", "*parser.ValuePattern Color.RED"},
+		{"(x)", "*parser.CapturePattern x"},
+		{"()", "*parser.SequencePattern ()"},
+		{"(x,)", "*parser.SequencePattern (x,)"},
+		{"[x, *rest]", "*parser.SequencePattern [x, *rest]"},
+		{"a, *_, b", "*parser.SequencePattern a, *_, b"},
+		{"{'id': id, Key.X: [_], **rest}", "*parser.MappingPattern {'id': id, Key.X: [_], **rest}"},
+		{"Point(0, y=y)", "*parser.ClassPattern Point(0, y=y)"},
+		{"ast.Call(func=ast.Name())", "*parser.ClassPattern ast.Call(func=ast.Name())"},
+		{"1 | 2 | x.y", "*parser.OrPattern 1 | 2 | x.y"},
+		{"(1 | 2) as n", "*parser.AsPattern (1 | 2) as n"},
+		{"[a as b, (c as d) | e]", "*parser.SequencePattern [a as b, (c as d) | e]"},
+	}
+
+	for _, tt := range tests {
+		input := "match s:\n    case " + tt.pattern + ":\n        pass\n"
+		program := parseValid(t, input)
+		pattern := program.Statements[0].(*parser.MatchStatement).Cases[0].Pattern
+		source := parser.Unparse(program)
+		got := fmt.Sprintf("%T %s", pattern, strings.TrimSuffix(strings.TrimPrefix(source, "match s:\n    case "), ":\n        pass\n"))
+		if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.pattern, got, tt.want)
+		}
+		if reparsed := parseValid(t, source); reparsed.String() != program.String() {
+			t.Errorf("%q: round trip through %q changed the tree", tt.pattern, source)
+		}
+
+		parser.InspectWithStack(program, func(n parser.Node, stack []parser.Node) bool {
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				if n.Pos().Before(parent.Pos()) || parent.End().Before(n.End()) {
+					t.Errorf("%q: %T %q lies outside its parent %T", tt.pattern, n, n, parent)
+				}
+			}
+			return true
+		})
+	}
+}
+
+func TestMatchPatternErrors(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"*a", "cannot use starred pattern here"},
+		{"[*a, *b]", "multiple starred names in sequence pattern"},
+		{"x as _", "cannot use '_' as a target"},
+		{"{x: 1}", "mapping pattern keys may only match literals and attribute lookups"},
+		{"{**r, 'a': 1}", "expected '}' after '**' pattern"},
+		{"P(x=1, 2)", "positional patterns follow keyword patterns"},
+		{"f'{x}'", "patterns may only match literals and attribute lookups"},
+		{"1 + 2", "expected imaginary number"},
+		{"a + 1", "expected ':'"},
+	}
+
+	for _, tt := range tests {
+		input := "match s:\n    case " + tt.pattern + ":\n        pass\n"
+		_, diags := parse(t, input)
+		if len(diags) == 0 || !strings.Contains(diags[0].Message, tt.want) {
+			t.Errorf("%q: got %v, want %q", tt.pattern, diags, tt.want)
+		}
+	}
+}
+
+func TestMatchSoftKeywords(t *testing.T) {
+	input := "match = re.match(p, s)\nmatch(x)\ncase = match.group(1)\nmatch match:\n    case case:\n        pass\n"
+	program := parseValid(t, input)
+	if len(program.Statements) != 4 {
+		t.Fatalf("expected 4 statements, got %d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[3].(*parser.MatchStatement)
+	if !ok || stmt.Subject.String() != "match" || stmt.Cases[0].Pattern.String() != "case" {
+		t.Errorf("unexpected match statement %v", program.Statements[3])
+	}
+}
+
+func TestAnnotationsAndTypeParams(t *testing.T) {
+	input := `x: int = 5
+self.conn: Connection | None
+cache[key]: dict[str, list[int]] = {}
+type Pair[T: (int, str), *Ts] = tuple[T, *Ts]
+def handle[T, **P](request: HttpRequest, sql: LiteralString) -> T | None:
+    pass
+class Box[T = int](Generic[T]):
+    value: T
+`
+	program := parseValid(t, input)
+
+	want := []string{
+		"x: int = 5",
+		"self.conn: (Connection | None)",
+		"cache[key]: dict[str, list[int]] = {}",
+		"type Pair[T: (int, str), *Ts] = tuple[T, *Ts]",
+		"def handle[T, **P](request: HttpRequest, sql: LiteralString) -> (T | None): pass",
+		"class Box[T = int](Generic[T]): value: T",
+	}
+	for i, stmt := range program.Statements {
+		if got := stmt.String(); got != want[i] {
+			t.Errorf("statement %d: got %q, want %q", i, got, want[i])
+		}
+	}
+
+	ann := program.Statements[1].(*parser.AnnAssignStatement)
+	union, ok := ann.Annotation.(*parser.InfixExpression)
+	if !ok || union.Operator != "|" || ann.Value != nil {
+		t.Errorf("annotation: got %#v", ann.Annotation)
+	}
+
+	alias := program.Statements[3].(*parser.TypeAliasStatement)
+	if len(alias.TypeParams) != 2 || alias.TypeParams[0].Bound == nil || alias.TypeParams[1].Kind != parser.TypeVarTuple {
+		t.Errorf("type params: got %v", alias.TypeParams)
+	}
+
+	fn := program.Statements[4].(*parser.FunctionDef)
+	if fn.TypeParams[1].Kind != parser.ParamSpec || fn.Parameters[1].Annotation.String() != "LiteralString" {
+		t.Errorf("function: got %v", fn)
+	}
+
+	source := parser.Unparse(program)
+	if reparsed := parseValid(t, source); reparsed.String() != program.String() {
+		t.Errorf("round trip through %q changed the tree", source)
+	}
+
+	parser.InspectWithStack(program, func(n parser.Node, stack []parser.Node) bool {
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			if n.Pos().Before(parent.Pos()) || parent.End().Before(n.End()) {
+				t.Errorf("%T %q lies outside its parent %T", n, n, parent)
+			}
+		}
+		return true
+	})
+}
+
+func TestAnnotationErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"a, b: int\n", "only single target (not tuple) can be annotated"},
+		{"f(): int\n", "illegal target for annotation"},
+		{"type X[] = int\n", "expected type parameter"},
+		{"def f[*Ts: int](): pass\n", "cannot use bound with TypeVarTuple"},
+	}
+
+	for _, tt := range tests {
+		_, diags := parse(t, tt.input)
+		if len(diags) == 0 || !strings.Contains(diags[0].Message, tt.want) {
+			t.Errorf("%q: got %v, want %q", tt.input, diags, tt.want)
+		}
+	}
+}
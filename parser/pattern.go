@@ -0,0 +1,399 @@
+package parser
+
+import (
+	sasttoken "github.com/coiloffaraday/python_sast/token"
+)
+
+// parsePatterns parses the pattern of a case clause, which may be a bare
+// sequence such as "first, *rest".
+func (p *Parser) parsePatterns() (Pattern, error) {
+	start := p.curToken
+	first, err := p.parseMaybeStarPattern()
+	if err != nil {
+		return nil, err
+	}
+	if !p.curTokenIs(sasttoken.COMMA) {
+		if _, ok := first.(*StarPattern); ok {
+			return nil, p.errorf("cannot use starred pattern here")
+		}
+		return first, nil
+	}
+
+	seq := &SequencePattern{Token: start, Patterns: []Pattern{first}}
+	for p.curTokenIs(sasttoken.COMMA) {
+		p.nextToken()
+		if !p.canStartPattern() {
+			break
+		}
+		pattern, err := p.parseMaybeStarPattern()
+		if err != nil {
+			return nil, err
+		}
+		seq.Patterns = append(seq.Patterns, pattern)
+	}
+
+	return seq, p.checkStarPatterns(seq)
+}
+
+// parsePattern parses an OR pattern with an optional "as" target.
+func (p *Parser) parsePattern() (Pattern, error) {
+	pattern, err := p.parseOrPattern()
+	if err != nil || !p.curTokenIs(sasttoken.AS) {
+		return pattern, err
+	}
+
+	as := &AsPattern{Token: p.curToken, Pattern: pattern}
+	p.nextToken()
+	if !p.curTokenIs(sasttoken.IDENT) {
+		return nil, p.expectedError("name")
+	}
+	if p.curToken.Literal == "_" {
+		return nil, p.errorf("cannot use '_' as a target")
+	}
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	as.Name = name.(*Identifier)
+
+	return as, nil
+}
+
+func (p *Parser) parseOrPattern() (Pattern, error) {
+	first, err := p.parseClosedPattern()
+	if err != nil || !p.curTokenIs(sasttoken.PIPE) {
+		return first, err
+	}
+
+	or := &OrPattern{Token: p.curToken, Patterns: []Pattern{first}}
+	for p.curTokenIs(sasttoken.PIPE) {
+		p.nextToken()
+		pattern, err := p.parseClosedPattern()
+		if err != nil {
+			return nil, err
+		}
+		or.Patterns = append(or.Patterns, pattern)
+	}
+
+	return or, nil
+}
+
+// parseClosedPattern parses a pattern that needs no parentheses to be an
+// alternative of an OR pattern.
+func (p *Parser) parseClosedPattern() (Pattern, error) {
+	switch p.curToken.Type {
+	case sasttoken.IDENT:
+		return p.parseNamePattern()
+	case sasttoken.LPAREN, sasttoken.LBRACKET:
+		return p.parseSequencePattern()
+	case sasttoken.LBRACE:
+		return p.parseMappingPattern()
+	default:
+		start := p.curToken
+		value, err := p.parseLiteralPatternValue()
+		if err != nil {
+			return nil, err
+		}
+		return &LiteralPattern{Token: start, Value: value}, nil
+	}
+}
+
+// parseMaybeStarPattern parses an element of a sequence pattern, which may
+// be "*name" or "*_".
+func (p *Parser) parseMaybeStarPattern() (Pattern, error) {
+	if !p.curTokenIs(sasttoken.ASTERISK) {
+		return p.parsePattern()
+	}
+
+	star := &StarPattern{Token: p.curToken}
+	p.nextToken()
+	if !p.curTokenIs(sasttoken.IDENT) {
+		return nil, p.expectedError("name")
+	}
+	if p.curToken.Literal == "_" {
+		star.Pattern = &WildcardPattern{Token: p.curToken}
+		p.nextToken()
+		return star, nil
+	}
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	star.Pattern = &CapturePattern{Token: name.(*Identifier).Token, Name: name.(*Identifier)}
+
+	return star, nil
+}
+
+// parseNamePattern parses a pattern starting with a name: a capture, the
+// wildcard, a dotted value or a class pattern.
+func (p *Parser) parseNamePattern() (Pattern, error) {
+	start := p.curToken
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	if !p.curTokenIs(sasttoken.DOT) && !p.curTokenIs(sasttoken.LPAREN) {
+		if start.Literal == "_" {
+			return &WildcardPattern{Token: start}, nil
+		}
+		return &CapturePattern{Token: start, Name: name.(*Identifier)}, nil
+	}
+
+	var value Expression = name
+	for p.curTokenIs(sasttoken.DOT) {
+		if value, err = p.parseAttributeExpression(value); err != nil {
+			return nil, err
+		}
+	}
+	if p.curTokenIs(sasttoken.LPAREN) {
+		return p.parseClassPattern(value)
+	}
+
+	return &ValuePattern{Token: start, Value: value}, nil
+}
+
+// parseClassPattern parses the arguments of a class pattern: positional
+// patterns followed by "name=pattern" keyword patterns.
+func (p *Parser) parseClassPattern(class Expression) (Pattern, error) {
+	pattern := &ClassPattern{Token: p.curToken, Class: class}
+	p.nextToken()
+
+	for !p.curTokenIs(sasttoken.RPAREN) {
+		if p.curTokenIs(sasttoken.IDENT) && p.peekTokenIs(sasttoken.ASSIGN) {
+			keyword := &KeywordPattern{Token: p.curToken}
+			name, err := p.parseIdentifier()
+			if err != nil {
+				return nil, err
+			}
+			keyword.Name = name.(*Identifier)
+			p.nextToken()
+			if keyword.Pattern, err = p.parsePattern(); err != nil {
+				return nil, err
+			}
+			pattern.Keywords = append(pattern.Keywords, keyword)
+		} else {
+			if len(pattern.Keywords) > 0 {
+				return nil, p.errorf("positional patterns follow keyword patterns")
+			}
+			positional, err := p.parsePattern()
+			if err != nil {
+				return nil, err
+			}
+			pattern.Patterns = append(pattern.Patterns, positional)
+		}
+
+		if !p.curTokenIs(sasttoken.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	rparen, err := p.expect(sasttoken.RPAREN)
+	if err != nil {
+		return nil, err
+	}
+	pattern.Rparen = rparen
+
+	return pattern, nil
+}
+
+// parseSequencePattern parses a bracketed or parenthesized sequence
+// pattern. A single parenthesized pattern without a comma only groups.
+func (p *Parser) parseSequencePattern() (Pattern, error) {
+	seq := &SequencePattern{Token: p.curToken}
+	closing := sasttoken.TokenType(sasttoken.RBRACKET)
+	if p.curTokenIs(sasttoken.LPAREN) {
+		closing = sasttoken.RPAREN
+	}
+	p.nextToken()
+
+	comma := false
+	for !p.curTokenIs(closing) {
+		pattern, err := p.parseMaybeStarPattern()
+		if err != nil {
+			return nil, err
+		}
+		seq.Patterns = append(seq.Patterns, pattern)
+		if !p.curTokenIs(sasttoken.COMMA) {
+			break
+		}
+		comma = true
+		p.nextToken()
+	}
+
+	close, err := p.expect(closing)
+	if err != nil {
+		return nil, err
+	}
+	seq.Close = close
+
+	if closing == sasttoken.RPAREN && len(seq.Patterns) == 1 && !comma {
+		if _, ok := seq.Patterns[0].(*StarPattern); !ok {
+			return seq.Patterns[0], nil
+		}
+	}
+
+	return seq, p.checkStarPatterns(seq)
+}
+
+// checkStarPatterns rejects a sequence pattern with more than one star.
+func (p *Parser) checkStarPatterns(seq *SequencePattern) error {
+	stars := 0
+	for _, pattern := range seq.Patterns {
+		if _, ok := pattern.(*StarPattern); ok {
+			stars++
+			if stars > 1 {
+				return p.errorf("multiple starred names in sequence pattern")
+			}
+		}
+	}
+	return nil
+}
+
+// parseMappingPattern parses "{key: pattern, ..., **rest}".
+func (p *Parser) parseMappingPattern() (Pattern, error) {
+	mapping := &MappingPattern{Token: p.curToken}
+	p.nextToken()
+
+	for !p.curTokenIs(sasttoken.RBRACE) {
+		if mapping.Rest != nil {
+			return nil, p.expectedError("'}' after '**' pattern")
+		}
+
+		if p.curTokenIs(sasttoken.POWER) {
+			p.nextToken()
+			if !p.curTokenIs(sasttoken.IDENT) {
+				return nil, p.expectedError("name")
+			}
+			if p.curToken.Literal == "_" {
+				return nil, p.errorf("cannot use '_' as a target")
+			}
+			rest, err := p.parseIdentifier()
+			if err != nil {
+				return nil, err
+			}
+			mapping.Rest = rest.(*Identifier)
+		} else {
+			key, err := p.parseMappingKey()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(sasttoken.COLON); err != nil {
+				return nil, err
+			}
+			pattern, err := p.parsePattern()
+			if err != nil {
+				return nil, err
+			}
+			mapping.Keys = append(mapping.Keys, key)
+			mapping.Patterns = append(mapping.Patterns, pattern)
+		}
+
+		if !p.curTokenIs(sasttoken.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	rbrace, err := p.expect(sasttoken.RBRACE)
+	if err != nil {
+		return nil, err
+	}
+	mapping.Rbrace = rbrace
+
+	return mapping, nil
+}
+
+// parseMappingKey parses the key of a mapping pattern, a literal or a
+// dotted name.
+func (p *Parser) parseMappingKey() (Expression, error) {
+	if !p.curTokenIs(sasttoken.IDENT) {
+		return p.parseLiteralPatternValue()
+	}
+
+	pattern, err := p.parseNamePattern()
+	if err != nil {
+		return nil, err
+	}
+	value, ok := pattern.(*ValuePattern)
+	if !ok {
+		return nil, p.errorf("mapping pattern keys may only match literals and attribute lookups")
+	}
+	return value.Value, nil
+}
+
+// parseLiteralPatternValue parses the value of a literal pattern: a
+// string, None, True, False, a signed number or a complex number.
+func (p *Parser) parseLiteralPatternValue() (Expression, error) {
+	switch p.curToken.Type {
+	case sasttoken.STRING, sasttoken.NONE, sasttoken.TRUE, sasttoken.FALSE:
+		value, err := p.prefixParseFns[p.curToken.Type]()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := value.(*JoinedStr); ok {
+			return nil, p.errorf("patterns may only match literals and attribute lookups")
+		}
+		return value, nil
+	case sasttoken.MINUS, sasttoken.INT, sasttoken.FLOAT, sasttoken.IMAGINARY:
+	default:
+		return nil, p.expectedError("pattern")
+	}
+
+	value, err := p.parseSignedNumber()
+	if err != nil {
+		return nil, err
+	}
+	if p.curTokenIs(sasttoken.PLUS) || p.curTokenIs(sasttoken.MINUS) {
+		complex := &InfixExpression{Token: p.curToken, Left: value, Operator: p.curToken.Literal}
+		p.nextToken()
+		if !p.curTokenIs(sasttoken.IMAGINARY) {
+			return nil, p.expectedError("imaginary number")
+		}
+		if complex.Right, err = p.parseImaginaryLiteral(); err != nil {
+			return nil, err
+		}
+		value = complex
+	}
+
+	return value, nil
+}
+
+// parseSignedNumber parses a number with an optional minus sign.
+func (p *Parser) parseSignedNumber() (Expression, error) {
+	var minus *PrefixExpression
+	if p.curTokenIs(sasttoken.MINUS) {
+		minus = &PrefixExpression{Token: p.curToken, Operator: "-"}
+		p.nextToken()
+	}
+
+	var number Expression
+	var err error
+	switch p.curToken.Type {
+	case sasttoken.INT:
+		number, err = p.parseIntegerLiteral()
+	case sasttoken.FLOAT:
+		number, err = p.parseFloatLiteral()
+	case sasttoken.IMAGINARY:
+		number, err = p.parseImaginaryLiteral()
+	default:
+		return nil, p.expectedError("number")
+	}
+	if err != nil || minus == nil {
+		return number, err
+	}
+	minus.Right = number
+
+	return minus, nil
+}
+
+// canStartPattern reports whether the current token can begin a pattern.
+func (p *Parser) canStartPattern() bool {
+	switch p.curToken.Type {
+	case sasttoken.IDENT, sasttoken.STRING, sasttoken.NONE, sasttoken.TRUE, sasttoken.FALSE,
+		sasttoken.MINUS, sasttoken.INT, sasttoken.FLOAT, sasttoken.IMAGINARY,
+		sasttoken.LPAREN, sasttoken.LBRACKET, sasttoken.LBRACE, sasttoken.ASTERISK:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"strings"
+
+	sasttoken "github.com/coiloffaraday/python_sast/token"
+)
+
+var (
+	tokenType  = reflect.TypeOf(sasttoken.Token{})
+	tokensType = reflect.TypeOf([]sasttoken.Token(nil))
+	bigIntPtr  = reflect.TypeOf((*big.Int)(nil))
+)
+
+// Fprint writes an indented dump of the tree rooted at node to w, in the
+// spirit of Python's ast.dump. Each node is shown with its type and the
+// line:column span from Pos to End. Tokens, nil fields, empty lists and
+// zero values are left out.
+func Fprint(w io.Writer, node Node) error {
+	var out strings.Builder
+	dumpNode(&out, reflect.ValueOf(node), 0)
+	out.WriteString("\n")
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// Dump returns the output of Fprint as a string.
+func Dump(node Node) string {
+	var out strings.Builder
+	Fprint(&out, node)
+	return out.String()
+}
+
+func dumpNode(out *strings.Builder, v reflect.Value, depth int) {
+	n := v.Interface().(Node)
+	fmt.Fprintf(out, "%s %s-%s {", v.Elem().Type().Name(), formatPosition(n.Pos()), formatPosition(n.End()))
+
+	var fields strings.Builder
+	dumpFields(&fields, v.Elem(), depth+1)
+	if fields.Len() == 0 {
+		out.WriteString("}")
+		return
+	}
+	out.WriteString(fields.String())
+	out.WriteString("\n")
+	out.WriteString(strings.Repeat("  ", depth))
+	out.WriteString("}")
+}
+
+func dumpFields(out *strings.Builder, s reflect.Value, depth int) {
+	for i := 0; i < s.NumField(); i++ {
+		field, value := s.Type().Field(i), s.Field(i)
+		if field.Anonymous {
+			dumpFields(out, value, depth)
+			continue
+		}
+		if field.Type == tokenType || field.Type == tokensType || value.IsZero() {
+			continue
+		}
+		if value.Kind() == reflect.Slice && value.Len() == 0 {
+			continue
+		}
+		out.WriteString("\n")
+		out.WriteString(strings.Repeat("  ", depth))
+		out.WriteString(field.Name)
+		out.WriteString(": ")
+		dumpValue(out, value, depth)
+	}
+}
+
+func dumpValue(out *strings.Builder, v reflect.Value, depth int) {
+	switch {
+	case v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer:
+		if v.IsNil() {
+			out.WriteString("nil")
+			return
+		}
+		if v.Type() == bigIntPtr {
+			out.WriteString(v.Interface().(*big.Int).String())
+			return
+		}
+		if v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		dumpNode(out, v, depth)
+	case v.Kind() == reflect.Slice:
+		out.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			out.WriteString("\n")
+			out.WriteString(strings.Repeat("  ", depth+1))
+			dumpValue(out, v.Index(i), depth+1)
+		}
+		out.WriteString("\n")
+		out.WriteString(strings.Repeat("  ", depth))
+		out.WriteString("]")
+	case v.Kind() == reflect.String:
+		fmt.Fprintf(out, "%q", v.String())
+	case v.Kind() == reflect.Uint8:
+		fmt.Fprintf(out, "%q", rune(v.Uint()))
+	default:
+		fmt.Fprintf(out, "%v", v.Interface())
+	}
+}
+
+func formatPosition(pos sasttoken.Position) string {
+	return fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+}
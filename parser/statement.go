@@ -1,430 +1,1420 @@
 package parser
 
 import (
-	"fmt"
+	"strings"
 
 	sasttoken "github.com/coiloffaraday/python_sast/token"
 )
 
-func (p *Parser) parseStatement() (Node, error) {
+// augmentedAssignments holds the operators of augmented assignments.
+var augmentedAssignments = map[sasttoken.TokenType]bool{
+	sasttoken.PLUS_ASSIGN:      true,
+	sasttoken.MINUS_ASSIGN:     true,
+	sasttoken.ASTERISK_ASSIGN:  true,
+	sasttoken.POWER_ASSIGN:     true,
+	sasttoken.SLASH_ASSIGN:     true,
+	sasttoken.FLOORDIV_ASSIGN:  true,
+	sasttoken.AT_ASSIGN:        true,
+	sasttoken.MOD_ASSIGN:       true,
+	sasttoken.AMPERSAND_ASSIGN: true,
+	sasttoken.PIPE_ASSIGN:      true,
+	sasttoken.CARET_ASSIGN:     true,
+	sasttoken.LSHIFT_ASSIGN:    true,
+	sasttoken.RSHIFT_ASSIGN:    true,
+}
+
+// parseStatement parses one statement. It starts at the statement's first
+// token and consumes the NEWLINE or ';' ending a simple statement, or the
+// whole block of a compound one.
+func (p *Parser) parseStatement() (Statement, error) {
 	switch p.curToken.Type {
 	case sasttoken.DEF:
-		return p.parseFunction()
+		return p.parseFunction(nil)
+	case sasttoken.CLASS:
+		return p.parseClass(nil)
+	case sasttoken.AT:
+		return p.parseDecorated()
+	case sasttoken.ASYNC:
+		return p.parseAsyncStatement(nil)
+	case sasttoken.IF:
+		return p.parseIfStatement()
+	case sasttoken.FOR:
+		return p.parseForStatement(sasttoken.Token{})
+	case sasttoken.WHILE:
+		return p.parseWhileStatement()
+	case sasttoken.TRY:
+		return p.parseTryStatement()
+	case sasttoken.WITH:
+		return p.parseWithStatement(sasttoken.Token{})
+	case sasttoken.MATCH:
+		return p.parseMatchStatement()
+	case sasttoken.INDENT:
+		return nil, p.errorf("unexpected indent")
+	default:
+		stmt, err := p.parseSimpleStatement()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.endSimpleStatement(); err != nil {
+			return nil, err
+		}
+		return stmt, nil
+	}
+}
+
+// parseSimpleStatement parses a statement that fits on one logical line,
+// without the NEWLINE or ';' that ends it.
+func (p *Parser) parseSimpleStatement() (Statement, error) {
+	switch p.curToken.Type {
 	case sasttoken.RETURN:
 		return p.parseReturnStatement()
 	case sasttoken.IMPORT:
 		return p.parseImportStatement()
 	case sasttoken.FROM:
 		return p.parseImportFromStatement()
-	case sasttoken.IDENT:
-		return p.parseIdentifierStatement()
-	case sasttoken.IF:
-		return p.parseIfStatement()
-	case sasttoken.ELIF:
-		return p.parseElifStatement()
-	case sasttoken.ELSE:
-		return p.parseElseStatement()
-	case sasttoken.FOR:
-		return p.parseForStatement()
-	case sasttoken.WHILE:
-		return p.parseWhileStatement()
 	case sasttoken.PASS:
 		return p.parsePassStatement()
 	case sasttoken.BREAK:
 		return p.parseBreakStatement()
 	case sasttoken.CONTINUE:
 		return p.parseContinueStatement()
+	case sasttoken.RAISE:
+		return p.parseRaiseStatement()
+	case sasttoken.ASSERT:
+		return p.parseAssertStatement()
+	case sasttoken.DEL:
+		return p.parseDelStatement()
+	case sasttoken.GLOBAL:
+		return p.parseGlobalStatement()
+	case sasttoken.NONLOCAL:
+		return p.parseNonlocalStatement()
+	case sasttoken.TYPE:
+		return p.parseTypeAliasStatement()
+	case sasttoken.PRINT:
+		return p.parsePrintStatement()
+	case sasttoken.EXEC:
+		return p.parseExecStatement()
 	default:
-		expression, err := p.parseExpressionStatement()
+		return p.parseExpressionStatement()
+	}
+}
+
+// endSimpleStatement consumes the ';' or NEWLINE after a simple statement.
+// A ';' that ends the line takes the NEWLINE with it.
+func (p *Parser) endSimpleStatement() error {
+	if p.curTokenIs(sasttoken.SEMICOLON) {
+		p.nextToken()
+		if !p.curTokenIs(sasttoken.NEWLINE) {
+			return nil
+		}
+	}
+	if p.curTokenIs(sasttoken.EOF) {
+		return nil
+	}
+	_, err := p.expect(sasttoken.NEWLINE)
+	return err
+}
+
+// atSimpleStatementEnd reports whether the current token ends a simple
+// statement.
+func (p *Parser) atSimpleStatementEnd() bool {
+	switch p.curToken.Type {
+	case sasttoken.NEWLINE, sasttoken.SEMICOLON, sasttoken.EOF:
+		return true
+	}
+	return false
+}
+
+// parseDecorated parses the decorators in front of a function or class
+// definition, and the definition.
+func (p *Parser) parseDecorated() (Statement, error) {
+	var decorators []*Decorator
+
+	for p.curTokenIs(sasttoken.AT) {
+		decorator := &Decorator{Token: p.curToken}
+		p.nextToken()
+		expr, err := p.parseExpression(LOWEST)
 		if err != nil {
 			return nil, err
 		}
-		return expression, nil
+		decorator.Expression = expr
+		if _, err := p.expect(sasttoken.NEWLINE); err != nil {
+			return nil, err
+		}
+		decorators = append(decorators, decorator)
+	}
+
+	switch p.curToken.Type {
+	case sasttoken.DEF:
+		return p.parseFunction(decorators)
+	case sasttoken.CLASS:
+		return p.parseClass(decorators)
+	case sasttoken.ASYNC:
+		if p.peekTokenIs(sasttoken.DEF) {
+			return p.parseAsyncStatement(decorators)
+		}
+	}
+	return nil, p.expectedError("function or class definition")
+}
+
+// parseAsyncStatement parses "async def", "async for" or "async with".
+func (p *Parser) parseAsyncStatement(decorators []*Decorator) (Statement, error) {
+	async := p.curToken
+	p.nextToken()
+
+	switch p.curToken.Type {
+	case sasttoken.DEF:
+		fn, err := p.parseFunctionDef(decorators)
+		if err != nil {
+			return nil, err
+		}
+		return &AsyncFunctionDef{FunctionDef: *fn, Async: async}, nil
+	case sasttoken.FOR:
+		return p.parseForStatement(async)
+	case sasttoken.WITH:
+		return p.parseWithStatement(async)
+	}
+	return nil, p.expectedError("'def', 'for' or 'with' after 'async'")
+}
+
+func (p *Parser) parseFunction(decorators []*Decorator) (Statement, error) {
+	fn, err := p.parseFunctionDef(decorators)
+	if err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
+
+func (p *Parser) parseFunctionDef(decorators []*Decorator) (*FunctionDef, error) {
+	fn := &FunctionDef{Token: p.curToken, Decorators: decorators}
+	p.nextToken()
+
+	if !p.curTokenIs(sasttoken.IDENT) {
+		return nil, p.expectedError("function name")
+	}
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	fn.Name = name.(*Identifier)
+
+	if p.curTokenIs(sasttoken.LBRACKET) {
+		if fn.TypeParams, err = p.parseTypeParams(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.expect(sasttoken.LPAREN); err != nil {
+		return nil, err
+	}
+	params, err := p.parseParameters(sasttoken.RPAREN, true)
+	if err != nil {
+		return nil, err
+	}
+	fn.Parameters = params
+	if _, err := p.expect(sasttoken.RPAREN); err != nil {
+		return nil, err
+	}
+
+	if p.curTokenIs(sasttoken.ARROW) {
+		p.nextToken()
+		returns, err := p.parseExpression(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		fn.Returns = returns
+	}
+
+	if _, err := p.expect(sasttoken.COLON); err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseBlockStatement()
+	if err != nil {
+		return nil, err
+	}
+	fn.Body = body
+
+	return fn, nil
+}
+
+func (p *Parser) parseClass(decorators []*Decorator) (Statement, error) {
+	class := &ClassDef{Token: p.curToken, Decorators: decorators}
+	p.nextToken()
+
+	if !p.curTokenIs(sasttoken.IDENT) {
+		return nil, p.expectedError("class name")
+	}
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	class.Name = name.(*Identifier)
+
+	if p.curTokenIs(sasttoken.LBRACKET) {
+		if class.TypeParams, err = p.parseTypeParams(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.curTokenIs(sasttoken.LPAREN) {
+		bases, keywords, _, err := p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+		class.Bases = bases
+		class.Keywords = keywords
+	}
+
+	if _, err := p.expect(sasttoken.COLON); err != nil {
+		return nil, err
+	}
+	body, err := p.parseBlockStatement()
+	if err != nil {
+		return nil, err
+	}
+	class.Body = body
+
+	return class, nil
+}
+
+// parseTypeParams parses the bracketed type parameter list of a generic
+// class, function or type alias.
+func (p *Parser) parseTypeParams() ([]*TypeParam, error) {
+	p.nextToken()
+
+	var params []*TypeParam
+	for !p.curTokenIs(sasttoken.RBRACKET) || len(params) == 0 {
+		param, err := p.parseTypeParam()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+		if !p.curTokenIs(sasttoken.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if _, err := p.expect(sasttoken.RBRACKET); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+func (p *Parser) parseTypeParam() (*TypeParam, error) {
+	param := &TypeParam{Token: p.curToken, Kind: TypeVar}
+	switch p.curToken.Type {
+	case sasttoken.ASTERISK:
+		param.Kind = TypeVarTuple
+		p.nextToken()
+	case sasttoken.POWER:
+		param.Kind = ParamSpec
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(sasttoken.IDENT) {
+		return nil, p.expectedError("type parameter")
+	}
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	param.Name = name.(*Identifier)
+
+	if p.curTokenIs(sasttoken.COLON) {
+		if param.Kind != TypeVar {
+			return nil, p.errorf("cannot use bound with %s", param.Kind)
+		}
+		p.nextToken()
+		if param.Bound, err = p.parseExpression(LOWEST); err != nil {
+			return nil, err
+		}
+	}
+	if p.curTokenIs(sasttoken.ASSIGN) {
+		p.nextToken()
+		if param.Default, err = p.parseExpression(LOWEST); err != nil {
+			return nil, err
+		}
+	}
+
+	return param, nil
+}
+
+func (p *Parser) parseReturnStatement() (Statement, error) {
+	stmt := &ReturnStatement{Token: p.curToken}
+	p.nextToken()
+
+	if p.atSimpleStatementEnd() {
+		return stmt, nil
+	}
+
+	retValue, err := p.parseStatementExpressionList()
+	if err != nil {
+		return nil, err
+	}
+	stmt.ReturnValue = retValue
+
+	return stmt, nil
+}
+
+func (p *Parser) parseRaiseStatement() (Statement, error) {
+	stmt := &RaiseStatement{Token: p.curToken}
+	p.nextToken()
+
+	if p.atSimpleStatementEnd() {
+		return stmt, nil
+	}
+
+	exception, err := p.parseExpression(LOWEST)
+	if err != nil {
+		return nil, err
+	}
+	stmt.Exception = exception
+
+	if p.version == sasttoken.Python2 && p.curTokenIs(sasttoken.COMMA) {
+		if stmt.Exception, err = p.parsePython2Raise(exception); err != nil {
+			return nil, err
+		}
+		return stmt, nil
+	}
+
+	if p.curTokenIs(sasttoken.FROM) {
+		p.nextToken()
+		cause, err := p.parseExpression(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		stmt.Cause = cause
+	}
+
+	return stmt, nil
+}
+
+// parsePython2Raise parses the rest of a Python 2 "raise E, V, T"
+// statement, from the first comma, into the exception that Python 3 code
+// would raise: E(V).with_traceback(T). As in Python 2, a parenthesized
+// tuple V holds the arguments of E and None stands for no argument.
+func (p *Parser) parsePython2Raise(exception Expression) (Expression, error) {
+	call := &CallExpression{Token: p.curToken, Function: exception}
+	p.nextToken()
+
+	value, err := p.parseExpression(NAMED)
+	if err != nil {
+		return nil, err
+	}
+	if tuple, ok := value.(*TupleLiteral); ok && tuple.Rparen.Type != "" {
+		call.Arguments = tuple.Elements
+	} else if _, ok := value.(*NoneLiteral); !ok {
+		call.Arguments = []Expression{value}
+	}
+	call.Rparen = p.prevToken
+
+	if !p.curTokenIs(sasttoken.COMMA) {
+		return call, nil
+	}
+	comma := p.curToken
+	p.nextToken()
+
+	traceback, err := p.parseExpression(NAMED)
+	if err != nil {
+		return nil, err
+	}
+	method := &AttributeExpression{Token: comma, Object: call, Attribute: &Identifier{Token: comma, Value: "with_traceback"}}
+	return &CallExpression{Token: comma, Function: method, Arguments: []Expression{traceback}, Rparen: p.prevToken}, nil
+}
+
+func (p *Parser) parseAssertStatement() (Statement, error) {
+	stmt := &AssertStatement{Token: p.curToken}
+	p.nextToken()
+
+	test, err := p.parseExpression(LOWEST)
+	if err != nil {
+		return nil, err
+	}
+	stmt.Test = test
+
+	if p.curTokenIs(sasttoken.COMMA) {
+		p.nextToken()
+		msg, err := p.parseExpression(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		stmt.Message = msg
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) parseDelStatement() (Statement, error) {
+	stmt := &DelStatement{Token: p.curToken}
+	p.nextToken()
+
+	targets, err := p.parseExpressionList(LOWEST)
+	if err != nil {
+		return nil, err
+	}
+	if tuple, ok := targets.(*TupleLiteral); ok && tuple.Rparen.Type == "" {
+		stmt.Targets = tuple.Elements
+	} else {
+		stmt.Targets = []Expression{targets}
+	}
+	for _, target := range stmt.Targets {
+		if err := p.checkTarget(target, "delete", false); err != nil {
+			return nil, err
+		}
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) parseGlobalStatement() (Statement, error) {
+	stmt := &GlobalStatement{Token: p.curToken}
+	p.nextToken()
+
+	names, err := p.parseIdentifierList()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Names = names
+
+	return stmt, nil
+}
+
+func (p *Parser) parseNonlocalStatement() (Statement, error) {
+	stmt := &NonlocalStatement{Token: p.curToken}
+	p.nextToken()
+
+	names, err := p.parseIdentifierList()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Names = names
+
+	return stmt, nil
+}
+
+// parsePrintStatement parses a Python 2 print statement into the call of the
+// print function that replaces it, so "print >>f, x," becomes
+// print(x, file=f, end=' ').
+func (p *Parser) parsePrintStatement() (Statement, error) {
+	stmt := &ExpressionStatement{Token: p.curToken}
+	call := &CallExpression{Token: p.curToken, Function: &Identifier{Token: p.curToken, Value: "print"}}
+	stmt.Expression = call
+	p.nextToken()
+
+	if p.curTokenIs(sasttoken.RSHIFT) {
+		redirect := p.curToken
+		p.nextToken()
+		file, err := p.parseExpression(NAMED)
+		if err != nil {
+			return nil, err
+		}
+		call.Keywords = append(call.Keywords, &KeywordArgument{Token: redirect, Name: &Identifier{Token: redirect, Value: "file"}, Value: file})
+		if p.curTokenIs(sasttoken.COMMA) {
+			p.nextToken()
+			if !p.canStartExpression() {
+				return nil, p.expectedError("expression")
+			}
+		}
+	}
+
+	for p.canStartExpression() {
+		value, err := p.parseExpression(NAMED)
+		if err != nil {
+			return nil, err
+		}
+		call.Arguments = append(call.Arguments, value)
+		if !p.curTokenIs(sasttoken.COMMA) {
+			break
+		}
+		comma := p.curToken
+		p.nextToken()
+		if !p.canStartExpression() {
+			// A trailing comma suppresses the newline.
+			space := comma
+			space.Type, space.Literal, space.Value = sasttoken.STRING, "' '", " "
+			call.Keywords = append(call.Keywords, &KeywordArgument{Token: comma, Name: &Identifier{Token: comma, Value: "end"}, Value: &StringLiteral{Token: space, Value: " "}})
+		}
+	}
+	call.Rparen = p.prevToken
+
+	return stmt, nil
+}
+
+// parseExecStatement parses a Python 2 exec statement into a call of the
+// exec function, so "exec code in g, l" and "exec(code, g, l)" both become
+// exec(code, g, l).
+func (p *Parser) parseExecStatement() (Statement, error) {
+	stmt := &ExpressionStatement{Token: p.curToken}
+	call := &CallExpression{Token: p.curToken, Function: &Identifier{Token: p.curToken, Value: "exec"}}
+	stmt.Expression = call
+	p.nextToken()
+
+	code, err := p.parseExpression(COMPARISON)
+	if err != nil {
+		return nil, err
+	}
+	if tuple, ok := code.(*TupleLiteral); ok && tuple.Rparen.Type != "" && (len(tuple.Elements) == 2 || len(tuple.Elements) == 3) {
+		call.Arguments = tuple.Elements
+	} else {
+		call.Arguments = []Expression{code}
+	}
+
+	if p.curTokenIs(sasttoken.IN) {
+		p.nextToken()
+		for {
+			namespace, err := p.parseExpression(NAMED)
+			if err != nil {
+				return nil, err
+			}
+			call.Arguments = append(call.Arguments, namespace)
+			if len(call.Arguments) == 3 || !p.curTokenIs(sasttoken.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+	}
+	call.Rparen = p.prevToken
+
+	return stmt, nil
+}
+
+func (p *Parser) parseTypeAliasStatement() (Statement, error) {
+	stmt := &TypeAliasStatement{Token: p.curToken}
+	p.nextToken()
+
+	if !p.curTokenIs(sasttoken.IDENT) {
+		return nil, p.expectedError("type alias name")
+	}
+	name, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = name.(*Identifier)
+
+	if p.curTokenIs(sasttoken.LBRACKET) {
+		if stmt.TypeParams, err = p.parseTypeParams(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.expect(sasttoken.ASSIGN); err != nil {
+		return nil, err
+	}
+	if stmt.Value, err = p.parseExpression(LOWEST); err != nil {
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) parseImportStatement() (Statement, error) {
+	stmt := &ImportStatement{Token: p.curToken}
+	p.nextToken()
+
+	for {
+		module, err := p.parseDottedName()
+		if err != nil {
+			return nil, err
+		}
+		spec := &ImportSpec{Name: module}
+		if p.curTokenIs(sasttoken.AS) {
+			p.nextToken()
+			if spec.Alias, err = p.parseAlias(); err != nil {
+				return nil, err
+			}
+		}
+		stmt.Names = append(stmt.Names, spec)
+
+		if !p.curTokenIs(sasttoken.COMMA) {
+			return stmt, nil
+		}
+		p.nextToken()
+	}
+}
+
+func (p *Parser) parseImportFromStatement() (Statement, error) {
+	stmt := &FromImportStatement{Token: p.curToken}
+	p.nextToken()
+
+	// "..." is lexed as a single token.
+	for p.curTokenIs(sasttoken.DOT) || p.curTokenIs(sasttoken.ELLIPSIS) {
+		stmt.Level += len(p.curToken.Literal)
+		p.nextToken()
+	}
+	if stmt.Level == 0 || !p.curTokenIs(sasttoken.IMPORT) {
+		module, err := p.parseDottedName()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Module = module
+	}
+
+	if _, err := p.expect(sasttoken.IMPORT); err != nil {
+		return nil, err
+	}
+
+	switch p.curToken.Type {
+	case sasttoken.ASTERISK:
+		star := &Identifier{Token: p.curToken, Value: "*"}
+		stmt.ImportList = []*ImportSpec{{Name: star}}
+		p.nextToken()
+	case sasttoken.LPAREN:
+		p.nextToken()
+		names, err := p.parseImportAsNames(true)
+		if err != nil {
+			return nil, err
+		}
+		stmt.ImportList = names
+		if stmt.Rparen, err = p.expect(sasttoken.RPAREN); err != nil {
+			return nil, err
+		}
+	default:
+		names, err := p.parseImportAsNames(false)
+		if err != nil {
+			return nil, err
+		}
+		stmt.ImportList = names
+	}
+
+	if p.version == sasttoken.Python2 && stmt.Level == 0 && stmt.Module.Value == "__future__" {
+		for _, spec := range stmt.ImportList {
+			if spec.Name.Value == "print_function" {
+				// print is an ordinary name from here on.
+				p.printFunction = true
+				if p.peekTokenIs(sasttoken.PRINT) {
+					p.peekToken.Type = sasttoken.IDENT
+				}
+			}
+		}
+	}
+
+	return stmt, nil
+}
+
+// parseImportAsNames parses the "name [as alias]" list of a from-import. A
+// parenthesized list may end with a comma.
+func (p *Parser) parseImportAsNames(parenthesized bool) ([]*ImportSpec, error) {
+	var specs []*ImportSpec
+
+	for {
+		if !p.curTokenIs(sasttoken.IDENT) {
+			return nil, p.expectedError("name")
+		}
+		name, err := p.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		spec := &ImportSpec{Name: name.(*Identifier)}
+		if p.curTokenIs(sasttoken.AS) {
+			p.nextToken()
+			if spec.Alias, err = p.parseAlias(); err != nil {
+				return nil, err
+			}
+		}
+		specs = append(specs, spec)
+
+		if !p.curTokenIs(sasttoken.COMMA) {
+			return specs, nil
+		}
+		p.nextToken()
+		if parenthesized && p.curTokenIs(sasttoken.RPAREN) {
+			return specs, nil
+		}
+	}
+}
+
+// parseAlias parses the name after "as".
+func (p *Parser) parseAlias() (*Identifier, error) {
+	if !p.curTokenIs(sasttoken.IDENT) {
+		return nil, p.expectedError("name")
+	}
+	alias, err := p.parseIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	return alias.(*Identifier), nil
+}
+
+// parseDottedName parses a module path such as "os.path" into a single
+// Identifier spanning it.
+func (p *Parser) parseDottedName() (*Identifier, error) {
+	if !p.curTokenIs(sasttoken.IDENT) {
+		return nil, p.expectedError("module name")
+	}
+	ident := &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	p.nextToken()
+
+	for p.curTokenIs(sasttoken.DOT) {
+		p.nextToken()
+		if !p.curTokenIs(sasttoken.IDENT) {
+			return nil, p.expectedError("name")
+		}
+		ident.Value += "." + p.curToken.Literal
+		ident.Token.Literal = ident.Value
+		ident.Token.EndLine = p.curToken.EndLine
+		ident.Token.EndColumn = p.curToken.EndColumn
+		ident.Token.EndOffset = p.curToken.EndOffset
+		p.nextToken()
 	}
+
+	return ident, nil
 }
 
-func (p *Parser) parseFunction() (Node, error) {
+func (p *Parser) parseIfStatement() (Statement, error) {
+	stmt := &IfStatement{Token: p.curToken}
 	p.nextToken()
-	functionName, err := p.parseIdentifier()
+	condition, err := p.parseExpression(LOWEST)
 	if err != nil {
 		return nil, err
 	}
+	stmt.Condition = condition
 
-	p.expectPeek(sasttoken.LPAREN)
-	params, err := p.parseFunctionParameters()
-	if err != nil {
+	if _, err := p.expect(sasttoken.COLON); err != nil {
 		return nil, err
 	}
-	p.expectPeek(sasttoken.RPAREN)
-	p.expectPeek(sasttoken.COLON)
-
-	body, err := p.parseBlockStatement()
+	consequence, err := p.parseBlockStatement()
 	if err != nil {
 		return nil, err
 	}
+	stmt.Consequence = consequence
+
+	for p.curTokenIs(sasttoken.ELIF) {
+		elif, err := p.parseElifStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmt.ElifClauses = append(stmt.ElifClauses, elif)
+	}
+
+	if p.curTokenIs(sasttoken.ELSE) {
+		stmt.ElseClause, err = p.parseElseStatement()
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	return &Function{
-		Name:       functionName.(*Identifier),
-		Parameters: params,
-		Body:       body,
-	}, nil
+	return stmt, nil
 }
 
-func (p *Parser) parseReturnStatement() (Node, error) {
+// parseElifStatement parses an elif clause of an if statement.
+func (p *Parser) parseElifStatement() (*ElifStatement, error) {
+	stmt := &ElifStatement{Token: p.curToken}
 	p.nextToken()
 
-	retValue, err := p.parseExpression(LOWEST)
+	condition, err := p.parseExpression(LOWEST)
 	if err != nil {
 		return nil, err
 	}
+	stmt.Condition = condition
+
+	if _, err := p.expect(sasttoken.COLON); err != nil {
+		return nil, err
+	}
 
-	p.expectPeek(sasttoken.SEMICOLON)
+	block, err := p.parseBlockStatement()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Body = block
 
-	return &ReturnStatement{ReturnValue: retValue}, nil
+	return stmt, nil
 }
 
-func (p *Parser) parseImportStatement() (Node, error) {
+// parseElseStatement parses the else clause of an if or try statement.
+func (p *Parser) parseElseStatement() (*ElseStatement, error) {
+	stmt := &ElseStatement{Token: p.curToken}
 	p.nextToken()
 
-	module, err := p.parseIdentifier()
+	block, err := p.parseClauseBody()
 	if err != nil {
 		return nil, err
 	}
+	stmt.Body = block
+
+	return stmt, nil
+}
 
-	return &ImportStatement{Module: module.(*Identifier)}, nil
+// parseClauseBody parses the ':' and block of a clause introduced by a
+// keyword alone, such as "else:" or "finally:".
+func (p *Parser) parseClauseBody() (*BlockStatement, error) {
+	if _, err := p.expect(sasttoken.COLON); err != nil {
+		return nil, err
+	}
+	return p.parseBlockStatement()
 }
 
-func (p *Parser) parseImportFromStatement() (Node, error) {
+func (p *Parser) parseForStatement(async sasttoken.Token) (Statement, error) {
+	stmt := &ForStatement{Token: p.curToken, Async: async}
 	p.nextToken()
 
-	module, err := p.parseIdentifier()
+	// The target stops short of comparisons so that "in" is left alone.
+	target, err := p.parseExpressionList(COMPARISON)
 	if err != nil {
 		return nil, err
 	}
+	stmt.Target = target
 
-	p.expectPeek(sasttoken.IMPORT)
-	imports, err := p.parseIdentifierList()
+	if _, err := p.expect(sasttoken.IN); err != nil {
+		return nil, err
+	}
+	iterable, err := p.parseExpressionList(LOWEST)
 	if err != nil {
 		return nil, err
 	}
+	stmt.Iterable = iterable
 
-	return &ImportFromStatement{Module: module.(*Identifier), Imports: imports}, nil
-}
-
-func (p *Parser) parseIdentifierStatement() (Node, error) {
-	ident, err := p.parseIdentifier()
+	if _, err := p.expect(sasttoken.COLON); err != nil {
+		return nil, err
+	}
+	body, err := p.parseBlockStatement()
 	if err != nil {
 		return nil, err
 	}
+	stmt.Body = body
 
-	if p.peekTokenIs(sasttoken.ASSIGN) {
+	if p.curTokenIs(sasttoken.ELSE) {
 		p.nextToken()
-		p.nextToken()
-		value, err := p.parseExpression(LOWEST)
-		if err != nil {
+		if stmt.ElseBody, err = p.parseClauseBody(); err != nil {
 			return nil, err
 		}
-
-		return &AssignmentStatement{Left: ident, Value: value}, nil
 	}
 
-	return ident, nil
+	return stmt, nil
 }
 
-func (p *Parser) parseIfStatement() (Node, error) {
+func (p *Parser) parseWhileStatement() (Statement, error) {
+	stmt := &WhileStatement{Token: p.curToken}
 	p.nextToken()
 	condition, err := p.parseExpression(LOWEST)
 	if err != nil {
 		return nil, err
 	}
+	stmt.Condition = condition
 
-	p.expectPeek(sasttoken.COLON)
-	consequence, err := p.parseBlockStatement()
+	if _, err := p.expect(sasttoken.COLON); err != nil {
+		return nil, err
+	}
+	body, err := p.parseBlockStatement()
 	if err != nil {
 		return nil, err
 	}
+	stmt.Body = body
 
-	var alternatives []*ElifStatement
-	var elseStmt *ElseStatement
-
-	for p.peekTokenIs(sasttoken.ELIF) {
+	if p.curTokenIs(sasttoken.ELSE) {
 		p.nextToken()
-		elifCondition, err := p.parseExpression(LOWEST)
+		if stmt.ElseBody, err = p.parseClauseBody(); err != nil {
+			return nil, err
+		}
+	}
+
+	return stmt, nil
+}
+
+func (p *Parser) parseTryStatement() (Statement, error) {
+	stmt := &TryStatement{Token: p.curToken}
+	p.nextToken()
+	tryBlock, err := p.parseClauseBody()
+	if err != nil {
+		return nil, err
+	}
+	stmt.TryBlock = tryBlock
+
+	for p.curTokenIs(sasttoken.EXCEPT) {
+		except, err := p.parseExceptStatement()
 		if err != nil {
 			return nil, err
 		}
-		p.expectPeek(sasttoken.COLON)
-		elifConsequence, err := p.parseBlockStatement()
+		stmt.ExceptClauses = append(stmt.ExceptClauses, except)
+	}
+
+	if p.curTokenIs(sasttoken.ELSE) && len(stmt.ExceptClauses) > 0 {
+		stmt.ElseClause, err = p.parseElseStatement()
 		if err != nil {
 			return nil, err
 		}
-		alternatives = append(alternatives, &ElifStatement{Condition: elifCondition, Consequence: elifConsequence})
 	}
 
-	if p.peekTokenIs(sasttoken.ELSE) {
+	if p.curTokenIs(sasttoken.FINALLY) {
+		finallyStmt := &FinallyStatement{Token: p.curToken}
 		p.nextToken()
-		p.expectPeek(sasttoken.COLON)
-		elseBody, err := p.parseBlockStatement()
+		finallyStmt.Body, err = p.parseClauseBody()
 		if err != nil {
 			return nil, err
 		}
-		elseStmt = &ElseStatement{Body: elseBody}
+		stmt.FinallyClause = finallyStmt
+	}
+
+	if len(stmt.ExceptClauses) == 0 && stmt.FinallyClause == nil {
+		return nil, p.expectedError("'except' or 'finally' block")
 	}
 
-	return &IfStatement{
-		Condition:   condition,
-		Consequence: consequence,
-		ElifClauses: alternatives,
-		ElseClause:  elseStmt,
-	}, nil
+	return stmt, nil
 }
 
-func (p *Parser) parseForStatement() (Node, error) {
+// parseExceptStatement parses "except [*] [Type [as name]]:" and its block.
+func (p *Parser) parseExceptStatement() (*ExceptStatement, error) {
+	except := &ExceptStatement{Token: p.curToken}
 	p.nextToken()
-	ident, err := p.parseIdentifier()
-	if err != nil {
-		return nil, err
+
+	if p.curTokenIs(sasttoken.ASTERISK) {
+		except.Star = true
+		p.nextToken()
 	}
 
-	p.expectPeek(sasttoken.IN)
-	iterable, err := p.parseExpression(LOWEST)
-	if err != nil {
-		return nil, err
+	if !p.curTokenIs(sasttoken.COLON) {
+		exceptionType, err := p.parseExpression(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		except.ExceptionType = exceptionType
+
+		if p.curTokenIs(sasttoken.AS) || (p.version == sasttoken.Python2 && p.curTokenIs(sasttoken.COMMA)) {
+			p.nextToken()
+			if except.Name, err = p.parseAlias(); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	p.expectPeek(sasttoken.COLON)
-	body, err := p.parseBlockStatement()
+	body, err := p.parseClauseBody()
 	if err != nil {
 		return nil, err
 	}
+	except.Body = body
 
-	return &ForStatement{Identifier: ident, Iterable: iterable, Body: body}, nil
+	return except, nil
 }
 
-func (p *Parser) parseWhileStatement() (Node, error) {
+func (p *Parser) parseWithStatement(async sasttoken.Token) (Statement, error) {
+	stmt := &WithStatement{Token: p.curToken, Async: async}
 	p.nextToken()
-	condition, err := p.parseExpression(LOWEST)
+
+	items, err := p.parseWithItems()
 	if err != nil {
 		return nil, err
 	}
+	stmt.Items = items
 
-	p.expectPeek(sasttoken.COLON)
-	body, err := p.parseBlockStatement()
+	body, err := p.parseClauseBody()
 	if err != nil {
 		return nil, err
 	}
+	stmt.Body = body
 
-	return &WhileStatement{Condition: condition, Body: body}, nil
+	return stmt, nil
 }
 
-func (p *Parser) parseTryStatement() (Node, error) {
+// parseWithItems parses the items of a with statement. Since Python 3.9
+// they may be enclosed in parentheses, which is only known to be the case
+// once the ')' turns out to be followed by the ':'.
+func (p *Parser) parseWithItems() ([]*WithItem, error) {
+	if !p.curTokenIs(sasttoken.LPAREN) {
+		return p.parseWithItemList(nil)
+	}
+
+	lparen := p.curToken
 	p.nextToken()
-	p.expectPeek(sasttoken.COLON)
-	tryBlock, err := p.parseBlockStatement()
+	var items []*WithItem
+	for !p.curTokenIs(sasttoken.RPAREN) {
+		item, err := p.parseWithItem()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if !p.curTokenIs(sasttoken.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	rparen, err := p.expect(sasttoken.RPAREN)
 	if err != nil {
 		return nil, err
 	}
+	if p.curTokenIs(sasttoken.COLON) {
+		return items, nil
+	}
 
-	var exceptClauses []*ExceptStatement
-	var elseStmt *ElseStatement
-	var finallyStmt *FinallyStatement
-
-	for p.peekTokenIs(sasttoken.EXCEPT) {
-		p.nextToken()
-		var exceptType Node
-		if !p.peekTokenIs(sasttoken.COLON) {
-			exceptType, err = p.parseExpression(LOWEST)
-			if err != nil {
-				return nil, err
+	// The parentheses belong to the first context expression, as in
+	// "with (yield x) as y:" or "with (a, b) as c:".
+	var first Expression
+	if len(items) == 1 && items[0].Target == nil {
+		first = items[0].Context
+	} else {
+		tuple := &TupleLiteral{Token: lparen, Rparen: rparen}
+		for _, item := range items {
+			if item.Target != nil {
+				return nil, p.expectedError("':'")
 			}
+			tuple.Elements = append(tuple.Elements, item.Context)
 		}
-		p.expectPeek(sasttoken.COLON)
-		exceptBlock, err := p.parseBlockStatement()
-		if err != nil {
-			return nil, err
-		}
-		exceptClauses = append(exceptClauses, &ExceptStatement{ExceptionType: exceptType, Body: exceptBlock})
+		first = tuple
 	}
+	context, err := p.parseInfixExpressions(first, LOWEST)
+	if err != nil {
+		return nil, err
+	}
+	return p.parseWithItemList(context)
+}
 
-	if p.peekTokenIs(sasttoken.ELSE) {
-		p.nextToken()
-		p.expectPeek(sasttoken.COLON)
-		elseBody, err := p.parseBlockStatement()
+// parseWithItemList parses comma-separated with items. If first is set, it
+// is the already parsed context expression of the first item.
+func (p *Parser) parseWithItemList(first Expression) ([]*WithItem, error) {
+	var items []*WithItem
+	for {
+		var item *WithItem
+		var err error
+		if first != nil {
+			item, err = p.parseWithTarget(first)
+			first = nil
+		} else {
+			item, err = p.parseWithItem()
+		}
 		if err != nil {
 			return nil, err
 		}
-		elseStmt = &ElseStatement{Body: elseBody}
+		items = append(items, item)
+
+		if !p.curTokenIs(sasttoken.COMMA) {
+			return items, nil
+		}
+		p.nextToken()
+	}
+}
+
+func (p *Parser) parseWithItem() (*WithItem, error) {
+	context, err := p.parseExpression(LOWEST)
+	if err != nil {
+		return nil, err
 	}
+	return p.parseWithTarget(context)
+}
 
-	if p.peekTokenIs(sasttoken.FINALLY) {
+// parseWithTarget parses the optional "as target" after a context
+// expression.
+func (p *Parser) parseWithTarget(context Expression) (*WithItem, error) {
+	item := &WithItem{Context: context}
+	if p.curTokenIs(sasttoken.AS) {
 		p.nextToken()
-		p.expectPeek(sasttoken.COLON)
-		finallyBody, err := p.parseBlockStatement()
+		target, err := p.parseExpression(LOWEST)
 		if err != nil {
 			return nil, err
 		}
-		finallyStmt = &FinallyStatement{Body: finallyBody}
+		item.Target = target
 	}
-
-	return &TryStatement{
-		TryBlock:      tryBlock,
-		ExceptClauses: exceptClauses,
-		ElseClause:    elseStmt,
-		FinallyClause: finallyStmt,
-	}, nil
+	return item, nil
 }
 
-func (p *Parser) parseExpressionStatement() (*ExpressionStatement, error) {
-	stmt := &ExpressionStatement{Token: p.curToken}
+func (p *Parser) parseMatchStatement() (Statement, error) {
+	stmt := &MatchStatement{Token: p.curToken}
+	p.nextToken()
 
-	exp, err := p.parseExpression(LOWEST)
+	subject, err := p.parseExpressionList(LOWEST)
 	if err != nil {
 		return nil, err
 	}
-	stmt.Expression = exp
+	stmt.Subject = subject
+
+	if _, err := p.expect(sasttoken.COLON); err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(sasttoken.NEWLINE); err != nil {
+		return nil, err
+	}
+	if !p.curTokenIs(sasttoken.INDENT) {
+		return nil, p.errorf("expected an indented block")
+	}
+	p.nextToken()
 
-	if p.peekTokenIs(sasttoken.SEMICOLON) {
+	for !p.curTokenIs(sasttoken.DEDENT) && !p.curTokenIs(sasttoken.EOF) {
+		matchCase, err := p.parseMatchCase()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Cases = append(stmt.Cases, matchCase)
+	}
+	if len(stmt.Cases) == 0 {
+		return nil, p.expectedError("'case' block")
+	}
+	if p.curTokenIs(sasttoken.DEDENT) {
 		p.nextToken()
 	}
 
 	return stmt, nil
 }
 
-func (p *Parser) parseElseStatement() (Node, error) {
-	stmt := &ElseStatement{}
+func (p *Parser) parseMatchCase() (*MatchCase, error) {
+	if !p.curTokenIs(sasttoken.CASE) {
+		return nil, p.expectedError("'case'")
+	}
+	matchCase := &MatchCase{Token: p.curToken}
+	p.nextToken()
 
-	if !p.expectPeek(sasttoken.COLON) {
-		return nil, fmt.Errorf("expected ':' after 'else', got %s", p.peekToken)
+	pattern, err := p.parsePatterns()
+	if err != nil {
+		return nil, err
 	}
+	matchCase.Pattern = pattern
 
-	p.nextToken()
+	if p.curTokenIs(sasttoken.IF) {
+		p.nextToken()
+		guard, err := p.parseExpression(LOWEST)
+		if err != nil {
+			return nil, err
+		}
+		matchCase.Guard = guard
+	}
 
-	block, err := p.parseBlockStatement()
+	body, err := p.parseClauseBody()
 	if err != nil {
 		return nil, err
 	}
-	stmt.Block = block
+	matchCase.Body = body
 
-	return stmt, nil
+	return matchCase, nil
 }
 
-func (p *Parser) parseElifStatement() (Node, error) {
-	stmt := &ElifStatement{}
+// parseExpressionStatement parses an expression statement or an
+// assignment, whose target is parsed as an expression first.
+func (p *Parser) parseExpressionStatement() (Statement, error) {
+	start := p.curToken
 
-	p.nextToken()
+	exp, err := p.parseStatementExpressionList()
+	if err != nil {
+		return nil, err
+	}
 
-	condition, err := p.parseExpression(LOWEST)
+	if augmentedAssignments[p.curToken.Type] {
+		stmt := &AugAssignStatement{
+			Token:    p.curToken,
+			Target:   exp,
+			Operator: strings.TrimSuffix(p.curToken.Literal, "="),
+		}
+		p.nextToken()
+		value, err := p.parseStatementExpressionList()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Value = value
+		return stmt, nil
+	}
+
+	if p.curTokenIs(sasttoken.COLON) {
+		return p.parseAnnAssignStatement(exp)
+	}
+
+	if !p.curTokenIs(sasttoken.ASSIGN) {
+		return &ExpressionStatement{Token: start, Expression: exp}, nil
+	}
+
+	// An assignment, possibly chained: every expression but the last is a
+	// target.
+	stmt := &AssignmentStatement{Token: p.curToken, Targets: []Expression{exp}}
+	for p.curTokenIs(sasttoken.ASSIGN) {
+		p.nextToken()
+		value, err := p.parseStatementExpressionList()
+		if err != nil {
+			return nil, err
+		}
+		if p.curTokenIs(sasttoken.ASSIGN) {
+			stmt.Targets = append(stmt.Targets, value)
+		} else {
+			stmt.Value = value
+		}
+	}
+	for _, target := range stmt.Targets {
+		if err := p.checkTarget(target, "assign to", true); err != nil {
+			return nil, err
+		}
+	}
+
+	return stmt, nil
+}
+
+// parseStatementExpressionList parses the expressions of an expression
+// statement, an assignment or a return statement, where an assignment
+// expression must be parenthesized.
+func (p *Parser) parseStatementExpressionList() (Expression, error) {
+	exp, err := p.parseExpressionList(NAMED)
 	if err != nil {
 		return nil, err
 	}
-	stmt.Condition = condition
+	if p.curTokenIs(sasttoken.WALRUS) {
+		// The ':=' follows the last element of a tuple.
+		target := exp
+		if tuple, ok := exp.(*TupleLiteral); ok && tuple.Rparen.Type == "" {
+			target = tuple.Elements[len(tuple.Elements)-1]
+		}
+		if _, ok := target.(*Identifier); !ok {
+			// Fails on the target, which is the worse error.
+			return p.parseNamedExpression(target)
+		}
+		return nil, p.errorf("assignment expression must be parenthesized here")
+	}
+	return exp, nil
+}
+
+// checkTarget returns a diagnostic if target cannot be assigned to or
+// deleted, as verb says: a target is a name, attribute or subscript, or a
+// tuple or list of targets. An assignment's tuple or list may also hold one
+// starred target.
+func (p *Parser) checkTarget(target Expression, verb string, starred bool) error {
+	var elements []Expression
+	switch t := target.(type) {
+	case *Identifier, *AttributeExpression, *SubscriptExpression:
+		return nil
+	case *TupleLiteral:
+		elements = t.Elements
+	case *ListLiteral:
+		elements = t.Elements
+	case *StarredExpression:
+		if starred {
+			return p.errorAt(t, "starred assignment target must be in a list or tuple")
+		}
+		return p.errorAt(t, "cannot %s starred", verb)
+	default:
+		return p.errorAt(t, "cannot %s %s", verb, describeExpression(t))
+	}
+
+	seen := false
+	for _, element := range elements {
+		if s, ok := element.(*StarredExpression); ok && starred {
+			if seen {
+				return p.errorAt(s, "multiple starred expressions in assignment")
+			}
+			seen = true
+			element = s.Value
+		}
+		if err := p.checkTarget(element, verb, starred); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	if !p.expectPeek(sasttoken.COLON) {
-		return nil, fmt.Errorf("expected ':' after 'elif' condition, got %s", p.peekToken)
+// parseAnnAssignStatement parses the annotation and optional value of an
+// annotated assignment after its target.
+func (p *Parser) parseAnnAssignStatement(target Expression) (Statement, error) {
+	switch t := target.(type) {
+	case *Identifier, *AttributeExpression, *SubscriptExpression:
+	case *TupleLiteral:
+		return nil, p.errorf("only single target (not tuple) can be annotated")
+	default:
+		return nil, p.errorf("illegal target for annotation: %s", t)
 	}
 
+	stmt := &AnnAssignStatement{Token: p.curToken, Target: target}
 	p.nextToken()
 
-	block, err := p.parseBlockStatement()
+	annotation, err := p.parseExpression(LOWEST)
 	if err != nil {
 		return nil, err
 	}
-	stmt.Block = block
+	stmt.Annotation = annotation
+
+	if p.curTokenIs(sasttoken.ASSIGN) {
+		p.nextToken()
+		if stmt.Value, err = p.parseStatementExpressionList(); err != nil {
+			return nil, err
+		}
+	}
 
 	return stmt, nil
 }
 
-func (p *Parser) parsePassStatement() (Node, error) {
-	stmt := &PassStatement{}
+func (p *Parser) parsePassStatement() (Statement, error) {
+	stmt := &PassStatement{Token: p.curToken}
 
 	p.nextToken()
 
 	return stmt, nil
 }
 
-func (p *Parser) parseBreakStatement() (Node, error) {
-	stmt := &BreakStatement{}
+func (p *Parser) parseBreakStatement() (Statement, error) {
+	stmt := &BreakStatement{Token: p.curToken}
 
 	p.nextToken()
 
 	return stmt, nil
 }
 
-func (p *Parser) parseContinueStatement() (Node, error) {
-	stmt := &ContinueStatement{}
+func (p *Parser) parseContinueStatement() (Statement, error) {
+	stmt := &ContinueStatement{Token: p.curToken}
 
 	p.nextToken()
 
 	return stmt, nil
 }
 
-func (p *Parser) parseFunctionParameters() ([]*Identifier, error) {
+func (p *Parser) parseIdentifierList() ([]*Identifier, error) {
 	var identifiers []*Identifier
 
-	if p.peekTokenIs(sasttoken.RPAREN) {
-		p.nextToken()
-		return identifiers, nil
-	}
-
-	p.nextToken()
-	ident, err := p.parseIdentifier()
-	if err != nil {
-		return nil, err
-	}
-	identifiers = append(identifiers, ident.(*Identifier))
-
-	for p.peekTokenIs(sasttoken.COMMA) {
-		p.nextToken()
-		p.nextToken()
-
+	for {
+		if !p.curTokenIs(sasttoken.IDENT) {
+			return nil, p.expectedError("name")
+		}
 		ident, err := p.parseIdentifier()
 		if err != nil {
 			return nil, err
 		}
 		identifiers = append(identifiers, ident.(*Identifier))
-	}
-
-	return identifiers, nil
-}
-
-func (p *Parser) parseIdentifierList() ([]*Identifier, error) {
-	var identifiers []*Identifier
 
-	p.nextToken()
-	ident, err := p.parseIdentifier()
-	if err != nil {
-		return nil, err
+		if !p.curTokenIs(sasttoken.COMMA) {
+			return identifiers, nil
+		}
+		p.nextToken()
 	}
-	identifiers = append(identifiers, ident.(*Identifier))
+}
 
-	for p.peekTokenIs(sasttoken.COMMA) {
-		p.nextToken()
-		p.nextToken()
+// parseBlockStatement parses the body of a compound statement, starting
+// after its ':'. The body is either an indented block or simple statements
+// on the same line.
+func (p *Parser) parseBlockStatement() (*BlockStatement, error) {
+	block := &BlockStatement{Token: p.curToken}
 
-		ident, err := p.parseIdentifier()
-		if err != nil {
+	if !p.curTokenIs(sasttoken.NEWLINE) {
+		for {
+			stmt, err := p.parseSimpleStatement()
+			if err != nil {
+				return nil, err
+			}
+			block.Statements = append(block.Statements, stmt)
+			if !p.curTokenIs(sasttoken.SEMICOLON) {
+				break
+			}
+			p.nextToken()
+			if p.curTokenIs(sasttoken.NEWLINE) {
+				break
+			}
+		}
+		if _, err := p.expect(sasttoken.NEWLINE); err != nil {
 			return nil, err
 		}
-		identifiers = append(identifiers, ident.(*Identifier))
+		return block, nil
 	}
 
-	return identifiers, nil
-}
+	p.nextToken()
+	if !p.curTokenIs(sasttoken.INDENT) {
+		return nil, p.errorf("expected an indented block")
+	}
+	block.Token = p.curToken
+	p.parseIndentedBlock(block)
 
-func (p *Parser) parseBlockStatement() (*BlockStatement, error) {
-	block := &BlockStatement{}
-	block.Statements = []Node{}
+	return block, nil
+}
 
+// parseIndentedBlock parses the statements from an INDENT token through
+// the matching DEDENT into block, recovering from errors in each.
+func (p *Parser) parseIndentedBlock(block *BlockStatement) {
 	p.nextToken()
 
 	for !p.curTokenIs(sasttoken.DEDENT) && !p.curTokenIs(sasttoken.EOF) {
-		stmt, err := p.parseStatement()
-		if err != nil {
-			return nil, err
-		}
-		block.Statements = append(block.Statements, stmt)
-		p.nextToken()
+		block.Statements = append(block.Statements, p.parseStatementOrRecover())
 	}
 
-	return block, nil
+	if p.curTokenIs(sasttoken.DEDENT) {
+		p.nextToken()
+	}
 }
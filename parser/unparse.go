@@ -0,0 +1,802 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+
+	sasttoken "github.com/coiloffaraday/python_sast/token"
+)
+
+// Unparse regenerates Python source for the tree rooted at node, in the
+// spirit of Python's ast.unparse. Parsing the result yields the same tree
+// up to positions and redundant parentheses. Statements end with a
+// newline; expressions do not. Formatting and comments are not preserved,
+// and statements that failed to parse come out as comments. Trees parsed as
+// Python 2 come out as Python 3 code.
+func Unparse(node Node) string {
+	u := &unparser{}
+	switch n := node.(type) {
+	case *Program:
+		u.statements(n.Statements)
+	case *BlockStatement:
+		u.statements(n.Statements)
+	case Statement:
+		u.statement(n)
+	case Expression:
+		u.expression(n, LOWEST)
+	default:
+		u.other(n)
+	}
+	return u.out.String()
+}
+
+type unparser struct {
+	out    strings.Builder
+	indent int
+}
+
+func (u *unparser) write(parts ...string) {
+	for _, part := range parts {
+		u.out.WriteString(part)
+	}
+}
+
+// line starts a new line at the current indentation.
+func (u *unparser) line(parts ...string) {
+	u.out.WriteString(strings.Repeat("    ", u.indent))
+	u.write(parts...)
+}
+
+func (u *unparser) statements(stmts []Statement) {
+	for _, stmt := range stmts {
+		u.statement(stmt)
+	}
+}
+
+// block writes the ':' ending a clause header and the indented body.
+func (u *unparser) block(body *BlockStatement) {
+	u.write(":\n")
+	u.indent++
+	if body == nil || len(body.Statements) == 0 {
+		u.line("pass\n")
+	} else {
+		u.statements(body.Statements)
+	}
+	u.indent--
+}
+
+func (u *unparser) statement(stmt Statement) {
+	switch s := stmt.(type) {
+	case *FunctionDef:
+		u.functionDef(s, "")
+	case *AsyncFunctionDef:
+		u.functionDef(&s.FunctionDef, "async ")
+	case *ClassDef:
+		u.decorators(s.Decorators)
+		u.line("class ", s.Name.Value)
+		u.typeParams(s.TypeParams)
+		if len(s.Bases) > 0 || len(s.Keywords) > 0 {
+			u.write("(")
+			u.arguments(s.Bases, s.Keywords)
+			u.write(")")
+		}
+		u.block(s.Body)
+	case *IfStatement:
+		u.line("if ")
+		u.expression(s.Condition, NAMED)
+		u.block(s.Consequence)
+		for _, elif := range s.ElifClauses {
+			u.line("elif ")
+			u.expression(elif.Condition, NAMED)
+			u.block(elif.Body)
+		}
+		if s.ElseClause != nil {
+			u.line("else")
+			u.block(s.ElseClause.Body)
+		}
+	case *ForStatement:
+		u.line(asyncPrefix(s.Async.Type != ""), "for ")
+		u.expressionList(s.Target)
+		u.write(" in ")
+		u.expressionList(s.Iterable)
+		u.block(s.Body)
+		u.elseBlock(s.ElseBody)
+	case *WhileStatement:
+		u.line("while ")
+		u.expression(s.Condition, NAMED)
+		u.block(s.Body)
+		u.elseBlock(s.ElseBody)
+	case *TryStatement:
+		u.line("try")
+		u.block(s.TryBlock)
+		for _, except := range s.ExceptClauses {
+			u.line("except")
+			if except.Star {
+				u.write("*")
+			}
+			if except.ExceptionType != nil {
+				u.write(" ")
+				u.expression(except.ExceptionType, TERNARY)
+				if except.Name != nil {
+					u.write(" as ", except.Name.Value)
+				}
+			}
+			u.block(except.Body)
+		}
+		if s.ElseClause != nil {
+			u.line("else")
+			u.block(s.ElseClause.Body)
+		}
+		if s.FinallyClause != nil {
+			u.line("finally")
+			u.block(s.FinallyClause.Body)
+		}
+	case *WithStatement:
+		u.line(asyncPrefix(s.Async.Type != ""), "with ")
+		for i, item := range s.Items {
+			if i > 0 {
+				u.write(", ")
+			}
+			u.expression(item.Context, TERNARY)
+			if item.Target != nil {
+				u.write(" as ")
+				u.expression(item.Target, TERNARY)
+			}
+		}
+		u.block(s.Body)
+	case *MatchStatement:
+		u.line("match ")
+		u.expressionList(s.Subject)
+		u.write(":\n")
+		u.indent++
+		for _, c := range s.Cases {
+			u.line("case ")
+			u.pattern(c.Pattern, false)
+			if c.Guard != nil {
+				u.write(" if ")
+				u.expression(c.Guard, NAMED)
+			}
+			u.block(c.Body)
+		}
+		u.indent--
+	case *ErrorNode:
+		u.line("# syntax error: ", strings.ReplaceAll(s.Token.Literal, "\n", " "), "\n")
+		if s.Body != nil {
+			u.statements(s.Body.Statements)
+		}
+	default:
+		u.line()
+		u.simpleStatement(stmt)
+		u.write("\n")
+	}
+}
+
+func (u *unparser) simpleStatement(stmt Statement) {
+	switch s := stmt.(type) {
+	case *ExpressionStatement:
+		u.valueList(s.Expression)
+	case *AssignmentStatement:
+		for _, target := range s.Targets {
+			u.expressionList(target)
+			u.write(" = ")
+		}
+		u.valueList(s.Value)
+	case *AnnAssignStatement:
+		u.expression(s.Target, TERNARY)
+		u.write(": ")
+		u.expression(s.Annotation, TERNARY)
+		if s.Value != nil {
+			u.write(" = ")
+			u.valueList(s.Value)
+		}
+	case *TypeAliasStatement:
+		u.write("type ", s.Name.Value)
+		u.typeParams(s.TypeParams)
+		u.write(" = ")
+		u.expression(s.Value, TERNARY)
+	case *AugAssignStatement:
+		u.expression(s.Target, TERNARY)
+		u.write(" ", s.Operator, "= ")
+		u.valueList(s.Value)
+	case *ReturnStatement:
+		u.write("return")
+		if s.ReturnValue != nil {
+			u.write(" ")
+			u.expressionList(s.ReturnValue)
+		}
+	case *RaiseStatement:
+		u.write("raise")
+		if s.Exception != nil {
+			u.write(" ")
+			u.expression(s.Exception, TERNARY)
+		}
+		if s.Cause != nil {
+			u.write(" from ")
+			u.expression(s.Cause, TERNARY)
+		}
+	case *AssertStatement:
+		u.write("assert ")
+		u.expression(s.Test, TERNARY)
+		if s.Message != nil {
+			u.write(", ")
+			u.expression(s.Message, TERNARY)
+		}
+	case *DelStatement:
+		u.write("del ")
+		u.expressions(s.Targets, TERNARY)
+	case *GlobalStatement:
+		u.write("global ", joinIdentifiers(s.Names))
+	case *NonlocalStatement:
+		u.write("nonlocal ", joinIdentifiers(s.Names))
+	case *ImportStatement:
+		u.write(s.String())
+	case *FromImportStatement:
+		u.write(s.String())
+	case *PassStatement:
+		u.write("pass")
+	case *BreakStatement:
+		u.write("break")
+	case *ContinueStatement:
+		u.write("continue")
+	default:
+		u.write(stmt.String())
+	}
+}
+
+func (u *unparser) functionDef(fn *FunctionDef, async string) {
+	u.decorators(fn.Decorators)
+	u.line(async, "def ", fn.Name.Value)
+	u.typeParams(fn.TypeParams)
+	u.write("(")
+	u.parameters(fn.Parameters)
+	u.write(")")
+	if fn.Returns != nil {
+		u.write(" -> ")
+		u.expression(fn.Returns, TERNARY)
+	}
+	u.block(fn.Body)
+}
+
+func (u *unparser) decorators(decorators []*Decorator) {
+	for _, d := range decorators {
+		u.line("@")
+		u.expression(d.Expression, NAMED)
+		u.write("\n")
+	}
+}
+
+func (u *unparser) elseBlock(body *BlockStatement) {
+	if body != nil {
+		u.line("else")
+		u.block(body)
+	}
+}
+
+func asyncPrefix(async bool) string {
+	if async {
+		return "async "
+	}
+	return ""
+}
+
+// other writes the nodes that are neither statements nor expressions.
+func (u *unparser) other(node Node) {
+	switch n := node.(type) {
+	case *Parameter:
+		u.parameter(n)
+	case *ForClause:
+		u.forClause(n)
+	case *WithItem:
+		u.expression(n.Context, TERNARY)
+		if n.Target != nil {
+			u.write(" as ")
+			u.expression(n.Target, TERNARY)
+		}
+	case *Decorator:
+		u.write("@")
+		u.expression(n.Expression, NAMED)
+	default:
+		u.write(node.String())
+	}
+}
+
+// stringTokens writes the source of adjacent string literals, separated by
+// spaces.
+func (u *unparser) stringTokens(first sasttoken.Token, rest []sasttoken.Token) {
+	for i, tok := range append([]sasttoken.Token{first}, rest...) {
+		if i > 0 {
+			u.write(" ")
+		}
+		if prefix, _ := splitStringLiteral(tok.Literal); prefix == "ur" {
+			// Python 3 has no "ur" prefix; raw strings are Unicode anyway.
+			u.write(tok.Literal[1:])
+		} else {
+			u.write(tok.Literal)
+		}
+	}
+}
+
+// expressionList writes an expression where a tuple needs no parentheses,
+// such as the iterable of a for loop. Assignment expressions and yield
+// are parenthesized, since Python does not allow them bare there.
+func (u *unparser) expressionList(expr Expression) {
+	if tuple, ok := expr.(*TupleLiteral); ok && len(tuple.Elements) > 0 {
+		u.expressions(tuple.Elements, TERNARY)
+		if len(tuple.Elements) == 1 {
+			u.write(",")
+		}
+		return
+	}
+	u.expression(expr, TERNARY)
+}
+
+// valueList is expressionList for the value of an assignment and for an
+// expression statement, which may also be a bare yield.
+func (u *unparser) valueList(expr Expression) {
+	if _, ok := expr.(*YieldExpression); ok {
+		u.expression(expr, LOWEST)
+		return
+	}
+	u.expressionList(expr)
+}
+
+// pattern writes a case pattern. A nested AS or OR pattern is
+// parenthesized, since it would otherwise extend over its neighbours.
+func (u *unparser) pattern(pattern Pattern, nested bool) {
+	switch pt := pattern.(type) {
+	case *LiteralPattern:
+		u.expression(pt.Value, LOWEST)
+	case *ValuePattern:
+		u.expression(pt.Value, LOWEST)
+	case *CapturePattern:
+		u.write(pt.Name.Value)
+	case *WildcardPattern:
+		u.write("_")
+	case *StarPattern:
+		u.write("*")
+		u.pattern(pt.Pattern, true)
+	case *SequencePattern:
+		open, close := "[", "]"
+		if pt.Close.Type != sasttoken.RBRACKET {
+			open, close = "(", ")"
+		}
+		if pt.Close.Type == "" && !nested {
+			open, close = "", ""
+		}
+		u.write(open)
+		for i, element := range pt.Patterns {
+			if i > 0 {
+				u.write(", ")
+			}
+			u.pattern(element, false)
+		}
+		if len(pt.Patterns) == 1 && open != "[" {
+			u.write(",")
+		}
+		u.write(close)
+	case *MappingPattern:
+		u.write("{")
+		for i, key := range pt.Keys {
+			if i > 0 {
+				u.write(", ")
+			}
+			u.expression(key, LOWEST)
+			u.write(": ")
+			u.pattern(pt.Patterns[i], false)
+		}
+		if pt.Rest != nil {
+			if len(pt.Keys) > 0 {
+				u.write(", ")
+			}
+			u.write("**", pt.Rest.Value)
+		}
+		u.write("}")
+	case *ClassPattern:
+		u.expression(pt.Class, CALL)
+		u.write("(")
+		for i, arg := range pt.Patterns {
+			if i > 0 {
+				u.write(", ")
+			}
+			u.pattern(arg, false)
+		}
+		for i, keyword := range pt.Keywords {
+			if i > 0 || len(pt.Patterns) > 0 {
+				u.write(", ")
+			}
+			u.write(keyword.Name.Value, "=")
+			u.pattern(keyword.Pattern, false)
+		}
+		u.write(")")
+	case *OrPattern:
+		if nested {
+			defer u.write(")")
+			u.write("(")
+		}
+		for i, alternative := range pt.Patterns {
+			if i > 0 {
+				u.write(" | ")
+			}
+			u.pattern(alternative, true)
+		}
+	case *AsPattern:
+		if nested {
+			defer u.write(")")
+			u.write("(")
+		}
+		u.pattern(pt.Pattern, true)
+		u.write(" as ", pt.Name.Value)
+	}
+}
+
+func (u *unparser) expressions(exprs []Expression, precedence int) {
+	for i, expr := range exprs {
+		if i > 0 {
+			u.write(", ")
+		}
+		u.expression(expr, precedence)
+	}
+}
+
+// expressionPrecedence returns the precedence of the operator that forms
+// expr; operands of operators binding at least as tight need no
+// parentheses.
+func expressionPrecedence(expr Expression) int {
+	switch e := expr.(type) {
+	case *YieldExpression:
+		return LOWEST
+	case *NamedExpression:
+		return NAMED
+	case *IfExpression, *LambdaExpression:
+		return TERNARY
+	case *InfixExpression:
+		return binaryPrecedence[e.Operator]
+	case *PrefixExpression:
+		if e.Operator == "not" {
+			return NOT
+		}
+		return PREFIX
+	case *ComparisonExpression:
+		return COMPARISON
+	case *AwaitExpression:
+		return AWAIT
+	}
+	return CALL
+}
+
+var binaryPrecedence = map[string]int{
+	"or":  OR,
+	"and": AND,
+	"|":   BITOR,
+	"^":   BITXOR,
+	"&":   BITAND,
+	"<<":  SHIFT,
+	">>":  SHIFT,
+	"+":   SUM,
+	"-":   SUM,
+	"*":   PRODUCT,
+	"@":   PRODUCT,
+	"/":   PRODUCT,
+	"//":  PRODUCT,
+	"%":   PRODUCT,
+	"**":  POWER,
+}
+
+// expression writes expr, in parentheses if its operator binds looser
+// than precedence.
+func (u *unparser) expression(expr Expression, precedence int) {
+	if expressionPrecedence(expr) < precedence {
+		u.write("(")
+		defer u.write(")")
+	}
+
+	switch e := expr.(type) {
+	case *Identifier:
+		u.write(e.Value)
+	case *IntegerLiteral:
+		// Python 3 has no long suffix.
+		literal := strings.TrimRight(e.TokenLiteral(), "Ll")
+		if len(literal) > 1 && literal[0] == '0' && '0' <= literal[1] && literal[1] <= '9' && strings.Trim(literal, "0_") != "" {
+			// A Python 2 octal literal such as 0777.
+			literal = "0o" + literal[1:]
+		}
+		u.write(literal)
+	case *FloatLiteral, *ImaginaryLiteral:
+		u.write(e.TokenLiteral())
+	case *StringLiteral:
+		if e.Token.Literal == "" {
+			u.write(strconv.Quote(e.Value))
+		} else {
+			u.stringTokens(e.Token, e.Rest)
+		}
+	case *JoinedStr:
+		u.stringTokens(e.Token, e.Rest)
+	case *BooleanLiteral:
+		if e.Value {
+			u.write("True")
+		} else {
+			u.write("False")
+		}
+	case *NoneLiteral:
+		u.write("None")
+	case *Ellipsis:
+		u.write("...")
+	case *InfixExpression:
+		prec := binaryPrecedence[e.Operator]
+		left, right := prec, prec+1
+		if e.Operator == "**" {
+			// ** is right-associative.
+			left, right = prec+1, prec
+		}
+		u.expression(e.Left, left)
+		u.write(" ", e.Operator, " ")
+		u.expression(e.Right, right)
+	case *PrefixExpression:
+		if e.Operator == "not" {
+			u.write("not ")
+			u.expression(e.Right, NOT)
+		} else {
+			u.write(e.Operator)
+			u.expression(e.Right, PREFIX)
+		}
+	case *ComparisonExpression:
+		u.expression(e.Left, COMPARISON+1)
+		for i, op := range e.Operators {
+			u.write(" ", op, " ")
+			u.expression(e.Comparators[i], COMPARISON+1)
+		}
+	case *IfExpression:
+		u.expression(e.Consequence, TERNARY+1)
+		u.write(" if ")
+		u.expression(e.Condition, TERNARY+1)
+		u.write(" else ")
+		u.expression(e.Alternative, TERNARY)
+	case *NamedExpression:
+		u.write(e.Target.Value, " := ")
+		u.expression(e.Value, TERNARY)
+	case *LambdaExpression:
+		u.write("lambda")
+		if len(e.Parameters) > 0 {
+			u.write(" ")
+			u.parameters(e.Parameters)
+		}
+		u.write(": ")
+		u.expression(e.Body, TERNARY)
+	case *AwaitExpression:
+		u.write("await ")
+		u.expression(e.Value, CALL)
+	case *YieldExpression:
+		u.write("yield")
+		if e.From {
+			u.write(" from ")
+			u.expression(e.Value, TERNARY)
+		} else if e.Value != nil {
+			u.write(" ")
+			u.expressionList(e.Value)
+		}
+	case *StarredExpression:
+		u.write("*")
+		u.expression(e.Value, BITOR)
+	case *AttributeExpression:
+		if _, ok := e.Object.(*IntegerLiteral); ok {
+			// "1.real" would be read as a float.
+			u.write("(", e.Object.TokenLiteral(), ")")
+		} else {
+			u.expression(e.Object, CALL)
+		}
+		u.write(".", e.Attribute.Value)
+	case *CallExpression:
+		u.expression(e.Function, CALL)
+		u.write("(")
+		if gen := bareGenerator(e); gen != nil {
+			u.comprehension(gen.Element, gen.Generators)
+		} else {
+			u.arguments(e.Arguments, e.Keywords)
+		}
+		u.write(")")
+	case *SubscriptExpression:
+		u.expression(e.Object, CALL)
+		u.write("[")
+		u.expressionList(e.Index)
+		u.write("]")
+	case *SliceExpression:
+		if e.Lower != nil {
+			u.expression(e.Lower, TERNARY)
+		}
+		u.write(":")
+		if e.Upper != nil {
+			u.expression(e.Upper, TERNARY)
+		}
+		if e.Step != nil {
+			u.write(":")
+			u.expression(e.Step, TERNARY)
+		}
+	case *TupleLiteral:
+		u.write("(")
+		u.expressions(e.Elements, NAMED)
+		if len(e.Elements) == 1 {
+			u.write(",")
+		}
+		u.write(")")
+	case *ListLiteral:
+		u.write("[")
+		u.expressions(e.Elements, NAMED)
+		u.write("]")
+	case *SetLiteral:
+		u.write("{")
+		u.expressions(e.Elements, NAMED)
+		u.write("}")
+	case *DictLiteral:
+		u.write("{")
+		for i, key := range e.Keys {
+			if i > 0 {
+				u.write(", ")
+			}
+			if key == nil {
+				u.write("**")
+				u.expression(e.Values[i], BITOR)
+				continue
+			}
+			u.expression(key, TERNARY)
+			u.write(": ")
+			u.expression(e.Values[i], TERNARY)
+		}
+		u.write("}")
+	case *ListComprehension:
+		u.write("[")
+		u.comprehension(e.Element, e.Generators)
+		u.write("]")
+	case *SetComprehension:
+		u.write("{")
+		u.comprehension(e.Element, e.Generators)
+		u.write("}")
+	case *DictComprehension:
+		u.write("{")
+		u.expression(e.Key, TERNARY)
+		u.write(": ")
+		u.expression(e.Value, TERNARY)
+		u.write(" ")
+		u.forClauses(e.Generators)
+		u.write("}")
+	case *GeneratorExpression:
+		u.write("(")
+		u.comprehension(e.Element, e.Generators)
+		u.write(")")
+	case *KeywordArgument:
+		u.keyword(e)
+	default:
+		u.write(expr.String())
+	}
+}
+
+// bareGenerator returns the sole argument of call if it is a generator
+// expression sharing the call's parentheses, and nil otherwise.
+func bareGenerator(call *CallExpression) *GeneratorExpression {
+	if len(call.Arguments) != 1 || len(call.Keywords) != 0 {
+		return nil
+	}
+	gen, ok := call.Arguments[0].(*GeneratorExpression)
+	if !ok || gen.Token.Offset != call.Token.Offset || gen.Token.Type != call.Token.Type {
+		return nil
+	}
+	return gen
+}
+
+func (u *unparser) comprehension(element Expression, generators []*ForClause) {
+	u.expression(element, NAMED)
+	u.write(" ")
+	u.forClauses(generators)
+}
+
+func (u *unparser) forClauses(clauses []*ForClause) {
+	for i, clause := range clauses {
+		if i > 0 {
+			u.write(" ")
+		}
+		u.forClause(clause)
+	}
+}
+
+func (u *unparser) forClause(clause *ForClause) {
+	u.write(asyncPrefix(clause.Async.Type != ""), "for ")
+	u.expressionList(clause.Target)
+	u.write(" in ")
+	u.expression(clause.Iter, TERNARY+1)
+	for _, cond := range clause.Ifs {
+		u.write(" if ")
+		u.expression(cond, TERNARY+1)
+	}
+}
+
+func (u *unparser) arguments(args []Expression, keywords []*KeywordArgument) {
+	u.expressions(args, NAMED)
+	for i, keyword := range keywords {
+		if i > 0 || len(args) > 0 {
+			u.write(", ")
+		}
+		u.keyword(keyword)
+	}
+}
+
+func (u *unparser) keyword(keyword *KeywordArgument) {
+	if keyword.Name == nil {
+		u.write("**")
+	} else {
+		u.write(keyword.Name.Value, "=")
+	}
+	u.expression(keyword.Value, TERNARY)
+}
+
+// parameters writes a parameter list, restoring the "/" and "*"
+// separators as joinParameters does.
+func (u *unparser) parameters(params []*Parameter) {
+	for i, param := range params {
+		if i > 0 {
+			u.write(", ")
+		}
+		if i > 0 && params[i-1].Kind == PositionalOnly && param.Kind != PositionalOnly {
+			u.write("/, ")
+		}
+		if param.Kind == KeywordOnly && (i == 0 || params[i-1].Kind != KeywordOnly && params[i-1].Kind != VarPositional) {
+			u.write("*, ")
+		}
+		u.parameter(param)
+	}
+	if n := len(params); n > 0 && params[n-1].Kind == PositionalOnly {
+		u.write(", /")
+	}
+}
+
+func (u *unparser) typeParams(params []*TypeParam) {
+	if len(params) == 0 {
+		return
+	}
+	u.write("[")
+	for i, param := range params {
+		if i > 0 {
+			u.write(", ")
+		}
+		u.typeParam(param)
+	}
+	u.write("]")
+}
+
+func (u *unparser) typeParam(param *TypeParam) {
+	switch param.Kind {
+	case TypeVarTuple:
+		u.write("*")
+	case ParamSpec:
+		u.write("**")
+	}
+	u.write(param.Name.Value)
+	if param.Bound != nil {
+		u.write(": ")
+		u.expression(param.Bound, TERNARY)
+	}
+	if param.Default != nil {
+		u.write(" = ")
+		u.expression(param.Default, TERNARY)
+	}
+}
+
+func (u *unparser) parameter(param *Parameter) {
+	switch param.Kind {
+	case VarPositional:
+		u.write("*")
+	case VarKeyword:
+		u.write("**")
+	}
+	u.write(param.Name.Value)
+	if param.Annotation != nil {
+		u.write(": ")
+		u.expression(param.Annotation, TERNARY)
+	}
+	if param.Default != nil {
+		if param.Annotation != nil {
+			u.write(" = ")
+		} else {
+			u.write("=")
+		}
+		u.expression(param.Default, TERNARY)
+	}
+}
@@ -0,0 +1,103 @@
+package parser
+
+//go:generate go run gen_children.go
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children of
+// node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor w for
+// each of the children of node, followed by a call of w.Visit(nil).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	for _, child := range node.Children() {
+		Walk(v, child)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the children of node, followed by a call of
+// f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// InspectWithStack is like Inspect, but also passes f the path from the
+// root to the node's parent, outermost first. The stack is only valid
+// during the call and must be copied to be kept. f is not called with nil.
+func InspectWithStack(node Node, f func(node Node, stack []Node) bool) {
+	var stack []Node
+	var visit func(Node)
+	visit = func(n Node) {
+		if !f(n, stack) {
+			return
+		}
+		stack = append(stack, n)
+		for _, child := range n.Children() {
+			visit(child)
+		}
+		stack = stack[:len(stack)-1]
+	}
+	visit(node)
+}
+
+// Parents maps every node below root to its parent.
+func Parents(root Node) map[Node]Node {
+	parents := make(map[Node]Node)
+	InspectWithStack(root, func(n Node, stack []Node) bool {
+		if len(stack) > 0 {
+			parents[n] = stack[len(stack)-1]
+		}
+		return true
+	})
+	return parents
+}
+
+// FindAll returns the nodes of type T in the tree rooted at root, in
+// depth-first order, including nodes nested inside other matches.
+func FindAll[T Node](root Node) []T {
+	var found []T
+	Inspect(root, func(n Node) bool {
+		if t, ok := n.(T); ok {
+			found = append(found, t)
+		}
+		return true
+	})
+	return found
+}
+
+// FindFirst returns the first node of type T in the tree rooted at root,
+// in depth-first order.
+func FindFirst[T Node](root Node) (T, bool) {
+	var first T
+	found := false
+	Inspect(root, func(n Node) bool {
+		if found {
+			return false
+		}
+		if t, ok := n.(T); ok {
+			first, found = t, true
+			return false
+		}
+		return true
+	})
+	return first, found
+}
@@ -0,0 +1,355 @@
+// Package project loads the Python files of a project into a module graph
+// keyed by dotted module name, so that analyses can follow names and
+// values from one module into another.
+package project
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/coiloffaraday/python_sast/lexer"
+	"github.com/coiloffaraday/python_sast/parser"
+	"github.com/coiloffaraday/python_sast/semantic"
+	sasttoken "github.com/coiloffaraday/python_sast/token"
+)
+
+// Config controls how files map to module names.
+type Config struct {
+	// SearchPaths are the import roots of the project, like the entries of
+	// sys.path, relative to the project directory. When empty, the project
+	// directory is used, along with its src directory for a src layout.
+	SearchPaths []string
+}
+
+// Module is a parsed Python file.
+type Module struct {
+	Name        string // The dotted module name, such as "app.views"
+	Path        string
+	IsPackage   bool // Set for a package's __init__.py
+	Program     *parser.Program
+	Version     sasttoken.Version // The language version the file was parsed as
+	Info        *semantic.Info
+	Diagnostics parser.DiagnosticList // Syntax errors; the rest of the file is still analyzed
+}
+
+// Graph holds the modules of a project.
+type Graph struct {
+	Dir   string
+	roots []string // Absolute search paths, longest first
+
+	modules map[string]*Module
+}
+
+// New returns an empty graph for the project in dir.
+func New(dir string, config Config) (*Graph, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Graph{Dir: dir, modules: map[string]*Module{}}
+	paths := config.SearchPaths
+	if len(paths) == 0 {
+		paths = []string{"."}
+		if isDir(filepath.Join(dir, "src")) && !isFile(filepath.Join(dir, "src", "__init__.py")) {
+			paths = append(paths, "src")
+		}
+	}
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		g.roots = append(g.roots, filepath.Clean(path))
+	}
+	sort.SliceStable(g.roots, func(i, j int) bool { return len(g.roots[i]) > len(g.roots[j]) })
+
+	return g, nil
+}
+
+// Load parses every Python file under dir into a graph. Files that cannot
+// be read are skipped and reported in the returned error, which is nil
+// when every file loaded.
+func Load(dir string, config Config) (*Graph, error) {
+	g, err := New(dir, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	err = filepath.WalkDir(g.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if d.IsDir() {
+			if path != g.Dir && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), ".py") {
+			if _, err := g.AddFile(path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return g, errors.Join(errs...)
+}
+
+// skipDir reports whether a directory holds no project sources, such as a
+// virtual environment or a cache.
+func skipDir(name string) bool {
+	switch name {
+	case "__pycache__", "node_modules", "site-packages", "venv", "env":
+		return true
+	}
+	return strings.HasPrefix(name, ".")
+}
+
+// AddFile parses a file and adds it to the graph. A file whose module name
+// is already taken replaces the earlier one.
+func (g *Graph) AddFile(path string) (*Module, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return g.AddSource(path, string(content)), nil
+}
+
+// AddSource parses the source of the file at path and adds it to the
+// graph. A file that fails to parse as Python 3 but parses as Python 2 is
+// taken to be Python 2.
+func (g *Graph) AddSource(path string, source string) *Module {
+	path, _ = filepath.Abs(path)
+	name, isPackage := g.ModuleName(path)
+
+	p := parser.New(lexer.NewLexer(source, path), parser.DetectVersion())
+	program, _ := p.ParseProgram()
+
+	info := semantic.Analyze(program)
+	info.Name = name
+	info.IsPackage = isPackage
+	info.Modules = g.info
+
+	m := &Module{
+		Name:        name,
+		Path:        path,
+		IsPackage:   isPackage,
+		Program:     program,
+		Version:     p.Version(),
+		Info:        info,
+		Diagnostics: p.Diagnostics(),
+	}
+	g.modules[name] = m
+
+	return m
+}
+
+// ModuleName returns the dotted module name of the file at path and
+// whether it is a package's __init__.py.
+//
+// Directories with an __init__.py are packages, so the file's name is
+// built by climbing them up to the first directory that is not one. If
+// that directory is below a search path, the directories in between are
+// namespace packages and become part of the name too.
+func (g *Graph) ModuleName(path string) (string, bool) {
+	path, _ = filepath.Abs(path)
+	dir := filepath.Dir(path)
+	base := strings.TrimSuffix(filepath.Base(path), ".py")
+
+	var parts []string
+	isPackage := base == "__init__"
+	if !isPackage {
+		parts = append(parts, base)
+	}
+	for isFile(filepath.Join(dir, "__init__.py")) {
+		parts = append(parts, filepath.Base(dir))
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	for _, root := range g.roots {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if rel != "." {
+			namespace := strings.Split(rel, string(filepath.Separator))
+			for i := len(namespace) - 1; i >= 0; i-- {
+				parts = append(parts, namespace[i])
+			}
+		}
+		break
+	}
+
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, "."), isPackage
+}
+
+// Module returns the module with the given dotted name, or nil.
+func (g *Graph) Module(name string) *Module {
+	return g.modules[name]
+}
+
+// ModuleAt returns the module loaded from the file at path, or nil.
+func (g *Graph) ModuleAt(path string) *Module {
+	path, _ = filepath.Abs(path)
+	for _, m := range g.modules {
+		if m.Path == path {
+			return m
+		}
+	}
+	return nil
+}
+
+// Modules returns the modules of the graph sorted by name.
+func (g *Graph) Modules() []*Module {
+	modules := make([]*Module, 0, len(g.modules))
+	for _, m := range g.modules {
+		modules = append(modules, m)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Name < modules[j].Name })
+	return modules
+}
+
+func (g *Graph) info(name string) *semantic.Info {
+	if m := g.modules[name]; m != nil {
+		return m.Info
+	}
+	return nil
+}
+
+// Imports returns the project modules that m imports, sorted by name.
+// "import a.b" imports both a and a.b, and "from a import b" imports a and,
+// if it is a module, a.b.
+func (g *Graph) Imports(m *Module) []*Module {
+	names := map[string]bool{}
+	add := func(name string) {
+		for {
+			names[name] = true
+			i := strings.LastIndexByte(name, '.')
+			if i < 0 {
+				return
+			}
+			name = name[:i]
+		}
+	}
+
+	parser.Inspect(m.Program, func(node parser.Node) bool {
+		switch n := node.(type) {
+		case *parser.ImportStatement:
+			for _, spec := range n.Names {
+				add(spec.Name.Value)
+			}
+		case *parser.FromImportStatement:
+			module := m.Info.FromModule(n)
+			if module == "" {
+				return true
+			}
+			add(module)
+			for _, spec := range n.ImportList {
+				if spec.Name.Value != "*" {
+					names[module+"."+spec.Name.Value] = true
+				}
+			}
+		}
+		return true
+	})
+
+	var imports []*Module
+	for name := range names {
+		if imported := g.modules[name]; imported != nil && imported != m {
+			imports = append(imports, imported)
+		}
+	}
+	sort.Slice(imports, func(i, j int) bool { return imports[i].Name < imports[j].Name })
+	return imports
+}
+
+// Lookup finds where a fully qualified name, as returned by
+// semantic.Info.QualifiedName, is defined in the project: the module and
+// the symbol for a name such as "app.db.query" or "app.models.User.save".
+// Names re-exported by a package are followed to their definition. It
+// returns nil for a name outside the project and a nil symbol for a name
+// that is a module.
+func (g *Graph) Lookup(name string) (*Module, *semantic.Symbol) {
+	return g.lookup(name, 0)
+}
+
+func (g *Graph) lookup(name string, depth int) (*Module, *semantic.Symbol) {
+	if m := g.modules[name]; m != nil {
+		return m, nil
+	}
+	if depth > 16 {
+		return nil, nil
+	}
+
+	for i := strings.LastIndexByte(name, '.'); i > 0; i = strings.LastIndexByte(name[:i], '.') {
+		m := g.modules[name[:i]]
+		if m == nil {
+			continue
+		}
+
+		parts := strings.Split(name[i+1:], ".")
+		sym := m.Info.Module.Symbol(parts[0])
+		if sym == nil || len(sym.Definitions) == 0 {
+			return nil, nil
+		}
+		// A name the module imports is looked up where it comes from.
+		if def := sym.Definitions[0]; def.Kind == semantic.Import {
+			target := m.Info.QualifiedName(def.Ident)
+			if target == "" || target == name[:i+1+len(parts[0])] {
+				return nil, nil
+			}
+			if len(parts) > 1 {
+				target += "." + strings.Join(parts[1:], ".")
+			}
+			return g.lookup(target, depth+1)
+		}
+		for _, part := range parts[1:] {
+			if sym = memberOf(m.Info, sym, part); sym == nil {
+				return nil, nil
+			}
+		}
+		return m, sym
+	}
+	return nil, nil
+}
+
+// memberOf returns the symbol for name in the body of the class sym is
+// defined as, or nil.
+func memberOf(info *semantic.Info, sym *semantic.Symbol, name string) *semantic.Symbol {
+	for _, def := range sym.Definitions {
+		if def.Kind != semantic.ClassDefinition {
+			continue
+		}
+		if scope := info.Scope(def.Node); scope != nil {
+			return scope.Symbol(name)
+		}
+	}
+	return nil
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,212 @@
+package project_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/parser"
+	"github.com/coiloffaraday/python_sast/project"
+	"github.com/coiloffaraday/python_sast/semantic"
+	sasttoken "github.com/coiloffaraday/python_sast/token"
+)
+
+// writeFiles creates the given files, keyed by slash-separated path, under
+// a temporary directory and returns the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func moduleNames(g *project.Graph) string {
+	var names []string
+	for _, m := range g.Modules() {
+		name := m.Name
+		if m.IsPackage {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestModuleNames(t *testing.T) {
+	tests := []struct {
+		files    []string
+		config   project.Config
+		expected string
+	}{
+		{
+			[]string{"manage.py", "app/__init__.py", "app/views.py", "app/db/__init__.py", "app/db/utils.py"},
+			project.Config{},
+			"app/ app.db/ app.db.utils app.views manage",
+		},
+		{
+			// A src layout.
+			[]string{"setup.py", "src/app/__init__.py", "src/app/views.py", "tests/test_views.py"},
+			project.Config{},
+			"app/ app.views setup tests.test_views",
+		},
+		{
+			// A namespace package has no __init__.py.
+			[]string{"ns/plugin/__init__.py", "ns/plugin/core.py", "ns/helpers.py"},
+			project.Config{},
+			"ns.helpers ns.plugin/ ns.plugin.core",
+		},
+		{
+			[]string{"backend/service/__init__.py", "backend/service/api.py", "backend/run.py"},
+			project.Config{SearchPaths: []string{"backend"}},
+			"run service/ service.api",
+		},
+		{
+			[]string{"venv/lib/site.py", ".git/hooks/x.py", "app/__pycache__/x.py", "main.py"},
+			project.Config{},
+			"main",
+		},
+	}
+
+	for _, tt := range tests {
+		files := map[string]string{}
+		for _, name := range tt.files {
+			files[name] = ""
+		}
+		g, err := project.Load(writeFiles(t, files), tt.config)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.files, err)
+		}
+		if got := moduleNames(g); got != tt.expected {
+			t.Errorf("%v: got %q, want %q", tt.files, got, tt.expected)
+		}
+	}
+}
+
+func TestPackageRoot(t *testing.T) {
+	// The project directory is itself a package.
+	dir := writeFiles(t, map[string]string{"__init__.py": "", "views.py": ""})
+	g, err := project.Load(dir, project.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := filepath.Base(dir)
+	if got := moduleNames(g); got != pkg+"/ "+pkg+".views" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestCrossModuleLookup(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app/__init__.py":    "from .db.utils import query\n",
+		"app/db/__init__.py": "",
+		"app/db/utils.py": `import sqlite3
+
+def query(sql):
+    return sqlite3.connect("app.db").execute(sql)
+
+class Repo:
+    def find(self, key):
+        return query(key)
+`,
+		"app/views.py": `from flask import request
+from app import query
+from .db import utils
+from .db.utils import Repo as R
+
+def index():
+    query(request.args["q"])
+    utils.query("x")
+    R.find(None, 1)
+`,
+	})
+	g, err := project.Load(dir, project.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	views := g.Module("app.views")
+	if views == nil {
+		t.Fatalf("app.views not loaded: %s", moduleNames(g))
+	}
+	var imports []string
+	for _, m := range g.Imports(views) {
+		imports = append(imports, m.Name)
+	}
+	if got := strings.Join(imports, " "); got != "app app.db app.db.utils" {
+		t.Errorf("imports: got %q", got)
+	}
+
+	var names []string
+	for _, call := range parser.FindAll[*parser.CallExpression](views.Program) {
+		names = append(names, views.Info.CallName(call))
+	}
+	if got := strings.Join(names, " "); got != "app.db.utils.query app.db.utils.query app.db.utils.Repo.find" {
+		t.Errorf("call names: got %q", got)
+	}
+
+	for _, name := range []string{"app.db.utils.query", "app.query"} {
+		m, sym := g.Lookup(name)
+		if m != g.Module("app.db.utils") || sym == nil || sym.Definitions[0].Kind != semantic.FunctionDefinition {
+			t.Errorf("%s: got %v, %v", name, m, sym)
+		}
+	}
+	m, sym := g.Lookup("app.db.utils.Repo.find")
+	if m == nil || sym == nil || sym.Scope.Kind != semantic.ClassScope {
+		t.Errorf("method: got %v, %v", m, sym)
+	}
+	if m, sym := g.Lookup("app.db"); m != g.Module("app.db") || sym != nil {
+		t.Errorf("module: got %v, %v", m, sym)
+	}
+	if m, _ := g.Lookup("flask.request"); m != nil {
+		t.Errorf("external name: got %v", m)
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"bad.py": "y = = 2\nx = 1\n"})
+	g, err := project.Load(dir, project.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := g.Module("bad")
+	if m == nil || len(m.Diagnostics) == 0 {
+		t.Fatalf("expected diagnostics, got %v", m)
+	}
+	if m.Info.Module.Symbol("x") == nil {
+		t.Errorf("the rest of the file was not analyzed")
+	}
+}
+
+func TestPython2(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"legacy.py": "import os\n\ntry:\n    print `os.getpid()`, 0777L\nexcept OSError, e:\n    print >>sys.stderr, e\n",
+		"modern.py": "print(f'{1}')\n",
+	})
+	g, err := project.Load(dir, project.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy := g.Module("legacy")
+	if legacy == nil || len(legacy.Diagnostics) > 0 || legacy.Version != sasttoken.Python2 {
+		t.Fatalf("expected legacy to parse as Python 2, got %v", legacy)
+	}
+	if _, ok := legacy.Program.Statements[1].(*parser.TryStatement); !ok {
+		t.Errorf("expected a try statement, got %s", legacy.Program.Statements[1])
+	}
+	if legacy.Info.Module.Symbol("e") == nil {
+		t.Errorf("the except clause was not analyzed")
+	}
+	if modern := g.Module("modern"); modern == nil || modern.Version != sasttoken.Python3 {
+		t.Errorf("expected modern to parse as Python 3, got %v", modern)
+	}
+}
@@ -2,6 +2,7 @@ package reporter
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
@@ -37,19 +38,28 @@ func (r *Reporter) GenerateReport(outputFile string) error {
 	}
 	defer f.Close()
 
+	r.writeReport(f)
+	return nil
+}
+
+// PrintReport 将报告输出到标准输出
+func (r *Reporter) PrintReport() {
+	r.writeReport(os.Stdout)
+}
+
+// writeReport 将报告标题和每个问题项写入 w
+func (r *Reporter) writeReport(w io.Writer) {
 	// 生成报告标题
 	title := "Python SAST Report"
 	hr := strings.Repeat("=", len(title))
-	_, _ = fmt.Fprintf(f, "%s\n%s\n%s\n\n", hr, title, hr)
+	_, _ = fmt.Fprintf(w, "%s\n%s\n%s\n\n", hr, title, hr)
 
 	// 输出每个问题项
 	for _, item := range r.reportItems {
-		_, _ = fmt.Fprintf(f, "Rule ID: %s\n", item.RuleID)
-		_, _ = fmt.Fprintf(f, "Description: %s\n", item.Description)
-		_, _ = fmt.Fprintf(f, "Severity: %s\n", item.Severity)
-		_, _ = fmt.Fprintf(f, "Location: %s\n", item.Location)
-		_, _ = fmt.Fprintln(f, strings.Repeat("-", 80))
+		_, _ = fmt.Fprintf(w, "Rule ID: %s\n", item.RuleID)
+		_, _ = fmt.Fprintf(w, "Description: %s\n", item.Description)
+		_, _ = fmt.Fprintf(w, "Severity: %s\n", item.Severity)
+		_, _ = fmt.Fprintf(w, "Location: %s\n", item.Location)
+		_, _ = fmt.Fprintln(w, strings.Repeat("-", 80))
 	}
-
-	return nil
 }
@@ -0,0 +1,63 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coiloffaraday/python_sast/parser"
+	"github.com/coiloffaraday/python_sast/project"
+	"github.com/coiloffaraday/python_sast/reporter"
+)
+
+// newReportItem 返回指向模块中 node 处的问题项，位置为 "文件:行:列"
+func newReportItem(module *project.Module, node parser.Node, ruleID, severity, description string) reporter.ReportItem {
+	pos := node.Pos()
+	return reporter.ReportItem{
+		RuleID:      ruleID,
+		Description: description,
+		Severity:    severity,
+		Location:    fmt.Sprintf("%s:%d:%d", module.Path, pos.Line, pos.Column),
+	}
+}
+
+// stringValue 返回字符串常量的值；f-string 只返回其中的文本部分。
+// 第二个返回值表示 expr 是否是字符串
+func stringValue(expr parser.Expression) (string, bool) {
+	switch e := expr.(type) {
+	case *parser.StringLiteral:
+		return e.Value, true
+	case *parser.JoinedStr:
+		var text strings.Builder
+		for _, value := range e.Values {
+			if lit, ok := value.(*parser.StringLiteral); ok {
+				text.WriteString(lit.Value)
+			}
+		}
+		return text.String(), true
+	}
+	return "", false
+}
+
+// methodName 返回方法调用的方法名，如 "cur.execute(sql)" 的 "execute"；
+// 不是方法调用时返回 ""
+func methodName(call *parser.CallExpression) string {
+	if attr, ok := call.Function.(*parser.AttributeExpression); ok {
+		return attr.Attribute.Value
+	}
+	return ""
+}
+
+// argument 返回调用的第 index 个位置参数，或名为 keyword 的关键字参数；不存在时返回 nil
+func argument(call *parser.CallExpression, index int, keyword string) parser.Expression {
+	if index < len(call.Arguments) {
+		if _, ok := call.Arguments[index].(*parser.StarredExpression); !ok {
+			return call.Arguments[index]
+		}
+	}
+	for _, kw := range call.Keywords {
+		if kw.Name != nil && kw.Name.Value == keyword {
+			return kw.Value
+		}
+	}
+	return nil
+}
@@ -1,10 +1,23 @@
 package rules
 
 import (
+	"regexp"
+
+	"github.com/coiloffaraday/python_sast/analyzer"
 	"github.com/coiloffaraday/python_sast/parser"
+	"github.com/coiloffaraday/python_sast/project"
 	"github.com/coiloffaraday/python_sast/reporter"
 )
 
+// csrfExemptDecorators 是关闭视图 CSRF 保护的装饰器的完全限定名。
+// Flask-WTF 的 "@csrf.exempt" 装饰在 CSRFProtect 实例上，无法解析出完全限定名，按属性名匹配
+var csrfExemptDecorators = map[string]bool{
+	"django.views.decorators.csrf.csrf_exempt": true,
+}
+
+// dangerousSQL 匹配修改数据的 SQL 语句
+var dangerousSQL = regexp.MustCompile(`(?i)^\s*(DELETE|UPDATE)\s`)
+
 type RuleCSRF struct {
 	reporter *reporter.Reporter
 }
@@ -16,39 +29,90 @@ func NewRuleCSRF(reporter *reporter.Reporter) *RuleCSRF {
 	}
 }
 
+// Check 实现 analyzer.Rule 接口，返回模块中所有未受 CSRF 保护的视图
+func (r *RuleCSRF) Check(ctx *analyzer.Context) []reporter.ReportItem {
+	return append(r.CheckConditionA(ctx.Module), r.CheckConditionB(ctx.Module)...)
+}
+
 // Apply 应用规则并将结果添加到报告中
-func (r *RuleCSRF) Apply(ast *parser.ast.Node) {
-	r.CheckConditionA(ast)
-	r.CheckConditionB(ast)
-}
-
-// CheckConditionA 检查是否存在未进行CSRF保护的HTTP请求，并报告
-func (r *RuleCSRF) CheckConditionA(ast *parser.ast.Node) {
-	// 检查所有HTTP请求
-	httpRequests := ast.FindAll(parser.NodeTypeHTTPRequest)
-	for _, httpRequest := range httpRequests {
-		// 检查是否存在CSRF保护
-		csrfProtection := httpRequest.FindFirst(parser.NodeTypeCSRFProtection)
-		if csrfProtection == nil {
-			// 发现未进行CSRF保护的HTTP请求
-			r.reporter.AddIssue(httpRequest.File, httpRequest.Line, "HTTP request is not protected against CSRF attacks")
-		}
+func (r *RuleCSRF) Apply(module *project.Module) {
+	for _, item := range r.Check(&analyzer.Context{Module: module}) {
+		r.reporter.AddReportItem(item)
 	}
 }
 
-// CheckConditionB 检查是否存在未进行CSRF保护的HTTP请求，但同时具有危险操作（例如删除，修改等），并报告
-func (r *RuleCSRF) CheckConditionB(ast *parser.ast.Node) {
-	// 检查所有HTTP请求
-	httpRequests := ast.FindAll(parser.NodeTypeHTTPRequest)
-	for _, httpRequest := range httpRequests {
-		// 检查是否存在危险操作
-		if httpRequest.Contains(parser.NodeTypeDelete) || httpRequest.Contains(parser.NodeTypeUpdate) {
-			// 检查是否存在CSRF保护
-			csrfProtection := httpRequest.FindFirst(parser.NodeTypeCSRFProtection)
-			if csrfProtection == nil {
-				// 发现未进行CSRF保护的HTTP请求
-				r.reporter.AddIssue(httpRequest.File, httpRequest.Line, "HTTP request with dangerous operation is not protected against CSRF attacks")
+// CheckConditionA 检查是否存在未进行CSRF保护的视图，并报告；含有危险操作的视图由 CheckConditionB 报告
+func (r *RuleCSRF) CheckConditionA(module *project.Module) []reporter.ReportItem {
+	var items []reporter.ReportItem
+	r.inspectViews(module, func(name string, node parser.Node, body *parser.BlockStatement) {
+		if !r.hasDangerousOperation(body) {
+			items = append(items, newReportItem(module, node, "CSRF", "Medium", "View "+name+" is not protected against CSRF attacks"))
+		}
+	})
+	return items
+}
+
+// CheckConditionB 检查是否存在未进行CSRF保护的视图，但同时具有危险操作（例如删除，修改等），并报告
+func (r *RuleCSRF) CheckConditionB(module *project.Module) []reporter.ReportItem {
+	var items []reporter.ReportItem
+	r.inspectViews(module, func(name string, node parser.Node, body *parser.BlockStatement) {
+		if r.hasDangerousOperation(body) {
+			items = append(items, newReportItem(module, node, "CSRF", "High", "View "+name+" with dangerous operation is not protected against CSRF attacks"))
+		}
+	})
+	return items
+}
+
+// inspectViews 对模块中每个关闭了 CSRF 保护的函数调用 f
+func (r *RuleCSRF) inspectViews(module *project.Module, f func(name string, node parser.Node, body *parser.BlockStatement)) {
+	parser.Inspect(module.Program, func(node parser.Node) bool {
+		switch n := node.(type) {
+		case *parser.FunctionDef:
+			if r.isExempt(module, n.Decorators) {
+				f(n.Name.Value, n.Name, n.Body)
+			}
+		case *parser.AsyncFunctionDef:
+			if r.isExempt(module, n.Decorators) {
+				f(n.Name.Value, n.Name, n.Body)
 			}
 		}
+		return true
+	})
+}
+
+// isExempt 检查装饰器中是否有关闭 CSRF 保护的装饰器
+func (r *RuleCSRF) isExempt(module *project.Module, decorators []*parser.Decorator) bool {
+	for _, d := range decorators {
+		expr := d.Expression
+		if call, ok := expr.(*parser.CallExpression); ok {
+			expr = call.Function
+		}
+		if csrfExemptDecorators[module.Info.QualifiedName(expr)] {
+			return true
+		}
+		if attr, ok := expr.(*parser.AttributeExpression); ok && attr.Attribute.Value == "exempt" {
+			return true
+		}
 	}
+	return false
+}
+
+// hasDangerousOperation 检查视图中是否有删除或修改数据的操作：
+// 名为 delete 或 update 的方法调用，或 DELETE、UPDATE 语句
+func (r *RuleCSRF) hasDangerousOperation(body *parser.BlockStatement) bool {
+	found := false
+	parser.Inspect(body, func(node parser.Node) bool {
+		switch n := node.(type) {
+		case *parser.CallExpression:
+			if name := methodName(n); name == "delete" || name == "update" {
+				found = true
+			}
+		case *parser.StringLiteral:
+			if dangerousSQL.MatchString(n.Value) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
 }
@@ -0,0 +1,40 @@
+package rules_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/rules"
+)
+
+func TestRuleCSRF(t *testing.T) {
+	input := `from django.views.decorators.csrf import csrf_exempt
+from flask_wtf.csrf import CSRFProtect
+
+csrf = CSRFProtect(app)
+
+@csrf_exempt
+def webhook(request):
+    return handle(request.body)
+
+@csrf.exempt
+@app.route("/users/<id>", methods=["POST"])
+def remove_user(id):
+    User.query.filter_by(id=id).delete()
+
+@csrf_exempt
+def rename(request):
+    db.execute("UPDATE users SET name = ?", (request.POST["name"],))
+
+def protected(request):
+    Item.objects.filter(id=1).delete()
+`
+	expected := strings.Join([]string{
+		"m.py:7:5: View webhook is not protected against CSRF attacks",
+		"m.py:12:5: View remove_user with dangerous operation is not protected against CSRF attacks",
+		"m.py:16:5: View rename with dangerous operation is not protected against CSRF attacks",
+	}, "\n")
+	if got := check(t, rules.NewRuleCSRF(nil), input); got != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", got, expected)
+	}
+}
@@ -4,10 +4,30 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/coiloffaraday/python_sast/analyzer"
 	"github.com/coiloffaraday/python_sast/parser"
+	"github.com/coiloffaraday/python_sast/project"
 	"github.com/coiloffaraday/python_sast/reporter"
 )
 
+// includeFunctions 是加载并执行代码文件或模块的函数的完全限定名，值为路径或模块名参数的位置
+var includeFunctions = map[string]int{
+	"builtins.__import__":                  0,
+	"builtins.execfile":                    0,
+	"importlib.import_module":              0,
+	"importlib.machinery.SourceFileLoader": 1,
+	"imp.load_source":                      1,
+	"runpy.run_module":                     0,
+	"runpy.run_path":                       0,
+}
+
+// execFunctions 是执行字符串中代码的函数的完全限定名
+var execFunctions = map[string]bool{
+	"builtins.exec":    true,
+	"builtins.eval":    true,
+	"builtins.compile": true,
+}
+
 type RuleFileInclude struct {
 	reporter *reporter.Reporter
 }
@@ -19,34 +39,67 @@ func NewRuleFileInclude(reporter *reporter.Reporter) *RuleFileInclude {
 	}
 }
 
+// Check 实现 analyzer.Rule 接口，返回模块中所有的文件包含问题
+func (r *RuleFileInclude) Check(ctx *analyzer.Context) []reporter.ReportItem {
+	return append(r.CheckConditionA(ctx.Module), r.CheckConditionB(ctx.Module)...)
+}
+
 // Apply 应用规则并将结果添加到报告中
-func (r *RuleFileInclude) Apply(ast *parser.ast.Node) {
-	r.CheckConditionA(ast)
-	r.CheckConditionB(ast)
-}
-
-// CheckConditionA 检查本地文件包含
-func (r *RuleFileInclude) CheckConditionA(ast *parser.ast.Node) {
-	findLocalFileInclude := func(node *parser.ast.Node) {
-		if node.Type == parser.ImportStmt && node.token.Token.Literal == "include" {
-			arg := node.Children[0].token.Token.Literal
-			if strings.HasPrefix(arg, "./") || strings.HasPrefix(arg, "../") {
-				r.reporter.AddIssue(node.token.Token.SourceFile, node.token.Token.Line, fmt.Sprintf("Local file inclusion detected: %s", arg))
-			}
+func (r *RuleFileInclude) Apply(module *project.Module) {
+	for _, item := range r.Check(&analyzer.Context{Module: module}) {
+		r.reporter.AddReportItem(item)
+	}
+}
+
+// CheckConditionA 检查本地文件包含：加载的路径或模块名不是常量，或是依赖当前目录的相对路径
+func (r *RuleFileInclude) CheckConditionA(module *project.Module) []reporter.ReportItem {
+	var items []reporter.ReportItem
+	for _, call := range parser.FindAll[*parser.CallExpression](module.Program) {
+		index, ok := includeFunctions[module.Info.CallName(call)]
+		if !ok || index >= len(call.Arguments) {
+			continue
+		}
+		arg := call.Arguments[index]
+		if value, ok := arg.(*parser.StringLiteral); !ok || strings.HasPrefix(value.Value, "./") || strings.HasPrefix(value.Value, "../") {
+			items = append(items, newReportItem(module, call, "FILE_INCLUDE", "High", fmt.Sprintf("Local file inclusion detected: %s", parser.Unparse(arg))))
 		}
 	}
-	ast.Traverse(findLocalFileInclude)
+	return items
+}
+
+// CheckConditionB 检查远程文件包含：执行的代码来自网络请求或 URL
+func (r *RuleFileInclude) CheckConditionB(module *project.Module) []reporter.ReportItem {
+	var items []reporter.ReportItem
+	parser.Inspect(module.Program, func(node parser.Node) bool {
+		call, ok := node.(*parser.CallExpression)
+		if !ok || !execFunctions[module.Info.CallName(call)] || len(call.Arguments) == 0 {
+			return true
+		}
+		if arg := call.Arguments[0]; r.isRemote(module, arg) {
+			items = append(items, newReportItem(module, call, "FILE_INCLUDE", "High", fmt.Sprintf("Remote file inclusion detected: %s", parser.Unparse(arg))))
+			// 如 exec(compile(...)) 只报告最外层的调用
+			return false
+		}
+		return true
+	})
+	return items
 }
 
-// CheckConditionB 检查远程文件包含
-func (r *RuleFileInclude) CheckConditionB(ast *parser.ast.Node) {
-	findRemoteFileInclude := func(node *parser.ast.Node) {
-		if node.Type == parser.CallExpr && node.FunctionName() == "file_get_contents" {
-			arg := node.Arguments[0].token.Token.Literal
-			if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
-				r.reporter.AddIssue(node.token.Token.SourceFile, node.token.Token.Line, fmt.Sprintf("Remote file inclusion detected: %s", arg))
+// isRemote 检查表达式中是否有网络请求或 http(s) URL
+func (r *RuleFileInclude) isRemote(module *project.Module, expr parser.Expression) bool {
+	found := false
+	parser.Inspect(expr, func(node parser.Node) bool {
+		switch n := node.(type) {
+		case *parser.CallExpression:
+			if ssrfFunctions[module.Info.CallName(n)] {
+				found = true
+			}
+		case *parser.StringLiteral:
+			if strings.HasPrefix(n.Value, "http://") || strings.HasPrefix(n.Value, "https://") {
+				found = true
 			}
 		}
-	}
-	ast.Traverse(findRemoteFileInclude)
+		return !found
+	})
+	return found
 }
@@ -0,0 +1,30 @@
+package rules_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/rules"
+)
+
+func TestRuleFileInclude(t *testing.T) {
+	input := `import importlib, runpy, requests
+from urllib.request import urlopen
+
+importlib.import_module("plugins.csv")
+importlib.import_module("plugins." + name)
+runpy.run_path("../scripts/setup.py")
+exec(requests.get(url).text)
+exec(compile(urlopen("https://example.com/x.py").read(), "x", "exec"))
+exec(open("local.py").read())
+`
+	expected := strings.Join([]string{
+		`m.py:5:1: Local file inclusion detected: "plugins." + name`,
+		`m.py:6:1: Local file inclusion detected: "../scripts/setup.py"`,
+		`m.py:7:1: Remote file inclusion detected: requests.get(url).text`,
+		`m.py:8:1: Remote file inclusion detected: compile(urlopen("https://example.com/x.py").read(), "x", "exec")`,
+	}, "\n")
+	if got := check(t, rules.NewRuleFileInclude(nil), input); got != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", got, expected)
+	}
+}
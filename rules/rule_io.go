@@ -3,10 +3,33 @@ package rules
 import (
 	"strings"
 
+	"github.com/coiloffaraday/python_sast/analyzer"
 	"github.com/coiloffaraday/python_sast/parser"
+	"github.com/coiloffaraday/python_sast/project"
 	"github.com/coiloffaraday/python_sast/reporter"
 )
 
+// commandFunctions 是执行外部命令或启动进程的函数的完全限定名
+var commandFunctions = map[string]bool{
+	"os.system":               true,
+	"os.popen":                true,
+	"subprocess.call":         true,
+	"subprocess.run":          true,
+	"subprocess.Popen":        true,
+	"subprocess.check_call":   true,
+	"subprocess.check_output": true,
+	"multiprocessing.Process": true,
+}
+
+// deleteFunctions 是删除文件或目录的函数的完全限定名
+var deleteFunctions = map[string]bool{
+	"shutil.rmtree": true,
+	"os.remove":     true,
+	"os.rmdir":      true,
+	"os.removedirs": true,
+	"os.unlink":     true,
+}
+
 type RuleIO struct {
 	reporter *reporter.Reporter
 }
@@ -18,97 +41,80 @@ func NewRuleIO(reporter *reporter.Reporter) *RuleIO {
 	}
 }
 
+// Check 实现 analyzer.Rule 接口，返回模块中所有的文件和命令操作问题
+func (r *RuleIO) Check(ctx *analyzer.Context) []reporter.ReportItem {
+	items := r.CheckConditionA(ctx.Module)
+	items = append(items, r.CheckConditionB(ctx.Module)...)
+	items = append(items, r.CheckConditionC(ctx.Module)...)
+	return items
+}
+
 // Apply 应用规则IO，并将结果添加到报告中
-func (r *RuleIO) Apply(ast *parser.ast.Node) {
-	r.CheckConditionA(ast)
-	r.CheckConditionB(ast)
-	r.CheckConditionC(ast)
+func (r *RuleIO) Apply(module *project.Module) {
+	for _, item := range r.Check(&analyzer.Context{Module: module}) {
+		r.reporter.AddReportItem(item)
+	}
 }
 
 // CheckConditionA 检查所有使用os、subprocess、multiprocessing模块的函数是否传入了命令参数，并报告
-func (r *RuleIO) CheckConditionA(ast *parser.ast.Node) {
+func (r *RuleIO) CheckConditionA(module *project.Module) []reporter.ReportItem {
+	var items []reporter.ReportItem
 	// 搜索所有使用os、subprocess、multiprocessing模块的函数调用表达式
-	expressions := ast.SearchExpressions(func(node *parser.ast.Node) bool {
-		if node.Type == parser.ExpressionTypeCall {
-			function := node.Children[0]
-			if function.token.TokenLiteral() == "os.system" || function.token.TokenLiteral() == "subprocess.call" || function.token.TokenLiteral() == "multiprocessing.Process" {
-				return true
-			}
+	for _, call := range parser.FindAll[*parser.CallExpression](module.Program) {
+		if !commandFunctions[module.Info.CallName(call)] {
+			continue
 		}
-		return false
-	})
-
-	// 检查是否传入了命令参数
-	for _, expression := range expressions {
-		for _, argument := range expression.Children[1:] {
-			if argument.Type == parser.ExpressionTypeString {
-				if strings.HasPrefix(strings.TrimSpace(argument.token.TokenLiteral()), "-") {
-					r.reporter.AddIssue(expression.token.TokenLiteral(), expression.token.Token.Line, "调用命令时未指定命令参数")
-				}
-			} else if argument.Type == parser.ExpressionTypeIdentifier {
-				if strings.HasPrefix(strings.TrimSpace(argument.token.TokenLiteral()), "-") {
-					r.reporter.AddIssue(expression.token.TokenLiteral(), expression.token.Token.Line, "调用命令时未指定命令参数")
-				}
+		// 检查是否传入了命令参数：以 "-" 开头的字符串只是选项，没有命令
+		for _, arg := range call.Arguments {
+			if value, ok := stringValue(arg); ok && strings.HasPrefix(strings.TrimSpace(value), "-") {
+				items = append(items, newReportItem(module, call, "IO", "Medium", "调用命令时未指定命令参数"))
+				break
 			}
 		}
 	}
+	return items
 }
 
 // CheckConditionB 检查所有使用shutil、os模块的函数是否调用了rm、rmdir、remove、unlink等删除文件/目录的函数，并报告
-func (r *RuleIO) CheckConditionB(ast *parser.ast.Node) {
-	// 搜索所有使用shutil、os模块的函数调用表达式
-	expressions := ast.SearchExpressions(func(node *parser.ast.Node) bool {
-		if node.Type == parser.ExpressionTypeCall {
-			function := node.Children[0]
-			if function.token.TokenLiteral() == "shutil.rmtree" || function.token.TokenLiteral() == "os.remove" || function.token.TokenLiteral() == "os.rmdir" || function.token.TokenLiteral() == "os.unlink" {
-				return true
-			}
+func (r *RuleIO) CheckConditionB(module *project.Module) []reporter.ReportItem {
+	var items []reporter.ReportItem
+	for _, call := range parser.FindAll[*parser.CallExpression](module.Program) {
+		// 报告所有删除文件/目录的调用
+		if name := module.Info.CallName(call); deleteFunctions[name] {
+			items = append(items, newReportItem(module, call, "IO", "Low", "调用了删除文件/目录的函数："+name))
 		}
-		return false
-	})
-
-	// 报告所有删除文件/目录的调用
-	for _, expression := range expressions {
-		r.reporter.AddIssue(expression.token.TokenLiteral(), expression.token.Token.Line, "调用了删除文件/目录的函数："+expression.Children[0].token.TokenLiteral())
 	}
+	return items
 }
 
-// CheckConditionC 检查所有使用open函数打开文件的函数是否使用了完整的文件路径，防止访问意外文件，并报告
-func (r *RuleIO) CheckConditionC(ast *parser.ast.Node) {
-	for _, node := range ast.Children {
-		switch n := node.(type) {
+// CheckConditionC 检查模块中定义的函数和类是否写入了文件，防止覆盖意外的文件，并报告
+func (r *RuleIO) CheckConditionC(module *project.Module) []reporter.ReportItem {
+	var items []reporter.ReportItem
+	for _, stmt := range module.Program.Statements {
+		switch n := stmt.(type) {
 		case *parser.FunctionDef:
 			// 检查函数中是否包含文件写入操作
-			if r.hasFileWriteOperation(n) {
-				r.reporter.AddIssue(
-					ast.Filename,
-					n.LineNumber,
-					"Potential security issue: writing to file in function "+n.Name.Value,
-				)
+			if r.hasFileWriteOperation(module, n) {
+				items = append(items, newReportItem(module, n.Name, "IO", "Low", "Potential security issue: writing to file in function "+n.Name.Value))
+			}
+		case *parser.AsyncFunctionDef:
+			if r.hasFileWriteOperation(module, n) {
+				items = append(items, newReportItem(module, n.Name, "IO", "Low", "Potential security issue: writing to file in function "+n.Name.Value))
 			}
 		case *parser.ClassDef:
 			// 检查类中是否包含文件写入操作
-			if r.hasFileWriteOperation(n) {
-				r.reporter.AddIssue(
-					ast.Filename,
-					n.LineNumber,
-					"Potential security issue: writing to file in class "+n.Name.Value,
-				)
+			if r.hasFileWriteOperation(module, n) {
+				items = append(items, newReportItem(module, n.Name, "IO", "Low", "Potential security issue: writing to file in class "+n.Name.Value))
 			}
 		}
 	}
+	return items
 }
 
 // hasFileWriteOperation 检查函数或类中是否包含文件写入操作
-func (r *RuleIO) hasFileWriteOperation(node parser.Node) bool {
-	for _, child := range node.Children {
-		if op, ok := child.(*parser.Call); ok {
-			// 检查函数调用中是否包含文件写入操作
-			if r.isFileWriteOperation(op) {
-				return true
-			}
-		}
-		if r.hasFileWriteOperation(child) {
+func (r *RuleIO) hasFileWriteOperation(module *project.Module, node parser.Node) bool {
+	for _, call := range parser.FindAll[*parser.CallExpression](node) {
+		if r.isFileWriteOperation(module, call) {
 			return true
 		}
 	}
@@ -116,18 +122,12 @@ func (r *RuleIO) hasFileWriteOperation(node parser.Node) bool {
 }
 
 // isFileWriteOperation 检查函数调用是否是文件写入操作
-func (r *RuleIO) isFileWriteOperation(call *parser.Call) bool {
-	if len(call.Args) < 2 {
-		return false
-	}
+func (r *RuleIO) isFileWriteOperation(module *project.Module, call *parser.CallExpression) bool {
 	// 检查函数名是否为 open
-	if funcName, ok := call.Func.(*parser.Name); ok && funcName.Id == "open" {
-		// 检查文件操作模式是否为写入
-		if mode, ok := call.Args[1].(*parser.Str); ok {
-			if strings.Contains(mode.S, "w") || strings.Contains(mode.S, "a") {
-				return true
-			}
-		}
+	if module.Info.CallName(call) != "builtins.open" {
+		return false
 	}
-	return false
+	// 检查文件操作模式是否为写入
+	mode, ok := stringValue(argument(call, 1, "mode"))
+	return ok && (strings.Contains(mode, "w") || strings.Contains(mode, "a"))
 }
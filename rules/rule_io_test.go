@@ -0,0 +1,41 @@
+package rules_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/rules"
+)
+
+func TestRuleIO(t *testing.T) {
+	input := `import os, shutil
+from subprocess import call as run
+
+run("-rf /tmp")
+os.system("ls -l")
+shutil.rmtree(path)
+
+def save(data):
+    with open("out.txt", mode="a") as f:
+        f.write(data)
+
+class Cache:
+    def load(self):
+        return open(self.path, "rb").read()
+
+    def store(self, data):
+        open(self.path, "w").write(data)
+
+def read():
+    return open("in.txt").read()
+`
+	expected := strings.Join([]string{
+		"m.py:4:1: 调用命令时未指定命令参数",
+		"m.py:6:1: 调用了删除文件/目录的函数：shutil.rmtree",
+		"m.py:8:5: Potential security issue: writing to file in function save",
+		"m.py:12:7: Potential security issue: writing to file in class Cache",
+	}, "\n")
+	if got := check(t, rules.NewRuleIO(nil), input); got != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", got, expected)
+	}
+}
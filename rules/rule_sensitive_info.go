@@ -1,45 +1,149 @@
 package rules
 
 import (
+	"regexp"
+	"strings"
+
+	"github.com/coiloffaraday/python_sast/analyzer"
 	"github.com/coiloffaraday/python_sast/parser"
+	"github.com/coiloffaraday/python_sast/project"
 	"github.com/coiloffaraday/python_sast/reporter"
 )
 
+// sensitiveName 匹配保存敏感信息的变量名、属性名和参数名
+var sensitiveName = regexp.MustCompile(`(?i)(passw(or)?d|pwd|secret|token|api_?key|private_?key|access_?key|credential)`)
+
+// logMethods 是日志对象的输出方法，日志对象通常无法解析出完全限定名，按方法名匹配
+var logMethods = map[string]bool{
+	"debug":     true,
+	"info":      true,
+	"warning":   true,
+	"warn":      true,
+	"error":     true,
+	"critical":  true,
+	"exception": true,
+	"log":       true,
+}
+
+// weakCryptoFunctions 是使用不安全的哈希或加密算法的函数的完全限定名
+var weakCryptoFunctions = map[string]bool{
+	"hashlib.md5":                    true,
+	"hashlib.sha1":                   true,
+	"Crypto.Cipher.DES.new":          true,
+	"Crypto.Cipher.ARC4.new":         true,
+	"Crypto.Cipher.Blowfish.new":     true,
+	"Cryptodome.Cipher.DES.new":      true,
+	"Cryptodome.Cipher.ARC4.new":     true,
+	"Cryptodome.Cipher.Blowfish.new": true,
+}
+
 type RuleSensitiveInfo struct {
 	reporter *reporter.Reporter
 }
 
+// NewRuleSensitiveInfo 创建并返回一个新的RuleSensitiveInfo实例
 func NewRuleSensitiveInfo(reporter *reporter.Reporter) *RuleSensitiveInfo {
 	return &RuleSensitiveInfo{
 		reporter: reporter,
 	}
 }
 
-func (r *RuleSensitiveInfo) Apply(ast *parser.ast.Node) {
-	r.CheckConditionA(ast)
-	r.CheckConditionB(ast)
-	r.CheckConditionC(ast)
+// Check 实现 analyzer.Rule 接口，返回模块中所有的敏感信息问题
+func (r *RuleSensitiveInfo) Check(ctx *analyzer.Context) []reporter.ReportItem {
+	items := r.CheckConditionA(ctx.Module)
+	items = append(items, r.CheckConditionB(ctx.Module)...)
+	items = append(items, r.CheckConditionC(ctx.Module)...)
+	return items
+}
+
+// Apply 应用规则并将结果添加到报告中
+func (r *RuleSensitiveInfo) Apply(module *project.Module) {
+	for _, item := range r.Check(&analyzer.Context{Module: module}) {
+		r.reporter.AddReportItem(item)
+	}
 }
 
-func (r *RuleSensitiveInfo) CheckConditionA(ast *parser.ast.Node) {
-	// 检查敏感信息泄露
-	// 根据你的语言和场景设计相关正则表达式和关键字
-	// 使用ast.FindAll函数查找相关语法节点
-	// 将发现的问题添加到报告中
+// CheckConditionA 检查敏感信息泄露：把非空字符串常量赋给敏感名称，即硬编码的密码、密钥等
+func (r *RuleSensitiveInfo) CheckConditionA(module *project.Module) []reporter.ReportItem {
+	var items []reporter.ReportItem
+	check := func(target parser.Expression, value parser.Expression) {
+		name := targetName(target)
+		if s, ok := value.(*parser.StringLiteral); ok && s.Value != "" && sensitiveName.MatchString(name) {
+			items = append(items, newReportItem(module, value, "SENSITIVE_INFO", "High", "Hard-coded sensitive information in "+name))
+		}
+	}
+	parser.Inspect(module.Program, func(node parser.Node) bool {
+		switch n := node.(type) {
+		case *parser.AssignmentStatement:
+			for _, target := range n.Targets {
+				check(target, n.Value)
+			}
+		case *parser.AnnAssignStatement:
+			if n.Value != nil {
+				check(n.Target, n.Value)
+			}
+		case *parser.KeywordArgument:
+			if n.Name != nil {
+				check(n.Name, n.Value)
+			}
+		}
+		return true
+	})
+	return items
 }
 
-func (r *RuleSensitiveInfo) CheckConditionB(ast *parser.ast.Node) {
-	// 检查敏感信息写入
-	// 根据你的语言和场景设计相关正则表达式和关键字
-	// 使用ast.FindAll函数查找相关语法节点
-	// 将发现的问题添加到报告中
+// CheckConditionB 检查敏感信息写入：把敏感名称的值打印或写入日志
+func (r *RuleSensitiveInfo) CheckConditionB(module *project.Module) []reporter.ReportItem {
+	var items []reporter.ReportItem
+	for _, call := range parser.FindAll[*parser.CallExpression](module.Program) {
+		// 日志对象的方法调用没有完全限定名，logging 模块的函数则有
+		name := module.Info.CallName(call)
+		isLog := logMethods[methodName(call)] && (name == "" || strings.HasPrefix(name, "logging."))
+		if name != "builtins.print" && !isLog {
+			continue
+		}
+		for _, arg := range call.Arguments {
+			parser.Inspect(arg, func(node parser.Node) bool {
+				expr, ok := node.(parser.Expression)
+				if !ok {
+					return true
+				}
+				if name := targetName(expr); name != "" && sensitiveName.MatchString(name) {
+					items = append(items, newReportItem(module, expr, "SENSITIVE_INFO", "Medium", "Sensitive information "+name+" is written to the output"))
+					return false
+				}
+				return true
+			})
+		}
+	}
+	return items
 }
 
-func (r *RuleSensitiveInfo) CheckConditionC(ast *parser.ast.Node) {
-	// 检查敏感信息加密
-	// 根据你的语言和场景设计相关正则表达式和关键字
-	// 使用ast.FindAll函数查找相关语法节点
-	// 将发现的问题添加到报告中
+// CheckConditionC 检查敏感信息加密：使用了不安全的哈希或加密算法
+func (r *RuleSensitiveInfo) CheckConditionC(module *project.Module) []reporter.ReportItem {
+	var items []reporter.ReportItem
+	for _, call := range parser.FindAll[*parser.CallExpression](module.Program) {
+		name := module.Info.CallName(call)
+		if name == "hashlib.new" {
+			// hashlib.new("md5") 与 hashlib.md5() 相同
+			if algorithm, ok := stringValue(argument(call, 0, "name")); ok {
+				name = "hashlib." + strings.ToLower(algorithm)
+			}
+		}
+		if weakCryptoFunctions[name] {
+			items = append(items, newReportItem(module, call, "SENSITIVE_INFO", "Medium", "Weak cryptographic algorithm: "+name))
+		}
+	}
+	return items
 }
 
-// ...更多检查敏感信息的函数
+// targetName 返回名称或属性的名字，如 "self.api_key" 的 "api_key"；其他表达式返回 ""
+func targetName(expr parser.Expression) string {
+	switch e := expr.(type) {
+	case *parser.Identifier:
+		return e.Value
+	case *parser.AttributeExpression:
+		return e.Attribute.Value
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+package rules_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/rules"
+)
+
+func TestRuleSensitiveInfo(t *testing.T) {
+	input := `import hashlib, logging
+from Crypto.Cipher import DES
+
+DB_PASSWORD = "hunter2"
+password = ""
+client = Client(api_key="sk-123", timeout=3)
+log = logging.getLogger(__name__)
+
+def login(user, password):
+    log.info("login %s %s", user, password)
+    print(user.token.upper())
+    print(f"{user.name}")
+    digest = hashlib.new("MD5", password.encode())
+    key = DES.new(secret_key, DES.MODE_ECB)
+    return hashlib.sha256(password.encode())
+`
+	expected := strings.Join([]string{
+		"m.py:4:15: Hard-coded sensitive information in DB_PASSWORD",
+		"m.py:6:25: Hard-coded sensitive information in api_key",
+		"m.py:10:35: Sensitive information password is written to the output",
+		"m.py:11:11: Sensitive information token is written to the output",
+		"m.py:13:14: Weak cryptographic algorithm: hashlib.md5",
+		"m.py:14:11: Weak cryptographic algorithm: Crypto.Cipher.DES.new",
+	}, "\n")
+	if got := check(t, rules.NewRuleSensitiveInfo(nil), input); got != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", got, expected)
+	}
+}
@@ -3,10 +3,17 @@ package rules
 import (
 	"regexp"
 
+	"github.com/coiloffaraday/python_sast/analyzer"
 	"github.com/coiloffaraday/python_sast/parser"
+	"github.com/coiloffaraday/python_sast/project"
 	"github.com/coiloffaraday/python_sast/reporter"
 )
 
+// sqlInjectionPattern 是检测SQL注入风险的模式：以SQL语句开头的字符串
+var sqlInjectionPattern = regexp.MustCompile(`(?i)^\s*(SELECT|INSERT|UPDATE|DELETE|CREATE|DROP|ALTER)\s`)
+
+// RuleSQLInjection 只看语法：报告用拼接、格式化等方式拼出的 SQL 语句，不论拼入的数据来自哪里。
+// 追踪数据来源的版本见 rules/sem
 type RuleSQLInjection struct {
 	reporter *reporter.Reporter
 }
@@ -18,27 +25,55 @@ func NewRuleSQLInjection(reporter *reporter.Reporter) *RuleSQLInjection {
 	}
 }
 
-// Apply 应用规则SQL注入，并将结果添加到报告中
-func (r *RuleSQLInjection) Apply(ast *parser.Node) {
-	r.CheckSQLInjection(ast)
+// Check 实现 analyzer.Rule 接口，返回模块中所有动态拼出的 SQL 语句
+func (r *RuleSQLInjection) Check(ctx *analyzer.Context) []reporter.ReportItem {
+	return r.CheckSQLInjection(ctx.Module)
 }
 
-// CheckSQLInjection 检查SQL注入并报告
-func (r *RuleSQLInjection) CheckSQLInjection(node *parser.Node) {
-	// 检查字符串拼接、格式化字符串等可能导致SQL注入的用法
-	// 遍历AST查找相关语法节点
-	// 将发现的问题添加到报告中
-
-	// 正则表达式检测SQL注入风险的模式
-	sqlInjectionPattern := regexp.MustCompile(`(?i)(SELECT|INSERT|UPDATE|DELETE|CREATE|DROP|ALTER)\s`)
+// Apply 应用规则SQL注入，并将结果添加到报告中
+func (r *RuleSQLInjection) Apply(module *project.Module) {
+	for _, item := range r.Check(&analyzer.Context{Module: module}) {
+		r.reporter.AddReportItem(item)
+	}
+}
 
-	if node.Type == parser.NodeString {
-		if sqlInjectionPattern.MatchString(node.Properties["value"].(string)) {
-			r.reporter.AddIssue(node.FilePath, node.Line, "Possible SQL injection vulnerability: "+node.Properties["value"].(string))
+// CheckSQLInjection 检查字符串拼接、格式化字符串等可能导致SQL注入的用法
+func (r *RuleSQLInjection) CheckSQLInjection(module *project.Module) []reporter.ReportItem {
+	var items []reporter.ReportItem
+	parser.Inspect(module.Program, func(node parser.Node) bool {
+		var query parser.Expression
+		switch n := node.(type) {
+		case *parser.InfixExpression:
+			// "select ... " + x 和 "select ... %s" % x
+			if n.Operator == "+" || n.Operator == "%" {
+				query = n.Left
+			}
+		case *parser.CallExpression:
+			// "select ... {}".format(x)
+			if attr, ok := n.Function.(*parser.AttributeExpression); ok && attr.Attribute.Value == "format" {
+				query = attr.Object
+			}
+		case *parser.JoinedStr:
+			// f"select ... {x}"
+			if hasFormattedValue(n) {
+				query = n
+			}
 		}
-	}
+		if value, ok := stringValue(query); ok && sqlInjectionPattern.MatchString(value) {
+			items = append(items, newReportItem(module, node, "SQL_INJECTION_PATTERN", "Medium", "Possible SQL injection vulnerability: "+parser.Unparse(node)))
+			return false
+		}
+		return true
+	})
+	return items
+}
 
-	for _, child := range node.Children {
-		r.CheckSQLInjection(child)
+// hasFormattedValue 检查 f-string 中是否有替换字段
+func hasFormattedValue(str *parser.JoinedStr) bool {
+	for _, value := range str.Values {
+		if _, ok := value.(*parser.FormattedValue); ok {
+			return true
+		}
 	}
+	return false
 }
@@ -0,0 +1,28 @@
+package rules_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/rules"
+)
+
+func TestRuleSQLInjection(t *testing.T) {
+	input := `cur.execute("SELECT * FROM users WHERE id = " + uid)
+cur.execute("DELETE FROM t WHERE name = '%s'" % name)
+cur.execute("update t set a = {}".format(a))
+cur.execute(f"select * from t where id = {uid}")
+cur.execute("SELECT * FROM users WHERE id = ?", (uid,))
+cur.execute(f"select 1")
+message = "Selected " + str(n)
+`
+	expected := strings.Join([]string{
+		`m.py:1:13: Possible SQL injection vulnerability: "SELECT * FROM users WHERE id = " + uid`,
+		`m.py:2:13: Possible SQL injection vulnerability: "DELETE FROM t WHERE name = '%s'" % name`,
+		`m.py:3:13: Possible SQL injection vulnerability: "update t set a = {}".format(a)`,
+		`m.py:4:13: Possible SQL injection vulnerability: f"select * from t where id = {uid}"`,
+	}, "\n")
+	if got := check(t, rules.NewRuleSQLInjection(nil), input); got != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", got, expected)
+	}
+}
@@ -1,10 +1,41 @@
 package rules
 
 import (
+	"fmt"
+
+	"github.com/coiloffaraday/python_sast/analyzer"
 	"github.com/coiloffaraday/python_sast/parser"
 	"github.com/coiloffaraday/python_sast/reporter"
+	"github.com/coiloffaraday/python_sast/semantic"
 )
 
+// ssrfFunctions 是可能导致 SSRF 的函数的完全限定名。
+// 调用名经过导入解析，因此 "from requests import get as fetch" 和
+// "import requests as r" 这样的写法同样能匹配。
+var ssrfFunctions = map[string]bool{
+	"requests.get":                true,
+	"requests.post":               true,
+	"requests.put":                true,
+	"requests.patch":              true,
+	"requests.delete":             true,
+	"requests.head":               true,
+	"requests.options":            true,
+	"requests.request":            true,
+	"requests.api.get":            true,
+	"requests.api.post":           true,
+	"requests.api.request":        true,
+	"httpx.get":                   true,
+	"httpx.post":                  true,
+	"httpx.request":               true,
+	"urllib.request.urlopen":      true,
+	"urllib.request.Request":      true,
+	"urllib2.urlopen":             true,
+	"urllib.urlopen":              true,
+	"http.client.HTTPConnection":  true,
+	"http.client.HTTPSConnection": true,
+	// 添加更多潜在的 SSRF 函数
+}
+
 type RuleSSRF struct {
 	reporter *reporter.Reporter
 }
@@ -13,43 +44,45 @@ func NewRuleSSRF(reporter *reporter.Reporter) *RuleSSRF {
 	return &RuleSSRF{reporter: reporter}
 }
 
-func (r *RuleSSRF) CheckForSSRF(node parser.Node) {
-	// 递归地检查所有子节点
-	for _, child := range node.GetChildren() {
-		r.CheckForSSRF(child)
-	}
-
-	// 如果当前节点是一个函数调用
-	if callExpr, ok := node.(*parser.CallExpression); ok {
-		// 检查函数名称是否与潜在的 SSRF 函数匹配
-		if r.isSSRFFunction(callExpr.Function) {
-			// 如果匹配，则报告 SSRF
-			r.reporter.AddReportItem(reporter.ReportItem{
-				Filename:    callExpr.Token.FilePath,
-				Line:        callExpr.Token.Line,
-				Column:      callExpr.Token.Column,
-				RuleName:    "SSRF",
-				Description: "Possible SSRF detected",
-			})
-		}
-	}
+// Check 实现 analyzer.Rule 接口，返回模块中所有可能导致 SSRF 的函数调用
+func (r *RuleSSRF) Check(ctx *analyzer.Context) []reporter.ReportItem {
+	return r.findSSRF(ctx.Module.Program, ctx.Module.Info)
 }
 
-// isSSRFFunction 检查给定的函数名是否与潜在的 SSRF 函数匹配
-func (r *RuleSSRF) isSSRFFunction(functionName string) bool {
-	// 在这里，我们添加一些可能导致 SSRF 的函数名
-	ssrfFunctions := []string{
-		"requests.get",
-		"requests.post",
-		"requests.options",
-		// 添加更多潜在的 SSRF 函数
+// CheckForSSRF 检查程序中所有可能导致 SSRF 的函数调用并添加到报告中，info 是该程序的语义分析结果
+func (r *RuleSSRF) CheckForSSRF(program *parser.Program, info *semantic.Info) {
+	for _, item := range r.findSSRF(program, info) {
+		r.reporter.AddReportItem(item)
 	}
+}
 
-	for _, ssrfFunc := range ssrfFunctions {
-		if functionName == ssrfFunc {
+func (r *RuleSSRF) findSSRF(program *parser.Program, info *semantic.Info) []reporter.ReportItem {
+	var items []reporter.ReportItem
+	parser.Inspect(program, func(node parser.Node) bool {
+		// 如果当前节点是一个函数调用
+		callExpr, ok := node.(*parser.CallExpression)
+		if !ok {
 			return true
 		}
-	}
 
-	return false
+		// 检查完全限定的函数名是否与潜在的 SSRF 函数匹配
+		name := info.CallName(callExpr)
+		if r.isSSRFFunction(name) {
+			// 如果匹配，则报告 SSRF
+			pos := callExpr.Pos()
+			items = append(items, reporter.ReportItem{
+				RuleID:      "SSRF",
+				Description: fmt.Sprintf("Possible SSRF detected: call to %s", name),
+				Severity:    "High",
+				Location:    fmt.Sprintf("%s:%d:%d", callExpr.Token.FilePath, pos.Line, pos.Column),
+			})
+		}
+		return true
+	})
+	return items
+}
+
+// isSSRFFunction 检查给定的完全限定函数名是否与潜在的 SSRF 函数匹配
+func (r *RuleSSRF) isSSRFFunction(qualifiedName string) bool {
+	return ssrfFunctions[qualifiedName]
 }
@@ -0,0 +1,43 @@
+package rules_test
+
+import (
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/rules"
+)
+
+func TestRuleSSRF(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"import requests as r\nr.get(url)\n",
+			"m.py:2:1: Possible SSRF detected: call to requests.get",
+		},
+		{
+			"from requests import get as fetch\nfetch(url)\n",
+			"m.py:2:1: Possible SSRF detected: call to requests.get",
+		},
+		{
+			"import urllib.request\nurllib.request.urlopen(url)\n",
+			"m.py:2:1: Possible SSRF detected: call to urllib.request.urlopen",
+		},
+		{
+			// A local function of the same name is not requests.get.
+			"import requests\n\ndef get(url):\n    return url\n\nget(url)\n",
+			"",
+		},
+		{
+			// Neither is a name that was rebound after the import.
+			"from requests import get\nget = print\nget(url)\n",
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := check(t, rules.NewRuleSSRF(nil), tt.input); got != tt.expected {
+			t.Errorf("%q:\n got: %s\nwant: %s", tt.input, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package rules_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/analyzer"
+	"github.com/coiloffaraday/python_sast/project"
+)
+
+// check runs rule on a module m.py made of input, and returns the problems
+// found as "m.py:line:column: description", one per line.
+func check(t *testing.T, rule analyzer.Rule, input string) string {
+	t.Helper()
+	g, err := project.New(t.TempDir(), project.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := g.AddSource(g.Dir+"/m.py", input)
+	if len(m.Diagnostics) > 0 {
+		t.Fatalf("%q: %v", input, m.Diagnostics)
+	}
+
+	var lines []string
+	for _, item := range rule.Check(&analyzer.Context{Module: m, Project: g}) {
+		lines = append(lines, strings.TrimPrefix(item.Location, g.Dir+"/")+": "+item.Description)
+	}
+	return strings.Join(lines, "\n")
+}
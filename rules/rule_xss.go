@@ -4,10 +4,20 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/coiloffaraday/python_sast/analyzer"
 	"github.com/coiloffaraday/python_sast/parser"
+	"github.com/coiloffaraday/python_sast/project"
 	"github.com/coiloffaraday/python_sast/reporter"
 )
 
+// eventHandlerPattern 匹配 HTML 标签中的事件处理属性（如onclick、onmouseover等）
+var eventHandlerPattern = regexp.MustCompile(`(?i)<[^>]*?\s(on\w+)=["']?([^"'>]+)["']?`)
+
+// dangerousScriptPattern 匹配 JavaScript 中危险的函数和属性
+var dangerousScriptPattern = regexp.MustCompile(`(?i)\b(eval|setInterval|setTimeout|document\.write|document\.writeln|\.innerhtml|window\.location)\b`)
+
+// RuleXSS 只看语法：报告 Python 代码中写死的 HTML 和 JavaScript 里的危险用法。
+// 追踪用户输入的版本见 rules/sem
 type RuleXSS struct {
 	reporter *reporter.Reporter
 }
@@ -19,40 +29,58 @@ func NewRuleXSS(reporter *reporter.Reporter) *RuleXSS {
 	}
 }
 
+// Check 实现 analyzer.Rule 接口，返回模块中 HTML 和 JavaScript 字符串里的 XSS 风险
+func (r *RuleXSS) Check(ctx *analyzer.Context) []reporter.ReportItem {
+	return append(r.CheckConditionA(ctx.Module), r.CheckConditionB(ctx.Module)...)
+}
+
 // Apply 应用规则XSS，并将结果添加到报告中
-func (r *RuleXSS) Apply(ast *parser.Node) {
-	r.CheckConditionA(ast)
-	r.CheckConditionB(ast)
+func (r *RuleXSS) Apply(module *project.Module) {
+	for _, item := range r.Check(&analyzer.Context{Module: module}) {
+		r.reporter.AddReportItem(item)
+	}
 }
 
-// CheckConditionA 检查敏感信息泄露并报告
-func (r *RuleXSS) CheckConditionA(ast *parser.Node) {
-	// 查找HTML标签和属性值，检查是否存在危险的HTML属性（如onclick、onmouseover等）
-	// 使用ast.FindAll函数查找相关语法节点
-	// 将发现的问题添加到报告中
-	re := regexp.MustCompile(`(?i)<[^>]*?\s(on\w+)=["']?([^"'>]+)["']?`)
-	nodes := ast.FindAll(parser.NodeTypeElement)
-	for _, node := range nodes {
-		if !strings.HasPrefix(node.Properties["token"].(parser.Token).Literal(), "<script") && !strings.HasPrefix(node.Properties["token"].(parser.Token).Literal(), "<style") {
-			for _, attr := range node.Properties["attributes"].([]parser.Attribute) {
-				if re.MatchString(attr.Value) {
-					r.reporter.AddIssue(ast.FilePath, node.Line, "Possible XSS vulnerability in attribute '"+attr.Key+"'")
-				}
-			}
+// CheckConditionA 查找HTML标签和属性值，检查是否存在危险的HTML属性（如onclick、onmouseover等）并报告
+func (r *RuleXSS) CheckConditionA(module *project.Module) []reporter.ReportItem {
+	var items []reporter.ReportItem
+	inspectStrings(module.Program, func(str parser.Expression, value string) {
+		lower := strings.TrimSpace(strings.ToLower(value))
+		if strings.HasPrefix(lower, "<script") || strings.HasPrefix(lower, "<style") {
+			return
 		}
-	}
+		for _, match := range eventHandlerPattern.FindAllStringSubmatch(value, -1) {
+			items = append(items, newReportItem(module, str, "XSS_PATTERN", "Medium", "Possible XSS vulnerability in attribute '"+match[1]+"'"))
+		}
+	})
+	return items
 }
 
-// CheckConditionB 检查敏感信息泄露并报告
-func (r *RuleXSS) CheckConditionB(ast *parser.Node) {
-	// 检查JavaScript中是否存在危险的函数（如eval、setInterval等）
-	// 使用ast.FindAll函数查找相关语法节点
-	// 将发现的问题添加到报告中
-	re := regexp.MustCompile(`(?i)\b(eval|setInterval|setTimeout|document\.write|document\.writeln|document\.innerhtml|window\.location)\b`)
-	nodes := ast.FindAll(parser.NodeTypeScript)
-	for _, node := range nodes {
-		if re.MatchString(node.Properties["token"].(parser.Token).Literal()) {
-			r.reporter.AddIssue(ast.FilePath, node.Line, "Possible XSS vulnerability in script: "+node.Properties["token"].(parser.Token).Literal())
+// CheckConditionB 检查JavaScript中是否存在危险的函数（如eval、setInterval等）并报告
+func (r *RuleXSS) CheckConditionB(module *project.Module) []reporter.ReportItem {
+	var items []reporter.ReportItem
+	inspectStrings(module.Program, func(str parser.Expression, value string) {
+		if !strings.Contains(strings.ToLower(value), "<script") {
+			return
 		}
-	}
+		for _, match := range dangerousScriptPattern.FindAllString(value, -1) {
+			items = append(items, newReportItem(module, str, "XSS_PATTERN", "Medium", "Possible XSS vulnerability in script: "+match))
+		}
+	})
+	return items
+}
+
+// inspectStrings 对树中每个字符串调用 f。f-string 作为一个整体，value 是其中的文本部分
+func inspectStrings(root parser.Node, f func(str parser.Expression, value string)) {
+	parser.Inspect(root, func(node parser.Node) bool {
+		switch n := node.(type) {
+		case *parser.StringLiteral:
+			f(n, n.Value)
+		case *parser.JoinedStr:
+			value, _ := stringValue(n)
+			f(n, value)
+			return false
+		}
+		return true
+	})
 }
@@ -0,0 +1,26 @@
+package rules_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/rules"
+)
+
+func TestRuleXSS(t *testing.T) {
+	input := `html = '<a href="#" onclick="go(%s)">x</a>' % target
+page = f"<img src={src} onerror='alert(1)'>"
+script = "<script>document.write(location.hash); el.innerHTML = x</script>"
+style = "<style>p { color: red }</style>"
+plain = "<p class='note'>hi</p>"
+`
+	expected := strings.Join([]string{
+		"m.py:1:8: Possible XSS vulnerability in attribute 'onclick'",
+		"m.py:2:8: Possible XSS vulnerability in attribute 'onerror'",
+		"m.py:3:10: Possible XSS vulnerability in script: document.write",
+		"m.py:3:10: Possible XSS vulnerability in script: .innerHTML",
+	}, "\n")
+	if got := check(t, rules.NewRuleXSS(nil), input); got != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", got, expected)
+	}
+}
@@ -0,0 +1,53 @@
+package rules_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/analyzer"
+	"github.com/coiloffaraday/python_sast/project"
+	rules "github.com/coiloffaraday/python_sast/rules/sem"
+)
+
+func TestTaintRules(t *testing.T) {
+	g, err := project.New(t.TempDir(), project.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.AddSource(g.Dir+"/queries.py", `def find_user(db, name):
+    return db.cursor().execute("select * from users where name = '%s'" % name)
+`)
+	g.AddSource(g.Dir+"/views.py", `from flask import request, make_response
+from html import escape
+from queries import find_user
+
+def user(db):
+    name = request.args.get("name")
+    find_user(db, name)
+    return make_response("<h1>%s</h1>" % escape(name))
+
+def search(request):
+    q = request.GET["q"]
+    return make_response(f"<p>{q}</p>")
+`)
+
+	a := analyzer.NewAnalyzer(nil, rules.NewRuleSQLInjection(), rules.NewRuleXSSSemantic())
+	items := a.AnalyzeProject(g)
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %v", items)
+	}
+
+	ids := map[string]string{}
+	for _, item := range items {
+		ids[item.RuleID] = item.Location + "\n" + item.Description
+	}
+	sql := ids["SQL_INJECTION"]
+	for _, want := range []string{"queries.py:2:", "views.py:6:", "passed to find_user as name", "reaches SQL query (*.execute)"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("the SQL injection should mention %q:\n%s", want, sql)
+		}
+	}
+	if xss := ids["XSS"]; !strings.Contains(xss, "views.py:12:") || !strings.Contains(xss, "Django request (*.GET)") {
+		t.Errorf("the XSS should be the one in search:\n%s", xss)
+	}
+}
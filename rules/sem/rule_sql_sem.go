@@ -1,77 +1,45 @@
 package rules
 
 import (
-	"github.com/coiloffaraday/python_sast/parser"
-	"github.com/coiloffaraday/python_sast/reporter"
+	"github.com/coiloffaraday/python_sast/taint"
 )
 
-type RuleSQLInjection struct {
-	reporter *reporter.Reporter
-}
-
-func NewRuleSQLInjection(reporter *reporter.Reporter) *RuleSQLInjection {
-	return &RuleSQLInjection{
-		reporter: reporter,
-	}
+// sqlSpec 声明 SQL 注入的污点规则：用户输入流入 SQL 语句
+var sqlSpec = taint.Spec{
+	Sources: webSources,
+	Sinks: []taint.Sink{
+		// DB-API 游标的类型无法确定，按方法名匹配，只检查 SQL 语句参数
+		{Name: "*.execute", Description: "SQL query", Args: []int{0}, Keywords: []string{"sql", "query", "operation"}},
+		{Name: "*.executemany", Description: "SQL query", Args: []int{0}, Keywords: []string{"sql", "query", "operation"}},
+		{Name: "*.executescript", Description: "SQL script", Args: []int{0}, Keywords: []string{"sql_script"}},
+		// Django ORM 中的原生 SQL
+		{Name: "*.raw", Description: "raw SQL query", Args: []int{0}, Keywords: []string{"raw_query"}},
+		{Name: "*.extra", Description: "raw SQL fragment", Keywords: []string{"where", "select", "tables"}},
+		{Name: "django.db.models.expressions.RawSQL", Description: "raw SQL fragment", Args: []int{0}, Keywords: []string{"sql"}},
+		{Name: "sqlalchemy.text", Description: "SQL text", Args: []int{0}, Keywords: []string{"text"}},
+		{Name: "sqlalchemy.sql.text", Description: "SQL text", Args: []int{0}, Keywords: []string{"text"}},
+		{Name: "pandas.read_sql", Description: "SQL query", Args: []int{0}, Keywords: []string{"sql"}},
+		{Name: "pandas.read_sql_query", Description: "SQL query", Args: []int{0}, Keywords: []string{"sql"}},
+	},
+	Sanitizers: []string{
+		"builtins.int", "builtins.float", "builtins.bool",
+		"pymysql.converters.escape_string", "MySQLdb.escape_string",
+		"psycopg2.extensions.quote_ident", "*.escape_string",
+	},
+	Propagators: taint.DefaultPropagators,
 }
 
-func (r *RuleSQLInjection) Apply(ast *parser.ast.Node) {
-	r.CheckSQLInjection(ast)
-}
-
-func (r *RuleSQLInjection) CheckSQLInjection(ast *parser.ast.Node) {
-	for _, node := range ast.Children {
-		if node.Type == parser.NodeTypeFunctionCall {
-			functionNameNode := node.Children[0]
-			if functionNameNode.Type == parser.NodeTypeIdentifier {
-				functionName := functionNameNode.token.TokenLiteral()
-				if isSQLInjectionFunction(functionName) {
-					argumentNode := node.Children[1]
-					if argumentNode.Type == parser.NodeTypeStringLiteral {
-						if isUserInput(argumentNode.token.TokenLiteral()) {
-							r.reporter.AddIssue(ast.FilePath, argumentNode.Line, "Possible SQL injection vulnerability")
-						}
-					}
-				}
-			}
-		}
-	}
-
-	for _, child := range ast.Children {
-		r.CheckSQLInjection(child)
-	}
-}
-
-func isSQLInjectionFunction(functionName string) bool {
-	sqlFunctions := []string{"mysql_query", "mysqli_query", "pg_query", "sqlite_query", "pg_send_query", "pg_query_params"}
-	for _, f := range sqlFunctions {
-		if f == functionName {
-			return true
-		}
-	}
-	return false
+// RuleSQLInjection 检查用户输入未经处理就拼接进 SQL 语句的情况
+type RuleSQLInjection struct {
+	taintRule
 }
 
-func isUserInput(node *parser.ast.Node) bool {
-	// 检查节点类型是否为字符串字面量
-	if node.Type == parser.StringLiteral {
-		// 检查节点的父节点是否为函数调用
-		parent := node.Parent
-		if parent.Type == parser.CallExpression {
-			// 检查函数名是否为预定义的输入函数，如get/post/cookie等
-			function := parent.Children[0]
-			if function.token.TokenLiteral() == "get" || function.token.TokenLiteral() == "post" || function.token.TokenLiteral() == "cookie" {
-				return true
-			}
-		}
-	}
-
-	// 遍历子节点进行递归检查
-	for _, child := range node.Children {
-		if isUserInput(child) {
-			return true
-		}
-	}
-
-	return false
+// NewRuleSQLInjection 创建并返回一个新的 RuleSQLInjection 实例
+func NewRuleSQLInjection() *RuleSQLInjection {
+	return &RuleSQLInjection{taintRule{
+		id:       "SQL_INJECTION",
+		severity: "High",
+		message:  "Possible SQL injection",
+		spec:     sqlSpec,
+	}}
 }
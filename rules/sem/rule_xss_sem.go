@@ -1,77 +1,42 @@
 package rules
 
 import (
-	"github.com/coiloffaraday/python_sast/parser"
-	"github.com/coiloffaraday/python_sast/reporter"
+	"github.com/coiloffaraday/python_sast/taint"
 )
 
-type RuleXSSSemantic struct {
-	reporter *reporter.Reporter
-}
-
-// NewRuleXSSSemantic 创建并返回一个新的RuleXSSSemantic实例
-func NewRuleXSSSemantic(reporter *reporter.Reporter) *RuleXSSSemantic {
-	return &RuleXSSSemantic{
-		reporter: reporter,
-	}
+// xssSpec 声明 XSS 的污点规则：用户输入未经 HTML 编码就输出到页面
+var xssSpec = taint.Spec{
+	Sources: webSources,
+	Sinks: []taint.Sink{
+		{Name: "flask.make_response", Description: "HTTP response", Args: []int{0}},
+		{Name: "flask.Response", Description: "HTTP response", Args: []int{0}, Keywords: []string{"response"}},
+		{Name: "flask.render_template_string", Description: "template", Args: []int{0}, Keywords: []string{"source"}},
+		{Name: "django.http.HttpResponse", Description: "HTTP response", Args: []int{0}, Keywords: []string{"content"}},
+		{Name: "django.utils.safestring.mark_safe", Description: "safe string", Args: []int{0}, Keywords: []string{"s"}},
+		{Name: "markupsafe.Markup", Description: "safe markup", Args: []int{0}},
+		{Name: "jinja2.Markup", Description: "safe markup", Args: []int{0}},
+		{Name: "flask.Markup", Description: "safe markup", Args: []int{0}},
+		{Name: "jinja2.Template", Description: "template", Args: []int{0}, Keywords: []string{"source"}},
+	},
+	Sanitizers: []string{
+		"html.escape", "cgi.escape", "markupsafe.escape", "flask.escape",
+		"django.utils.html.escape", "django.utils.html.strip_tags", "bleach.clean",
+		"builtins.int", "builtins.float",
+	},
+	Propagators: taint.DefaultPropagators,
 }
 
-// Apply 应用规则并将结果添加到报告中
-func (r *RuleXSSSemantic) Apply(ast *parser.ast.Node) {
-	r.checkForXSS(ast)
+// RuleXSSSemantic 检查用户输入未经 HTML 编码就写入响应的情况
+type RuleXSSSemantic struct {
+	taintRule
 }
 
-// checkForXSS 使用语义分析检查XSS漏洞并将结果添加到报告中
-func (r *RuleXSS) checkForXSS(node *parser.ast.Node) {
-	// 找到所有与输出相关的节点，例如函数调用和变量赋值语句
-	outputNodes := node.FindAll(parser.NodeTypeFunctionCall, parser.NodeTypeAssignment)
-
-	for _, outputNode := range outputNodes {
-		// 获取输出值
-		outputValue := outputNode.GetChildByIndex(len(outputNode.Children) - 1)
-
-		// 判断输出值是否需要编码
-		isEncoded := false
-		switch outputNode.Type {
-		case parser.NodeTypeFunctionCall:
-			// 判断函数是否是 HTML 编码函数
-			functionName := outputNode.GetChildByIndex(0).token.TokenLiteral()
-			if functionName == "html" || functionName == "htmlspecialchars" {
-				isEncoded = true
-			}
-		case parser.NodeTypeAssignment:
-			// 判断赋值语句右侧的表达式是否是 HTML 编码函数的调用
-			expr := outputNode.GetChildByIndex(1)
-			if expr.Type == parser.NodeTypeFunctionCall {
-				functionName := expr.GetChildByIndex(0).token.TokenLiteral()
-				if functionName == "html" || functionName == "htmlspecialchars" {
-					isEncoded = true
-				}
-			}
-		}
-
-		// 如果输出值未经过编码，则进行进一步检查
-		if !isEncoded {
-			// 检查输出值是否包含用户输入
-			inputNodes := outputNode.FindAll(parser.NodeTypeIdentifier)
-			for _, inputNode := range inputNodes {
-				inputName := inputNode.token.TokenLiteral()
-
-				// 根据变量名查找变量定义
-				varDecl := node.FindVariableDeclaration(inputName)
-
-				// 如果变量未被定义，或者未被赋值，则忽略
-				if varDecl == nil || varDecl.GetChildByIndex(1) == nil {
-					continue
-				}
-
-				// 获取变量赋值的值，并判断是否是用户输入
-				varValue := varDecl.GetChildByIndex(1)
-				if varValue.Type == parser.NodeTypeInput {
-					// 发现了未编码的用户输入，将其添加到报告中
-					r.reporter.AddIssue(node.Filename, outputNode.Line, "Unencoded user input found in output: "+outputValue.token.TokenLiteral())
-				}
-			}
-		}
-	}
+// NewRuleXSSSemantic 创建并返回一个新的 RuleXSSSemantic 实例
+func NewRuleXSSSemantic() *RuleXSSSemantic {
+	return &RuleXSSSemantic{taintRule{
+		id:       "XSS",
+		severity: "High",
+		message:  "Possible XSS",
+		spec:     xssSpec,
+	}}
 }
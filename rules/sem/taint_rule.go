@@ -0,0 +1,71 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/coiloffaraday/python_sast/analyzer"
+	"github.com/coiloffaraday/python_sast/project"
+	"github.com/coiloffaraday/python_sast/reporter"
+	"github.com/coiloffaraday/python_sast/taint"
+)
+
+// webSources 是 Web 框架中来自用户的输入，供各污点规则共用。
+// Django 和 Tornado 的请求对象是视图的参数，无法解析出完全限定名，因此按属性名匹配
+var webSources = []taint.Source{
+	{Name: "flask.request", Description: "Flask request"},
+	{Name: "bottle.request", Description: "Bottle request"},
+	{Name: "*.GET", Description: "Django request"},
+	{Name: "*.POST", Description: "Django request"},
+	{Name: "*.COOKIES", Description: "Django request"},
+	{Name: "*.get_argument", Description: "Tornado request"},
+	{Name: "*.get_query_argument", Description: "Tornado request"},
+	{Name: "*.get_body_argument", Description: "Tornado request"},
+	{Name: "*.get_cookie", Description: "Tornado request"},
+	{Name: "builtins.input", Description: "user input"},
+	{Name: "builtins.raw_input", Description: "user input"},
+}
+
+// taintRule 是基于污点分析的规则：规则只声明源、汇、净化函数和传播函数，
+// 由 taint 引擎找出从源到汇的路径
+type taintRule struct {
+	id       string
+	severity string
+	message  string // 问题描述，如 "Possible SQL injection"
+	spec     taint.Spec
+
+	// 引擎缓存了已分析函数的摘要，同一项目的各模块共用一个引擎
+	mu     sync.Mutex
+	graph  *project.Graph
+	engine *taint.Engine
+}
+
+// Check 实现 analyzer.Rule 接口，返回模块中每条从源到汇的路径，描述中附带完整的传播过程
+func (r *taintRule) Check(ctx *analyzer.Context) []reporter.ReportItem {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.engine == nil || r.graph != ctx.Project {
+		r.graph = ctx.Project
+		r.engine = taint.New(r.spec, ctx.Project)
+	}
+
+	var items []reporter.ReportItem
+	for _, f := range r.engine.Analyze(ctx.Module) {
+		// 每一步单独一行：位置和说明
+		var trace strings.Builder
+		for _, step := range f.Trace {
+			fmt.Fprintf(&trace, "\n    %s", step)
+		}
+		sink := f.Location()
+		pos := sink.Node.Pos()
+		items = append(items, reporter.ReportItem{
+			RuleID:      r.id,
+			Description: fmt.Sprintf("%s: untrusted data reaches %s%s", r.message, f.Sink.Name, trace.String()),
+			Severity:    r.severity,
+			Location:    fmt.Sprintf("%s:%d:%d", sink.File, pos.Line, pos.Column),
+		})
+	}
+	return items
+}
@@ -0,0 +1,536 @@
+package semantic
+
+import (
+	"strings"
+
+	"github.com/coiloffaraday/python_sast/parser"
+)
+
+// Analyze builds the scopes of a module and resolves every identifier in
+// it.
+//
+// It works in two passes. The first walks the tree, creating scopes and
+// recording every binding, global and nonlocal declaration and name
+// occurrence. Whether a name is local to a scope depends on bindings that
+// may come after its uses, so the second pass places the bindings into
+// symbols and only then resolves the occurrences.
+func Analyze(program *parser.Program) *Info {
+	info := &Info{
+		scopes:   map[parser.Node]*Scope{},
+		within:   map[parser.Node]*Scope{},
+		bindings: map[*parser.Identifier]*Binding{},
+	}
+	b := &builder{info: info}
+	info.Module = b.push(ModuleScope, program)
+	info.scopes[program] = info.Module
+	info.within[program] = info.Module
+	for _, stmt := range program.Statements {
+		b.visit(stmt)
+	}
+	b.resolve()
+
+	return info
+}
+
+// pending is a definition found by the first pass.
+type pending struct {
+	scope *Scope
+	name  string
+	def   *Definition
+}
+
+// occurrence is an identifier naming a variable. Uses are reads; the other
+// occurrences bind or declare the name.
+type occurrence struct {
+	scope *Scope
+	name  string
+	ident *parser.Identifier
+	use   bool
+}
+
+type builder struct {
+	info        *Info
+	scope       *Scope
+	stmt        parser.Statement
+	definitions []pending
+	occurrences []occurrence
+}
+
+func (b *builder) push(kind ScopeKind, node parser.Node) *Scope {
+	b.scope = newScope(kind, node, b.scope)
+	return b.scope
+}
+
+func (b *builder) pop() {
+	b.scope = b.scope.Parent
+}
+
+func (b *builder) use(ident *parser.Identifier) {
+	b.occurrences = append(b.occurrences, occurrence{b.scope, ident.Value, ident, true})
+}
+
+// bind records a definition of name in the current scope.
+func (b *builder) bind(name string, def *Definition) {
+	b.bindIn(b.scope, name, def)
+}
+
+func (b *builder) bindIn(scope *Scope, name string, def *Definition) {
+	def.Statement = b.stmt
+	b.definitions = append(b.definitions, pending{scope, name, def})
+	b.occurrences = append(b.occurrences, occurrence{scope, name, def.Ident, false})
+}
+
+// bindIdent binds ident in the current scope, filling in the definition's
+// Ident.
+func (b *builder) bindIdent(ident *parser.Identifier, def Definition) {
+	if ident == nil {
+		return
+	}
+	def.Ident = ident
+	b.bind(ident.Value, &def)
+}
+
+func (b *builder) visit(node parser.Node) {
+	b.info.within[node] = b.scope
+	if stmt, ok := node.(parser.Statement); ok {
+		if _, ok := stmt.(*parser.BlockStatement); !ok {
+			outer := b.stmt
+			b.stmt = stmt
+			defer func() { b.stmt = outer }()
+		}
+	}
+
+	switch n := node.(type) {
+	case *parser.Identifier:
+		b.use(n)
+	case *parser.AttributeExpression:
+		// The attribute is not a variable.
+		b.visit(n.Object)
+	case *parser.KeywordArgument:
+		b.visit(n.Value)
+
+	case *parser.AssignmentStatement:
+		b.visit(n.Value)
+		for _, target := range n.Targets {
+			b.assign(target, n.Value, Definition{Kind: Assignment, Node: n})
+		}
+	case *parser.AugAssignStatement:
+		b.visit(n.Value)
+		if ident, ok := n.Target.(*parser.Identifier); ok {
+			// x += 1 reads x before binding it again.
+			b.use(ident)
+			b.definitions = append(b.definitions, pending{b.scope, ident.Value, &Definition{
+				Kind: AugmentedAssignment, Ident: ident, Node: n, Statement: n, Value: n.Value,
+			}})
+		} else {
+			b.visit(n.Target)
+		}
+	case *parser.AnnAssignStatement:
+		b.visit(n.Annotation)
+		if n.Value != nil {
+			b.visit(n.Value)
+		}
+		b.assign(n.Target, n.Value, Definition{Kind: AnnotatedAssignment, Node: n, Annotation: n.Annotation})
+	case *parser.NamedExpression:
+		b.visit(n.Value)
+		// The target of a walrus in a comprehension is bound in the scope
+		// containing the comprehension, and resolved there.
+		scope := b.scope
+		for scope.Kind == ComprehensionScope {
+			scope = scope.Parent
+		}
+		b.info.within[n.Target] = scope
+		b.bindIn(scope, n.Target.Value, &Definition{Kind: NamedExpression, Ident: n.Target, Node: n, Value: n.Value})
+	case *parser.DelStatement:
+		for _, target := range n.Targets {
+			b.delete(target, n)
+		}
+
+	case *parser.ForStatement:
+		b.visit(n.Iterable)
+		b.assign(n.Target, nil, Definition{Kind: ForTarget, Node: n})
+		b.visit(n.Body)
+		if n.ElseBody != nil {
+			b.visit(n.ElseBody)
+		}
+	case *parser.WithStatement:
+		for _, item := range n.Items {
+			b.info.within[item] = b.scope
+			b.visit(item.Context)
+			if item.Target != nil {
+				b.assign(item.Target, item.Context, Definition{Kind: WithTarget, Node: item})
+			}
+		}
+		b.visit(n.Body)
+	case *parser.ExceptStatement:
+		if n.ExceptionType != nil {
+			b.visit(n.ExceptionType)
+		}
+		if n.Name != nil {
+			b.info.within[n.Name] = b.scope
+			b.bindIdent(n.Name, Definition{Kind: ExceptTarget, Node: n})
+		}
+		b.visit(n.Body)
+	case *parser.MatchStatement:
+		b.visit(n.Subject)
+		for _, c := range n.Cases {
+			b.info.within[c] = b.scope
+			b.pattern(c.Pattern)
+			if c.Guard != nil {
+				b.visit(c.Guard)
+			}
+			b.visit(c.Body)
+		}
+
+	case *parser.ImportStatement:
+		for _, spec := range n.Names {
+			b.importSpec(spec, true)
+		}
+	case *parser.FromImportStatement:
+		for _, spec := range n.ImportList {
+			if spec.Name.Value == "*" {
+				b.scope.StarImport = true
+				b.info.starImports = append(b.info.starImports, n)
+				continue
+			}
+			b.importSpec(spec, false)
+		}
+	case *parser.GlobalStatement:
+		b.declare(n.Names, Global)
+	case *parser.NonlocalStatement:
+		b.declare(n.Names, Enclosing)
+
+	case *parser.FunctionDef:
+		b.function(n, n)
+	case *parser.AsyncFunctionDef:
+		b.function(n, &n.FunctionDef)
+	case *parser.LambdaExpression:
+		b.parameterValues(n.Parameters)
+		b.info.scopes[n] = b.push(FunctionScope, n)
+		b.parameters(n.Parameters)
+		b.visit(n.Body)
+		b.pop()
+	case *parser.ClassDef:
+		b.class(n)
+	case *parser.TypeAliasStatement:
+		b.info.within[n.Name] = b.scope
+		b.bindIdent(n.Name, Definition{Kind: TypeAlias, Node: n, Value: n.Value})
+		// The value is evaluated lazily, in a scope of its own that also
+		// holds the type parameters.
+		b.info.scopes[n] = b.push(AnnotationScope, n)
+		b.typeParams(n.TypeParams)
+		b.visit(n.Value)
+		b.pop()
+
+	case *parser.ListComprehension:
+		b.comprehension(n, n.Generators, n.Element)
+	case *parser.SetComprehension:
+		b.comprehension(n, n.Generators, n.Element)
+	case *parser.GeneratorExpression:
+		b.comprehension(n, n.Generators, n.Element)
+	case *parser.DictComprehension:
+		b.comprehension(n, n.Generators, n.Key, n.Value)
+
+	default:
+		for _, child := range node.Children() {
+			b.visit(child)
+		}
+	}
+}
+
+// assign binds the names in an assignment target. When the value is known
+// it is paired with the names, element by element for tuple unpacking.
+// Attributes and subscripts bind no name; their parts are reads.
+func (b *builder) assign(target parser.Expression, value parser.Expression, def Definition) {
+	b.info.within[target] = b.scope
+	switch t := target.(type) {
+	case *parser.Identifier:
+		def.Value = value
+		b.bindIdent(t, def)
+	case *parser.TupleLiteral:
+		b.assignElements(t.Elements, value, def)
+	case *parser.ListLiteral:
+		b.assignElements(t.Elements, value, def)
+	case *parser.StarredExpression:
+		b.assign(t.Value, nil, def)
+	default:
+		b.visit(target)
+	}
+}
+
+func (b *builder) assignElements(targets []parser.Expression, value parser.Expression, def Definition) {
+	var values []parser.Expression
+	switch v := value.(type) {
+	case *parser.TupleLiteral:
+		values = v.Elements
+	case *parser.ListLiteral:
+		values = v.Elements
+	}
+	if len(values) != len(targets) || hasStarred(targets) || hasStarred(values) {
+		values = nil
+	}
+
+	for i, target := range targets {
+		var value parser.Expression
+		if values != nil {
+			value = values[i]
+		}
+		b.assign(target, value, def)
+	}
+}
+
+func hasStarred(exprs []parser.Expression) bool {
+	for _, expr := range exprs {
+		if _, ok := expr.(*parser.StarredExpression); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// delete handles a target of a del statement. Deleting a name makes it
+// local just as binding it does.
+func (b *builder) delete(target parser.Expression, stmt *parser.DelStatement) {
+	b.info.within[target] = b.scope
+	switch t := target.(type) {
+	case *parser.Identifier:
+		b.bindIdent(t, Definition{Kind: Deletion, Node: stmt})
+	case *parser.TupleLiteral:
+		for _, element := range t.Elements {
+			b.delete(element, stmt)
+		}
+	case *parser.ListLiteral:
+		for _, element := range t.Elements {
+			b.delete(element, stmt)
+		}
+	default:
+		b.visit(target)
+	}
+}
+
+// importSpec binds the name an import introduces. "import a.b" binds a,
+// while "import a.b as c" and "from m import a.b" bind the final name.
+func (b *builder) importSpec(spec *parser.ImportSpec, module bool) {
+	b.info.within[spec] = b.scope
+	ident := spec.Name
+	name := ident.Value
+	if spec.Alias != nil {
+		ident, name = spec.Alias, spec.Alias.Value
+	} else if module {
+		name, _, _ = strings.Cut(name, ".")
+	}
+	b.bind(name, &Definition{Kind: Import, Ident: ident, Node: spec})
+}
+
+func (b *builder) declare(names []*parser.Identifier, kind BindingKind) {
+	for _, name := range names {
+		b.info.within[name] = b.scope
+		b.scope.declared[name.Value] = kind
+		b.occurrences = append(b.occurrences, occurrence{b.scope, name.Value, name, false})
+	}
+}
+
+// pattern binds the capture names of a case pattern and visits the values
+// it compares against.
+func (b *builder) pattern(pattern parser.Pattern) {
+	b.info.within[pattern] = b.scope
+	switch pt := pattern.(type) {
+	case *parser.CapturePattern:
+		b.bindIdent(pt.Name, Definition{Kind: MatchCapture, Node: pt})
+	case *parser.AsPattern:
+		b.pattern(pt.Pattern)
+		b.bindIdent(pt.Name, Definition{Kind: MatchCapture, Node: pt})
+	case *parser.StarPattern:
+		b.pattern(pt.Pattern)
+	case *parser.SequencePattern:
+		for _, sub := range pt.Patterns {
+			b.pattern(sub)
+		}
+	case *parser.OrPattern:
+		for _, sub := range pt.Patterns {
+			b.pattern(sub)
+		}
+	case *parser.MappingPattern:
+		for i, key := range pt.Keys {
+			b.visit(key)
+			b.pattern(pt.Patterns[i])
+		}
+		b.bindIdent(pt.Rest, Definition{Kind: MatchCapture, Node: pt})
+	case *parser.ClassPattern:
+		b.visit(pt.Class)
+		for _, sub := range pt.Patterns {
+			b.pattern(sub)
+		}
+		for _, keyword := range pt.Keywords {
+			b.info.within[keyword] = b.scope
+			b.pattern(keyword.Pattern)
+		}
+	case *parser.ValuePattern:
+		b.visit(pt.Value)
+	case *parser.LiteralPattern:
+		b.visit(pt.Value)
+	}
+}
+
+// function handles a def. node is the *FunctionDef or *AsyncFunctionDef,
+// and def the FunctionDef in it.
+func (b *builder) function(node parser.Node, def *parser.FunctionDef) {
+	for _, decorator := range def.Decorators {
+		b.visit(decorator)
+	}
+	b.info.within[def.Name] = b.scope
+	b.bindIdent(def.Name, Definition{Kind: FunctionDefinition, Node: node})
+
+	// The type parameters of a generic function are visible in its
+	// signature as well as its body.
+	if len(def.TypeParams) > 0 {
+		b.push(AnnotationScope, node)
+		b.typeParams(def.TypeParams)
+	}
+	b.parameterValues(def.Parameters)
+	if def.Returns != nil {
+		b.visit(def.Returns)
+	}
+
+	scope := b.push(FunctionScope, node)
+	b.info.scopes[node] = scope
+	b.info.scopes[def] = scope
+	b.parameters(def.Parameters)
+	b.visit(def.Body)
+	b.pop()
+
+	if len(def.TypeParams) > 0 {
+		b.pop()
+	}
+}
+
+// parameterValues visits the defaults and annotations of parameters, which
+// are evaluated where the function is defined.
+func (b *builder) parameterValues(params []*parser.Parameter) {
+	for _, param := range params {
+		if param.Annotation != nil {
+			b.visit(param.Annotation)
+		}
+		if param.Default != nil {
+			b.visit(param.Default)
+		}
+	}
+}
+
+func (b *builder) parameters(params []*parser.Parameter) {
+	for _, param := range params {
+		b.info.within[param] = b.scope
+		if param.Name != nil {
+			b.info.within[param.Name] = b.scope
+		}
+		b.bindIdent(param.Name, Definition{
+			Kind: Parameter, Node: param, Value: param.Default, Annotation: param.Annotation,
+		})
+	}
+}
+
+func (b *builder) class(class *parser.ClassDef) {
+	for _, decorator := range class.Decorators {
+		b.visit(decorator)
+	}
+	b.info.within[class.Name] = b.scope
+	b.bindIdent(class.Name, Definition{Kind: ClassDefinition, Node: class})
+
+	if len(class.TypeParams) > 0 {
+		b.push(AnnotationScope, class)
+		b.typeParams(class.TypeParams)
+	}
+	for _, base := range class.Bases {
+		b.visit(base)
+	}
+	for _, keyword := range class.Keywords {
+		b.visit(keyword)
+	}
+
+	b.info.scopes[class] = b.push(ClassScope, class)
+	b.visit(class.Body)
+	b.pop()
+
+	if len(class.TypeParams) > 0 {
+		b.pop()
+	}
+}
+
+func (b *builder) typeParams(params []*parser.TypeParam) {
+	for _, param := range params {
+		b.info.within[param] = b.scope
+		b.info.within[param.Name] = b.scope
+		b.bindIdent(param.Name, Definition{Kind: TypeParameter, Node: param})
+		if param.Bound != nil {
+			b.visit(param.Bound)
+		}
+		if param.Default != nil {
+			b.visit(param.Default)
+		}
+	}
+}
+
+// comprehension handles a comprehension or generator expression. Its first
+// iterable is evaluated in the enclosing scope; everything else, including
+// the targets, belongs to the comprehension's own scope.
+func (b *builder) comprehension(node parser.Node, generators []*parser.ForClause, elements ...parser.Expression) {
+	if len(generators) > 0 {
+		b.visit(generators[0].Iter)
+	}
+	b.info.scopes[node] = b.push(ComprehensionScope, node)
+	for i, generator := range generators {
+		b.info.within[generator] = b.scope
+		if i > 0 {
+			b.visit(generator.Iter)
+		}
+		b.assign(generator.Target, nil, Definition{Kind: ForTarget, Node: generator})
+		for _, cond := range generator.Ifs {
+			b.visit(cond)
+		}
+	}
+	for _, element := range elements {
+		b.visit(element)
+	}
+	b.pop()
+}
+
+// resolve is the second pass. Definitions go to the symbol of the scope
+// they bind in, which "global" and "nonlocal" redirect; plain bindings are
+// placed first so that nonlocal names find the enclosing binding wherever
+// it appears.
+func (b *builder) resolve() {
+	for _, round := range []BindingKind{Local, Global, Enclosing} {
+		for _, d := range b.definitions {
+			declared, ok := d.scope.declared[d.name]
+			if !ok {
+				declared = Local
+			}
+			if declared != round {
+				continue
+			}
+			sym := d.scope.owner(d.name)
+			sym.Definitions = append(sym.Definitions, d.def)
+		}
+	}
+
+	for _, o := range b.occurrences {
+		kind, sym := o.scope.lookup(o.name)
+		b.info.bindings[o.ident] = &Binding{Kind: kind, Symbol: sym, Scope: o.scope}
+		if o.use && sym != nil {
+			sym.Uses = append(sym.Uses, o.ident)
+		}
+	}
+}
+
+// owner returns the symbol that a binding of name in s defines.
+func (s *Scope) owner(name string) *Symbol {
+	switch s.declared[name] {
+	case Global:
+		return s.Module().symbol(name)
+	case Enclosing:
+		if sym := s.enclosing(name); sym != nil {
+			return sym
+		}
+	}
+	return s.symbol(name)
+}
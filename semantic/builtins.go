@@ -0,0 +1,62 @@
+package semantic
+
+// builtins holds the names of the builtins module that a name resolves to
+// when no scope binds it. It covers Python 3 and the Python 2 names that
+// were dropped since, so that either kind of module resolves.
+var builtins = map[string]bool{}
+
+func init() {
+	for _, name := range []string{
+		// Functions and types
+		"abs", "aiter", "all", "anext", "any", "ascii", "bin", "bool",
+		"breakpoint", "bytearray", "bytes", "callable", "chr", "classmethod",
+		"compile", "complex", "delattr", "dict", "dir", "divmod", "enumerate",
+		"eval", "exec", "filter", "float", "format", "frozenset", "getattr",
+		"globals", "hasattr", "hash", "help", "hex", "id", "input", "int",
+		"isinstance", "issubclass", "iter", "len", "list", "locals", "map",
+		"max", "memoryview", "min", "next", "object", "oct", "open", "ord",
+		"pow", "print", "property", "range", "repr", "reversed", "round",
+		"set", "setattr", "slice", "sorted", "staticmethod", "str", "sum",
+		"super", "tuple", "type", "vars", "zip", "__import__",
+
+		// Constants and module attributes
+		"None", "True", "False", "Ellipsis", "NotImplemented", "__debug__",
+		"__name__", "__doc__", "__file__", "__spec__", "__loader__",
+		"__package__", "__builtins__", "__path__", "__annotations__",
+		"__dict__", "copyright", "credits", "license", "exit", "quit",
+
+		// Exceptions and warnings
+		"BaseException", "BaseExceptionGroup", "Exception", "ExceptionGroup",
+		"ArithmeticError", "AssertionError", "AttributeError", "BufferError",
+		"EOFError", "FloatingPointError", "GeneratorExit", "ImportError",
+		"ModuleNotFoundError", "IndexError", "KeyError", "KeyboardInterrupt",
+		"LookupError", "MemoryError", "NameError", "NotImplementedError",
+		"OSError", "OverflowError", "RecursionError", "ReferenceError",
+		"RuntimeError", "StopAsyncIteration", "StopIteration", "SyntaxError",
+		"IndentationError", "TabError", "SystemError", "SystemExit",
+		"TypeError", "UnboundLocalError", "UnicodeError",
+		"UnicodeDecodeError", "UnicodeEncodeError", "UnicodeTranslateError",
+		"ValueError", "ZeroDivisionError", "EnvironmentError", "IOError",
+		"BlockingIOError", "ChildProcessError", "ConnectionError",
+		"BrokenPipeError", "ConnectionAbortedError", "ConnectionRefusedError",
+		"ConnectionResetError", "FileExistsError", "FileNotFoundError",
+		"InterruptedError", "IsADirectoryError", "NotADirectoryError",
+		"PermissionError", "ProcessLookupError", "TimeoutError",
+		"EncodingWarning", "Warning", "UserWarning", "DeprecationWarning",
+		"PendingDeprecationWarning", "SyntaxWarning", "RuntimeWarning",
+		"FutureWarning", "ImportWarning", "UnicodeWarning", "BytesWarning",
+		"ResourceWarning",
+
+		// Python 2
+		"apply", "basestring", "buffer", "cmp", "coerce", "execfile", "file",
+		"intern", "long", "raw_input", "reduce", "reload", "unichr",
+		"unicode", "xrange", "StandardError",
+	} {
+		builtins[name] = true
+	}
+}
+
+// IsBuiltin reports whether name is a builtin.
+func IsBuiltin(name string) bool {
+	return builtins[name]
+}
@@ -0,0 +1,294 @@
+package semantic
+
+import (
+	"strings"
+
+	"github.com/coiloffaraday/python_sast/parser"
+)
+
+// maxResolveDepth bounds how many assignments and re-exports are followed
+// when resolving a name, which also guards against import cycles.
+const maxResolveDepth = 32
+
+// QualifiedName returns the fully qualified name of what expr refers to,
+// such as "subprocess.Popen" for Popen after "from subprocess import
+// Popen", or for sp.Popen after "import subprocess as sp". Builtins are
+// qualified with "builtins", so rules can match calls on canonical names
+// however they were imported.
+//
+// Names bound by imports, by module-level definitions, by assignments of
+// such names and by importlib.import_module or __import__ with constant
+// arguments resolve. For anything else, such as a parameter or the result
+// of a call, QualifiedName returns "".
+func (info *Info) QualifiedName(expr parser.Expression) string {
+	return info.qualify(expr, 0)
+}
+
+// CallName returns the fully qualified name of the function a call calls,
+// or "" if it is unknown.
+func (info *Info) CallName(call *parser.CallExpression) string {
+	return info.qualify(call.Function, 0)
+}
+
+func (info *Info) qualify(expr parser.Expression, depth int) string {
+	if depth > maxResolveDepth {
+		return ""
+	}
+
+	switch e := expr.(type) {
+	case *parser.Identifier:
+		b := info.Resolve(e)
+		if b == nil {
+			return ""
+		}
+		switch b.Kind {
+		case Builtin:
+			return "builtins." + e.Value
+		case Unresolved:
+			return info.starImported(e.Value, depth)
+		}
+		if b.Symbol == nil {
+			return ""
+		}
+		return info.symbolName(b.Symbol, depth)
+	case *parser.AttributeExpression:
+		object := info.qualify(e.Object, depth)
+		if object == "" {
+			return ""
+		}
+		return info.canonical(object+"."+e.Attribute.Value, depth)
+	case *parser.CallExpression:
+		return info.importCall(e, depth)
+	}
+	return ""
+}
+
+// symbolName returns the fully qualified name of what a symbol holds. It
+// is "" unless every definition of the symbol resolves to the same name.
+func (info *Info) symbolName(sym *Symbol, depth int) string {
+	name := ""
+	for _, def := range sym.Definitions {
+		var defName string
+		switch def.Kind {
+		case Deletion:
+			continue
+		case Import:
+			defName = info.importName(def, depth)
+		case Assignment, AnnotatedAssignment, NamedExpression:
+			if def.Value != nil {
+				defName = info.qualify(def.Value, depth+1)
+			}
+		case FunctionDefinition, ClassDefinition:
+			defName = info.definedName(sym)
+		}
+		if defName == "" || (name != "" && defName != name) {
+			return ""
+		}
+		name = defName
+	}
+	return name
+}
+
+// definedName returns the qualified name of a function or class defined
+// at module level or in a class body, such as "app.models.User.save".
+func (info *Info) definedName(sym *Symbol) string {
+	parts := []string{sym.Name}
+	for scope := sym.Scope; scope.Kind != ModuleScope; scope = scope.Parent {
+		class, ok := scope.Node.(*parser.ClassDef)
+		if scope.Kind != ClassScope || !ok {
+			return ""
+		}
+		parts = append(parts, class.Name.Value)
+	}
+	if info.Name != "" {
+		parts = append(parts, info.Name)
+	}
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, ".")
+}
+
+// importName returns the fully qualified name an import binds. "import
+// a.b" binds the package a, "import a.b as c" the module a.b and "from m
+// import x" the name m.x.
+func (info *Info) importName(def *Definition, depth int) string {
+	spec, ok := def.Node.(*parser.ImportSpec)
+	if !ok {
+		return ""
+	}
+	switch stmt := def.Statement.(type) {
+	case *parser.ImportStatement:
+		if spec.Alias != nil {
+			return spec.Name.Value
+		}
+		name, _, _ := strings.Cut(spec.Name.Value, ".")
+		return name
+	case *parser.FromImportStatement:
+		module := info.FromModule(stmt)
+		if module == "" {
+			return ""
+		}
+		return info.canonical(module+"."+spec.Name.Value, depth+1)
+	}
+	return ""
+}
+
+// starImported resolves a name no scope binds through the module's
+// "from m import *" statements. A name is taken from the last star-imported
+// module known to define it; with a single star import and nothing known
+// about the module, the name is assumed to come from it.
+func (info *Info) starImported(name string, depth int) string {
+	for i := len(info.starImports) - 1; i >= 0; i-- {
+		module := info.FromModule(info.starImports[i])
+		if module == "" || info.Modules == nil {
+			continue
+		}
+		if other := info.Modules(module); other != nil && other.Module.Symbol(name) != nil {
+			return info.canonical(module+"."+name, depth+1)
+		}
+	}
+
+	if len(info.starImports) == 1 {
+		module := info.FromModule(info.starImports[0])
+		if module != "" && (info.Modules == nil || info.Modules(module) == nil) {
+			return module + "." + name
+		}
+	}
+	return ""
+}
+
+// canonical follows re-exports: if name is "pkg.helper" and pkg's
+// __init__.py imported helper from pkg.impl, it returns "pkg.impl.helper".
+// Without Modules, or for modules outside the project, name is returned
+// unchanged.
+func (info *Info) canonical(name string, depth int) string {
+	if info.Modules == nil || depth > maxResolveDepth {
+		return name
+	}
+
+	// Find the longest prefix of name that is a known module.
+	for i := strings.LastIndexByte(name, '.'); i > 0; i = strings.LastIndexByte(name[:i], '.') {
+		module := info.Modules(name[:i])
+		if module == nil {
+			continue
+		}
+		first, rest, _ := strings.Cut(name[i+1:], ".")
+		sym := module.Module.Symbol(first)
+		if sym == nil {
+			return name
+		}
+		resolved := module.symbolName(sym, depth+1)
+		if resolved == "" || resolved == name[:i+1+len(first)] {
+			return name
+		}
+		if rest != "" {
+			return module.canonical(resolved+"."+rest, depth+1)
+		}
+		return resolved
+	}
+	return name
+}
+
+// importCall resolves importlib.import_module and __import__ called with
+// constant arguments to the module they return.
+func (info *Info) importCall(call *parser.CallExpression, depth int) string {
+	switch info.qualify(call.Function, depth+1) {
+	case "importlib.import_module":
+		name, ok := stringArgument(call, 0, "name")
+		if !ok {
+			return ""
+		}
+		if !strings.HasPrefix(name, ".") {
+			return name
+		}
+		pkg, ok := stringArgument(call, 1, "package")
+		if !ok {
+			return ""
+		}
+		module := strings.TrimLeft(name, ".")
+		return resolveRelative(pkg, len(name)-len(module), module)
+	case "builtins.__import__":
+		name, ok := stringArgument(call, 0, "name")
+		if !ok || strings.HasPrefix(name, ".") {
+			return ""
+		}
+		// Without a fromlist, __import__("a.b") returns the package a.
+		if len(call.Arguments) < 4 && keywordArgument(call, "fromlist") == nil {
+			name, _, _ = strings.Cut(name, ".")
+		}
+		return name
+	}
+	return ""
+}
+
+// stringArgument returns the value of a call argument given as a string
+// literal, either at position index or by keyword.
+func stringArgument(call *parser.CallExpression, index int, keyword string) (string, bool) {
+	var arg parser.Expression
+	if index < len(call.Arguments) {
+		arg = call.Arguments[index]
+	} else {
+		arg = keywordArgument(call, keyword)
+	}
+	str, ok := arg.(*parser.StringLiteral)
+	if !ok {
+		return "", false
+	}
+	return str.Value, true
+}
+
+func keywordArgument(call *parser.CallExpression, keyword string) parser.Expression {
+	for _, kw := range call.Keywords {
+		if kw.Name != nil && kw.Name.Value == keyword {
+			return kw.Value
+		}
+	}
+	return nil
+}
+
+// FromModule returns the absolute name of the module a from-import
+// imports from, resolving relative imports against the module's Name. It
+// returns "" for a relative import that cannot be resolved.
+func (info *Info) FromModule(stmt *parser.FromImportStatement) string {
+	module := ""
+	if stmt.Module != nil {
+		module = stmt.Module.Value
+	}
+	if stmt.Level == 0 {
+		return module
+	}
+	return resolveRelative(info.Package(), stmt.Level, module)
+}
+
+// Package returns the name of the package the module belongs to: the
+// module itself for an __init__.py, and its parent otherwise.
+func (info *Info) Package() string {
+	if info.IsPackage {
+		return info.Name
+	}
+	if i := strings.LastIndexByte(info.Name, '.'); i >= 0 {
+		return info.Name[:i]
+	}
+	return ""
+}
+
+// resolveRelative returns the absolute name of the module imported with
+// the given number of leading dots from package pkg.
+func resolveRelative(pkg string, level int, module string) string {
+	if pkg == "" {
+		return ""
+	}
+	base := pkg
+	for i := 1; i < level; i++ {
+		j := strings.LastIndexByte(base, '.')
+		if j < 0 {
+			return ""
+		}
+		base = base[:j]
+	}
+	if module == "" {
+		return base
+	}
+	return base + "." + module
+}
@@ -0,0 +1,340 @@
+// Package semantic resolves the names of a Python module. It builds the
+// module, class, function, comprehension and annotation scopes of a
+// parser.Program following Python's scoping rules and binds every
+// identifier to the symbol it refers to, so that rules can tell a local
+// variable from a global, a builtin or an enclosing function's variable of
+// the same name.
+package semantic
+
+import (
+	"fmt"
+
+	"github.com/coiloffaraday/python_sast/parser"
+)
+
+type ScopeKind int
+
+const (
+	ModuleScope        ScopeKind = iota
+	ClassScope                   // A class body
+	FunctionScope                // A def or lambda
+	ComprehensionScope           // A comprehension or generator expression
+	AnnotationScope              // The type parameters of a generic definition, or the value of a type alias
+)
+
+func (k ScopeKind) String() string {
+	switch k {
+	case ModuleScope:
+		return "ModuleScope"
+	case ClassScope:
+		return "ClassScope"
+	case FunctionScope:
+		return "FunctionScope"
+	case ComprehensionScope:
+		return "ComprehensionScope"
+	case AnnotationScope:
+		return "AnnotationScope"
+	}
+	return fmt.Sprintf("ScopeKind(%d)", int(k))
+}
+
+// Scope is a namespace: the module, or the body of a class, function,
+// lambda or comprehension.
+type Scope struct {
+	Kind     ScopeKind
+	Node     parser.Node // The *Program, definition, lambda or comprehension that introduces the scope
+	Parent   *Scope      // Nil for the module
+	Children []*Scope
+
+	// StarImport is set when the scope has a "from m import *", which may
+	// bind names that do not appear in it.
+	StarImport bool
+
+	symbols  map[string]*Symbol
+	order    []*Symbol
+	declared map[string]BindingKind // Global or Enclosing, for "global" and "nonlocal" names
+}
+
+func newScope(kind ScopeKind, node parser.Node, parent *Scope) *Scope {
+	s := &Scope{
+		Kind:     kind,
+		Node:     node,
+		Parent:   parent,
+		symbols:  map[string]*Symbol{},
+		declared: map[string]BindingKind{},
+	}
+	if parent != nil {
+		parent.Children = append(parent.Children, s)
+	}
+	return s
+}
+
+// Symbol returns the symbol for a name bound in the scope itself, or nil.
+// Names declared global or nonlocal belong to the scope they refer to.
+func (s *Scope) Symbol(name string) *Symbol {
+	return s.symbols[name]
+}
+
+// Symbols returns the symbols bound in the scope, in order of first
+// binding.
+func (s *Scope) Symbols() []*Symbol {
+	return s.order
+}
+
+func (s *Scope) symbol(name string) *Symbol {
+	sym, ok := s.symbols[name]
+	if !ok {
+		sym = &Symbol{Name: name, Scope: s}
+		s.symbols[name] = sym
+		s.order = append(s.order, sym)
+	}
+	return sym
+}
+
+// Module returns the module scope that s belongs to.
+func (s *Scope) Module() *Scope {
+	for s.Parent != nil {
+		s = s.Parent
+	}
+	return s
+}
+
+// Lookup resolves name as if it were read in s.
+func (s *Scope) Lookup(name string) *Binding {
+	kind, sym := s.lookup(name)
+	return &Binding{Kind: kind, Symbol: sym, Scope: s}
+}
+
+func (s *Scope) lookup(name string) (BindingKind, *Symbol) {
+	switch s.declared[name] {
+	case Global:
+		return Global, s.Module().symbols[name]
+	case Enclosing:
+		if sym := s.enclosing(name); sym != nil {
+			return Enclosing, sym
+		}
+	}
+	if sym := s.symbols[name]; sym != nil {
+		if s.Kind == ModuleScope {
+			return Global, sym
+		}
+		return Local, sym
+	}
+
+	if sym := s.enclosing(name); sym != nil {
+		if sym.Scope.Kind == ModuleScope {
+			return Global, sym
+		}
+		return Enclosing, sym
+	}
+	if sym := s.Module().symbols[name]; sym != nil {
+		return Global, sym
+	}
+	if builtins[name] {
+		return Builtin, nil
+	}
+	return Unresolved, nil
+}
+
+// enclosing finds the binding of name in the function scopes around s.
+// Class bodies are skipped, since their names are not visible in nested
+// scopes, except to an annotation scope directly inside the class.
+func (s *Scope) enclosing(name string) *Symbol {
+	for p := s.Parent; p != nil && p.Kind != ModuleScope; p = p.Parent {
+		if p.Kind == ClassScope && !(p == s.Parent && s.Kind == AnnotationScope) {
+			continue
+		}
+		switch p.declared[name] {
+		case Global:
+			return p.Module().symbols[name]
+		case Enclosing:
+			return p.enclosing(name)
+		}
+		if sym := p.symbols[name]; sym != nil {
+			return sym
+		}
+	}
+	return nil
+}
+
+// BindingKind says where the binding of a name was found.
+type BindingKind int
+
+const (
+	Unresolved BindingKind = iota // Bound nowhere
+	Local                         // In the scope where the name is used
+	Enclosing                     // In an enclosing function, or by "nonlocal"
+	Global                        // At module level, or by "global"
+	Builtin                       // A builtin, such as len or open
+)
+
+func (k BindingKind) String() string {
+	switch k {
+	case Unresolved:
+		return "Unresolved"
+	case Local:
+		return "Local"
+	case Enclosing:
+		return "Enclosing"
+	case Global:
+		return "Global"
+	case Builtin:
+		return "Builtin"
+	}
+	return fmt.Sprintf("BindingKind(%d)", int(k))
+}
+
+// Binding is what a name refers to at one place in the source.
+type Binding struct {
+	Kind   BindingKind
+	Symbol *Symbol // Nil for builtins and unresolved names
+	Scope  *Scope  // The scope in which the name appears
+}
+
+// Symbol is a variable: a name bound in one scope, together with every
+// place that binds it and every place that reads it.
+type Symbol struct {
+	Name        string
+	Scope       *Scope
+	Definitions []*Definition
+	Uses        []*parser.Identifier
+}
+
+func (s *Symbol) String() string {
+	return fmt.Sprintf("%s (%s)", s.Name, s.Scope.Kind)
+}
+
+type DefinitionKind int
+
+const (
+	Assignment          DefinitionKind = iota // x = value, including unpacking
+	AugmentedAssignment                       // x += value
+	AnnotatedAssignment                       // x: T = value, or x: T without a value
+	NamedExpression                           // (x := value)
+	ForTarget                                 // for x in ..., in a statement or a comprehension
+	WithTarget                                // with value as x
+	ExceptTarget                              // except E as x
+	Import                                    // import x, from m import x
+	FunctionDefinition                        // def x
+	ClassDefinition                           // class x
+	Parameter                                 // def f(x), lambda x: ...
+	TypeParameter                             // def f[x](), class C[x], type A[x] = ...
+	TypeAlias                                 // type x = value
+	MatchCapture                              // case x, case ... as x
+	Deletion                                  // del x, which makes x local without binding it
+)
+
+func (k DefinitionKind) String() string {
+	switch k {
+	case Assignment:
+		return "Assignment"
+	case AugmentedAssignment:
+		return "AugmentedAssignment"
+	case AnnotatedAssignment:
+		return "AnnotatedAssignment"
+	case NamedExpression:
+		return "NamedExpression"
+	case ForTarget:
+		return "ForTarget"
+	case WithTarget:
+		return "WithTarget"
+	case ExceptTarget:
+		return "ExceptTarget"
+	case Import:
+		return "Import"
+	case FunctionDefinition:
+		return "FunctionDefinition"
+	case ClassDefinition:
+		return "ClassDefinition"
+	case Parameter:
+		return "Parameter"
+	case TypeParameter:
+		return "TypeParameter"
+	case TypeAlias:
+		return "TypeAlias"
+	case MatchCapture:
+		return "MatchCapture"
+	case Deletion:
+		return "Deletion"
+	}
+	return fmt.Sprintf("DefinitionKind(%d)", int(k))
+}
+
+// Definition is one place that binds a symbol.
+type Definition struct {
+	Kind DefinitionKind
+
+	// Ident is the identifier being bound. For "import a.b" it is the
+	// whole dotted name, which binds "a".
+	Ident *parser.Identifier
+
+	// Node is the construct that binds the name: the statement, or the
+	// *Parameter, *ImportSpec, *TypeParam, *ForClause, pattern or
+	// *NamedExpression within it.
+	Node parser.Node
+
+	// Statement is the statement containing the binding.
+	Statement parser.Statement
+
+	// Value is the expression assigned to the name when it is known: the
+	// right-hand side of an assignment to the bare name or to its position
+	// in a tuple, the context manager of a with statement, a parameter's
+	// default or a type alias's value. It is nil otherwise.
+	Value parser.Expression
+
+	// Annotation is the type annotation of an annotated assignment or a
+	// parameter; nil otherwise.
+	Annotation parser.Expression
+}
+
+// Info holds the scopes of a module and the binding of each identifier in
+// it.
+type Info struct {
+	Module *Scope
+
+	// Name is the dotted name of the module, such as "app.views", and
+	// IsPackage is set when the module is a package's __init__.py. They
+	// are needed to resolve relative imports; Analyze leaves them unset.
+	Name      string
+	IsPackage bool
+
+	// Modules looks up the other modules of the project by dotted name,
+	// so that names re-exported by a package resolve to where they are
+	// defined. It may be nil, and returns nil for unknown modules.
+	Modules func(name string) *Info
+
+	starImports []*parser.FromImportStatement
+
+	scopes   map[parser.Node]*Scope // The scope each scope-introducing node creates
+	within   map[parser.Node]*Scope // The scope each node is evaluated in
+	bindings map[*parser.Identifier]*Binding
+}
+
+// Scope returns the scope introduced by node, a *Program, definition,
+// lambda or comprehension, or nil if node introduces none. A generic
+// definition also introduces an annotation scope, which is the parent of
+// the returned scope.
+func (info *Info) Scope(node parser.Node) *Scope {
+	return info.scopes[node]
+}
+
+// ScopeOf returns the scope in which node is evaluated. A definition is
+// evaluated in the scope around it.
+func (info *Info) ScopeOf(node parser.Node) *Scope {
+	return info.within[node]
+}
+
+// Resolve returns the binding of an identifier of the program, or nil if
+// the identifier does not name a variable, such as the attribute of an
+// attribute expression or the name of a keyword argument.
+func (info *Info) Resolve(ident *parser.Identifier) *Binding {
+	return info.bindings[ident]
+}
+
+// Definitions returns the definitions of the symbol ident refers to.
+func (info *Info) Definitions(ident *parser.Identifier) []*Definition {
+	if b := info.bindings[ident]; b != nil && b.Symbol != nil {
+		return b.Symbol.Definitions
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package semantic_test
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/lexer"
+	"github.com/coiloffaraday/python_sast/parser"
+	"github.com/coiloffaraday/python_sast/semantic"
+)
+
+func analyze(t *testing.T, input string) (*parser.Program, *semantic.Info) {
+	t.Helper()
+	p := parser.New(lexer.NewLexer(input, "test.py"))
+	program, err := p.ParseProgram()
+	if err != nil {
+		t.Fatalf("%q: unexpected errors: %v", input, p.Diagnostics())
+	}
+	return program, semantic.Analyze(program)
+}
+
+// resolved lists the resolved identifiers of input in source order, as
+// "name:Kind".
+func resolved(t *testing.T, input string) string {
+	t.Helper()
+	program, info := analyze(t, input)
+	idents := parser.FindAll[*parser.Identifier](program)
+	sort.SliceStable(idents, func(i, j int) bool {
+		return idents[i].Token.Offset < idents[j].Token.Offset
+	})
+
+	var out []string
+	for _, ident := range idents {
+		if b := info.Resolve(ident); b != nil {
+			out = append(out, ident.Value+":"+b.Kind.String())
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"x = 1\nprint(x, y)",
+			"x:Global print:Builtin x:Global y:Unresolved",
+		},
+		{
+			"x = 1\ndef f(a):\n    b = a + x\n    return b",
+			"x:Global f:Global a:Local b:Local a:Local x:Global b:Local",
+		},
+		{
+			// A name bound anywhere in a function is local throughout it.
+			"x = 1\ndef f():\n    print(x)\n    x = 2",
+			"x:Global f:Global print:Builtin x:Local x:Local",
+		},
+		{
+			"def f():\n    global x\n    x = 1\nx",
+			"f:Global x:Global x:Global x:Global",
+		},
+		{
+			"def f():\n    x = 1\n    def g():\n        nonlocal x\n        x = 2\n    return x",
+			"f:Global x:Local g:Local x:Enclosing x:Enclosing x:Local",
+		},
+		{
+			"def f():\n    x = 1\n    def g():\n        return x",
+			"f:Global x:Local g:Local x:Enclosing",
+		},
+		{
+			// Class bodies are not visible to the methods in them.
+			"class C:\n    x = 1\n    def m(self):\n        return x\n    y = x",
+			"C:Global x:Local m:Local self:Local x:Unresolved y:Local x:Local",
+		},
+		{
+			// The first iterable is evaluated outside the comprehension.
+			"class C:\n    xs = [1]\n    ys = [x for x in xs if x]",
+			"C:Global xs:Local ys:Local x:Local x:Local xs:Local x:Local",
+		},
+		{
+			"def f(xs):\n    if any((y := x) for x in xs):\n        return y",
+			"f:Global xs:Local any:Builtin y:Local x:Local x:Local xs:Local y:Local",
+		},
+		{
+			"import os.path as p, sys\nfrom m import a, b as c\nos",
+			"p:Global sys:Global a:Global c:Global os:Unresolved",
+		},
+		{
+			"import os.path\nos",
+			"os.path:Global os:Global",
+		},
+		{
+			"f = lambda a, b=c: a + b",
+			"f:Global a:Local b:Local c:Unresolved a:Local b:Local",
+		},
+		{
+			"for i, v in enumerate(xs):\n    pass\nelse:\n    i",
+			"i:Global v:Global enumerate:Builtin xs:Unresolved i:Global",
+		},
+		{
+			"def f():\n    with open(p) as fh:\n        pass\n    try:\n        pass\n    except E as e:\n        del fh",
+			"f:Global open:Builtin p:Unresolved fh:Local E:Unresolved e:Local fh:Local",
+		},
+		{
+			"def f(v):\n    match v:\n        case Point(x=px, y=0) | [px, *_] as q:\n            return px, q",
+			"f:Global v:Local v:Local Point:Unresolved px:Local px:Local q:Local px:Local q:Local",
+		},
+		{
+			"obj.attr = f(key=value)",
+			"obj:Unresolved f:Unresolved value:Unresolved",
+		},
+		{
+			"class C[T: int](Base[T]):\n    def m[U](self, a: T) -> U:\n        return T",
+			"C:Global T:Local int:Builtin Base:Unresolved T:Local m:Local U:Local self:Local a:Local T:Enclosing U:Local T:Enclosing",
+		},
+		{
+			"type Pair[K] = tuple[K, K]",
+			"Pair:Global K:Local tuple:Builtin K:Local K:Local",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := resolved(t, tt.input); got != tt.expected {
+			t.Errorf("%q:\n got %s\nwant %s", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	input := `import os
+
+x, y = 1, compute()
+x += 2
+
+def handler(request, *args):
+    data = request.args.get("q")
+    return os.system(data)
+
+class Service:
+    name: str = "svc"
+`
+	program, info := analyze(t, input)
+	module := info.Module
+	if module.Kind != semantic.ModuleScope || info.Scope(program) != module {
+		t.Fatalf("module scope: got %v", module.Kind)
+	}
+
+	var names []string
+	for _, sym := range module.Symbols() {
+		names = append(names, sym.Name)
+	}
+	if got := strings.Join(names, " "); got != "os x y handler Service" {
+		t.Errorf("module symbols: got %s", got)
+	}
+
+	x := module.Symbol("x")
+	if len(x.Definitions) != 2 || x.Definitions[0].Kind != semantic.Assignment || x.Definitions[1].Kind != semantic.AugmentedAssignment {
+		t.Fatalf("x definitions: got %v", x.Definitions)
+	}
+	if got := x.Definitions[0].Value.String(); got != "1" {
+		t.Errorf("x value: got %s", got)
+	}
+	if got := module.Symbol("y").Definitions[0].Value.String(); got != "compute()" {
+		t.Errorf("y value: got %s", got)
+	}
+	if len(x.Uses) != 1 {
+		t.Errorf("x uses: got %d", len(x.Uses))
+	}
+
+	handler, _ := parser.FindFirst[*parser.FunctionDef](program)
+	scope := info.Scope(handler)
+	if scope == nil || scope.Kind != semantic.FunctionScope || scope.Parent != module {
+		t.Fatalf("handler scope: got %v", scope)
+	}
+	if info.ScopeOf(handler) != module {
+		t.Errorf("handler is not evaluated in the module scope")
+	}
+	request := scope.Symbol("request")
+	if request == nil || request.Definitions[0].Kind != semantic.Parameter || len(request.Uses) != 1 {
+		t.Fatalf("request: got %v", request)
+	}
+	data := scope.Symbol("data")
+	if got := data.Definitions[0].Value.String(); got != `request.args.get("q")` {
+		t.Errorf("data value: got %s", got)
+	}
+	if scope.Symbol("args") == nil || scope.Symbol("get") != nil {
+		t.Errorf("handler symbols: got %v", scope.Symbols())
+	}
+
+	os := module.Symbol("os")
+	if len(os.Uses) != 1 || info.ScopeOf(os.Uses[0]) != scope {
+		t.Errorf("os uses: got %v", os.Uses)
+	}
+	if _, ok := os.Definitions[0].Node.(*parser.ImportSpec); !ok {
+		t.Errorf("os definition node: got %T", os.Definitions[0].Node)
+	}
+	if _, ok := os.Definitions[0].Statement.(*parser.ImportStatement); !ok {
+		t.Errorf("os definition statement: got %T", os.Definitions[0].Statement)
+	}
+
+	class, _ := parser.FindFirst[*parser.ClassDef](program)
+	name := info.Scope(class).Symbol("name")
+	if name == nil || name.Definitions[0].Kind != semantic.AnnotatedAssignment || name.Definitions[0].Annotation.String() != "str" {
+		t.Errorf("class attribute: got %v", name)
+	}
+}
+
+func TestStarImport(t *testing.T) {
+	_, info := analyze(t, "from os import *\nsystem('ls')")
+	if !info.Module.StarImport {
+		t.Errorf("StarImport not set")
+	}
+	if len(info.Module.Symbols()) != 0 {
+		t.Errorf("symbols: got %v", info.Module.Symbols())
+	}
+}
+
+// callNames lists the qualified names of the calls in a module, in order.
+func callNames(program *parser.Program, info *semantic.Info) string {
+	var names []string
+	for _, call := range parser.FindAll[*parser.CallExpression](program) {
+		names = append(names, info.CallName(call))
+	}
+	return strings.Join(names, " ")
+}
+
+func TestQualifiedNames(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"import requests as r\nr.get(url)", "requests.get"},
+		{"from requests import get as fetch\nfetch(url)", "requests.get"},
+		{"import os.path\nos.path.join(a, b)", "os.path.join"},
+		{"import os.path as osp\nosp.join(a, b)", "os.path.join"},
+		{"from subprocess import Popen\nrun = Popen\nrun(cmd)", "subprocess.Popen"},
+		{"open(path).read()", " builtins.open"},
+		{"import importlib\nm = importlib.import_module('subprocess')\nm.Popen(cmd)", "importlib.import_module subprocess.Popen"},
+		{"from importlib import import_module\nimport_module('.shell', package='app.utils').run()", "app.utils.shell.run importlib.import_module"},
+		{"p = __import__('os.path')\np.path.exists(x)", "builtins.__import__ os.path.exists"},
+		{"from os import *\nsystem(cmd)", "os.system"},
+		{"def f(requests):\n    requests.get(url)", ""},
+		{"import subprocess\ndef f():\n    subprocess = None\n    subprocess.call(x)", ""},
+	}
+
+	for _, tt := range tests {
+		program, info := analyze(t, tt.input)
+		if got := callNames(program, info); got != tt.expected {
+			t.Errorf("%q: got %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestRelativeImports(t *testing.T) {
+	input := "from .db import query\nfrom .. import settings\nfrom . import forms\nquery(q)\nforms.validate(x)\nsettings.load()\nclass View:\n    def get(self):\n        pass\nView.get(v)\nhelper(x)"
+
+	program, info := analyze(t, input)
+	info.Name = "app.views"
+	if got := callNames(program, info); got != "app.db.query app.forms.validate  app.views.View.get " {
+		t.Errorf("module: got %q", got)
+	}
+
+	info.Name, info.IsPackage = "app.views", true
+	if got := callNames(program, info); got != "app.views.db.query app.views.forms.validate app.settings.load app.views.View.get " {
+		t.Errorf("package: got %q", got)
+	}
+}
+
+func TestReexports(t *testing.T) {
+	sources := map[string]string{
+		"pkg":      "from .impl import helper\nfrom . import impl as _impl\n",
+		"pkg.impl": "import subprocess\ndef helper(cmd):\n    pass\nrun = subprocess.run\n",
+		"main":     "import pkg\nfrom pkg import helper\nfrom pkg.impl import run\nhelper(x)\npkg.helper(x)\npkg._impl.helper(x)\nrun(x)",
+	}
+	modules := map[string]*semantic.Info{}
+	programs := map[string]*parser.Program{}
+	for name, source := range sources {
+		programs[name], modules[name] = analyze(t, source)
+		modules[name].Name = name
+		modules[name].Modules = func(name string) *semantic.Info { return modules[name] }
+	}
+	modules["pkg"].IsPackage = true
+
+	expected := "pkg.impl.helper pkg.impl.helper pkg.impl.helper subprocess.run"
+	if got := callNames(programs["main"], modules["main"]); got != expected {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+}
@@ -0,0 +1,850 @@
+package taint
+
+import (
+	"fmt"
+
+	"github.com/coiloffaraday/python_sast/cfg"
+	"github.com/coiloffaraday/python_sast/parser"
+	"github.com/coiloffaraday/python_sast/project"
+	"github.com/coiloffaraday/python_sast/semantic"
+)
+
+// flow is one way a value came to be tainted: from a source, or, while a
+// function is summarized, from one of its parameters.
+type flow struct {
+	source *Source
+	origin parser.Node // The source expression; nil for a parameter
+	param  int         // The index of the parameter when source is nil
+	steps  []Step
+}
+
+// through returns the flow continued by steps.
+func (f *flow) through(steps ...Step) *flow {
+	g := *f
+	g.steps = append(f.steps[:len(f.steps):len(f.steps)], steps...)
+	return &g
+}
+
+type flowKey struct {
+	source *Source
+	origin parser.Node
+	param  int
+}
+
+func (f *flow) key() flowKey {
+	return flowKey{f.source, f.origin, f.param}
+}
+
+// taint is the set of flows that reach a value, at most one per origin.
+// Taints are never modified once built, so states can share them.
+type taint []*flow
+
+// union returns the flows of t and u, keeping t's flow for an origin both
+// have.
+func (t taint) union(u taint) taint {
+	if len(u) == 0 {
+		return t
+	}
+	if len(t) == 0 {
+		return u
+	}
+	out := t[:len(t):len(t)]
+	for _, f := range u {
+		if !out.has(f.key()) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func (t taint) has(key flowKey) bool {
+	for _, f := range t {
+		if f.key() == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (t taint) through(steps ...Step) taint {
+	if len(t) == 0 {
+		return nil
+	}
+	out := make(taint, len(t))
+	for i, f := range t {
+		out[i] = f.through(steps...)
+	}
+	return out
+}
+
+// state maps the variables of a function to their taint at a point of it.
+// Untainted variables are absent.
+type state map[*semantic.Symbol]taint
+
+func (s state) copy() state {
+	c := make(state, len(s))
+	for sym, t := range s {
+		c[sym] = t
+	}
+	return c
+}
+
+// join adds the taint of u to s, and reports whether s gained an origin.
+// A nil s is a block not reached yet.
+func join(s, u state) (state, bool) {
+	if s == nil {
+		return u.copy(), true
+	}
+	grew := false
+	for sym, t := range u {
+		old := s[sym]
+		for _, f := range t {
+			if !old.has(f.key()) {
+				s[sym] = old.union(t)
+				grew = true
+				break
+			}
+		}
+	}
+	return s, grew
+}
+
+// result is what the engine knows about a function, or a module's
+// top-level code, once analyzed.
+type result struct {
+	params   []*parser.Parameter // The named parameters, indexed by flow.param
+	returns  taint               // The flows to the return value or yielded values
+	sinks    []*sinkFlow         // Parameters that reach sinks
+	findings []*Finding
+}
+
+// sinkFlow is a parameter flow that reaches a sink; its last step is the
+// sink call.
+type sinkFlow struct {
+	flow *flow
+	sink *Sink
+}
+
+// analyze returns the result for a function of module, analyzing it
+// first if needed. A recursive call gets what its function's analysis has
+// found so far, which is nothing.
+func (e *Engine) analyze(module *project.Module, node parser.Node) *result {
+	if r := e.results[node]; r != nil {
+		return r
+	}
+	return e.analyzeGraph(module, cfg.New(node))
+}
+
+// analyzeGraph runs the analysis over the graph of a function until the
+// taint at the start of every block is stable, then runs it once more to
+// collect the return value, the sinks reached and the findings.
+func (e *Engine) analyzeGraph(module *project.Module, g *cfg.CFG) *result {
+	r := &result{}
+	e.results[g.Node] = r
+	a := &analysis{
+		engine:   e,
+		module:   module,
+		info:     module.Info,
+		node:     g.Node,
+		result:   r,
+		values:   map[parser.Node]parser.Expression{},
+		reported: map[flowKey]map[parser.Node]bool{},
+	}
+	a.prepare()
+
+	entry := state{}
+	for i, param := range r.params {
+		if sym := a.symbol(param.Name); sym != nil {
+			entry[sym] = taint{{param: i}}
+		}
+	}
+	in := make([]state, len(g.Blocks))
+	in[g.Entry.Index] = entry
+	for changed := true; changed; {
+		changed = false
+		for _, b := range g.Blocks {
+			if in[b.Index] == nil {
+				continue
+			}
+			out, raised := a.block(b, in[b.Index].copy())
+			for _, edge := range b.Succs {
+				s := out
+				if edge.Kind == cfg.Exception {
+					s = raised
+				}
+				var grew bool
+				if in[edge.To.Index], grew = join(in[edge.To.Index], s); grew {
+					changed = true
+				}
+			}
+		}
+	}
+
+	a.report = true
+	for _, b := range g.Blocks {
+		if in[b.Index] != nil {
+			a.block(b, in[b.Index].copy())
+		}
+	}
+	return r
+}
+
+// analysis is the analysis of one function.
+type analysis struct {
+	engine *Engine
+	module *project.Module
+	info   *semantic.Info
+	node   parser.Node // The *FunctionDef, *AsyncFunctionDef or *Program
+	result *result
+
+	// values holds what the nodes of the graph that bind names without
+	// being assignments take their value from: the iterable of a for
+	// target and the subject of a case pattern. An except clause's name
+	// maps to nil, as it never holds untrusted data.
+	values map[parser.Node]parser.Expression
+
+	// report is set for the last run, which records what reaches returns
+	// and sinks.
+	report   bool
+	reported map[flowKey]map[parser.Node]bool // The sink calls each origin was reported at
+}
+
+func (a *analysis) prepare() {
+	var body *parser.BlockStatement
+	switch n := a.node.(type) {
+	case *parser.FunctionDef:
+		body = n.Body
+		a.result.params = namedParams(n.Parameters)
+	case *parser.AsyncFunctionDef:
+		body = n.Body
+		a.result.params = namedParams(n.Parameters)
+	case *parser.Program:
+		body = &parser.BlockStatement{Statements: n.Statements}
+	}
+
+	parser.Inspect(body, func(node parser.Node) bool {
+		switch n := node.(type) {
+		case *parser.FunctionDef, *parser.AsyncFunctionDef, *parser.ClassDef, *parser.LambdaExpression:
+			return false
+		case *parser.ForStatement:
+			a.values[n.Target] = n.Iterable
+		case *parser.ExceptStatement:
+			if n.Name != nil {
+				a.values[n.Name] = nil
+			}
+		case *parser.MatchStatement:
+			for _, c := range n.Cases {
+				a.values[c.Pattern] = n.Subject
+			}
+		}
+		return true
+	})
+}
+
+func namedParams(params []*parser.Parameter) []*parser.Parameter {
+	var named []*parser.Parameter
+	for _, param := range params {
+		if param.Name != nil {
+			named = append(named, param)
+		}
+	}
+	return named
+}
+
+func (a *analysis) symbol(ident *parser.Identifier) *semantic.Symbol {
+	if ident == nil {
+		return nil
+	}
+	if b := a.info.Resolve(ident); b != nil {
+		return b.Symbol
+	}
+	return nil
+}
+
+func (a *analysis) step(node parser.Node, format string, args ...any) Step {
+	return Step{File: a.module.Path, Node: node, Description: fmt.Sprintf(format, args...)}
+}
+
+// block runs the nodes of b from s and returns the state at its end. If b
+// has an Exception edge, it also returns what a handler may see: the union
+// of the states before each node, since any of them may raise, and of the
+// state at the end, for a raise or a finally block that raises again.
+func (a *analysis) block(b *cfg.Block, s state) (out, raised state) {
+	raises := false
+	for _, edge := range b.Succs {
+		if edge.Kind == cfg.Exception {
+			raises = true
+		}
+	}
+	for _, node := range b.Nodes {
+		if raises {
+			raised, _ = join(raised, s)
+		}
+		a.transfer(node, s)
+	}
+	if raises {
+		raised, _ = join(raised, s)
+	}
+	return s, raised
+}
+
+// transfer updates s for the execution of a node of the graph.
+func (a *analysis) transfer(node parser.Node, s state) {
+	switch n := node.(type) {
+	case *parser.AssignmentStatement:
+		t := a.eval(n.Value, s)
+		for _, target := range n.Targets {
+			a.assign(target, n.Value, t, s)
+		}
+	case *parser.AugAssignStatement:
+		t := a.eval(n.Target, s).union(a.eval(n.Value, s))
+		a.assign(n.Target, nil, t, s)
+	case *parser.AnnAssignStatement:
+		if n.Value != nil {
+			a.assign(n.Target, n.Value, a.eval(n.Value, s), s)
+		}
+	case *parser.ExpressionStatement:
+		a.eval(n.Expression, s)
+	case *parser.ReturnStatement:
+		a.returned(a.eval(n.ReturnValue, s).through(a.step(n, "returned")))
+	case *parser.RaiseStatement:
+		a.eval(n.Exception, s)
+		a.eval(n.Cause, s)
+	case *parser.AssertStatement:
+		a.eval(n.Test, s)
+		a.eval(n.Message, s)
+	case *parser.DelStatement:
+		for _, target := range n.Targets {
+			a.assign(target, nil, nil, s)
+		}
+	case *parser.WithItem:
+		t := a.eval(n.Context, s)
+		if n.Target != nil {
+			a.assign(n.Target, nil, t, s)
+		}
+	case parser.Pattern:
+		a.pattern(n, a.eval(a.values[n], s), s)
+	case parser.Expression:
+		if value, ok := a.values[n]; ok {
+			a.assign(n, value, a.eval(value, s), s)
+			return
+		}
+		a.eval(n, s)
+	}
+}
+
+func (a *analysis) returned(t taint) {
+	if a.report {
+		a.result.returns = a.result.returns.union(t)
+	}
+}
+
+// assign binds target to a value with taint t. When value, the expression
+// assigned, is a tuple or list matching a tuple or list target, each
+// element is assigned on its own.
+func (a *analysis) assign(target parser.Expression, value parser.Expression, t taint, s state) {
+	switch tg := target.(type) {
+	case *parser.Identifier:
+		sym := a.symbol(tg)
+		if sym == nil {
+			return
+		}
+		if len(t) == 0 {
+			delete(s, sym)
+		} else {
+			s[sym] = t.through(a.step(tg, "assigned to %s", tg.Value))
+		}
+	case *parser.TupleLiteral:
+		a.assignElements(tg.Elements, value, t, s)
+	case *parser.ListLiteral:
+		a.assignElements(tg.Elements, value, t, s)
+	case *parser.StarredExpression:
+		a.assign(tg.Value, nil, t, s)
+	case *parser.AttributeExpression, *parser.SubscriptExpression:
+		// Storing into part of a variable taints all of it. The previous
+		// taint of the variable stays, since the rest of it is unchanged.
+		if len(t) == 0 {
+			return
+		}
+		if sym := a.symbol(root(tg)); sym != nil {
+			s[sym] = s[sym].union(t.through(a.step(tg, "stored in %s", parser.Unparse(tg))))
+		}
+	}
+}
+
+func (a *analysis) assignElements(targets []parser.Expression, value parser.Expression, t taint, s state) {
+	var values []parser.Expression
+	switch v := value.(type) {
+	case *parser.TupleLiteral:
+		values = v.Elements
+	case *parser.ListLiteral:
+		values = v.Elements
+	}
+	if len(values) != len(targets) || hasStarred(values) || hasStarred(targets) {
+		for _, target := range targets {
+			a.assign(target, nil, t, s)
+		}
+		return
+	}
+	for i, target := range targets {
+		a.assign(target, values[i], a.eval(values[i], s), s)
+	}
+}
+
+func hasStarred(exprs []parser.Expression) bool {
+	for _, expr := range exprs {
+		if _, ok := expr.(*parser.StarredExpression); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// root returns the variable an attribute or subscript expression is part
+// of, such as d for d["a"].b, or nil.
+func root(expr parser.Expression) *parser.Identifier {
+	for {
+		switch e := expr.(type) {
+		case *parser.Identifier:
+			return e
+		case *parser.AttributeExpression:
+			expr = e.Object
+		case *parser.SubscriptExpression:
+			expr = e.Object
+		default:
+			return nil
+		}
+	}
+}
+
+// pattern binds the names a case pattern captures to the subject's taint.
+func (a *analysis) pattern(pattern parser.Pattern, t taint, s state) {
+	parser.Inspect(pattern, func(node parser.Node) bool {
+		switch n := node.(type) {
+		case *parser.CapturePattern:
+			a.assign(n.Name, nil, t, s)
+		case *parser.AsPattern:
+			if n.Name != nil {
+				a.assign(n.Name, nil, t, s)
+			}
+		case *parser.MappingPattern:
+			if n.Rest != nil {
+				a.assign(n.Rest, nil, t, s)
+			}
+		case parser.Expression:
+			// Values to compare against, not names to bind.
+			return false
+		}
+		return true
+	})
+}
+
+// source returns a new flow if expr, whose qualified name is name and
+// whose attribute, for an attribute expression, is attr, is a source.
+func (a *analysis) source(expr parser.Expression, name, attr string) taint {
+	for i := range a.engine.spec.Sources {
+		src := &a.engine.spec.Sources[i]
+		if !matchSource(name, attr, src.Name) {
+			continue
+		}
+		if name == "" {
+			name = src.Name
+		}
+		f := &flow{source: src, origin: expr}
+		return taint{f.through(a.step(expr, "reads %s", label(name, src.Description)))}
+	}
+	return nil
+}
+
+func label(name, description string) string {
+	if description == "" {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", description, name)
+}
+
+// eval returns the taint of the value of expr, recording the sinks the
+// calls in it reach.
+func (a *analysis) eval(expr parser.Expression, s state) taint {
+	switch e := expr.(type) {
+	case *parser.Identifier:
+		if t := a.source(e, a.info.QualifiedName(e), ""); t != nil {
+			return t
+		}
+		if sym := a.symbol(e); sym != nil {
+			return s[sym]
+		}
+	case *parser.AttributeExpression:
+		if t := a.source(e, a.info.QualifiedName(e), e.Attribute.Value); t != nil {
+			return t
+		}
+		return a.eval(e.Object, s)
+	case *parser.SubscriptExpression:
+		a.eval(e.Index, s)
+		return a.eval(e.Object, s)
+	case *parser.CallExpression:
+		return a.call(e, s)
+	case *parser.InfixExpression:
+		// Arithmetic, concatenation, "%" formatting and the operands of
+		// "and" and "or" all carry taint.
+		return a.eval(e.Left, s).union(a.eval(e.Right, s))
+	case *parser.PrefixExpression:
+		t := a.eval(e.Right, s)
+		if e.Operator == "not" {
+			return nil
+		}
+		return t
+	case *parser.ComparisonExpression:
+		a.eval(e.Left, s)
+		a.evalAll(e.Comparators, s)
+		return nil
+	case *parser.IfExpression:
+		a.eval(e.Condition, s)
+		return a.eval(e.Consequence, s).union(a.eval(e.Alternative, s))
+	case *parser.JoinedStr:
+		return a.evalAll(e.Values, s)
+	case *parser.FormattedValue:
+		t := a.eval(e.Value, s)
+		if e.FormatSpec != nil {
+			t = t.union(a.eval(e.FormatSpec, s))
+		}
+		return t
+	case *parser.ListLiteral:
+		return a.evalAll(e.Elements, s)
+	case *parser.TupleLiteral:
+		return a.evalAll(e.Elements, s)
+	case *parser.SetLiteral:
+		return a.evalAll(e.Elements, s)
+	case *parser.DictLiteral:
+		return a.evalAll(e.Keys, s).union(a.evalAll(e.Values, s))
+	case *parser.StarredExpression:
+		return a.eval(e.Value, s)
+	case *parser.KeywordArgument:
+		return a.eval(e.Value, s)
+	case *parser.NamedExpression:
+		t := a.eval(e.Value, s)
+		a.assign(e.Target, e.Value, t, s)
+		return t
+	case *parser.AwaitExpression:
+		return a.eval(e.Value, s)
+	case *parser.YieldExpression:
+		t := a.eval(e.Value, s)
+		if !e.From {
+			a.returned(t.through(a.step(e, "yielded")))
+		}
+		return nil
+	case *parser.SliceExpression:
+		a.eval(e.Lower, s)
+		a.eval(e.Upper, s)
+		a.eval(e.Step, s)
+	case *parser.ListComprehension:
+		return a.comprehension(e.Generators, s, e.Element)
+	case *parser.SetComprehension:
+		return a.comprehension(e.Generators, s, e.Element)
+	case *parser.GeneratorExpression:
+		return a.comprehension(e.Generators, s, e.Element)
+	case *parser.DictComprehension:
+		return a.comprehension(e.Generators, s, e.Key, e.Value)
+	}
+	return nil
+}
+
+func (a *analysis) evalAll(exprs []parser.Expression, s state) taint {
+	var t taint
+	for _, expr := range exprs {
+		t = t.union(a.eval(expr, s))
+	}
+	return t
+}
+
+// comprehension returns the taint of the elements a comprehension builds.
+// Its targets are bound in a copy of s, as they are local to it.
+func (a *analysis) comprehension(generators []*parser.ForClause, s state, elements ...parser.Expression) taint {
+	inner := s.copy()
+	for _, gen := range generators {
+		a.assign(gen.Target, nil, a.eval(gen.Iter, inner), inner)
+		a.evalAll(gen.Ifs, inner)
+	}
+	return a.evalAll(elements, inner)
+}
+
+// mutators are the methods that add their arguments to the container they
+// are called on.
+var mutators = map[string]bool{
+	"append": true, "extend": true, "insert": true, "add": true,
+	"update": true, "setdefault": true, "appendleft": true, "extendleft": true,
+}
+
+// call returns the taint of the result of a call, recording the sinks it
+// reaches directly or through the function it calls.
+func (a *analysis) call(call *parser.CallExpression, s state) taint {
+	spec := &a.engine.spec
+	name := a.info.CallName(call)
+	method, _ := call.Function.(*parser.AttributeExpression)
+	attr := ""
+	if method != nil {
+		attr = method.Attribute.Value
+	}
+
+	args := make([]taint, len(call.Arguments))
+	for i, arg := range call.Arguments {
+		args[i] = a.eval(arg, s)
+	}
+	kwargs := make([]taint, len(call.Keywords))
+	for i, kw := range call.Keywords {
+		kwargs[i] = a.eval(kw.Value, s)
+	}
+
+	for i := range spec.Sinks {
+		if matchName(name, attr, spec.Sinks[i].Name) {
+			a.sink(call, &spec.Sinks[i], args, kwargs)
+		}
+	}
+
+	if t := a.source(call, name, attr); t != nil {
+		return t
+	}
+	for _, sanitizer := range spec.Sanitizers {
+		if matchName(name, attr, sanitizer) {
+			a.eval(call.Function, s)
+			return nil
+		}
+	}
+
+	// Calling a tainted object, or a method of one, gives a tainted result.
+	out := a.eval(call.Function, s)
+
+	all := taint(nil)
+	for _, t := range args {
+		all = all.union(t)
+	}
+	for _, t := range kwargs {
+		all = all.union(t)
+	}
+	if method != nil && mutators[attr] && len(all) > 0 {
+		if sym := a.symbol(root(method.Object)); sym != nil {
+			s[sym] = s[sym].union(all.through(a.step(call, "added to %s", parser.Unparse(method.Object))))
+		}
+	}
+
+	if module, def, bound := a.callee(call, name); def != nil {
+		r := a.engine.analyze(module, def)
+		var receiver taint
+		if bound {
+			receiver = a.eval(method.Object, s)
+		}
+		out = out.union(a.summary(call, r, bind(r, call, args, kwargs, receiver, bound)))
+	}
+	for _, propagator := range spec.Propagators {
+		if matchName(name, attr, propagator) {
+			out = out.union(all.through(a.step(call, "passed through %s", parser.Unparse(call.Function))))
+			break
+		}
+	}
+	return out
+}
+
+// sink records the flows of the checked arguments of a call to a sink.
+func (a *analysis) sink(call *parser.CallExpression, sink *Sink, args, kwargs []taint) {
+	all := len(sink.Args) == 0 && len(sink.Keywords) == 0
+	var checked taint
+	for i, t := range args {
+		if all || checksArg(sink, i, call.Arguments[i]) {
+			checked = checked.union(t)
+		}
+	}
+	for i, t := range kwargs {
+		if all || checksKeyword(sink, call.Keywords[i]) {
+			checked = checked.union(t)
+		}
+	}
+
+	step := a.step(call, "reaches %s", label(sink.Name, sink.Description))
+	for _, f := range checked {
+		a.reach(f.through(step), sink, call)
+	}
+}
+
+// checksArg reports whether the argument at position i is one a sink
+// checks. A starred argument may stand for any position from i on.
+func checksArg(sink *Sink, i int, arg parser.Expression) bool {
+	_, starred := arg.(*parser.StarredExpression)
+	for _, j := range sink.Args {
+		if j == i || (starred && j > i) {
+			return true
+		}
+	}
+	return false
+}
+
+// checksKeyword reports whether a keyword argument is one a sink checks.
+// A "**" argument may stand for any of them.
+func checksKeyword(sink *Sink, kw *parser.KeywordArgument) bool {
+	if kw.Name == nil {
+		return len(sink.Keywords) > 0
+	}
+	for _, name := range sink.Keywords {
+		if name == kw.Name.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// reach records a flow whose last step is a call to sink: a finding for a
+// source, part of the summary for a parameter. at is the call in the
+// analyzed function that leads to the sink, which is reported once for
+// each origin.
+func (a *analysis) reach(f *flow, sink *Sink, at parser.Node) {
+	if !a.report {
+		return
+	}
+	if a.reported[f.key()] == nil {
+		a.reported[f.key()] = map[parser.Node]bool{}
+	}
+	if a.reported[f.key()][at] {
+		return
+	}
+	a.reported[f.key()][at] = true
+
+	if f.source == nil {
+		a.result.sinks = append(a.result.sinks, &sinkFlow{flow: f, sink: sink})
+		return
+	}
+	a.result.findings = append(a.result.findings, &Finding{Source: f.source, Sink: sink, Trace: f.steps})
+}
+
+// callee returns the project function a call calls and the module it is
+// defined in, or a nil def. bound is set for a method called on self,
+// whose first parameter is the object.
+func (a *analysis) callee(call *parser.CallExpression, name string) (module *project.Module, def parser.Node, bound bool) {
+	var sym *semantic.Symbol
+	if graph := a.engine.graph; graph != nil && name != "" {
+		module, sym = graph.Lookup(name)
+	}
+	if sym == nil {
+		module = a.module
+		switch fn := call.Function.(type) {
+		case *parser.Identifier:
+			sym = a.symbol(fn)
+		case *parser.AttributeExpression:
+			sym, bound = a.selfMethod(fn), true
+		}
+	}
+	if sym == nil || len(sym.Definitions) != 1 || sym.Definitions[0].Kind != semantic.FunctionDefinition {
+		return nil, nil, false
+	}
+	return module, sym.Definitions[0].Node, bound
+}
+
+// selfMethod returns the method of the enclosing class that fn, an
+// attribute of the first parameter of the analyzed method, names, or nil.
+func (a *analysis) selfMethod(fn *parser.AttributeExpression) *semantic.Symbol {
+	self, ok := fn.Object.(*parser.Identifier)
+	if !ok || len(a.result.params) == 0 {
+		return nil
+	}
+	sym := a.symbol(self)
+	if sym == nil || sym != a.symbol(a.result.params[0].Name) {
+		return nil
+	}
+	class := sym.Scope.Parent
+	if class == nil || class.Kind != semantic.ClassScope {
+		return nil
+	}
+	return class.Symbol(fn.Attribute.Value)
+}
+
+// bind returns the taint each parameter of the callee receives from the
+// arguments of a call.
+func bind(r *result, call *parser.CallExpression, args, kwargs []taint, receiver taint, bound bool) []taint {
+	params := make([]taint, len(r.params))
+	var positional []int
+	varPositional, varKeyword := -1, -1
+	for i, param := range r.params {
+		switch param.Kind {
+		case parser.PositionalOnly, parser.PositionalOrKeyword:
+			positional = append(positional, i)
+		case parser.VarPositional:
+			varPositional = i
+		case parser.VarKeyword:
+			varKeyword = i
+		}
+	}
+
+	next := 0
+	if bound && len(positional) > 0 {
+		params[positional[0]] = receiver
+		next = 1
+	}
+	for i, arg := range call.Arguments {
+		if _, ok := arg.(*parser.StarredExpression); ok {
+			// The rest of the positional parameters may come from it.
+			for _, j := range positional[min(next, len(positional)):] {
+				params[j] = params[j].union(args[i])
+			}
+			if varPositional >= 0 {
+				params[varPositional] = params[varPositional].union(args[i])
+			}
+			continue
+		}
+		if next < len(positional) {
+			params[positional[next]] = params[positional[next]].union(args[i])
+			next++
+		} else if varPositional >= 0 {
+			params[varPositional] = params[varPositional].union(args[i])
+		}
+	}
+
+	for i, kw := range call.Keywords {
+		matched := false
+		for j, param := range r.params {
+			switch param.Kind {
+			case parser.PositionalOrKeyword, parser.KeywordOnly:
+				if kw.Name == nil || kw.Name.Value == param.Name.Value {
+					params[j] = params[j].union(kwargs[i])
+					matched = kw.Name != nil
+				}
+			}
+		}
+		if !matched && varKeyword >= 0 {
+			params[varKeyword] = params[varKeyword].union(kwargs[i])
+		}
+	}
+	return params
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// summary applies the result of a called function to a call: the flows of
+// its arguments through its parameters to its return value and to sinks,
+// and the flows from sources within it to its return value.
+func (a *analysis) summary(call *parser.CallExpression, r *result, params []taint) taint {
+	name := parser.Unparse(call.Function)
+	returned := a.step(call, "returned from %s", name)
+	passed := func(i int) Step {
+		return a.step(call, "passed to %s as %s", name, r.params[i].Name.Value)
+	}
+
+	var out taint
+	for _, f := range r.returns {
+		if f.source != nil {
+			out = out.union(taint{f.through(returned)})
+			continue
+		}
+		steps := append([]Step{passed(f.param)}, f.steps...)
+		out = out.union(params[f.param].through(append(steps, returned)...))
+	}
+	for _, sf := range r.sinks {
+		steps := append([]Step{passed(sf.flow.param)}, sf.flow.steps...)
+		for _, f := range params[sf.flow.param] {
+			a.reach(f.through(steps...), sf.sink, call)
+		}
+	}
+	return out
+}
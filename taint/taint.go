@@ -0,0 +1,157 @@
+// Package taint tracks untrusted data through Python programs. A rule
+// declares where untrusted data comes from (sources), where it must not
+// arrive (sinks), what makes it safe (sanitizers) and which calls pass it
+// on (propagators); the engine finds the paths from sources to sinks and
+// reports each with the trace of steps it took.
+//
+// The analysis runs forward over the control-flow graph of every function,
+// so a value sanitized on every path before a sink is not reported while
+// one sanitized on only some paths is. Taint follows assignments,
+// containers, string formatting and f-strings, attribute and subscript
+// reads, and method calls on tainted objects. Calls to functions of the
+// project are followed through per-function summaries of how taint flows
+// from parameters to the return value and to sinks, across modules when
+// the engine has a project graph. Writing a tainted value into an
+// attribute or item of a variable taints the whole variable. Closures,
+// generators' consumers, global variables read in functions and instance
+// method calls whose class is unknown are not followed.
+package taint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coiloffaraday/python_sast/cfg"
+	"github.com/coiloffaraday/python_sast/parser"
+	"github.com/coiloffaraday/python_sast/project"
+)
+
+// Spec declares what a rule tracks. Names are fully qualified, as returned
+// by semantic.Info.QualifiedName, such as "flask.request" or
+// "subprocess.Popen". A name of the form "*.attr" matches any attribute
+// or method named attr whatever its object, for objects whose type is
+// unknown, such as "*.execute" for a database cursor.
+type Spec struct {
+	Sources    []Source
+	Sinks      []Sink
+	Sanitizers []string // Calls whose result is safe
+
+	// Propagators are calls whose result is tainted when an argument is,
+	// such as "os.path.join". DefaultPropagators covers the common builtins
+	// and string methods. Calls to project functions are followed without
+	// being declared, and a method called on a tainted object always
+	// returns a tainted value.
+	Propagators []string
+}
+
+// Source is a name whose value is untrusted. A source also covers the
+// attributes, items and method results of its value, so "flask.request"
+// matches request.args["q"] and request.form.get("q").
+type Source struct {
+	Name        string
+	Description string // Such as "HTTP request"; optional
+}
+
+// Sink is a call that must not receive tainted data.
+type Sink struct {
+	Name        string
+	Description string // Such as "SQL query"; optional
+
+	// Args are the positions of the arguments that must not be tainted,
+	// and Keywords the names of the keyword arguments. When both are
+	// empty every argument is checked.
+	Args     []int
+	Keywords []string
+}
+
+// DefaultPropagators are calls that pass taint from their arguments to
+// their result in most programs.
+var DefaultPropagators = []string{
+	"builtins.str", "builtins.bytes", "builtins.repr", "builtins.format",
+	"builtins.list", "builtins.tuple", "builtins.set", "builtins.dict",
+	"builtins.sorted", "builtins.reversed", "builtins.iter", "builtins.next",
+	"builtins.min", "builtins.max", "builtins.unicode",
+	"os.path.join", "posixpath.join", "ntpath.join", "pathlib.Path",
+	"base64.b64decode", "base64.b64encode", "json.loads", "json.dumps",
+	"urllib.parse.unquote", "urllib.parse.unquote_plus",
+	"*.format", "*.join", "*.replace",
+}
+
+// Step is one point on the path from a source to a sink.
+type Step struct {
+	File        string
+	Node        parser.Node
+	Description string
+}
+
+func (s Step) String() string {
+	pos := s.Node.Pos()
+	return fmt.Sprintf("%s:%d:%d: %s", s.File, pos.Line, pos.Column, s.Description)
+}
+
+// Finding is a path from a source to a sink.
+type Finding struct {
+	Source *Source
+	Sink   *Sink
+	Trace  []Step // From the source to the sink; the last step is the sink call
+}
+
+// Location returns the step of the finding's sink.
+func (f *Finding) Location() Step {
+	return f.Trace[len(f.Trace)-1]
+}
+
+func (f *Finding) String() string {
+	steps := make([]string, len(f.Trace))
+	for i, step := range f.Trace {
+		steps[i] = step.String()
+	}
+	return strings.Join(steps, "\n")
+}
+
+// Engine runs a spec over the modules of a project. It remembers the
+// functions it has analyzed, so analyzing every module of a project
+// summarizes each function once. An engine is not safe for concurrent use.
+type Engine struct {
+	spec    Spec
+	graph   *project.Graph
+	results map[parser.Node]*result // By function, or by *Program for module code
+}
+
+// New returns an engine for spec. The graph, which may be nil, lets the
+// engine follow calls into other modules.
+func New(spec Spec, graph *project.Graph) *Engine {
+	return &Engine{spec: spec, graph: graph, results: map[parser.Node]*result{}}
+}
+
+// Analyze returns the findings whose sink is reached from code in module:
+// those of its top-level code, then those of each function in turn.
+func (e *Engine) Analyze(module *project.Module) []*Finding {
+	var findings []*Finding
+	for _, g := range cfg.Functions(module.Program) {
+		r := e.results[g.Node]
+		if r == nil {
+			r = e.analyzeGraph(module, g)
+		}
+		findings = append(findings, r.findings...)
+	}
+	return findings
+}
+
+// matchName reports whether a qualified name matches a spec name exactly,
+// or, for a "*.attr" pattern, ends in that attribute.
+func matchName(name, attr, pattern string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return attr == suffix
+	}
+	return name != "" && name == pattern
+}
+
+// matchSource reports whether a qualified name is a source or is within
+// one, such as flask.request.args within flask.request.
+func matchSource(name, attr, pattern string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return attr == suffix
+	}
+	return name != "" && (name == pattern || strings.HasPrefix(name, pattern+"."))
+}
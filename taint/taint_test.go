@@ -0,0 +1,209 @@
+package taint_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coiloffaraday/python_sast/project"
+	"github.com/coiloffaraday/python_sast/taint"
+)
+
+var spec = taint.Spec{
+	Sources: []taint.Source{
+		{Name: "flask.request", Description: "request"},
+		{Name: "builtins.input"},
+	},
+	Sinks: []taint.Sink{
+		{Name: "os.system"},
+		{Name: "*.execute", Args: []int{0}, Keywords: []string{"sql"}},
+	},
+	Sanitizers:  []string{"shlex.quote", "builtins.int"},
+	Propagators: taint.DefaultPropagators,
+}
+
+// findings analyzes every module of a project made of files, and returns
+// the findings' traces, one per line, with steps as "file:line: step".
+func findings(t *testing.T, files map[string]string) string {
+	t.Helper()
+	g, err := project.New(t.TempDir(), project.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, source := range files {
+		m := g.AddSource(filepath.Join(g.Dir, name), source)
+		if len(m.Diagnostics) > 0 {
+			t.Fatalf("%s: %v", name, m.Diagnostics)
+		}
+	}
+	return traces(g)
+}
+
+// traces analyzes every module of a project and returns the findings'
+// traces in the format of findings.
+func traces(g *project.Graph) string {
+	e := taint.New(spec, g)
+	var lines []string
+	for _, m := range g.Modules() {
+		for _, f := range e.Analyze(m) {
+			steps := make([]string, len(f.Trace))
+			for i, step := range f.Trace {
+				steps[i] = fmt.Sprintf("%s:%d: %s", filepath.Base(step.File), step.Node.Pos().Line, step.Description)
+			}
+			lines = append(lines, strings.Join(steps, "; "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+const header = "from flask import request\nimport os, shlex\n"
+
+func TestIntraprocedural(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"def view():\n    cmd = request.args['c']\n    os.system('ls ' + cmd)\n",
+			"m.py:4: reads request (flask.request.args); m.py:4: assigned to cmd; m.py:5: reaches os.system",
+		},
+		{
+			// f-strings, containers and str.join
+			"def view(cur):\n    q = f\"select {request.args.get('id')}\"\n    parts = [q, 'limit 1']\n    cur.execute(' '.join(parts))\n",
+			"m.py:4: reads request (flask.request.args.get); m.py:4: assigned to q; m.py:5: assigned to parts; m.py:6: passed through ' '.join; m.py:6: reaches *.execute",
+		},
+		{
+			// % formatting, methods of tainted values and subscripts
+			"def view(cur):\n    data = {'name': input().strip()}\n    cur.execute(sql='select %s' % data['name'])\n",
+			"m.py:4: reads builtins.input; m.py:4: assigned to data; m.py:5: reaches *.execute",
+		},
+		{
+			// Containers filled by methods, and loops
+			"def view():\n    names = []\n    for n in request.args.getlist('n'):\n        names.append(n)\n    os.system(str(names))\n",
+			"m.py:5: reads request (flask.request.args.getlist); m.py:5: assigned to n; m.py:6: added to names; m.py:7: passed through str; m.py:7: reaches os.system",
+		},
+		{
+			// Only the first argument of execute is checked.
+			"def view(cur):\n    cur.execute('select ?', (request.args['id'],))\n",
+			"",
+		},
+		{
+			"def view():\n    cmd = shlex.quote(request.args['c'])\n    os.system('ls ' + cmd)\n    os.system(int(input()))\n",
+			"",
+		},
+		{
+			"def view():\n    cmd = request.args['c']\n    cmd = 'ls'\n    os.system(cmd)\n",
+			"",
+		},
+		{
+			// Sanitized on every path
+			"def view(safe):\n    cmd = request.args['c']\n    if safe:\n        cmd = shlex.quote(cmd)\n    else:\n        cmd = 'ls'\n    os.system(cmd)\n",
+			"",
+		},
+		{
+			// Sanitized on one path only
+			"def view(safe):\n    cmd = request.args['c']\n    if safe:\n        cmd = shlex.quote(cmd)\n    os.system(cmd)\n",
+			"m.py:4: reads request (flask.request.args); m.py:4: assigned to cmd; m.py:7: reaches os.system",
+		},
+		{
+			// Unpacking, walrus and match
+			"def view():\n    a, b = request.args['a'], 'x'\n    os.system(b)\n    if (c := a):\n        match c:\n            case [first, *rest]:\n                os.system(first)\n",
+			"m.py:4: reads request (flask.request.args); m.py:4: assigned to a; m.py:6: assigned to c; m.py:8: assigned to first; m.py:9: reaches os.system",
+		},
+		{
+			// The sanitizer may not run before the exception is handled.
+			"def view():\n    try:\n        x = request.args['c']\n        x = shlex.quote(x)\n    except ValueError:\n        pass\n    os.system(x)\n",
+			"m.py:5: reads request (flask.request.args); m.py:5: assigned to x; m.py:9: reaches os.system",
+		},
+		{
+			// A context manager may suppress the exception.
+			"def view(path):\n    with open(path) as f:\n        x = request.args['c']\n        x = shlex.quote(x)\n    os.system(x)\n",
+			"m.py:5: reads request (flask.request.args); m.py:5: assigned to x; m.py:7: reaches os.system",
+		},
+		{
+			"def view():\n    try:\n        x = shlex.quote(request.args['c'])\n    except ValueError:\n        x = 'ls'\n    os.system(x)\n",
+			"",
+		},
+		{
+			"cmd = input()\nos.system(cmd)\n",
+			"m.py:3: reads builtins.input; m.py:3: assigned to cmd; m.py:4: reaches os.system",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := findings(t, map[string]string{"m.py": header + tt.input}); got != tt.expected {
+			t.Errorf("%q:\n got: %s\nwant: %s", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestInterprocedural(t *testing.T) {
+	files := map[string]string{
+		"db.py": `def build(table, key):
+    return f"select * from {table} where id = {key}"
+
+def run(cur, sql):
+    cur.execute(sql)
+
+def current_id():
+    return request.args["id"]
+
+from flask import request
+`,
+		"views.py": `from flask import request
+import db
+
+def by_key(cur):
+    db.run(cur, db.build("users", request.args["id"]))
+
+def by_table(cur):
+    db.run(cur, db.build(table="t", key=1))
+
+def current(cur):
+    cur.execute(db.current_id())
+
+class View:
+    def get(self, cur):
+        self.fetch(cur, request.form["q"])
+
+    def fetch(self, cur, q):
+        cur.execute(q)
+`,
+	}
+
+	expected := strings.Join([]string{
+		"views.py:5: reads request (flask.request.args); views.py:5: passed to db.build as key; db.py:2: returned; views.py:5: returned from db.build; " +
+			"views.py:5: passed to db.run as sql; db.py:5: reaches *.execute",
+		"db.py:8: reads request (flask.request.args); db.py:8: returned; views.py:11: returned from db.current_id; views.py:11: reaches *.execute",
+		"views.py:15: reads request (flask.request.form); views.py:15: passed to self.fetch as q; views.py:18: reaches *.execute",
+	}, "\n")
+	if got := findings(t, files); got != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+func TestRecursion(t *testing.T) {
+	input := header + "def f(x, n):\n    if n:\n        return f(x, n - 1)\n    os.system(x)\n    return x\n\nf(input(), 3)\n"
+	expected := "m.py:9: reads builtins.input; m.py:9: passed to f as x; m.py:6: reaches os.system"
+	if got := findings(t, map[string]string{"m.py": input}); got != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	// The block after a statement that failed to parse is still analyzed.
+	g, err := project.New(t.TempDir(), project.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := header + "def view():\n    cmd = request.args['c']\n    while cmd cmd:\n        os.system(cmd)\n"
+	if m := g.AddSource(filepath.Join(g.Dir, "m.py"), input); len(m.Diagnostics) != 1 {
+		t.Fatalf("expected one diagnostic, got %v", m.Diagnostics)
+	}
+
+	expected := "m.py:4: reads request (flask.request.args); m.py:4: assigned to cmd; m.py:6: reaches os.system"
+	if got := traces(g); got != expected {
+		t.Errorf("got:\n%s\nwant:\n%s", got, expected)
+	}
+}
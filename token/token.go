@@ -2,11 +2,60 @@ package token
 
 type TokenType string
 
+// Position is a location in a source file.
+type Position struct {
+	Line   int // 1-based
+	Column int // 1-based, counted in characters
+	Offset int // 0-based byte offset into the UTF-8 source
+}
+
+// IsValid reports whether the position is set.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// Before reports whether p comes before q in the source.
+func (p Position) Before(q Position) bool {
+	return p.Offset < q.Offset
+}
+
 type Token struct {
 	Type     TokenType
 	Literal  string
 	Line     int
+	Column   int // 1-based, counted in characters
+	Offset   int // 0-based byte offset into the UTF-8 source
 	FilePath string
+
+	// EndLine, EndColumn and EndOffset give the position just after the
+	// token's last character.
+	EndLine   int
+	EndColumn int
+	EndOffset int
+
+	// Comments holds the comments between the previous token and this one.
+	// A comment ending a line thus belongs to that line's NEWLINE token.
+	Comments []Comment
+
+	// Value holds the decoded contents of a STRING token. Literal keeps the
+	// source spelling, including prefix and quotes.
+	Value string
+}
+
+// Pos returns the position of the token's first character.
+func (t Token) Pos() Position {
+	return Position{Line: t.Line, Column: t.Column, Offset: t.Offset}
+}
+
+// End returns the position just after the token's last character.
+func (t Token) End() Position {
+	return Position{Line: t.EndLine, Column: t.EndColumn, Offset: t.EndOffset}
+}
+
+// Comment is a "#" comment, kept as trivia of the token that follows it.
+type Comment struct {
+	Text string // The comment including its '#'
+	Line int
 }
 
 const (
@@ -14,33 +63,59 @@ const (
 	EOF     = "EOF"
 
 	// Identifiers + literals
-	IDENT  = "IDENT" // add, foobar, x, y, ...
-	INT    = "INT"   // 123456
-	STRING = "STRING"
-	FLOAT  = "FLOAT" // 123.45
+	IDENT     = "IDENT" // add, foobar, x, y, ...
+	INT       = "INT"   // 123456
+	STRING    = "STRING"
+	FLOAT     = "FLOAT"     // 123.45
+	IMAGINARY = "IMAGINARY" // 2j
 
 	// Operators
-	ASSIGN   = "="
-	PLUS     = "+"
-	MINUS    = "-"
-	ASTERISK = "*"
-	SLASH    = "/"
-	BANG     = "!"
+	ASSIGN    = "="
+	PLUS      = "+"
+	MINUS     = "-"
+	ASTERISK  = "*"
+	POWER     = "**"
+	SLASH     = "/"
+	FLOORDIV  = "//"
+	AT        = "@"
+	BANG      = "!"
+	WALRUS    = ":="
+	ARROW     = "->"
+	DOT       = "."
+	ELLIPSIS  = "..."
+	LSHIFT    = "<<"
+	RSHIFT    = ">>"
+	PIPE      = "|"
+	AMPERSAND = "&"
+	MOD       = "%"
+	CARET     = "^"
+	TILDE     = "~"
 
 	EQ = "=="
 	NE = "!="
 	LT = "<"
 	GT = ">"
+	LE = "<="
+	GE = ">="
 
-	PIPE      = "|"
-	AMPERSAND = "&"
-	MOD       = "%"
-	CARET     = "^"
-	TILDE     = "~"
+	// Augmented assignment
+	PLUS_ASSIGN      = "+="
+	MINUS_ASSIGN     = "-="
+	ASTERISK_ASSIGN  = "*="
+	POWER_ASSIGN     = "**="
+	SLASH_ASSIGN     = "/="
+	FLOORDIV_ASSIGN  = "//="
+	AT_ASSIGN        = "@="
+	MOD_ASSIGN       = "%="
+	AMPERSAND_ASSIGN = "&="
+	PIPE_ASSIGN      = "|="
+	CARET_ASSIGN     = "^="
+	LSHIFT_ASSIGN    = "<<="
+	RSHIFT_ASSIGN    = ">>="
 
-	//boolean constants
-	TRUE  = "true"
-	FALSE = "false"
+	// Constants
+	TRUE  = "TRUE"
+	FALSE = "FALSE"
 	NONE  = "NONE"
 
 	// Delimiters
@@ -54,15 +129,17 @@ const (
 	LBRACKET = "["
 	RBRACKET = "]"
 
+	BACKTICK = "`" // Python 2 repr quotes
+
 	// Keywords
-	FUNCTION = "FUNCTION"
-	LET      = "LET"
 	IF       = "IF"
 	WHILE    = "WHILE"
 	FOR      = "FOR"
 	AND      = "AND"
 	AS       = "AS"
 	ASSERT   = "ASSERT"
+	ASYNC    = "ASYNC"
+	AWAIT    = "AWAIT"
 	BREAK    = "BREAK"
 	CLASS    = "CLASS"
 	CONTINUE = "CONTINUE"
@@ -89,32 +166,43 @@ const (
 	TRY      = "TRY"
 	WITH     = "WITH"
 	YIELD    = "YIELD"
-	DEDENT   = "DEDENT"
 	COLON    = ":"
+
+	// Soft keywords
+	MATCH      = "MATCH"
+	CASE       = "CASE"
+	TYPE       = "TYPE"
+	UNDERSCORE = "UNDERSCORE"
+
+	// Layout
+	NEWLINE = "NEWLINE"
+	INDENT  = "INDENT"
+	DEDENT  = "DEDENT"
 )
 
+// Keywords holds the reserved words of Python 3.12.
 var Keywords = map[string]TokenType{
-	"fn":       FUNCTION,
-	"let":      LET,
-	"if":       IF,
-	"while":    WHILE,
-	"for":      FOR,
-	"else":     ELSE,
-	"return":   RETURN,
+	"False":    FALSE,
+	"None":     NONE,
+	"True":     TRUE,
 	"and":      AND,
 	"as":       AS,
 	"assert":   ASSERT,
+	"async":    ASYNC,
+	"await":    AWAIT,
 	"break":    BREAK,
 	"class":    CLASS,
 	"continue": CONTINUE,
 	"def":      DEF,
 	"del":      DEL,
 	"elif":     ELIF,
+	"else":     ELSE,
 	"except":   EXCEPT,
-	"exec":     EXEC,
 	"finally":  FINALLY,
+	"for":      FOR,
 	"from":     FROM,
 	"global":   GLOBAL,
+	"if":       IF,
 	"import":   IMPORT,
 	"in":       IN,
 	"is":       IS,
@@ -123,14 +211,114 @@ var Keywords = map[string]TokenType{
 	"not":      NOT,
 	"or":       OR,
 	"pass":     PASS,
-	"print":    PRINT,
 	"raise":    RAISE,
+	"return":   RETURN,
 	"try":      TRY,
+	"while":    WHILE,
 	"with":     WITH,
 	"yield":    YIELD,
 }
 
+// Python2Keywords holds the reserved words of Python 2 that are ordinary
+// names in Python 3. True, False and None are kept as keywords in Python 2
+// mode too, since code that rebinds them is not worth supporting.
+var Python2Keywords = map[string]TokenType{
+	"exec":  EXEC,
+	"print": PRINT,
+}
+
+// python3Only holds the keywords that Python 2 does not reserve.
+var python3Only = map[string]bool{
+	"async":    true,
+	"await":    true,
+	"nonlocal": true,
+}
+
+// SoftKeywords holds the words that are keywords only in particular
+// contexts and ordinary names everywhere else. The lexer recognizes match,
+// case and type at the start of a statement; "_" is a keyword only inside a
+// case pattern and is left to the parser.
+var SoftKeywords = map[string]TokenType{
+	"match": MATCH,
+	"case":  CASE,
+	"type":  TYPE,
+	"_":     UNDERSCORE,
+}
+
+// Version selects the Python language version being scanned.
+type Version int
+
+const (
+	Python3 Version = iota // Python 3.12, the default
+	Python2
+)
+
+// Operators maps the spelling of every operator and non-bracket delimiter to
+// its token type. The lexer matches the longest spelling first.
+var Operators = map[string]TokenType{
+	"=":   ASSIGN,
+	"+":   PLUS,
+	"-":   MINUS,
+	"*":   ASTERISK,
+	"**":  POWER,
+	"/":   SLASH,
+	"//":  FLOORDIV,
+	"@":   AT,
+	"!":   BANG,
+	":=":  WALRUS,
+	"->":  ARROW,
+	".":   DOT,
+	"...": ELLIPSIS,
+	"<<":  LSHIFT,
+	">>":  RSHIFT,
+	"|":   PIPE,
+	"&":   AMPERSAND,
+	"%":   MOD,
+	"^":   CARET,
+	"~":   TILDE,
+	"==":  EQ,
+	"!=":  NE,
+	"<":   LT,
+	">":   GT,
+	"<=":  LE,
+	">=":  GE,
+	"+=":  PLUS_ASSIGN,
+	"-=":  MINUS_ASSIGN,
+	"*=":  ASTERISK_ASSIGN,
+	"**=": POWER_ASSIGN,
+	"/=":  SLASH_ASSIGN,
+	"//=": FLOORDIV_ASSIGN,
+	"@=":  AT_ASSIGN,
+	"%=":  MOD_ASSIGN,
+	"&=":  AMPERSAND_ASSIGN,
+	"|=":  PIPE_ASSIGN,
+	"^=":  CARET_ASSIGN,
+	"<<=": LSHIFT_ASSIGN,
+	">>=": RSHIFT_ASSIGN,
+	",":   COMMA,
+	";":   SEMICOLON,
+	":":   COLON,
+}
+
+// MaxOperatorLen is the length of the longest spelling in Operators.
+const MaxOperatorLen = 3
+
+// LookupIdent returns the keyword type of ident in Python 3, or IDENT.
 func LookupIdent(ident string) TokenType {
+	return LookupIdentVersion(ident, Python3)
+}
+
+// LookupIdentVersion returns the keyword type of ident in the given Python
+// version, or IDENT. Soft keywords are always reported as IDENT.
+func LookupIdentVersion(ident string, v Version) TokenType {
+	if v == Python2 {
+		if tok, ok := Python2Keywords[ident]; ok {
+			return tok
+		}
+		if python3Only[ident] {
+			return IDENT
+		}
+	}
 	if tok, ok := Keywords[ident]; ok {
 		return tok
 	}